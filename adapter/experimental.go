@@ -9,8 +9,12 @@ import (
 
 	"github.com/sagernet/sing-box/common/urltest"
 	"github.com/sagernet/sing-dns"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
 	"github.com/sagernet/sing/common/varbin"
+
+	mdns "github.com/miekg/dns"
 )
 
 type ClashServer interface {
@@ -21,6 +25,32 @@ type ClashServer interface {
 	HistoryStorage() *urltest.HistoryStorage
 	RoutedConnection(ctx context.Context, conn net.Conn, metadata InboundContext, matchedRule Rule) (net.Conn, Tracker)
 	RoutedPacketConnection(ctx context.Context, conn N.PacketConn, metadata InboundContext, matchedRule Rule) (N.PacketConn, Tracker)
+	DNSQueried(event DNSQueryEvent)
+}
+
+// DNSQueryEvent describes a DNS query handled by the router,
+// including queries answered from the cache.
+type DNSQueryEvent struct {
+	Time       time.Time
+	Duration   time.Duration
+	Inbound    string
+	Source     M.Socksaddr
+	Domain     string
+	QueryTypes []uint16
+	Rule       DNSRule
+	Transport  string
+	Blocked    bool
+	Cached     bool
+	RCode      int
+	Answers    []string
+	Error      error
+}
+
+type MetricsServer interface {
+	Service
+	RoutedConnection(ctx context.Context, conn net.Conn, metadata InboundContext, matchedRule Rule, chain []Outbound) (net.Conn, Tracker)
+	RoutedPacketConnection(ctx context.Context, conn N.PacketConn, metadata InboundContext, matchedRule Rule, chain []Outbound) (N.PacketConn, Tracker)
+	DNSExchanged(server string, cached bool, duration time.Duration, err error)
 }
 
 type CacheFile interface {
@@ -29,10 +59,15 @@ type CacheFile interface {
 
 	StoreFakeIP() bool
 	FakeIPStorage
+	NamedFakeIPStorage(name string) FakeIPStorage
 
 	StoreRDRC() bool
 	dns.RDRCStore
 
+	StoreDNS() bool
+	LoadDNSCache(transportName string, qName string, qType uint16) (response *mdns.Msg, expireAt time.Time, loaded bool)
+	SaveDNSCacheAsync(transportName string, qName string, qType uint16, response *mdns.Msg, expireAt time.Time, logger logger.Logger)
+
 	LoadMode() string
 	StoreMode(mode string) error
 	LoadSelected(group string) string
@@ -41,6 +76,8 @@ type CacheFile interface {
 	StoreGroupExpand(group string, expand bool) error
 	LoadRuleSet(tag string) *SavedRuleSet
 	SaveRuleSet(tag string, set *SavedRuleSet) error
+	LoadUserTraffic(inbound string) map[string]uint64
+	StoreUserTraffic(inbound string, traffic map[string]uint64) error
 }
 
 type SavedRuleSet struct {
@@ -2,6 +2,7 @@ package adapter
 
 import (
 	"net/netip"
+	"time"
 
 	"github.com/sagernet/sing-dns"
 	"github.com/sagernet/sing/common/logger"
@@ -12,9 +13,20 @@ type FakeIPStore interface {
 	Contains(address netip.Addr) bool
 	Create(domain string, isIPv6 bool) (netip.Addr, error)
 	Lookup(address netip.Addr) (string, bool)
+	Acquire(address netip.Addr) (release func())
+	Usage() []FakeIPUsage
 	Reset() error
 }
 
+type FakeIPUsage struct {
+	Name              string
+	Range             netip.Prefix
+	Size              uint64
+	Used              int
+	Active            int
+	LeastRecentlyUsed time.Time
+}
+
 type FakeIPStorage interface {
 	FakeIPMetadata() *FakeIPMetadata
 	FakeIPSaveMetadata(metadata *FakeIPMetadata) error
@@ -23,6 +35,7 @@ type FakeIPStorage interface {
 	FakeIPStoreAsync(address netip.Addr, domain string, logger logger.Logger)
 	FakeIPLoad(address netip.Addr) (string, bool)
 	FakeIPLoadDomain(domain string, isIPv6 bool) (netip.Addr, bool)
+	FakeIPAddresses() []netip.Addr
 	FakeIPReset() error
 }
 
@@ -24,6 +24,37 @@ type InjectableInbound interface {
 	NewPacketConnection(ctx context.Context, conn N.PacketConn, metadata InboundContext) error
 }
 
+// PortUnreachableInbound is implemented by inbounds that can answer a rejected
+// UDP packet connection with ICMP port unreachable, such as tun.
+type PortUnreachableInbound interface {
+	Inbound
+	WritePortUnreachable(source netip.AddrPort, destination netip.AddrPort) error
+}
+
+type ManagedUserInbound interface {
+	Inbound
+	Users() []InboundUser
+	AddUsers(users []InboundUser) error
+	RemoveUsers(names []string) error
+	ReplaceUsers(users []InboundUser) error
+}
+
+type InboundUser struct {
+	Name     string `json:"name"`
+	UUID     string `json:"uuid,omitempty"`
+	Password string `json:"password,omitempty"`
+	AlterId  int    `json:"alter_id,omitempty"`
+	Flow     string `json:"flow,omitempty"`
+	option.UserLimitOptions
+}
+
+// Redacted returns the user without its UUID and password, for listing users through APIs.
+func (u InboundUser) Redacted() InboundUser {
+	u.UUID = ""
+	u.Password = ""
+	return u
+}
+
 type InboundContext struct {
 	Inbound     string
 	InboundType string
@@ -36,6 +67,11 @@ type InboundContext struct {
 	User        string
 	Outbound    string
 
+	// sniffed from TLS ClientHello
+
+	ClientALPN        []string
+	ClientFingerprint string
+
 	// cache
 
 	InboundDetour        string
@@ -48,6 +84,7 @@ type InboundContext struct {
 	ProcessInfo          *process.Info
 	QueryType            uint16
 	FakeIP               bool
+	DNSSECStatus         string
 
 	// rule cache
 
@@ -4,6 +4,7 @@ import (
 	"context"
 	"net"
 
+	"github.com/sagernet/sing-box/common/ratelimit"
 	N "github.com/sagernet/sing/common/network"
 )
 
@@ -18,3 +19,18 @@ type Outbound interface {
 	NewConnection(ctx context.Context, conn net.Conn, metadata InboundContext) error
 	NewPacketConnection(ctx context.Context, conn N.PacketConn, metadata InboundContext) error
 }
+
+// BandwidthLimitedOutbound is an outbound with a bandwidth limit shared by
+// all connections routed to it. BandwidthLimiter returns nil if not limited.
+type BandwidthLimitedOutbound interface {
+	Outbound
+	BandwidthLimiter() *ratelimit.Limiter
+}
+
+// PerConnectionOutboundGroup is a group selecting a member for each connection,
+// Now only returns the last selected member. Bandwidth limits of the selected
+// member are applied by the group when dialing.
+type PerConnectionOutboundGroup interface {
+	OutboundGroup
+	SelectPerConnection()
+}
@@ -0,0 +1,23 @@
+package adapter
+
+import (
+	"context"
+	"time"
+
+	"github.com/sagernet/sing/common/x/list"
+)
+
+type OutboundProvider interface {
+	Service
+	PostStarter
+	Tag() string
+	Outbounds() []Outbound
+	Outbound(tag string) (Outbound, bool)
+	UpdatedAt() time.Time
+	Update(ctx context.Context) error
+	HealthCheck(ctx context.Context) (map[string]uint16, error)
+	RegisterCallback(callback OutboundProviderUpdateCallback) *list.Element[OutboundProviderUpdateCallback]
+	UnregisterCallback(element *list.Element[OutboundProviderUpdateCallback])
+}
+
+type OutboundProviderUpdateCallback func(it OutboundProvider)
@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 	"net/netip"
+	"time"
 
 	"github.com/sagernet/sing-box/common/geoip"
 	"github.com/sagernet/sing-dns"
@@ -23,17 +24,24 @@ type Router interface {
 	PostStarter
 	Cleanup() error
 
+	Inbound(tag string) (Inbound, bool)
+
 	Outbounds() []Outbound
 	Outbound(tag string) (Outbound, bool)
 	DefaultOutbound(network string) (Outbound, error)
 
+	OutboundProviders() []OutboundProvider
+	OutboundProvider(tag string) (OutboundProvider, bool)
+
 	FakeIPStore() FakeIPStore
+	FakeIPStores() []FakeIPStore
 
 	ConnectionRouter
 
 	GeoIPReader() *geoip.Reader
 	LoadGeosite(code string) (Rule, error)
 
+	RuleSets() []RuleSet
 	RuleSet(tag string) (RuleSet, bool)
 
 	NeedWIFIState() bool
@@ -63,6 +71,9 @@ type Router interface {
 	V2RayServer() V2RayServer
 	SetV2RayServer(server V2RayServer)
 
+	MetricsServer() MetricsServer
+	SetMetricsServer(server MetricsServer)
+
 	ResetNetwork() error
 }
 
@@ -85,6 +96,12 @@ type Rule interface {
 	Type() string
 	UpdateGeosite() error
 	Outbound() string
+	Action() RuleAction
+}
+
+type RuleAction interface {
+	Type() string
+	String() string
 }
 
 type DNSRule interface {
@@ -92,12 +109,20 @@ type DNSRule interface {
 	DisableCache() bool
 	RewriteTTL() *uint32
 	ClientSubnet() *netip.Prefix
+	Predefined() dns.Transport
+	DNSSEC() bool
 	WithAddressLimit() bool
 	MatchAddressLimit(metadata *InboundContext) bool
 }
 
 type RuleSet interface {
 	Name() string
+	Type() string
+	Format() string
+	Behavior() string
+	RuleCount() int
+	UpdatedAt() time.Time
+	Update(ctx context.Context) error
 	StartContext(ctx context.Context, startContext RuleSetStartContext) error
 	PostStart() error
 	Metadata() RuleSetMetadata
@@ -1,6 +1,12 @@
 package adapter
 
+import "github.com/sagernet/sing-box/option"
+
 type Service interface {
 	Start() error
 	Close() error
 }
+
+type ConfigReloader interface {
+	Reload(options option.Options) error
+}
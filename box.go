@@ -6,14 +6,17 @@ import (
 	"io"
 	"os"
 	"runtime/debug"
+	"sync"
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/common/taskmonitor"
+	"github.com/sagernet/sing-box/common/urltest"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/experimental"
 	"github.com/sagernet/sing-box/experimental/cachefile"
 	"github.com/sagernet/sing-box/experimental/libbox/platform"
+	"github.com/sagernet/sing-box/experimental/metrics"
 	"github.com/sagernet/sing-box/inbound"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
@@ -29,16 +32,21 @@ import (
 var _ adapter.Service = (*Box)(nil)
 
 type Box struct {
-	createdAt    time.Time
-	router       adapter.Router
-	inbounds     []adapter.Inbound
-	outbounds    []adapter.Outbound
-	logFactory   log.Factory
-	logger       log.ContextLogger
-	preServices1 map[string]adapter.Service
-	preServices2 map[string]adapter.Service
-	postServices map[string]adapter.Service
-	done         chan struct{}
+	createdAt         time.Time
+	ctx               context.Context
+	options           option.Options
+	platformInterface platform.Interface
+	router            *route.Router
+	inbounds          []adapter.Inbound
+	outbounds         []adapter.Outbound
+	providers         []adapter.OutboundProvider
+	logFactory        log.Factory
+	logger            log.ContextLogger
+	preServices1      map[string]adapter.Service
+	preServices2      map[string]adapter.Service
+	postServices      map[string]adapter.Service
+	reloadAccess      sync.Mutex
+	done              chan struct{}
 }
 
 type Options struct {
@@ -61,6 +69,7 @@ func New(options Options) (*Box, error) {
 	var needCacheFile bool
 	var needClashAPI bool
 	var needV2RayAPI bool
+	var needMetrics bool
 	if experimentalOptions.CacheFile != nil && experimentalOptions.CacheFile.Enabled || options.PlatformLogWriter != nil {
 		needCacheFile = true
 	}
@@ -70,6 +79,12 @@ func New(options Options) (*Box, error) {
 	if experimentalOptions.V2RayAPI != nil && experimentalOptions.V2RayAPI.Listen != "" {
 		needV2RayAPI = true
 	}
+	if experimentalOptions.Metrics != nil && experimentalOptions.Metrics.Listen != "" {
+		needMetrics = true
+		if service.PtrFromContext[urltest.HistoryStorage](ctx) == nil {
+			ctx = service.ContextWithPtr(ctx, urltest.NewHistoryStorage())
+		}
+	}
 	var defaultLogWriter io.Writer
 	if options.PlatformInterface != nil {
 		defaultLogWriter = io.Discard
@@ -139,7 +154,16 @@ func New(options Options) (*Box, error) {
 		}
 		outbounds = append(outbounds, out)
 	}
-	err = router.Initialize(inbounds, outbounds, func() adapter.Outbound {
+	providers := make([]adapter.OutboundProvider, 0, len(options.Providers))
+	for i, providerOptions := range options.Providers {
+		var provider adapter.OutboundProvider
+		provider, err = outbound.NewProvider(ctx, router, logFactory, providerOptions)
+		if err != nil {
+			return nil, E.Cause(err, "parse provider[", i, "]")
+		}
+		providers = append(providers, provider)
+	}
+	err = router.Initialize(inbounds, outbounds, providers, func() adapter.Outbound {
 		out, oErr := outbound.New(ctx, router, logFactory.NewLogger("outbound/direct"), "direct", option.Outbound{Type: "direct", Tag: "default"})
 		common.Must(oErr)
 		outbounds = append(outbounds, out)
@@ -176,25 +200,39 @@ func New(options Options) (*Box, error) {
 		preServices2["clash api"] = clashServer
 	}
 	if needV2RayAPI {
-		v2rayServer, err := experimental.NewV2RayServer(logFactory.NewLogger("v2ray-api"), common.PtrValueOrDefault(experimentalOptions.V2RayAPI))
+		v2rayServer, err := experimental.NewV2RayServer(router, logFactory.NewLogger("v2ray-api"), common.PtrValueOrDefault(experimentalOptions.V2RayAPI))
 		if err != nil {
 			return nil, E.Cause(err, "create v2ray api server")
 		}
 		router.SetV2RayServer(v2rayServer)
 		preServices2["v2ray api"] = v2rayServer
 	}
-	return &Box{
-		router:       router,
-		inbounds:     inbounds,
-		outbounds:    outbounds,
-		createdAt:    createdAt,
-		logFactory:   logFactory,
-		logger:       logFactory.Logger(),
-		preServices1: preServices1,
-		preServices2: preServices2,
-		postServices: postServices,
-		done:         make(chan struct{}),
-	}, nil
+	if needMetrics {
+		metricsServer, err := metrics.NewServer(ctx, router, logFactory.NewLogger("metrics"), common.PtrValueOrDefault(experimentalOptions.Metrics))
+		if err != nil {
+			return nil, E.Cause(err, "create metrics server")
+		}
+		router.SetMetricsServer(metricsServer)
+		preServices2["metrics"] = metricsServer
+	}
+	box := &Box{
+		ctx:               ctx,
+		options:           options.Options,
+		platformInterface: options.PlatformInterface,
+		router:            router,
+		inbounds:          inbounds,
+		outbounds:         outbounds,
+		providers:         providers,
+		createdAt:         createdAt,
+		logFactory:        logFactory,
+		logger:            logFactory.Logger(),
+		preServices1:      preServices1,
+		preServices2:      preServices2,
+		postServices:      postServices,
+		done:              make(chan struct{}),
+	}
+	service.MustRegister[adapter.ConfigReloader](ctx, box)
+	return box, nil
 }
 
 func (s *Box) PreStart() error {
@@ -267,6 +305,14 @@ func (s *Box) preStart() error {
 	if err != nil {
 		return E.Cause(err, "pre-start router")
 	}
+	for _, provider := range s.providers {
+		monitor.Start("initialize provider[", provider.Tag(), "]")
+		err = provider.Start()
+		monitor.Finish()
+		if err != nil {
+			return E.Cause(err, "initialize provider[", provider.Tag(), "]")
+		}
+	}
 	err = s.startOutbounds()
 	if err != nil {
 		return err
@@ -317,6 +363,12 @@ func (s *Box) postStart() error {
 			return E.Cause(err, "start ", serviceName)
 		}
 	}
+	for _, provider := range s.providers {
+		err := provider.PostStart()
+		if err != nil {
+			return E.Cause(err, "post-start provider[", provider.Tag(), "]")
+		}
+	}
 	// TODO: reorganize ALL start order
 	for _, out := range s.outbounds {
 		if lateOutbound, isLateOutbound := out.(adapter.PostStarter); isLateOutbound {
@@ -342,6 +394,8 @@ func (s *Box) postStart() error {
 }
 
 func (s *Box) Close() error {
+	s.reloadAccess.Lock()
+	defer s.reloadAccess.Unlock()
 	select {
 	case <-s.done:
 		return os.ErrClosed
@@ -371,6 +425,13 @@ func (s *Box) Close() error {
 		})
 		monitor.Finish()
 	}
+	for _, provider := range s.providers {
+		monitor.Start("close provider[", provider.Tag(), "]")
+		errors = E.Append(errors, provider.Close(), func(err error) error {
+			return E.Cause(err, "close provider[", provider.Tag(), "]")
+		})
+		monitor.Finish()
+	}
 	monitor.Start("close router")
 	if err := common.Close(s.router); err != nil {
 		errors = E.Append(errors, err, func(err error) error {
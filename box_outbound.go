@@ -12,10 +12,14 @@ import (
 )
 
 func (s *Box) startOutbounds() error {
+	return s.startOutboundsExcept(s.outbounds, nil)
+}
+
+func (s *Box) startOutboundsExcept(outboundList []adapter.Outbound, startedOutbounds map[adapter.Outbound]bool) error {
 	monitor := taskmonitor.New(s.logger, C.StartTimeout)
 	outboundTags := make(map[adapter.Outbound]string)
 	outbounds := make(map[string]adapter.Outbound)
-	for i, outboundToStart := range s.outbounds {
+	for i, outboundToStart := range outboundList {
 		var outboundTag string
 		if outboundToStart.Tag() == "" {
 			outboundTag = F.ToString(i)
@@ -29,10 +33,13 @@ func (s *Box) startOutbounds() error {
 		outbounds[outboundTag] = outboundToStart
 	}
 	started := make(map[string]bool)
+	for outboundStarted := range startedOutbounds {
+		started[outboundTags[outboundStarted]] = true
+	}
 	for {
 		canContinue := false
 	startOne:
-		for _, outboundToStart := range s.outbounds {
+		for _, outboundToStart := range outboundList {
 			outboundTag := outboundTags[outboundToStart]
 			if started[outboundTag] {
 				continue
@@ -56,13 +63,13 @@ func (s *Box) startOutbounds() error {
 				}
 			}
 		}
-		if len(started) == len(s.outbounds) {
+		if len(started) == len(outboundList) {
 			break
 		}
 		if canContinue {
 			continue
 		}
-		currentOutbound := common.Find(s.outbounds, func(it adapter.Outbound) bool {
+		currentOutbound := common.Find(outboundList, func(it adapter.Outbound) bool {
 			return !started[outboundTags[it]]
 		})
 		var lintOutbound func(oTree []string, oCurrent adapter.Outbound) error
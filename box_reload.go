@@ -0,0 +1,353 @@
+package box
+
+import (
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/inbound"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-box/outbound"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+)
+
+// Reload applies new options to a started box. Inbounds and outbounds whose options
+// and dependencies did not change are kept along with their connections, while router
+// rules, DNS rules and rule-sets are replaced. Changes that cannot be applied in place
+// are rejected with an error, in which case the caller should restart the box. If a new
+// inbound or outbound fails to start, the old configuration keeps serving.
+func (s *Box) Reload(options option.Options) error {
+	s.reloadAccess.Lock()
+	defer s.reloadAccess.Unlock()
+	select {
+	case <-s.done:
+		return E.New("box closed")
+	default:
+	}
+	err := checkReload(s.options, options)
+	if err != nil {
+		return err
+	}
+
+	oldOutboundOptions := make(map[string]option.Outbound)
+	oldOutbounds := make(map[string]adapter.Outbound)
+	for i, outboundOptions := range s.options.Outbounds {
+		tag := outboundTag(i, outboundOptions)
+		oldOutboundOptions[tag] = outboundOptions
+		oldOutbounds[tag] = s.outbounds[i]
+	}
+	newOutboundOptions := make(map[string]option.Outbound)
+	for i, outboundOptions := range options.Outbounds {
+		newOutboundOptions[outboundTag(i, outboundOptions)] = outboundOptions
+	}
+	changedOutbounds := make(map[string]bool)
+	for tag, outboundOptions := range oldOutboundOptions {
+		newOptions, loaded := newOutboundOptions[tag]
+		if !loaded || !option.Equal(&outboundOptions, &newOptions) {
+			changedOutbounds[tag] = true
+		}
+	}
+	for {
+		var dependencyChanged bool
+		for tag, oldOutbound := range oldOutbounds {
+			if changedOutbounds[tag] {
+				continue
+			}
+			if common.Any(oldOutbound.Dependencies(), func(it string) bool {
+				return changedOutbounds[it]
+			}) {
+				changedOutbounds[tag] = true
+				dependencyChanged = true
+			}
+		}
+		if !dependencyChanged {
+			break
+		}
+	}
+	err = checkDetours(options, changedOutbounds)
+	if err != nil {
+		return err
+	}
+
+	var (
+		inbounds          = make([]adapter.Inbound, 0, len(options.Inbounds))
+		newInbounds       []adapter.Inbound
+		newInboundIndexes []int
+		keptInbounds      = make(map[adapter.Inbound]bool)
+		outbounds         = make([]adapter.Outbound, 0, len(options.Outbounds))
+		newOutbounds      []adapter.Outbound
+		keptOutbounds     = make(map[adapter.Outbound]bool)
+		oldInboundByTag   = make(map[string]int)
+		defaultOutbound   adapter.Outbound
+		closeNewOnFailed  = func() {
+			closeInbounds(newInbounds)
+			for _, out := range newOutbounds {
+				common.Close(out)
+			}
+		}
+	)
+	for i, inboundOptions := range s.options.Inbounds {
+		oldInboundByTag[inboundTag(i, inboundOptions)] = i
+	}
+	for i, inboundOptions := range options.Inbounds {
+		tag := inboundTag(i, inboundOptions)
+		if oldIndex, loaded := oldInboundByTag[tag]; loaded && option.Equal(&s.options.Inbounds[oldIndex], &inboundOptions) {
+			inbounds = append(inbounds, s.inbounds[oldIndex])
+			keptInbounds[s.inbounds[oldIndex]] = true
+			continue
+		}
+		in, err := s.newInbound(tag, inboundOptions)
+		if err != nil {
+			closeNewOnFailed()
+			return E.Cause(err, "parse inbound[", i, "]")
+		}
+		newInboundIndexes = append(newInboundIndexes, i)
+		inbounds = append(inbounds, in)
+		newInbounds = append(newInbounds, in)
+	}
+	var replacedInbounds []int
+	for i, in := range s.inbounds {
+		if !keptInbounds[in] {
+			replacedInbounds = append(replacedInbounds, i)
+		}
+	}
+	for i, outboundOptions := range options.Outbounds {
+		tag := outboundTag(i, outboundOptions)
+		if oldOutbound, loaded := oldOutbounds[tag]; loaded && !changedOutbounds[tag] {
+			outbounds = append(outbounds, oldOutbound)
+			keptOutbounds[oldOutbound] = true
+			continue
+		}
+		out, err := outbound.New(
+			s.ctx,
+			s.router,
+			s.logFactory.NewLogger(F.ToString("outbound/", outboundOptions.Type, "[", tag, "]")),
+			tag,
+			outboundOptions)
+		if err != nil {
+			closeNewOnFailed()
+			return E.Cause(err, "parse outbound[", i, "]")
+		}
+		outbounds = append(outbounds, out)
+		newOutbounds = append(newOutbounds, out)
+	}
+	if len(s.outbounds) > len(s.options.Outbounds) {
+		defaultOutbound = s.outbounds[len(s.options.Outbounds)]
+	}
+
+	oldOutboundList := s.outbounds
+	var oldInboundsClosed bool
+	err = s.router.Reload(
+		common.PtrValueOrDefault(options.Route),
+		common.PtrValueOrDefault(options.DNS),
+		inbounds,
+		outbounds,
+		func() adapter.Outbound {
+			if defaultOutbound == nil {
+				out, oErr := outbound.New(s.ctx, s.router, s.logFactory.NewLogger("outbound/direct"), "direct", option.Outbound{Type: "direct", Tag: "default"})
+				common.Must(oErr)
+				defaultOutbound = out
+				newOutbounds = append(newOutbounds, out)
+			} else {
+				keptOutbounds[defaultOutbound] = true
+			}
+			outbounds = append(outbounds, defaultOutbound)
+			return defaultOutbound
+		},
+		func() error {
+			err := s.startOutboundsExcept(outbounds, keptOutbounds)
+			if err != nil {
+				return err
+			}
+			for _, out := range newOutbounds {
+				if lateOutbound, isLateOutbound := out.(adapter.PostStarter); isLateOutbound {
+					err = lateOutbound.PostStart()
+					if err != nil {
+						return E.Cause(err, "post-start outbound/", out.Tag())
+					}
+				}
+			}
+			// New inbounds start while the old ones still serve, so nothing is
+			// swapped in if one of them fails.
+			err = startInbounds(newInbounds)
+			if err == nil || len(replacedInbounds) == 0 {
+				return err
+			}
+			// A new inbound may listen on a port still held by the inbound it replaces:
+			// close the replaced ones and start the new ones again. Closed inbounds can not
+			// be started again, so both are recreated, the old ones only if this fails too.
+			closeInbounds(newInbounds)
+			for _, index := range replacedInbounds {
+				s.inbounds[index].Close()
+			}
+			oldInboundsClosed = true
+			newInbounds = newInbounds[:0]
+			for _, index := range newInboundIndexes {
+				in, err := s.newInbound(inboundTag(index, options.Inbounds[index]), options.Inbounds[index])
+				if err != nil {
+					return E.Cause(err, "parse inbound[", index, "]")
+				}
+				inbounds[index] = in
+				newInbounds = append(newInbounds, in)
+			}
+			return startInbounds(newInbounds)
+		},
+	)
+	if err != nil {
+		closeNewOnFailed()
+		if oldInboundsClosed {
+			s.restartInbounds(replacedInbounds)
+		}
+		return E.Cause(err, "reload router")
+	}
+	if oldInboundsClosed {
+		s.router.UpdateInbounds(inbounds)
+	}
+	s.outbounds = outbounds
+	for _, out := range oldOutboundList {
+		if keptOutbounds[out] {
+			continue
+		}
+		err = common.Close(out)
+		if err != nil {
+			s.logger.Error(E.Cause(err, "close outbound/", out.Type(), "[", out.Tag(), "]"))
+		}
+	}
+	if !oldInboundsClosed {
+		for _, index := range replacedInbounds {
+			in := s.inbounds[index]
+			err = in.Close()
+			if err != nil {
+				s.logger.Error(E.Cause(err, "close inbound/", in.Type(), "[", in.Tag(), "]"))
+			}
+		}
+	}
+	s.inbounds = inbounds
+	s.options = options
+	s.logger.Info("sing-box reloaded: ",
+		len(newInbounds), " inbound(s) and ", len(newOutbounds), " outbound(s) replaced")
+	return nil
+}
+
+func (s *Box) newInbound(tag string, options option.Inbound) (adapter.Inbound, error) {
+	return inbound.New(
+		s.ctx,
+		s.router,
+		s.logFactory.NewLogger(F.ToString("inbound/", options.Type, "[", tag, "]")),
+		tag,
+		options,
+		s.platformInterface,
+	)
+}
+
+// restartInbounds recreates and starts the inbounds at indexes from the current options,
+// after they were closed by a reload that failed.
+func (s *Box) restartInbounds(indexes []int) {
+	var restarted []adapter.Inbound
+	for _, index := range indexes {
+		inboundOptions := s.options.Inbounds[index]
+		in, err := s.newInbound(inboundTag(index, inboundOptions), inboundOptions)
+		if err != nil {
+			s.logger.Error(E.Cause(err, "parse inbound[", index, "]"))
+			continue
+		}
+		s.inbounds[index] = in
+		restarted = append(restarted, in)
+	}
+	err := startInbounds(restarted)
+	if err != nil {
+		s.logger.Error(E.Cause(err, "restart inbounds"))
+	}
+	s.router.UpdateInbounds(s.inbounds)
+}
+
+func startInbounds(inbounds []adapter.Inbound) error {
+	for _, in := range inbounds {
+		err := in.Start()
+		if err != nil {
+			return E.Cause(err, "initialize inbound/", in.Type(), "[", in.Tag(), "]")
+		}
+	}
+	for _, in := range inbounds {
+		if lateInbound, isLateInbound := in.(adapter.PostStarter); isLateInbound {
+			err := lateInbound.PostStart()
+			if err != nil {
+				return E.Cause(err, "post-start inbound/", in.Tag())
+			}
+		}
+	}
+	return nil
+}
+
+func closeInbounds(inbounds []adapter.Inbound) {
+	for _, in := range inbounds {
+		in.Close()
+	}
+}
+
+func checkReload(oldOptions option.Options, newOptions option.Options) error {
+	if !option.Equal(oldOptions.Log, newOptions.Log) {
+		return E.New("log options changed")
+	}
+	if !option.Equal(oldOptions.NTP, newOptions.NTP) {
+		return E.New("NTP options changed")
+	}
+	if !option.Equal(oldOptions.Experimental, newOptions.Experimental) {
+		return E.New("experimental options changed")
+	}
+	if !option.Equal(oldOptions.Providers, newOptions.Providers) {
+		return E.New("providers changed")
+	}
+	oldDNSOptions := common.PtrValueOrDefault(oldOptions.DNS)
+	newDNSOptions := common.PtrValueOrDefault(newOptions.DNS)
+	oldDNSOptions.Rules = nil
+	newDNSOptions.Rules = nil
+	if !option.Equal(&oldDNSOptions, &newDNSOptions) {
+		return E.New("DNS options other than rules changed")
+	}
+	oldRouteOptions := common.PtrValueOrDefault(oldOptions.Route)
+	newRouteOptions := common.PtrValueOrDefault(newOptions.Route)
+	oldRouteOptions.Rules, oldRouteOptions.RuleSet, oldRouteOptions.Final = nil, nil, ""
+	newRouteOptions.Rules, newRouteOptions.RuleSet, newRouteOptions.Final = nil, nil, ""
+	if !option.Equal(&oldRouteOptions, &newRouteOptions) {
+		return E.New("route options other than rules, rule-sets and final changed")
+	}
+	return nil
+}
+
+// checkDetours rejects changes to outbounds that are held by DNS servers, the NTP service,
+// providers or remote rule-sets, since those resolve their detour only once.
+func checkDetours(options option.Options, changedOutbounds map[string]bool) error {
+	for _, server := range common.PtrValueOrDefault(options.DNS).Servers {
+		if changedOutbounds[server.Detour] {
+			return E.New("outbound ", server.Detour, " used by DNS server changed")
+		}
+	}
+	if ntpOptions := common.PtrValueOrDefault(options.NTP); changedOutbounds[ntpOptions.Detour] {
+		return E.New("outbound ", ntpOptions.Detour, " used by NTP changed")
+	}
+	for _, provider := range options.Providers {
+		if changedOutbounds[provider.DownloadDetour] {
+			return E.New("outbound ", provider.DownloadDetour, " used by provider changed")
+		}
+	}
+	for _, ruleSet := range common.PtrValueOrDefault(options.Route).RuleSet {
+		if changedOutbounds[ruleSet.RemoteOptions.DownloadDetour] {
+			return E.New("outbound ", ruleSet.RemoteOptions.DownloadDetour, " used by rule-set changed")
+		}
+	}
+	return nil
+}
+
+func inboundTag(index int, options option.Inbound) string {
+	if options.Tag != "" {
+		return options.Tag
+	}
+	return F.ToString(index)
+}
+
+func outboundTag(index int, options option.Outbound) string {
+	if options.Tag != "" {
+		return options.Tag
+	}
+	return F.ToString(index)
+}
@@ -0,0 +1,156 @@
+package box_test
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/sagernet/sing-box"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	F "github.com/sagernet/sing/common/format"
+	N "github.com/sagernet/sing/common/network"
+
+	"github.com/stretchr/testify/require"
+)
+
+func reloadTestOptions() option.Options {
+	return option.Options{
+		Log: &option.LogOptions{
+			Disabled: true,
+		},
+		Outbounds: []option.Outbound{
+			{Type: C.TypeDirect, Tag: "a"},
+			{Type: C.TypeDirect, Tag: "b"},
+		},
+		Route: &option.RouteOptions{
+			Final: "a",
+		},
+	}
+}
+
+func TestReloadFailureKeepsOldConfig(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	instance, err := box.New(box.Options{
+		Context: ctx,
+		Options: reloadTestOptions(),
+	})
+	require.NoError(t, err)
+	require.NoError(t, instance.Start())
+	defer instance.Close()
+	router := instance.Router()
+
+	options := reloadTestOptions()
+	options.Outbounds = append(options.Outbounds, option.Outbound{Type: C.TypeDirect, Tag: "c"})
+	options.Route.Final = "b"
+	options.Route.Rules = []option.Rule{{
+		Type: C.RuleTypeDefault,
+		DefaultOptions: option.DefaultRule{
+			RuleSet:  []string{"missing"},
+			Outbound: "c",
+		},
+	}}
+	require.Error(t, instance.Reload(options))
+
+	defaultOutbound, err := router.DefaultOutbound(N.NetworkTCP)
+	require.NoError(t, err)
+	require.Equal(t, "a", defaultOutbound.Tag())
+	_, loaded := router.Outbound("c")
+	require.False(t, loaded)
+	require.Empty(t, router.Rules())
+
+	options.Route.Rules = []option.Rule{{
+		Type: C.RuleTypeDefault,
+		DefaultOptions: option.DefaultRule{
+			Network:  []string{N.NetworkUDP},
+			Outbound: "c",
+		},
+	}}
+	require.NoError(t, instance.Reload(options))
+	defaultOutbound, err = router.DefaultOutbound(N.NetworkTCP)
+	require.NoError(t, err)
+	require.Equal(t, "b", defaultOutbound.Tag())
+	_, loaded = router.Outbound("c")
+	require.True(t, loaded)
+	require.Len(t, router.Rules(), 1)
+}
+
+func reloadTestInbound(tag string, port uint16) option.Inbound {
+	return option.Inbound{
+		Type: C.TypeMixed,
+		Tag:  tag,
+		MixedOptions: option.HTTPMixedInboundOptions{
+			ListenOptions: option.ListenOptions{
+				Listen:     option.NewListenAddress(netip.IPv4Unspecified()),
+				ListenPort: port,
+			},
+		},
+	}
+}
+
+func reloadTestPort(t *testing.T) uint16 {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	return uint16(listener.Addr().(*net.TCPAddr).Port)
+}
+
+func requireListening(t *testing.T, port uint16) {
+	conn, err := net.Dial("tcp", net.JoinHostPort("127.0.0.1", F.ToString(port)))
+	require.NoError(t, err)
+	conn.Close()
+}
+
+func TestReloadInboundStartFailureKeepsOldConfig(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	port := reloadTestPort(t)
+	options := reloadTestOptions()
+	options.Inbounds = []option.Inbound{reloadTestInbound("in-a", port)}
+	instance, err := box.New(box.Options{
+		Context: ctx,
+		Options: options,
+	})
+	require.NoError(t, err)
+	require.NoError(t, instance.Start())
+	defer instance.Close()
+	router := instance.Router()
+	oldInbound, loaded := router.Inbound("in-a")
+	require.True(t, loaded)
+
+	busyListener, err := net.Listen("tcp", "0.0.0.0:0")
+	require.NoError(t, err)
+	defer busyListener.Close()
+	options = reloadTestOptions()
+	changedInbound := reloadTestInbound("in-a", port)
+	changedInbound.MixedOptions.InboundOptions.SniffEnabled = true
+	options.Inbounds = []option.Inbound{
+		changedInbound,
+		reloadTestInbound("in-b", uint16(busyListener.Addr().(*net.TCPAddr).Port)),
+	}
+	options.Route.Final = "b"
+	require.Error(t, instance.Reload(options))
+
+	defaultOutbound, err := router.DefaultOutbound(N.NetworkTCP)
+	require.NoError(t, err)
+	require.Equal(t, "a", defaultOutbound.Tag())
+	_, loaded = router.Inbound("in-b")
+	require.False(t, loaded)
+	restoredInbound, loaded := router.Inbound("in-a")
+	require.True(t, loaded)
+	require.NotEqual(t, oldInbound, restoredInbound)
+	requireListening(t, port)
+
+	// The inbound replacing in-a on the same port starts once the old one is closed.
+	options.Inbounds = options.Inbounds[:1]
+	require.NoError(t, instance.Reload(options))
+	defaultOutbound, err = router.DefaultOutbound(N.NetworkTCP)
+	require.NoError(t, err)
+	require.Equal(t, "b", defaultOutbound.Tag())
+	newInbound, loaded := router.Inbound("in-a")
+	require.True(t, loaded)
+	require.NotEqual(t, restoredInbound, newInbound)
+	requireListening(t, port)
+}
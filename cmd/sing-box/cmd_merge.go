@@ -73,6 +73,10 @@ func mergePathResources(options *option.Options) error {
 		if err != nil {
 			return err
 		}
+		switch inbound.Type {
+		case C.TypeSSH:
+			inbound.SSHOptions = mergeSSHInboundOptions(inbound.SSHOptions)
+		}
 		if tlsOptions, containsTLSOptions := rawOptions.(option.InboundTLSOptionsWrapper); containsTLSOptions {
 			tlsOptions.ReplaceInboundTLSOptions(mergeTLSInboundOptions(tlsOptions.TakeInboundTLSOptions()))
 		}
@@ -147,6 +151,15 @@ func mergeSSHOutboundOptions(options option.SSHOutboundOptions) option.SSHOutbou
 	return options
 }
 
+func mergeSSHInboundOptions(options option.SSHInboundOptions) option.SSHInboundOptions {
+	if options.HostKeyPath != "" {
+		if content, err := os.ReadFile(os.ExpandEnv(options.HostKeyPath)); err == nil {
+			options.HostKey = trimStringArray(strings.Split(string(content), "\n"))
+		}
+	}
+	return options
+}
+
 func trimStringArray(array []string) []string {
 	return common.Filter(array, func(it string) bool {
 		return strings.TrimSpace(it) != ""
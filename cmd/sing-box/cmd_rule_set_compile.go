@@ -73,7 +73,7 @@ func compileRuleSet(sourcePath string) error {
 	if err != nil {
 		return err
 	}
-	err = srs.Write(outputFile, ruleSet)
+	err = srs.Write(outputFile, ruleSet, uint8(plainRuleSet.Version))
 	if err != nil {
 		outputFile.Close()
 		os.Remove(outputPath)
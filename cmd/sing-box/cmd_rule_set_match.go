@@ -6,6 +6,7 @@ import (
 	"os"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/rulelist"
 	"github.com/sagernet/sing-box/common/srs"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
@@ -68,12 +69,23 @@ func ruleSetMatch(sourcePath string, domain string) error {
 			return err
 		}
 	case C.RuleSetFormatBinary:
-		plainRuleSet, err = srs.Read(bytes.NewReader(content), false)
+		var compat option.PlainRuleSetCompat
+		compat, err = srs.Read(bytes.NewReader(content), false)
+		if err != nil {
+			return err
+		}
+		plainRuleSet, err = compat.Upgrade()
 		if err != nil {
 			return err
 		}
 	default:
-		return E.New("unknown rule-set format: ", flagRuleSetMatchFormat)
+		if !rulelist.IsFormat(flagRuleSetMatchFormat) {
+			return E.New("unknown rule-set format: ", flagRuleSetMatchFormat)
+		}
+		plainRuleSet, err = rulelist.Parse(flagRuleSetMatchFormat, content)
+		if err != nil {
+			return err
+		}
 	}
 	ipAddress := M.ParseAddr(domain)
 	var metadata adapter.InboundContext
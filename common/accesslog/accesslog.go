@@ -0,0 +1,129 @@
+package accesslog
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+)
+
+// Record is the summary of a connection, written when it is closed or rejected.
+type Record struct {
+	ID          uint32    `json:"id,omitempty"`
+	StartTime   time.Time `json:"start_time"`
+	EndTime     time.Time `json:"end_time"`
+	Duration    int64     `json:"duration"`
+	Network     string    `json:"network"`
+	Inbound     string    `json:"inbound,omitempty"`
+	InboundType string    `json:"inbound_type,omitempty"`
+	User        string    `json:"user,omitempty"`
+	Source      string    `json:"source,omitempty"`
+	Destination string    `json:"destination,omitempty"`
+	Domain      string    `json:"domain,omitempty"`
+	Protocol    string    `json:"protocol,omitempty"`
+	ProcessPath string    `json:"process_path,omitempty"`
+	PackageName string    `json:"package_name,omitempty"`
+	ProcessUser string    `json:"process_user,omitempty"`
+	Rule        string    `json:"rule"`
+	Action      string    `json:"action"`
+	Outbound    string    `json:"outbound"`
+	Chain       []string  `json:"chain,omitempty"`
+	Upload      int64     `json:"upload"`
+	Download    int64     `json:"download"`
+	CloseReason string    `json:"close_reason"`
+}
+
+type Writer struct {
+	ctx        context.Context
+	logger     log.Logger
+	output     string
+	maxSize    int64
+	maxBackups int
+	syslog     *option.AccessLogSyslogOptions
+	access     sync.Mutex
+	writer     io.Writer
+	closers    []any
+}
+
+func NewWriter(ctx context.Context, logger log.Logger, options option.AccessLogOptions) (*Writer, error) {
+	if options.Output == "" && options.Syslog == nil {
+		return nil, E.New("missing output or syslog")
+	}
+	if options.MaxSize < 0 || options.MaxBackups < 0 {
+		return nil, E.New("invalid rotation options")
+	}
+	if options.Syslog != nil && !syslogSupported {
+		return nil, E.New("syslog is not supported on this platform")
+	}
+	return &Writer{
+		ctx:        ctx,
+		logger:     logger,
+		output:     options.Output,
+		maxSize:    int64(options.MaxSize),
+		maxBackups: options.MaxBackups,
+		syslog:     options.Syslog,
+	}, nil
+}
+
+func (w *Writer) Start() error {
+	var writers []io.Writer
+	switch w.output {
+	case "":
+	case "stdout":
+		writers = append(writers, os.Stdout)
+	case "stderr":
+		writers = append(writers, os.Stderr)
+	default:
+		file, err := openRotatingFile(w.ctx, w.output, w.maxSize, w.maxBackups)
+		if err != nil {
+			return E.Cause(err, "open access log")
+		}
+		writers = append(writers, file)
+		w.closers = append(w.closers, file)
+	}
+	if w.syslog != nil {
+		syslogWriter, err := dialSyslog(*w.syslog)
+		if err != nil {
+			return E.Cause(err, "dial syslog")
+		}
+		writers = append(writers, syslogWriter)
+		w.closers = append(w.closers, syslogWriter)
+	}
+	w.writer = io.MultiWriter(writers...)
+	return nil
+}
+
+func (w *Writer) Close() error {
+	w.access.Lock()
+	defer w.access.Unlock()
+	w.writer = nil
+	return common.Close(w.closers...)
+}
+
+func (w *Writer) Write(record Record) {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	err := encoder.Encode(record)
+	if err != nil {
+		w.logger.Error(E.Cause(err, "encode access log record"))
+		return
+	}
+	w.access.Lock()
+	defer w.access.Unlock()
+	if w.writer == nil {
+		return
+	}
+	_, err = w.writer.Write(buffer.Bytes())
+	if err != nil {
+		w.logger.Error(E.Cause(err, "write access log"))
+	}
+}
@@ -0,0 +1,92 @@
+package accesslog
+
+import (
+	"context"
+	"os"
+	"strconv"
+
+	"github.com/sagernet/sing/service/filemanager"
+)
+
+// rotatingFile appends to a file and, if maxSize is set, renames it to
+// path.1 once it would grow beyond maxSize, shifting older files up to
+// path.maxBackups and removing the rest.
+type rotatingFile struct {
+	ctx        context.Context
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+func openRotatingFile(ctx context.Context, path string, maxSize int64, maxBackups int) (*rotatingFile, error) {
+	file := &rotatingFile{
+		ctx:        ctx,
+		path:       filemanager.BasePath(ctx, path),
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+	}
+	err := file.open()
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+func (f *rotatingFile) open() error {
+	file, err := filemanager.OpenFile(f.ctx, f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	return nil
+}
+
+func (f *rotatingFile) Write(p []byte) (n int, err error) {
+	if f.maxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.maxSize {
+		err = f.rotate()
+		if err != nil {
+			return
+		}
+	}
+	n, err = f.file.Write(p)
+	f.size += int64(n)
+	return
+}
+
+func (f *rotatingFile) rotate() error {
+	err := f.file.Close()
+	if err != nil {
+		return err
+	}
+	if f.maxBackups == 0 {
+		err = os.Remove(f.path)
+	} else {
+		for i := f.maxBackups - 1; i > 0; i-- {
+			err = os.Rename(f.backupPath(i), f.backupPath(i+1))
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		err = os.Rename(f.path, f.backupPath(1))
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return f.open()
+}
+
+func (f *rotatingFile) backupPath(index int) string {
+	return f.path + "." + strconv.Itoa(index)
+}
+
+func (f *rotatingFile) Close() error {
+	return f.file.Close()
+}
@@ -0,0 +1,29 @@
+package accesslog
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRotatingFile(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "access.log")
+	file, err := openRotatingFile(context.Background(), path, 10, 2)
+	require.NoError(t, err)
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		_, err = file.Write([]byte(line))
+		require.NoError(t, err)
+	}
+	require.NoError(t, file.Close())
+	for suffix, content := range map[string]string{"": "fourth\n", ".1": "third\n", ".2": "second\n"} {
+		data, err := os.ReadFile(path + suffix)
+		require.NoError(t, err)
+		require.Equal(t, content, string(data))
+	}
+	_, err = os.Stat(path + ".3")
+	require.True(t, os.IsNotExist(err))
+}
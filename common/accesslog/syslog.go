@@ -0,0 +1,20 @@
+//go:build !windows && !plan9
+
+package accesslog
+
+import (
+	"io"
+	"log/syslog"
+
+	"github.com/sagernet/sing-box/option"
+)
+
+const syslogSupported = true
+
+func dialSyslog(options option.AccessLogSyslogOptions) (io.WriteCloser, error) {
+	tag := options.Tag
+	if tag == "" {
+		tag = "sing-box"
+	}
+	return syslog.Dial(options.Network, options.Address, syslog.LOG_INFO|syslog.LOG_DAEMON, tag)
+}
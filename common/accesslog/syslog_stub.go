@@ -0,0 +1,16 @@
+//go:build windows || plan9
+
+package accesslog
+
+import (
+	"io"
+	"os"
+
+	"github.com/sagernet/sing-box/option"
+)
+
+const syslogSupported = false
+
+func dialSyslog(options option.AccessLogSyslogOptions) (io.WriteCloser, error) {
+	return nil, os.ErrInvalid
+}
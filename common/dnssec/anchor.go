@@ -0,0 +1,51 @@
+package dnssec
+
+import (
+	"strings"
+
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"github.com/miekg/dns"
+)
+
+// rootAnchors are the DS records of the root key signing keys published by IANA,
+// KSK-2017 and KSK-2024.
+var rootAnchors = []string{
+	". IN DS 20326 8 2 E06D44B80B8F1D39A95C0B0D7C65D08458E880409BBC683457104237C7F8EC8D",
+	". IN DS 38696 8 2 683D2D0ACB8C9B712A1948B27F741219298D0A450D612C483AF444A4C0FB2B16",
+}
+
+func RootAnchors() []*dns.DS {
+	anchors, err := ParseTrustAnchors(rootAnchors)
+	if err != nil {
+		panic(err)
+	}
+	return anchors
+}
+
+// ParseTrustAnchors parses DS or DNSKEY records in presentation format,
+// DNSKEY records are converted to SHA-256 DS records.
+func ParseTrustAnchors(records []string) ([]*dns.DS, error) {
+	var anchors []*dns.DS
+	for _, record := range records {
+		record = strings.TrimSpace(record)
+		if record == "" {
+			continue
+		}
+		rr, err := dns.NewRR(record)
+		if err != nil {
+			return nil, E.Cause(err, "parse trust anchor: ", record)
+		}
+		switch anchor := rr.(type) {
+		case *dns.DS:
+			anchor.Hdr.Name = dns.CanonicalName(anchor.Hdr.Name)
+			anchors = append(anchors, anchor)
+		case *dns.DNSKEY:
+			anchor.Hdr.Name = dns.CanonicalName(anchor.Hdr.Name)
+			anchors = append(anchors, anchor.ToDS(dns.SHA256))
+		default:
+			return nil, E.New("trust anchor is not a DS or DNSKEY record: ", record)
+		}
+	}
+	return anchors, nil
+}
@@ -0,0 +1,268 @@
+package dnssec
+
+import (
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+const nsec3OptOut = 1
+
+// proveDenial checks that the NSEC or NSEC3 records prove that name does not exist,
+// or that it has no records of qType. Denials proven only through an NSEC3 opt-out
+// span are insecure, since an unsigned delegation may exist there.
+func proveDenial(sets []*rrset, name string, qType uint16, nameError bool) Status {
+	nsecRecords, nsec3Records, skipped := denialRecords(sets)
+	if len(nsecRecords) > 0 && proveNSECDenial(nsecRecords, name, qType, nameError) {
+		return StatusSecure
+	}
+	if len(nsec3Records) > 0 {
+		status := proveNSEC3Denial(nsec3Records, name, qType, nameError)
+		if status != StatusBogus {
+			return status
+		}
+	}
+	if skipped {
+		// RFC 9276: NSEC3 records with too many iterations are treated as insecure.
+		return StatusInsecure
+	}
+	return StatusBogus
+}
+
+// proveWildcardExpansion checks that name, answered by a wildcard whose signature has
+// the given label count, does not exist, so that no closer match could have answered.
+func proveWildcardExpansion(sets []*rrset, name string, labels int) bool {
+	nsecRecords, nsec3Records, _ := denialRecords(sets)
+	sourceOfSynthesis := ancestorWithLabels(name, labels)
+	if cover := findCoveringNSEC(nsecRecords, name); cover != nil && nsecClosestEncloser(cover, name) == sourceOfSynthesis {
+		return true
+	}
+	return findCoveringNSEC3(nsec3Records, ancestorWithLabels(name, labels+1)) != nil
+}
+
+func denialRecords(sets []*rrset) (nsecRecords []*dns.NSEC, nsec3Records []*dns.NSEC3, skipped bool) {
+	for _, set := range sets {
+		for _, record := range set.records {
+			switch denial := record.(type) {
+			case *dns.NSEC:
+				nsecRecords = append(nsecRecords, denial)
+			case *dns.NSEC3:
+				if denial.Iterations > maxIterations {
+					skipped = true
+					continue
+				}
+				nsec3Records = append(nsec3Records, denial)
+			}
+		}
+	}
+	return
+}
+
+func proveNSECDenial(records []*dns.NSEC, name string, qType uint16, nameError bool) bool {
+	if !nameError {
+		if nsec := findMatchingNSEC(records, name); nsec != nil {
+			if qType != dns.TypeDS && isDelegation(nsec.TypeBitMap) {
+				// RFC 6840 section 4.1: the parent side of a delegation
+				// only proves that the child has no DS records.
+				return false
+			}
+			return !hasType(nsec.TypeBitMap, qType) && !hasType(nsec.TypeBitMap, dns.TypeCNAME)
+		}
+	}
+	cover := findCoveringNSEC(records, name)
+	if cover == nil {
+		return false
+	}
+	if !nameError && dns.IsSubDomain(name, dns.CanonicalName(cover.NextDomain)) {
+		// empty non-terminal
+		return true
+	}
+	wildcard := wildcardName(nsecClosestEncloser(cover, name))
+	if nameError {
+		return findCoveringNSEC(records, wildcard) != nil
+	}
+	nsec := findMatchingNSEC(records, wildcard)
+	return nsec != nil && !hasType(nsec.TypeBitMap, qType) && !hasType(nsec.TypeBitMap, dns.TypeCNAME)
+}
+
+func proveNSEC3Denial(records []*dns.NSEC3, name string, qType uint16, nameError bool) Status {
+	if !nameError {
+		if nsec3 := findMatchingNSEC3(records, name); nsec3 != nil {
+			if qType != dns.TypeDS && isDelegation(nsec3.TypeBitMap) {
+				return StatusBogus
+			}
+			if hasType(nsec3.TypeBitMap, qType) || hasType(nsec3.TypeBitMap, dns.TypeCNAME) {
+				return StatusBogus
+			}
+			return StatusSecure
+		}
+	}
+	closestEncloser, nextCloser := nsec3ClosestEncloser(records, name)
+	if nextCloser == nil {
+		return StatusBogus
+	}
+	optOut := nextCloser.Flags&nsec3OptOut != 0
+	wildcard := wildcardName(closestEncloser)
+	if nameError {
+		if findCoveringNSEC3(records, wildcard) == nil {
+			return StatusBogus
+		}
+		if optOut {
+			return StatusInsecure
+		}
+		return StatusSecure
+	}
+	if qType == dns.TypeDS && optOut {
+		// RFC 5155 section 8.6: an unsigned delegation in an opt-out span.
+		return StatusInsecure
+	}
+	nsec3 := findMatchingNSEC3(records, wildcard)
+	if nsec3 == nil || hasType(nsec3.TypeBitMap, qType) || hasType(nsec3.TypeBitMap, dns.TypeCNAME) {
+		return StatusBogus
+	}
+	return StatusSecure
+}
+
+// nsec3ClosestEncloser returns the closest encloser of name and the record covering
+// the next closer name, as described in RFC 5155 section 8.3.
+func nsec3ClosestEncloser(records []*dns.NSEC3, name string) (string, *dns.NSEC3) {
+	labels := dns.CountLabel(name)
+	for count := labels - 1; count >= 0; count-- {
+		candidate := ancestorWithLabels(name, count)
+		nsec3 := findMatchingNSEC3(records, candidate)
+		if nsec3 == nil {
+			continue
+		}
+		if hasType(nsec3.TypeBitMap, dns.TypeDNAME) || isDelegation(nsec3.TypeBitMap) {
+			return "", nil
+		}
+		return candidate, findCoveringNSEC3(records, ancestorWithLabels(name, count+1))
+	}
+	return "", nil
+}
+
+func findMatchingNSEC(records []*dns.NSEC, name string) *dns.NSEC {
+	for _, nsec := range records {
+		if dns.CanonicalName(nsec.Hdr.Name) == name {
+			return nsec
+		}
+	}
+	return nil
+}
+
+// findCoveringNSEC returns a record proving that name does not exist, records of
+// delegations or DNAMEs above name can not prove anything below them.
+func findCoveringNSEC(records []*dns.NSEC, name string) *dns.NSEC {
+	for _, nsec := range records {
+		if !nsecCovers(nsec, name) {
+			continue
+		}
+		owner := dns.CanonicalName(nsec.Hdr.Name)
+		if dns.IsSubDomain(owner, name) && (isDelegation(nsec.TypeBitMap) || hasType(nsec.TypeBitMap, dns.TypeDNAME)) {
+			continue
+		}
+		return nsec
+	}
+	return nil
+}
+
+func findMatchingNSEC3(records []*dns.NSEC3, name string) *dns.NSEC3 {
+	for _, nsec3 := range records {
+		if nsec3.Match(name) {
+			return nsec3
+		}
+	}
+	return nil
+}
+
+func findCoveringNSEC3(records []*dns.NSEC3, name string) *dns.NSEC3 {
+	for _, nsec3 := range records {
+		if nsec3.Cover(name) {
+			return nsec3
+		}
+	}
+	return nil
+}
+
+// nsecClosestEncloser returns the longest existing ancestor of name proven by the record covering it.
+func nsecClosestEncloser(nsec *dns.NSEC, name string) string {
+	labels := dns.CompareDomainName(name, nsec.Hdr.Name)
+	if nextLabels := dns.CompareDomainName(name, nsec.NextDomain); nextLabels > labels {
+		labels = nextLabels
+	}
+	return ancestorWithLabels(name, labels)
+}
+
+// isDelegation reports whether the type bitmap belongs to the parent side of a zone cut.
+func isDelegation(bitmap []uint16) bool {
+	return hasType(bitmap, dns.TypeNS) && !hasType(bitmap, dns.TypeSOA)
+}
+
+func hasType(bitmap []uint16, rrType uint16) bool {
+	for _, it := range bitmap {
+		if it == rrType {
+			return true
+		}
+	}
+	return false
+}
+
+// ancestorWithLabels returns the ancestor of name with the given number of labels.
+func ancestorWithLabels(name string, labels int) string {
+	indexes := dns.Split(name)
+	if labels <= 0 || len(indexes) == 0 {
+		return "."
+	}
+	if labels >= len(indexes) {
+		return name
+	}
+	return dns.CanonicalName(name[indexes[len(indexes)-labels]:])
+}
+
+func wildcardName(name string) string {
+	if name == "." {
+		return "*."
+	}
+	return "*." + name
+}
+
+// ownerLabels returns the label count of name as in the Labels field of RRSIG records,
+// which does not count the leading wildcard label.
+func ownerLabels(name string) int {
+	labels := dns.CountLabel(name)
+	if strings.HasPrefix(name, "*.") {
+		labels--
+	}
+	return labels
+}
+
+// nsecCovers checks that name sorts between the owner and the next name of the record,
+// the last record of a zone wraps around to the apex.
+func nsecCovers(nsec *dns.NSEC, name string) bool {
+	owner := nsec.Hdr.Name
+	next := nsec.NextDomain
+	if compareNames(owner, next) < 0 {
+		return compareNames(owner, name) < 0 && compareNames(name, next) < 0
+	}
+	return compareNames(owner, name) < 0 || compareNames(name, next) < 0
+}
+
+// compareNames compares names in the canonical order of RFC 4034 section 6.1.
+func compareNames(a string, b string) int {
+	aLabels := dns.SplitDomainName(strings.ToLower(a))
+	bLabels := dns.SplitDomainName(strings.ToLower(b))
+	for i := 1; i <= len(aLabels) && i <= len(bLabels); i++ {
+		result := strings.Compare(aLabels[len(aLabels)-i], bLabels[len(bLabels)-i])
+		if result != 0 {
+			return result
+		}
+	}
+	switch {
+	case len(aLabels) < len(bLabels):
+		return -1
+	case len(aLabels) > len(bLabels):
+		return 1
+	default:
+		return 0
+	}
+}
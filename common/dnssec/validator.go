@@ -0,0 +1,538 @@
+package dnssec
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+type Status uint8
+
+const (
+	StatusInsecure Status = iota
+	StatusSecure
+	StatusBogus
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusSecure:
+		return "secure"
+	case StatusBogus:
+		return "bogus"
+	default:
+		return "insecure"
+	}
+}
+
+func worse(a Status, b Status) Status {
+	if a == StatusBogus || b == StatusBogus {
+		return StatusBogus
+	}
+	if a == StatusInsecure || b == StatusInsecure {
+		return StatusInsecure
+	}
+	return StatusSecure
+}
+
+const (
+	maxDepth      = 24
+	minCacheTTL   = time.Minute
+	maxCacheTTL   = time.Hour
+	maxIterations = 150
+)
+
+var supportedAlgorithms = map[uint8]bool{
+	dns.RSASHA1:          true,
+	dns.RSASHA1NSEC3SHA1: true,
+	dns.RSASHA256:        true,
+	dns.RSASHA512:        true,
+	dns.ECDSAP256SHA256:  true,
+	dns.ECDSAP384SHA384:  true,
+	dns.ED25519:          true,
+}
+
+var supportedDigests = map[uint8]bool{
+	dns.SHA1:   true,
+	dns.SHA256: true,
+	dns.SHA384: true,
+}
+
+type Exchanger interface {
+	Exchange(ctx context.Context, message *dns.Msg) (*dns.Msg, error)
+}
+
+// Validator validates responses of a recursive resolver by following the chain
+// of trust from the trust anchors, querying DS and DNSKEY records through the
+// same resolver.
+type Validator struct {
+	exchanger Exchanger
+	anchors   map[string][]*dns.DS
+	access    sync.Mutex
+	zones     map[string]*zone
+}
+
+type zone struct {
+	status   Status
+	keys     []*dns.DNSKEY
+	expireAt time.Time
+}
+
+type rrset struct {
+	name    string
+	rrType  uint16
+	records []dns.RR
+	sigs    []*dns.RRSIG
+}
+
+type rrsetKey struct {
+	name   string
+	rrType uint16
+}
+
+func NewValidator(exchanger Exchanger, anchors []*dns.DS) *Validator {
+	anchorMap := make(map[string][]*dns.DS)
+	for _, anchor := range anchors {
+		name := dns.CanonicalName(anchor.Hdr.Name)
+		anchorMap[name] = append(anchorMap[name], anchor)
+	}
+	return &Validator{
+		exchanger: exchanger,
+		anchors:   anchorMap,
+		zones:     make(map[string]*zone),
+	}
+}
+
+// Exchange sends the message with the DO and CD bits set and validates the response.
+func (v *Validator) Exchange(ctx context.Context, message *dns.Msg) (*dns.Msg, Status, error) {
+	request := message.Copy()
+	request.CheckingDisabled = true
+	if opt := request.IsEdns0(); opt != nil {
+		opt.SetDo()
+	} else {
+		request.SetEdns0(dns.DefaultMsgSize, true)
+	}
+	response, err := v.exchanger.Exchange(ctx, request)
+	if err != nil {
+		return nil, StatusBogus, err
+	}
+	return response, v.Validate(ctx, response), nil
+}
+
+// Validate returns the status of the answer to the first question of the response.
+// Responses with rcodes other than NOERROR and NXDOMAIN are insecure.
+func (v *Validator) Validate(ctx context.Context, response *dns.Msg) Status {
+	if len(response.Question) == 0 {
+		return StatusBogus
+	}
+	if response.Rcode != dns.RcodeSuccess && response.Rcode != dns.RcodeNameError {
+		return StatusInsecure
+	}
+	question := response.Question[0]
+	name := dns.CanonicalName(question.Name)
+	sets := splitRRsets(response.Answer)
+	status := StatusSecure
+	var expansions []wildcardExpansion
+	for _, set := range sets {
+		if set.rrType == dns.TypeCNAME && synthesizedFromDNAME(sets, set) {
+			continue
+		}
+		setStatus, sig := v.verifyRRsetSignature(ctx, set, 0)
+		status = worse(status, setStatus)
+		if status == StatusBogus {
+			return status
+		}
+		if sig != nil && int(sig.Labels) < ownerLabels(set.name) {
+			expansions = append(expansions, wildcardExpansion{set.name, int(sig.Labels)})
+		}
+	}
+	if len(expansions) > 0 {
+		status = worse(status, v.verifyExpansions(ctx, response, expansions))
+		if status == StatusBogus {
+			return status
+		}
+	}
+	if question.Qtype != dns.TypeCNAME {
+		for range sets {
+			cname := findRRset(sets, name, dns.TypeCNAME)
+			if cname == nil {
+				break
+			}
+			name = dns.CanonicalName(cname.records[0].(*dns.CNAME).Target)
+		}
+	}
+	if response.Rcode == dns.RcodeSuccess {
+		if question.Qtype == dns.TypeANY && len(sets) > 0 || findRRset(sets, name, question.Qtype) != nil {
+			return status
+		}
+	}
+	return worse(status, v.verifyDenial(ctx, response, name, question.Qtype))
+}
+
+type wildcardExpansion struct {
+	name   string
+	labels int
+}
+
+// verifyExpansions checks that the names answered by wildcards do not exist.
+func (v *Validator) verifyExpansions(ctx context.Context, response *dns.Msg, expansions []wildcardExpansion) Status {
+	sets, status := v.denialSets(ctx, response, expansions[0].name)
+	if status != StatusSecure {
+		return status
+	}
+	for _, expansion := range expansions {
+		if !proveWildcardExpansion(sets, expansion.name, expansion.labels) {
+			return StatusBogus
+		}
+	}
+	return StatusSecure
+}
+
+func (v *Validator) verifyDenial(ctx context.Context, response *dns.Msg, name string, qType uint16) Status {
+	sets, status := v.denialSets(ctx, response, name)
+	if status != StatusSecure {
+		return status
+	}
+	return proveDenial(sets, name, qType, response.Rcode == dns.RcodeNameError)
+}
+
+// denialSets returns the validated SOA, NSEC and NSEC3 sets of the authority section.
+func (v *Validator) denialSets(ctx context.Context, response *dns.Msg, name string) ([]*rrset, Status) {
+	var sets []*rrset
+	for _, set := range splitRRsets(response.Ns) {
+		switch set.rrType {
+		case dns.TypeSOA, dns.TypeNSEC, dns.TypeNSEC3:
+			sets = append(sets, set)
+		}
+	}
+	if len(sets) == 0 {
+		status := v.zoneStatus(ctx, name, 0)
+		if status == StatusSecure {
+			return nil, StatusBogus
+		}
+		return nil, status
+	}
+	status := StatusSecure
+	for _, set := range sets {
+		status = worse(status, v.verifyRRset(ctx, set, 0))
+	}
+	return sets, status
+}
+
+// verifyRRset returns secure if any signature of the set is made by a validated key
+// of its signer, unsigned sets are insecure only in insecure zones.
+func (v *Validator) verifyRRset(ctx context.Context, set *rrset, depth int) Status {
+	status, _ := v.verifyRRsetSignature(ctx, set, depth)
+	return status
+}
+
+// verifyRRsetSignature is verifyRRset that also returns the valid signature of a secure set.
+func (v *Validator) verifyRRsetSignature(ctx context.Context, set *rrset, depth int) (Status, *dns.RRSIG) {
+	if len(set.sigs) == 0 {
+		status := v.zoneStatus(ctx, set.name, depth)
+		if status == StatusSecure {
+			return StatusBogus, nil
+		}
+		return status, nil
+	}
+	for _, sig := range set.sigs {
+		signer := dns.CanonicalName(sig.SignerName)
+		if !dns.IsSubDomain(signer, set.name) || set.rrType == dns.TypeDS && signer == set.name {
+			continue
+		}
+		signerZone := v.zone(ctx, signer, depth+1)
+		switch signerZone.status {
+		case StatusInsecure:
+			return StatusInsecure, nil
+		case StatusSecure:
+			if verifySignature(sig, signerZone.keys, set.records) {
+				return StatusSecure, sig
+			}
+		}
+	}
+	return StatusBogus, nil
+}
+
+// zoneStatus returns the status of the zone containing name.
+func (v *Validator) zoneStatus(ctx context.Context, name string, depth int) Status {
+	if depth > maxDepth {
+		return StatusBogus
+	}
+	response, err := v.query(ctx, name, dns.TypeSOA)
+	if err != nil {
+		return StatusBogus
+	}
+	var zoneName string
+	for _, record := range append(response.Answer, response.Ns...) {
+		if soa, isSOA := record.(*dns.SOA); isSOA {
+			zoneName = dns.CanonicalName(soa.Hdr.Name)
+			break
+		}
+	}
+	if zoneName == "" || !dns.IsSubDomain(zoneName, name) {
+		return StatusBogus
+	}
+	return v.zone(ctx, zoneName, depth+1).status
+}
+
+func (v *Validator) zone(ctx context.Context, name string, depth int) *zone {
+	if depth > maxDepth {
+		return &zone{status: StatusBogus}
+	}
+	v.access.Lock()
+	cached, loaded := v.zones[name]
+	v.access.Unlock()
+	if loaded && time.Now().Before(cached.expireAt) {
+		return cached
+	}
+	result := v.loadZone(ctx, name, depth)
+	if !result.expireAt.IsZero() {
+		v.access.Lock()
+		v.zones[name] = result
+		v.access.Unlock()
+	}
+	return result
+}
+
+func (v *Validator) loadZone(ctx context.Context, name string, depth int) *zone {
+	dsSet, isAnchor := v.anchors[name]
+	if !isAnchor {
+		if name == "." {
+			return &zone{status: StatusInsecure, expireAt: time.Now().Add(maxCacheTTL)}
+		}
+		response, err := v.query(ctx, name, dns.TypeDS)
+		if err != nil || response.Rcode != dns.RcodeSuccess && response.Rcode != dns.RcodeNameError {
+			return &zone{status: StatusBogus}
+		}
+		dsRRset := findRRset(splitRRsets(response.Answer), name, dns.TypeDS)
+		if dsRRset == nil {
+			return v.loadUnsignedZone(ctx, name, response, depth)
+		}
+		if len(dsRRset.sigs) == 0 {
+			status := v.zoneStatus(ctx, parentName(name), depth+1)
+			if status == StatusSecure {
+				status = StatusBogus
+			}
+			return &zone{status: status, expireAt: cacheExpire(dsRRset.records)}
+		}
+		status := v.verifyRRset(ctx, dsRRset, depth)
+		if status != StatusSecure {
+			return &zone{status: status, expireAt: cacheExpire(dsRRset.records)}
+		}
+		dsSet = nil
+		for _, record := range dsRRset.records {
+			dsSet = append(dsSet, record.(*dns.DS))
+		}
+	}
+	response, err := v.query(ctx, name, dns.TypeDNSKEY)
+	if err != nil {
+		return &zone{status: StatusBogus}
+	}
+	keyRRset := findRRset(splitRRsets(response.Answer), name, dns.TypeDNSKEY)
+	if keyRRset == nil {
+		return &zone{status: StatusBogus, expireAt: time.Now().Add(minCacheTTL)}
+	}
+	keys := make([]*dns.DNSKEY, 0, len(keyRRset.records))
+	for _, record := range keyRRset.records {
+		keys = append(keys, record.(*dns.DNSKEY))
+	}
+	var supported bool
+	for _, ds := range dsSet {
+		if !supportedAlgorithms[ds.Algorithm] || !supportedDigests[ds.DigestType] {
+			continue
+		}
+		supported = true
+		for _, key := range keys {
+			if key.Algorithm != ds.Algorithm || key.KeyTag() != ds.KeyTag {
+				continue
+			}
+			keyDS := key.ToDS(ds.DigestType)
+			if keyDS == nil || !strings.EqualFold(keyDS.Digest, ds.Digest) {
+				continue
+			}
+			for _, sig := range keyRRset.sigs {
+				if verifySignature(sig, []*dns.DNSKEY{key}, keyRRset.records) {
+					return &zone{status: StatusSecure, keys: keys, expireAt: cacheExpire(keyRRset.records)}
+				}
+			}
+		}
+	}
+	if !supported {
+		return &zone{status: StatusInsecure, expireAt: cacheExpire(keyRRset.records)}
+	}
+	return &zone{status: StatusBogus, expireAt: time.Now().Add(minCacheTTL)}
+}
+
+// loadUnsignedZone checks the proof that the parent has no DS records for name.
+// If name turns out not to be a delegation, it belongs to the zone of the signer.
+func (v *Validator) loadUnsignedZone(ctx context.Context, name string, response *dns.Msg, depth int) *zone {
+	var (
+		signer string
+		sets   []*rrset
+	)
+	for _, set := range splitRRsets(response.Ns) {
+		switch set.rrType {
+		case dns.TypeSOA, dns.TypeNSEC, dns.TypeNSEC3:
+			sets = append(sets, set)
+		default:
+			continue
+		}
+		for _, sig := range set.sigs {
+			signer = dns.CanonicalName(sig.SignerName)
+		}
+	}
+	if signer == "" {
+		status := v.zoneStatus(ctx, parentName(name), depth+1)
+		if status == StatusSecure {
+			status = StatusBogus
+		}
+		return &zone{status: status, expireAt: time.Now().Add(minCacheTTL)}
+	}
+	if signer == name || !dns.IsSubDomain(signer, name) {
+		return &zone{status: StatusBogus}
+	}
+	for _, set := range sets {
+		status := v.verifyRRset(ctx, set, depth)
+		if status != StatusSecure {
+			return &zone{status: status, expireAt: time.Now().Add(minCacheTTL)}
+		}
+	}
+	expireAt := time.Now().Add(minCacheTTL)
+	for _, set := range sets {
+		for _, record := range set.records {
+			switch denial := record.(type) {
+			case *dns.NSEC:
+				if dns.CanonicalName(denial.Hdr.Name) == name {
+					if hasType(denial.TypeBitMap, dns.TypeDS) {
+						return &zone{status: StatusBogus}
+					}
+					if hasType(denial.TypeBitMap, dns.TypeNS) && !hasType(denial.TypeBitMap, dns.TypeSOA) {
+						return &zone{status: StatusInsecure, expireAt: expireAt}
+					}
+					return v.zone(ctx, signer, depth+1)
+				}
+				if nsecCovers(denial, name) {
+					return v.zone(ctx, signer, depth+1)
+				}
+			case *dns.NSEC3:
+				if denial.Iterations > maxIterations {
+					return &zone{status: StatusInsecure, expireAt: expireAt}
+				}
+				if denial.Match(name) {
+					if hasType(denial.TypeBitMap, dns.TypeDS) {
+						return &zone{status: StatusBogus}
+					}
+					if hasType(denial.TypeBitMap, dns.TypeNS) && !hasType(denial.TypeBitMap, dns.TypeSOA) {
+						return &zone{status: StatusInsecure, expireAt: expireAt}
+					}
+					return v.zone(ctx, signer, depth+1)
+				}
+				if denial.Cover(name) {
+					if denial.Flags&1 != 0 {
+						return &zone{status: StatusInsecure, expireAt: expireAt}
+					}
+					return v.zone(ctx, signer, depth+1)
+				}
+			}
+		}
+	}
+	return &zone{status: StatusBogus}
+}
+
+func (v *Validator) query(ctx context.Context, name string, qType uint16) (*dns.Msg, error) {
+	message := new(dns.Msg)
+	message.SetQuestion(name, qType)
+	message.CheckingDisabled = true
+	message.SetEdns0(dns.DefaultMsgSize, true)
+	return v.exchanger.Exchange(ctx, message)
+}
+
+func verifySignature(sig *dns.RRSIG, keys []*dns.DNSKEY, records []dns.RR) bool {
+	if !sig.ValidityPeriod(time.Now()) {
+		return false
+	}
+	for _, key := range keys {
+		if key.Algorithm != sig.Algorithm || key.KeyTag() != sig.KeyTag {
+			continue
+		}
+		if sig.Verify(key, records) == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func splitRRsets(records []dns.RR) []*rrset {
+	var (
+		sets  []*rrset
+		sigs  []*dns.RRSIG
+		index = make(map[rrsetKey]*rrset)
+	)
+	for _, record := range records {
+		switch sig := record.(type) {
+		case *dns.RRSIG:
+			sigs = append(sigs, sig)
+			continue
+		case *dns.OPT:
+			continue
+		}
+		key := rrsetKey{dns.CanonicalName(record.Header().Name), record.Header().Rrtype}
+		set := index[key]
+		if set == nil {
+			set = &rrset{name: key.name, rrType: key.rrType}
+			index[key] = set
+			sets = append(sets, set)
+		}
+		set.records = append(set.records, record)
+	}
+	for _, sig := range sigs {
+		set := index[rrsetKey{dns.CanonicalName(sig.Hdr.Name), sig.TypeCovered}]
+		if set != nil {
+			set.sigs = append(set.sigs, sig)
+		}
+	}
+	return sets
+}
+
+func findRRset(sets []*rrset, name string, rrType uint16) *rrset {
+	for _, set := range sets {
+		if set.name == name && set.rrType == rrType {
+			return set
+		}
+	}
+	return nil
+}
+
+func synthesizedFromDNAME(sets []*rrset, cname *rrset) bool {
+	for _, set := range sets {
+		if set.rrType == dns.TypeDNAME && set.name != cname.name && dns.IsSubDomain(set.name, cname.name) {
+			return true
+		}
+	}
+	return false
+}
+
+func cacheExpire(records []dns.RR) time.Time {
+	timeToLive := maxCacheTTL
+	for _, record := range records {
+		recordTTL := time.Duration(record.Header().Ttl) * time.Second
+		if recordTTL < timeToLive {
+			timeToLive = recordTTL
+		}
+	}
+	if timeToLive < minCacheTTL {
+		timeToLive = minCacheTTL
+	}
+	return time.Now().Add(timeToLive)
+}
+
+func parentName(name string) string {
+	offset, end := dns.NextLabel(name, 0)
+	if end || offset >= len(name) {
+		return "."
+	}
+	return name[offset:]
+}
@@ -0,0 +1,556 @@
+package dnssec
+
+import (
+	"context"
+	"crypto"
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+type testZone struct {
+	name    string
+	key     *dns.DNSKEY
+	signer  crypto.Signer
+	records []dns.RR
+	nsec3   bool
+	optOut  bool
+}
+
+// testResolver answers like a recursive resolver from locally signed zones.
+type testResolver struct {
+	t      *testing.T
+	zones  []*testZone
+	modify func(response *dns.Msg)
+}
+
+func newTestZone(t *testing.T, name string, signed bool, records ...string) *testZone {
+	zone := &testZone{name: name}
+	zone.records = append(zone.records, mustRR(t, name+" 3600 IN SOA ns.invalid. admin.invalid. 1 3600 600 86400 300"))
+	for _, record := range records {
+		zone.records = append(zone.records, mustRR(t, record))
+	}
+	if signed {
+		zone.key = &dns.DNSKEY{
+			Hdr:       dns.RR_Header{Name: name, Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: 3600},
+			Flags:     dns.ZONE | dns.SEP,
+			Protocol:  3,
+			Algorithm: dns.ECDSAP256SHA256,
+		}
+		privateKey, err := zone.key.Generate(256)
+		require.NoError(t, err)
+		zone.signer = privateKey.(crypto.Signer)
+		zone.records = append(zone.records, zone.key)
+	}
+	return zone
+}
+
+func (z *testZone) delegate(t *testing.T, child *testZone) {
+	z.records = append(z.records, mustRR(t, child.name+" 3600 IN NS ns.invalid."))
+	if child.key != nil {
+		ds := child.key.ToDS(dns.SHA256)
+		ds.Hdr.Ttl = 3600
+		z.records = append(z.records, ds)
+	}
+}
+
+func (z *testZone) names() []string {
+	nameMap := make(map[string]bool)
+	for _, record := range z.records {
+		nameMap[dns.CanonicalName(record.Header().Name)] = true
+	}
+	var names []string
+	for name := range nameMap {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return compareNames(names[i], names[j]) < 0
+	})
+	return names
+}
+
+// find returns the records of name with qType or a CNAME, and whether name or
+// any name below it exists.
+func (z *testZone) find(name string, qType uint16) ([]dns.RR, *dns.CNAME, bool) {
+	var (
+		answer []dns.RR
+		cname  *dns.CNAME
+		exists bool
+	)
+	for _, record := range z.records {
+		owner := dns.CanonicalName(record.Header().Name)
+		if dns.IsSubDomain(name, owner) {
+			exists = true
+		}
+		if owner != name {
+			continue
+		}
+		if record.Header().Rrtype == qType {
+			answer = append(answer, dns.Copy(record))
+		} else if record.Header().Rrtype == dns.TypeCNAME {
+			cname = dns.Copy(record).(*dns.CNAME)
+		}
+	}
+	return answer, cname, exists
+}
+
+func (z *testZone) closestEncloser(name string) string {
+	for labels := dns.CountLabel(name); labels > 0; labels-- {
+		ancestor := ancestorWithLabels(name, labels)
+		if _, _, exists := z.find(ancestor, dns.TypeNone); exists {
+			return ancestor
+		}
+	}
+	return z.name
+}
+
+func (z *testZone) sign(t *testing.T, records []dns.RR) []dns.RR {
+	if z.key == nil || len(records) == 0 {
+		return records
+	}
+	header := records[0].Header()
+	sig := &dns.RRSIG{
+		Hdr:        dns.RR_Header{Name: header.Name, Rrtype: dns.TypeRRSIG, Class: dns.ClassINET, Ttl: header.Ttl},
+		Inception:  uint32(time.Now().Add(-time.Hour).Unix()),
+		Expiration: uint32(time.Now().Add(time.Hour).Unix()),
+		KeyTag:     z.key.KeyTag(),
+		SignerName: z.name,
+		Algorithm:  z.key.Algorithm,
+	}
+	require.NoError(t, sig.Sign(z.signer, records))
+	return append(records, sig)
+}
+
+// signExpanded signs records of a wildcard and renames them to name.
+func (z *testZone) signExpanded(t *testing.T, records []dns.RR, name string) []dns.RR {
+	records = z.sign(t, records)
+	for _, record := range records {
+		record.Header().Name = name
+	}
+	return records
+}
+
+func (z *testZone) typeBitMap(name string) []uint16 {
+	typeMap := make(map[uint16]bool)
+	for _, record := range z.records {
+		if dns.CanonicalName(record.Header().Name) == name {
+			typeMap[record.Header().Rrtype] = true
+		}
+	}
+	if len(typeMap) > 0 {
+		typeMap[dns.TypeRRSIG] = true
+		if !z.nsec3 {
+			typeMap[dns.TypeNSEC] = true
+		}
+	}
+	var bitmap []uint16
+	for rrType := range typeMap {
+		bitmap = append(bitmap, rrType)
+	}
+	sort.Slice(bitmap, func(i, j int) bool {
+		return bitmap[i] < bitmap[j]
+	})
+	return bitmap
+}
+
+func (z *testZone) nsec(name string) *dns.NSEC {
+	names := z.names()
+	for i, owner := range names {
+		next := names[(i+1)%len(names)]
+		nsec := &dns.NSEC{
+			Hdr:        dns.RR_Header{Name: owner, Rrtype: dns.TypeNSEC, Class: dns.ClassINET, Ttl: 300},
+			NextDomain: next,
+		}
+		if owner == name || nsecCovers(nsec, name) {
+			nsec.TypeBitMap = z.typeBitMap(owner)
+			return nsec
+		}
+	}
+	return nil
+}
+
+// nsec3Chain returns the NSEC3 records of all names of the zone, including empty non-terminals.
+func (z *testZone) nsec3Chain() []*dns.NSEC3 {
+	nameMap := make(map[string]bool)
+	for _, name := range z.names() {
+		for labels := dns.CountLabel(name); labels >= dns.CountLabel(z.name); labels-- {
+			nameMap[ancestorWithLabels(name, labels)] = true
+		}
+	}
+	var chain []*dns.NSEC3
+	for name := range nameMap {
+		nsec3 := &dns.NSEC3{
+			Hdr:        dns.RR_Header{Name: strings.ToLower(dns.HashName(name, dns.SHA1, 0, "")) + "." + z.name, Rrtype: dns.TypeNSEC3, Class: dns.ClassINET, Ttl: 300},
+			Hash:       dns.SHA1,
+			SaltLength: 0,
+			Salt:       "",
+			TypeBitMap: z.typeBitMap(name),
+		}
+		if z.optOut {
+			nsec3.Flags = nsec3OptOut
+		}
+		chain = append(chain, nsec3)
+	}
+	sort.Slice(chain, func(i, j int) bool {
+		return chain[i].Hdr.Name < chain[j].Hdr.Name
+	})
+	for i, nsec3 := range chain {
+		next := chain[(i+1)%len(chain)]
+		nsec3.NextDomain = strings.ToUpper(dns.SplitDomainName(next.Hdr.Name)[0])
+		nsec3.HashLength = 20
+	}
+	return chain
+}
+
+func (z *testZone) nsec3Matching(name string) *dns.NSEC3 {
+	return findMatchingNSEC3(z.nsec3Chain(), name)
+}
+
+func (z *testZone) nsec3Covering(name string) *dns.NSEC3 {
+	return findCoveringNSEC3(z.nsec3Chain(), name)
+}
+
+// denial returns the signed NSEC or NSEC3 records proving that name has no records of the
+// query type, does not exist or was answered by the wildcard.
+func (z *testZone) denial(t *testing.T, name string, nameError bool, wildcard string, positive bool) []dns.RR {
+	var records []dns.RR
+	owners := make(map[string]bool)
+	add := func(record dns.RR) {
+		if record == nil || reflect.ValueOf(record).IsNil() || owners[record.Header().Name] {
+			return
+		}
+		owners[record.Header().Name] = true
+		records = append(records, z.sign(t, []dns.RR{record})...)
+	}
+	closestEncloser := z.closestEncloser(name)
+	if !z.nsec3 {
+		add(z.nsec(name))
+		if nameError {
+			add(z.nsec(wildcardName(closestEncloser)))
+		} else if wildcard != "" && !positive {
+			add(z.nsec(wildcard))
+		}
+		return records
+	}
+	if !nameError && wildcard == "" {
+		add(z.nsec3Matching(name))
+		return records
+	}
+	add(z.nsec3Covering(ancestorWithLabels(name, dns.CountLabel(closestEncloser)+1)))
+	if !positive {
+		add(z.nsec3Matching(closestEncloser))
+	}
+	if nameError {
+		add(z.nsec3Covering(wildcardName(closestEncloser)))
+	} else if wildcard != "" && !positive {
+		add(z.nsec3Matching(wildcard))
+	}
+	return records
+}
+
+func (r *testResolver) Exchange(ctx context.Context, message *dns.Msg) (*dns.Msg, error) {
+	question := message.Question[0]
+	response := new(dns.Msg)
+	response.SetReply(message)
+	name := dns.CanonicalName(question.Name)
+	for {
+		zone := r.zone(name, question.Qtype)
+		answer, cname, exists := zone.find(name, question.Qtype)
+		var wildcard string
+		if !exists {
+			wildcard = wildcardName(zone.closestEncloser(name))
+			answer, cname, exists = zone.find(wildcard, question.Qtype)
+			if !exists {
+				wildcard = ""
+			}
+		}
+		sign := func(records []dns.RR) []dns.RR {
+			if wildcard != "" {
+				return zone.signExpanded(r.t, records, name)
+			}
+			return zone.sign(r.t, records)
+		}
+		if len(answer) == 0 && cname != nil {
+			response.Answer = append(response.Answer, sign([]dns.RR{cname})...)
+			if wildcard != "" && zone.key != nil {
+				response.Ns = append(response.Ns, zone.denial(r.t, name, false, wildcard, true)...)
+			}
+			name = dns.CanonicalName(cname.Target)
+			continue
+		}
+		if len(answer) > 0 {
+			response.Answer = append(response.Answer, sign(answer)...)
+			if wildcard != "" && zone.key != nil {
+				response.Ns = append(response.Ns, zone.denial(r.t, name, false, wildcard, true)...)
+			}
+		} else {
+			if !exists {
+				response.Rcode = dns.RcodeNameError
+			}
+			response.Ns = append(zone.sign(r.t, []dns.RR{dns.Copy(zone.records[0])}), response.Ns...)
+			if zone.key != nil {
+				response.Ns = append(response.Ns, zone.denial(r.t, name, !exists, wildcard, false)...)
+			}
+		}
+		break
+	}
+	if r.modify != nil {
+		r.modify(response)
+	}
+	return response, nil
+}
+
+// zone returns the zone holding the records of name, DS records belong to the parent.
+func (r *testResolver) zone(name string, qType uint16) *testZone {
+	var zone *testZone
+	for _, it := range r.zones {
+		if !dns.IsSubDomain(it.name, name) || qType == dns.TypeDS && it.name == name && name != "." {
+			continue
+		}
+		if zone == nil || dns.CountLabel(it.name) > dns.CountLabel(zone.name) {
+			zone = it
+		}
+	}
+	return zone
+}
+
+func mustRR(t *testing.T, record string) dns.RR {
+	rr, err := dns.NewRR(record)
+	require.NoError(t, err)
+	return rr
+}
+
+func newTestResolver(t *testing.T) (*testResolver, []*dns.DS) {
+	root := newTestZone(t, ".", true)
+	example := newTestZone(t, "example.", true,
+		"www.example. 300 IN A 192.0.2.1",
+		"alias.example. 300 IN CNAME www.example.",
+		"a.b.example. 300 IN A 192.0.2.2",
+		"*.wild.example. 300 IN A 192.0.2.4",
+	)
+	hashed := newTestZone(t, "nsec3.", true,
+		"www.nsec3. 300 IN A 192.0.2.5",
+		"a.b.nsec3. 300 IN A 192.0.2.6",
+		"*.wild.nsec3. 300 IN A 192.0.2.7",
+	)
+	hashed.nsec3 = true
+	optOut := newTestZone(t, "optout.", true,
+		"www.optout. 300 IN A 192.0.2.8",
+	)
+	optOut.nsec3 = true
+	optOut.optOut = true
+	insecure := newTestZone(t, "insecure.", false,
+		"www.insecure. 300 IN A 192.0.2.3",
+	)
+	root.delegate(t, example)
+	root.delegate(t, hashed)
+	root.delegate(t, optOut)
+	root.delegate(t, insecure)
+	anchor := root.key.ToDS(dns.SHA256)
+	return &testResolver{t: t, zones: []*testZone{root, example, hashed, optOut, insecure}}, []*dns.DS{anchor}
+}
+
+func exchange(t *testing.T, validator *Validator, name string, qType uint16) (*dns.Msg, Status) {
+	message := new(dns.Msg)
+	message.SetQuestion(name, qType)
+	response, status, err := validator.Exchange(context.Background(), message)
+	require.NoError(t, err)
+	return response, status
+}
+
+func TestValidator(t *testing.T) {
+	t.Parallel()
+	resolver, anchors := newTestResolver(t)
+	validator := NewValidator(resolver, anchors)
+	for _, testCase := range []struct {
+		name   string
+		qType  uint16
+		rcode  int
+		status Status
+	}{
+		{"www.example.", dns.TypeA, dns.RcodeSuccess, StatusSecure},
+		{"alias.example.", dns.TypeA, dns.RcodeSuccess, StatusSecure},
+		{"www.example.", dns.TypeAAAA, dns.RcodeSuccess, StatusSecure},
+		{"missing.example.", dns.TypeA, dns.RcodeNameError, StatusSecure},
+		{"b.example.", dns.TypeA, dns.RcodeSuccess, StatusSecure},
+		{"x.wild.example.", dns.TypeA, dns.RcodeSuccess, StatusSecure},
+		{"x.y.wild.example.", dns.TypeA, dns.RcodeSuccess, StatusSecure},
+		{"x.wild.example.", dns.TypeAAAA, dns.RcodeSuccess, StatusSecure},
+		{"www.nsec3.", dns.TypeA, dns.RcodeSuccess, StatusSecure},
+		{"www.nsec3.", dns.TypeAAAA, dns.RcodeSuccess, StatusSecure},
+		{"missing.nsec3.", dns.TypeA, dns.RcodeNameError, StatusSecure},
+		{"x.missing.nsec3.", dns.TypeA, dns.RcodeNameError, StatusSecure},
+		{"b.nsec3.", dns.TypeA, dns.RcodeSuccess, StatusSecure},
+		{"x.wild.nsec3.", dns.TypeA, dns.RcodeSuccess, StatusSecure},
+		{"x.wild.nsec3.", dns.TypeAAAA, dns.RcodeSuccess, StatusSecure},
+		{"www.optout.", dns.TypeAAAA, dns.RcodeSuccess, StatusSecure},
+		{"missing.optout.", dns.TypeA, dns.RcodeNameError, StatusInsecure},
+		{"www.insecure.", dns.TypeA, dns.RcodeSuccess, StatusInsecure},
+		{"missing.insecure.", dns.TypeA, dns.RcodeNameError, StatusInsecure},
+	} {
+		response, status := exchange(t, validator, testCase.name, testCase.qType)
+		require.Equal(t, testCase.rcode, response.Rcode, testCase.name)
+		require.Equal(t, testCase.status, status, testCase.name, " ", dns.TypeToString[testCase.qType])
+	}
+}
+
+func TestValidatorBogus(t *testing.T) {
+	t.Parallel()
+	resolver, anchors := newTestResolver(t)
+	validator := NewValidator(resolver, anchors)
+	for _, testCase := range []struct {
+		name   string
+		modify func(response *dns.Msg)
+	}{
+		{"tampered answer", func(response *dns.Msg) {
+			for _, record := range response.Answer {
+				if a, isA := record.(*dns.A); isA {
+					a.A = net.IPv4(203, 0, 113, 1)
+				}
+			}
+		}},
+		{"stripped signatures", func(response *dns.Msg) {
+			var answer []dns.RR
+			for _, record := range response.Answer {
+				if _, isSig := record.(*dns.RRSIG); !isSig {
+					answer = append(answer, record)
+				}
+			}
+			response.Answer = answer
+		}},
+		{"forged denial", func(response *dns.Msg) {
+			if response.Question[0].Qtype == dns.TypeA {
+				response.Rcode = dns.RcodeNameError
+				response.Answer = nil
+			}
+		}},
+	} {
+		resolver.modify = testCase.modify
+		_, status := exchange(t, validator, "www.example.", dns.TypeA)
+		require.Equal(t, StatusBogus, status, testCase.name)
+	}
+}
+
+// dropDenial removes the NSEC or NSEC3 record owned by owner and its signature from the response.
+func dropDenial(response *dns.Msg, owner string) {
+	var records []dns.RR
+	for _, record := range response.Ns {
+		if dns.CanonicalName(record.Header().Name) == owner {
+			switch record := record.(type) {
+			case *dns.NSEC, *dns.NSEC3:
+				continue
+			case *dns.RRSIG:
+				if record.TypeCovered == dns.TypeNSEC || record.TypeCovered == dns.TypeNSEC3 {
+					continue
+				}
+			}
+		}
+		records = append(records, record)
+	}
+	response.Ns = records
+}
+
+func TestValidatorMissingProof(t *testing.T) {
+	t.Parallel()
+	resolver, anchors := newTestResolver(t)
+	validator := NewValidator(resolver, anchors)
+	example := resolver.zone("example.", dns.TypeA)
+	hashed := resolver.zone("nsec3.", dns.TypeA)
+	for _, testCase := range []struct {
+		name   string
+		qName  string
+		qType  uint16
+		modify func(response *dns.Msg)
+	}{
+		{"wildcard answer without proof", "x.wild.example.", dns.TypeA, func(response *dns.Msg) {
+			response.Ns = example.sign(t, []dns.RR{example.nsec("example.")})
+		}},
+		{"wildcard answer without nsec3 proof", "x.wild.nsec3.", dns.TypeA, func(response *dns.Msg) {
+			response.Ns = hashed.sign(t, []dns.RR{hashed.nsec3Matching("nsec3.")})
+		}},
+		{"name error without wildcard denial", "missing.example.", dns.TypeA, func(response *dns.Msg) {
+			dropDenial(response, example.nsec("*.example.").Hdr.Name)
+		}},
+		{"nsec3 name error without wildcard denial", "missing.nsec3.", dns.TypeA, func(response *dns.Msg) {
+			dropDenial(response, hashed.nsec3Covering("*.nsec3.").Hdr.Name)
+		}},
+		{"nsec3 name error without closest encloser", "missing.nsec3.", dns.TypeA, func(response *dns.Msg) {
+			dropDenial(response, hashed.nsec3Matching("nsec3.").Hdr.Name)
+		}},
+		{"nsec3 name error without next closer", "missing.nsec3.", dns.TypeA, func(response *dns.Msg) {
+			dropDenial(response, hashed.nsec3Covering("missing.nsec3.").Hdr.Name)
+		}},
+	} {
+		resolver.modify = func(response *dns.Msg) {
+			if response.Question[0].Name == testCase.qName {
+				testCase.modify(response)
+			}
+		}
+		_, status := exchange(t, validator, testCase.qName, testCase.qType)
+		require.Equal(t, StatusBogus, status, testCase.name)
+	}
+}
+
+func TestValidatorAncestorDelegation(t *testing.T) {
+	t.Parallel()
+	resolver, anchors := newTestResolver(t)
+	validator := NewValidator(resolver, anchors)
+	root := resolver.zone(".", dns.TypeA)
+	// The NSEC record of the parent side of the delegation, signed by the root zone.
+	delegation := root.nsec("example.")
+	require.True(t, isDelegation(delegation.TypeBitMap))
+	for _, testCase := range []struct {
+		name      string
+		nameError bool
+	}{
+		{"example.", false},
+		{"www.example.", true},
+		{"missing.example.", true},
+	} {
+		resolver.modify = func(response *dns.Msg) {
+			question := response.Question[0]
+			if question.Name != testCase.name || question.Qtype != dns.TypeA {
+				return
+			}
+			response.Answer = nil
+			response.Ns = append(root.sign(t, []dns.RR{dns.Copy(root.records[0])}), root.sign(t, []dns.RR{dns.Copy(delegation)})...)
+			if testCase.nameError {
+				response.Rcode = dns.RcodeNameError
+			}
+		}
+		_, status := exchange(t, validator, testCase.name, dns.TypeA)
+		require.Equal(t, StatusBogus, status, testCase.name)
+	}
+}
+
+func TestValidatorWrongAnchor(t *testing.T) {
+	t.Parallel()
+	resolver, _ := newTestResolver(t)
+	validator := NewValidator(resolver, RootAnchors())
+	_, status := exchange(t, validator, "www.example.", dns.TypeA)
+	require.Equal(t, StatusBogus, status)
+	_, status = exchange(t, validator, "www.insecure.", dns.TypeA)
+	require.Equal(t, StatusBogus, status)
+}
+
+func TestCompareNames(t *testing.T) {
+	t.Parallel()
+	names := []string{
+		"example.",
+		"a.example.",
+		"yljkjljk.a.example.",
+		"Z.a.example.",
+		"zABC.a.EXAMPLE.",
+		"z.example.",
+		"\\001.z.example.",
+	}
+	for i := 1; i < len(names); i++ {
+		require.Negative(t, compareNames(names[i-1], names[i]), names[i])
+	}
+}
@@ -0,0 +1,78 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Bucket is a token bucket of bytes shared by all connections attached to it.
+//
+// Callers take tokens before or after transferring data and sleep for the
+// deficit, so a single large transfer may briefly exceed the burst size.
+type Bucket struct {
+	access sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func NewBucket(bytesPerSecond uint64) *Bucket {
+	return &Bucket{
+		rate:   float64(bytesPerSecond),
+		burst:  float64(bytesPerSecond),
+		tokens: float64(bytesPerSecond),
+		last:   time.Now(),
+	}
+}
+
+// SetRate changes the rate of the bucket, the burst size follows the rate.
+func (b *Bucket) SetRate(bytesPerSecond uint64) {
+	b.access.Lock()
+	defer b.access.Unlock()
+	b.refill(time.Now())
+	b.rate = float64(bytesPerSecond)
+	b.burst = float64(bytesPerSecond)
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+}
+
+func (b *Bucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += elapsed.Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+	}
+	b.last = now
+}
+
+func (b *Bucket) reserve(now time.Time, n int) time.Duration {
+	b.access.Lock()
+	defer b.access.Unlock()
+	b.refill(now)
+	b.tokens -= float64(n)
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// Wait takes n tokens from the bucket and blocks until they are available
+// or done is closed.
+func (b *Bucket) Wait(n int, done <-chan struct{}) {
+	if n <= 0 {
+		return
+	}
+	delay := b.reserve(time.Now(), n)
+	if delay <= 0 {
+		return
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-done:
+	}
+}
@@ -0,0 +1,28 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucketReserve(t *testing.T) {
+	t.Parallel()
+	bucket := NewBucket(1000)
+	now := bucket.last
+	require.Zero(t, bucket.reserve(now, 1000))
+	require.Equal(t, 500*time.Millisecond, bucket.reserve(now, 500))
+	require.Zero(t, bucket.reserve(now.Add(time.Second), 0))
+	require.Equal(t, time.Second, bucket.reserve(now.Add(time.Second), 1500))
+}
+
+func TestBucketBurst(t *testing.T) {
+	t.Parallel()
+	bucket := NewBucket(1000)
+	now := bucket.last.Add(time.Hour)
+	require.Zero(t, bucket.reserve(now, 1000))
+	require.Equal(t, 100*time.Millisecond, bucket.reserve(now, 100))
+	bucket.SetRate(2000)
+	require.Equal(t, 100*time.Millisecond, bucket.reserve(bucket.last, 100))
+}
@@ -0,0 +1,118 @@
+package ratelimit
+
+import (
+	"net"
+	"sync"
+
+	"github.com/sagernet/sing/common/buf"
+	"github.com/sagernet/sing/common/bufio"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+type conn struct {
+	N.ExtendedConn
+	upload    *Bucket
+	download  *Bucket
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newConn(upstream net.Conn, upload *Bucket, download *Bucket) *conn {
+	return &conn{
+		ExtendedConn: bufio.NewExtendedConn(upstream),
+		upload:       upload,
+		download:     download,
+		done:         make(chan struct{}),
+	}
+}
+
+func (c *conn) Read(p []byte) (n int, err error) {
+	n, err = c.ExtendedConn.Read(p)
+	if c.upload != nil {
+		c.upload.Wait(n, c.done)
+	}
+	return
+}
+
+func (c *conn) ReadBuffer(buffer *buf.Buffer) error {
+	err := c.ExtendedConn.ReadBuffer(buffer)
+	if err != nil {
+		return err
+	}
+	if c.upload != nil {
+		c.upload.Wait(buffer.Len(), c.done)
+	}
+	return nil
+}
+
+func (c *conn) Write(p []byte) (n int, err error) {
+	if c.download != nil {
+		c.download.Wait(len(p), c.done)
+	}
+	return c.ExtendedConn.Write(p)
+}
+
+func (c *conn) WriteBuffer(buffer *buf.Buffer) error {
+	if c.download != nil {
+		c.download.Wait(buffer.Len(), c.done)
+	}
+	return c.ExtendedConn.WriteBuffer(buffer)
+}
+
+func (c *conn) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
+	return c.ExtendedConn.Close()
+}
+
+func (c *conn) Upstream() any {
+	return c.ExtendedConn
+}
+
+type packetConn struct {
+	N.PacketConn
+	upload    *Bucket
+	download  *Bucket
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newPacketConn(upstream N.PacketConn, upload *Bucket, download *Bucket) *packetConn {
+	return &packetConn{
+		PacketConn: upstream,
+		upload:     upload,
+		download:   download,
+		done:       make(chan struct{}),
+	}
+}
+
+func (c *packetConn) ReadPacket(buffer *buf.Buffer) (destination M.Socksaddr, err error) {
+	destination, err = c.PacketConn.ReadPacket(buffer)
+	if err != nil {
+		return
+	}
+	if c.upload != nil {
+		c.upload.Wait(buffer.Len(), c.done)
+	}
+	return
+}
+
+func (c *packetConn) WritePacket(buffer *buf.Buffer, destination M.Socksaddr) error {
+	if c.download != nil {
+		c.download.Wait(buffer.Len(), c.done)
+	}
+	return c.PacketConn.WritePacket(buffer, destination)
+}
+
+func (c *packetConn) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
+	return c.PacketConn.Close()
+}
+
+func (c *packetConn) Upstream() any {
+	return c.PacketConn
+}
@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"net"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/bufio"
+	N "github.com/sagernet/sing/common/network"
+)
+
+// Limiter limits the upload and download bandwidth of the connections it wraps.
+// Connections are wrapped on the inbound side, so reads are uploads and writes
+// are downloads.
+type Limiter struct {
+	upload   *Bucket
+	download *Bucket
+}
+
+// NewLimiter returns nil if neither direction is limited.
+func NewLimiter(options option.BandwidthLimitOptions) *Limiter {
+	if options.UpMbps <= 0 && options.DownMbps <= 0 {
+		return nil
+	}
+	limiter := &Limiter{}
+	if options.UpMbps > 0 {
+		limiter.upload = NewBucket(uint64(options.UpMbps * C.MbpsToBps))
+	}
+	if options.DownMbps > 0 {
+		limiter.download = NewBucket(uint64(options.DownMbps * C.MbpsToBps))
+	}
+	return limiter
+}
+
+// Update changes the rates of an existing limiter. A direction that was not
+// limited when the limiter was created stays unlimited.
+func (l *Limiter) Update(options option.BandwidthLimitOptions) {
+	if l.upload != nil && options.UpMbps > 0 {
+		l.upload.SetRate(uint64(options.UpMbps * C.MbpsToBps))
+	}
+	if l.download != nil && options.DownMbps > 0 {
+		l.download.SetRate(uint64(options.DownMbps * C.MbpsToBps))
+	}
+}
+
+func (l *Limiter) NewConn(conn net.Conn) net.Conn {
+	if l == nil {
+		return conn
+	}
+	return newConn(conn, l.upload, l.download)
+}
+
+func (l *Limiter) NewPacketConn(conn N.PacketConn) N.PacketConn {
+	if l == nil {
+		return conn
+	}
+	return newPacketConn(conn, l.upload, l.download)
+}
+
+// NewDialedConn wraps a connection dialed by an outbound, where reads are
+// downloads and writes are uploads.
+func (l *Limiter) NewDialedConn(conn net.Conn) net.Conn {
+	if l == nil {
+		return conn
+	}
+	return newConn(conn, l.download, l.upload)
+}
+
+func (l *Limiter) NewDialedPacketConn(conn net.PacketConn) net.PacketConn {
+	if l == nil {
+		return conn
+	}
+	return bufio.NewNetPacketConn(newPacketConn(bufio.NewPacketConn(conn), l.download, l.upload))
+}
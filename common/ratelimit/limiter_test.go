@@ -0,0 +1,38 @@
+package ratelimit
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiterDirections(t *testing.T) {
+	t.Parallel()
+	for _, dialed := range []bool{false, true} {
+		limiter := &Limiter{upload: NewBucket(1000), download: NewBucket(1000)}
+		client, peer := net.Pipe()
+		var conn net.Conn
+		if dialed {
+			conn = limiter.NewDialedConn(client)
+		} else {
+			conn = limiter.NewConn(client)
+		}
+		go peer.Read(make([]byte, 100))
+		_, err := conn.Write(make([]byte, 100))
+		require.NoError(t, err)
+		go peer.Write(make([]byte, 10))
+		_, err = conn.Read(make([]byte, 100))
+		require.NoError(t, err)
+		conn.Close()
+		peer.Close()
+
+		// inbound connections write downloads, dialed connections write uploads
+		written, read := limiter.download, limiter.upload
+		if dialed {
+			written, read = limiter.upload, limiter.download
+		}
+		require.InDelta(t, written.burst-100, written.tokens, 20, "dialed: ", dialed)
+		require.InDelta(t, read.burst-10, read.tokens, 20, "dialed: ", dialed)
+	}
+}
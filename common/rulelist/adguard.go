@@ -0,0 +1,139 @@
+package rulelist
+
+import (
+	"net/netip"
+	"regexp"
+	"strings"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+)
+
+// parseAdGuard converts AdGuard / Adblock Plus filter lists. Only network
+// rules that match on the host name are kept; cosmetic rules and rules with
+// modifiers other than $important are ignored. Exception rules (@@) are
+// subtracted from the blocking rules.
+func parseAdGuard(content []byte) ([]option.HeadlessRule, error) {
+	var blockRules, exceptionRules domainRuleBuilder
+	for _, line := range readLines(content) {
+		if isAdGuardComment(line) {
+			continue
+		}
+		builder := &blockRules
+		if strings.HasPrefix(line, "@@") {
+			builder = &exceptionRules
+			line = line[2:]
+		}
+		parseAdGuardLine(builder, line)
+	}
+	if blockRules.IsEmpty() {
+		return nil, nil
+	}
+	if exceptionRules.IsEmpty() {
+		return []option.HeadlessRule{newDefaultRule(blockRules.Build())}, nil
+	}
+	exceptionOptions := exceptionRules.Build()
+	exceptionOptions.Invert = true
+	return []option.HeadlessRule{{
+		Type: C.RuleTypeLogical,
+		LogicalOptions: option.LogicalHeadlessRule{
+			Mode: C.LogicalTypeAnd,
+			Rules: []option.HeadlessRule{
+				newDefaultRule(blockRules.Build()),
+				newDefaultRule(exceptionOptions),
+			},
+		},
+	}}, nil
+}
+
+func isAdGuardComment(line string) bool {
+	if line[0] == '!' || line[0] == '[' {
+		return true
+	}
+	if line[0] == '#' && !strings.HasPrefix(line, "##") {
+		return true
+	}
+	for _, marker := range []string{"##", "#@#", "#?#", "#$#", "#%#", "$$", "$@$"} {
+		if strings.Contains(line, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseAdGuardLine(builder *domainRuleBuilder, line string) {
+	if len(line) > 2 && line[0] == '/' && line[len(line)-1] == '/' {
+		expr := line[1 : len(line)-1]
+		if _, err := regexp.Compile(expr); err == nil {
+			builder.domainRegex.Add(expr)
+		}
+		return
+	}
+	if fields := strings.Fields(line); len(fields) >= 2 {
+		if _, err := netip.ParseAddr(fields[0]); err == nil {
+			for _, name := range fields[1:] {
+				if name[0] == '#' {
+					break
+				}
+				name = strings.ToLower(name)
+				if isDomain(name) && !isLocalHostName(name) {
+					builder.domain.Add(name)
+				}
+			}
+		}
+		return
+	}
+	if index := strings.LastIndexByte(line, '$'); index >= 0 {
+		for _, modifier := range strings.Split(line[index+1:], ",") {
+			if modifier != "important" {
+				return
+			}
+		}
+		line = line[:index]
+	}
+	var suffixAnchor, startAnchor, endAnchor bool
+	if strings.HasPrefix(line, "||") {
+		suffixAnchor = true
+		line = line[2:]
+	} else if strings.HasPrefix(line, "|") {
+		startAnchor = true
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "^|") {
+		endAnchor = true
+		line = line[:len(line)-2]
+	} else if strings.HasSuffix(line, "^") || strings.HasSuffix(line, "|") {
+		endAnchor = true
+		line = line[:len(line)-1]
+	}
+	line = strings.ToLower(line)
+	if line == "" || strings.ContainsAny(line, "^|/:") {
+		return
+	}
+	if isDomain(line) {
+		switch {
+		case suffixAnchor && endAnchor:
+			builder.domainSuffix.Add(line)
+			return
+		case startAnchor && endAnchor:
+			builder.domain.Add(line)
+			return
+		case !suffixAnchor && !startAnchor && !endAnchor:
+			builder.domainKeyword.Add(line)
+			return
+		}
+	} else if !isDomain(strings.ReplaceAll(line, "*", "")) {
+		return
+	}
+	var expr strings.Builder
+	if suffixAnchor {
+		expr.WriteString(`(^|\.)`)
+	} else if startAnchor {
+		expr.WriteString("^")
+	}
+	expr.WriteString(strings.ReplaceAll(regexp.QuoteMeta(line), `\*`, ".*"))
+	if endAnchor {
+		expr.WriteString("$")
+	}
+	builder.domainRegex.Add(expr.String())
+}
@@ -0,0 +1,216 @@
+package rulelist
+
+import (
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+
+	"gopkg.in/yaml.v3"
+)
+
+type clashRuleProvider struct {
+	Payload []string `yaml:"payload"`
+}
+
+// readClashPayload accepts both the YAML (payload: [...]) and the plain text
+// rule provider layouts.
+func readClashPayload(content []byte) ([]string, error) {
+	lines := readLines(content)
+	for _, line := range lines {
+		if strings.HasPrefix(line, "payload:") {
+			var provider clashRuleProvider
+			err := yaml.Unmarshal(content, &provider)
+			if err != nil {
+				return nil, E.Cause(err, "decode clash rule provider")
+			}
+			return provider.Payload, nil
+		}
+	}
+	payload := lines[:0]
+	for _, line := range lines {
+		if line[0] == '#' || strings.HasPrefix(line, "//") {
+			continue
+		}
+		payload = append(payload, line)
+	}
+	return payload, nil
+}
+
+func parseClashDomain(content []byte) ([]option.HeadlessRule, error) {
+	payload, err := readClashPayload(content)
+	if err != nil {
+		return nil, err
+	}
+	var builder domainRuleBuilder
+	for _, item := range payload {
+		addClashDomain(&builder, strings.ToLower(strings.TrimSpace(item)))
+	}
+	if builder.IsEmpty() {
+		return nil, nil
+	}
+	return []option.HeadlessRule{newDefaultRule(builder.Build())}, nil
+}
+
+func addClashDomain(builder *domainRuleBuilder, item string) {
+	switch {
+	case strings.HasPrefix(item, "+."):
+		if isDomain(item[2:]) {
+			builder.domainSuffix.Add(item[2:])
+		}
+	case strings.HasPrefix(item, "."):
+		if isDomain(item[1:]) {
+			builder.domainSuffix.Add(item)
+		}
+	case strings.Contains(item, "*"):
+		if !isDomain(strings.ReplaceAll(item, "*", "")) {
+			return
+		}
+		builder.domainRegex.Add("^" + strings.ReplaceAll(regexp.QuoteMeta(item), `\*`, `[^.]+`) + "$")
+	default:
+		if isDomain(item) {
+			builder.domain.Add(item)
+		}
+	}
+}
+
+func parseClashIPCIDR(content []byte) ([]option.HeadlessRule, error) {
+	payload, err := readClashPayload(content)
+	if err != nil {
+		return nil, err
+	}
+	var builder domainRuleBuilder
+	for _, item := range payload {
+		if prefix, ok := parseClashPrefix(item); ok {
+			builder.ipCIDR.Add(prefix)
+		}
+	}
+	if builder.IsEmpty() {
+		return nil, nil
+	}
+	return []option.HeadlessRule{newDefaultRule(builder.Build())}, nil
+}
+
+func parseClashPrefix(item string) (string, bool) {
+	item = strings.TrimSpace(item)
+	if prefix, err := netip.ParsePrefix(item); err == nil {
+		return prefix.Masked().String(), true
+	}
+	if addr, err := netip.ParseAddr(item); err == nil {
+		return netip.PrefixFrom(addr, addr.BitLen()).String(), true
+	}
+	return "", false
+}
+
+// parseClashClassical converts classical rule providers. Conditions are
+// grouped by kind into separate rules, since a rule-set matches if any of
+// its rules does. Rule types without a headless equivalent (GEOIP, AND, ...)
+// are ignored.
+func parseClashClassical(content []byte) ([]option.HeadlessRule, error) {
+	payload, err := readClashPayload(content)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		destination     domainRuleBuilder
+		sourceIPCIDR    stringSet
+		port            []uint16
+		portRange       []string
+		sourcePort      []uint16
+		sourcePortRange []string
+		processName     stringSet
+		processPath     stringSet
+		network         stringSet
+	)
+	for _, item := range payload {
+		fields := strings.Split(item, ",")
+		if len(fields) < 2 {
+			continue
+		}
+		value := strings.TrimSpace(fields[1])
+		if value == "" {
+			continue
+		}
+		switch strings.ToUpper(strings.TrimSpace(fields[0])) {
+		case "DOMAIN":
+			if value = strings.ToLower(value); isDomain(value) {
+				destination.domain.Add(value)
+			}
+		case "DOMAIN-SUFFIX":
+			if value = strings.ToLower(value); isDomain(value) {
+				destination.domainSuffix.Add(value)
+			}
+		case "DOMAIN-KEYWORD":
+			destination.domainKeyword.Add(strings.ToLower(value))
+		case "DOMAIN-REGEX":
+			if _, err = regexp.Compile(value); err == nil {
+				destination.domainRegex.Add(value)
+			}
+		case "DOMAIN-WILDCARD":
+			addClashDomain(&destination, strings.ToLower(value))
+		case "IP-CIDR", "IP-CIDR6":
+			if prefix, ok := parseClashPrefix(value); ok {
+				destination.ipCIDR.Add(prefix)
+			}
+		case "SRC-IP-CIDR":
+			if prefix, ok := parseClashPrefix(value); ok {
+				sourceIPCIDR.Add(prefix)
+			}
+		case "DST-PORT":
+			port, portRange = appendClashPort(port, portRange, value)
+		case "SRC-PORT":
+			sourcePort, sourcePortRange = appendClashPort(sourcePort, sourcePortRange, value)
+		case "PROCESS-NAME":
+			processName.Add(value)
+		case "PROCESS-PATH":
+			processPath.Add(value)
+		case "NETWORK":
+			switch strings.ToLower(value) {
+			case "tcp", "udp":
+				network.Add(strings.ToLower(value))
+			}
+		}
+	}
+	var rules []option.HeadlessRule
+	if !destination.IsEmpty() {
+		rules = append(rules, newDefaultRule(destination.Build()))
+	}
+	if len(sourceIPCIDR.values) > 0 {
+		rules = append(rules, newDefaultRule(option.DefaultHeadlessRule{SourceIPCIDR: sourceIPCIDR.Values()}))
+	}
+	if len(port) > 0 || len(portRange) > 0 {
+		rules = append(rules, newDefaultRule(option.DefaultHeadlessRule{Port: port, PortRange: portRange}))
+	}
+	if len(sourcePort) > 0 || len(sourcePortRange) > 0 {
+		rules = append(rules, newDefaultRule(option.DefaultHeadlessRule{SourcePort: sourcePort, SourcePortRange: sourcePortRange}))
+	}
+	if len(processName.values) > 0 {
+		rules = append(rules, newDefaultRule(option.DefaultHeadlessRule{ProcessName: processName.Values()}))
+	}
+	if len(processPath.values) > 0 {
+		rules = append(rules, newDefaultRule(option.DefaultHeadlessRule{ProcessPath: processPath.Values()}))
+	}
+	if len(network.values) > 0 {
+		rules = append(rules, newDefaultRule(option.DefaultHeadlessRule{Network: network.Values()}))
+	}
+	return rules, nil
+}
+
+func appendClashPort(ports []uint16, portRanges []string, value string) ([]uint16, []string) {
+	for _, item := range strings.Split(value, "/") {
+		if from, to, isRange := strings.Cut(item, "-"); isRange {
+			fromPort, fromErr := strconv.ParseUint(from, 10, 16)
+			toPort, toErr := strconv.ParseUint(to, 10, 16)
+			if fromErr == nil && toErr == nil && fromPort <= toPort {
+				portRanges = append(portRanges, F.ToString(fromPort, ":", toPort))
+			}
+		} else if port, err := strconv.ParseUint(item, 10, 16); err == nil {
+			ports = append(ports, uint16(port))
+		}
+	}
+	return ports, portRanges
+}
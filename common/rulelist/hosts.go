@@ -0,0 +1,45 @@
+package rulelist
+
+import (
+	"net/netip"
+	"strings"
+
+	"github.com/sagernet/sing-box/option"
+)
+
+// parseHosts converts a hosts(5) file into an exact domain rule. Addresses
+// are ignored, so both sinkhole (0.0.0.0) and redirect style lists work.
+func parseHosts(content []byte) ([]option.HeadlessRule, error) {
+	var builder domainRuleBuilder
+	for _, line := range readLines(content) {
+		if index := strings.IndexByte(line, '#'); index >= 0 {
+			line = line[:index]
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		if _, err := netip.ParseAddr(fields[0]); err != nil {
+			continue
+		}
+		for _, name := range fields[1:] {
+			name = strings.TrimSuffix(strings.ToLower(name), ".")
+			if isDomain(name) && !isLocalHostName(name) {
+				builder.domain.Add(name)
+			}
+		}
+	}
+	if builder.IsEmpty() {
+		return nil, nil
+	}
+	return []option.HeadlessRule{newDefaultRule(builder.Build())}, nil
+}
+
+func isLocalHostName(name string) bool {
+	switch name {
+	case "localhost", "localhost.localdomain", "local", "broadcasthost", "0.0.0.0":
+		return true
+	default:
+		return strings.HasPrefix(name, "ip6-")
+	}
+}
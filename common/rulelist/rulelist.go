@@ -0,0 +1,130 @@
+package rulelist
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+func IsFormat(format string) bool {
+	switch format {
+	case C.RuleSetFormatAdGuard, C.RuleSetFormatHosts, C.RuleSetFormatClashClassical, C.RuleSetFormatClashDomain, C.RuleSetFormatClashIPCIDR:
+		return true
+	default:
+		return false
+	}
+}
+
+// Parse converts a third-party rule list into a plain rule-set.
+// Lines that cannot be expressed as headless rules are skipped.
+func Parse(format string, content []byte) (option.PlainRuleSet, error) {
+	var (
+		rules []option.HeadlessRule
+		err   error
+	)
+	switch format {
+	case C.RuleSetFormatAdGuard:
+		rules, err = parseAdGuard(content)
+	case C.RuleSetFormatHosts:
+		rules, err = parseHosts(content)
+	case C.RuleSetFormatClashClassical:
+		rules, err = parseClashClassical(content)
+	case C.RuleSetFormatClashDomain:
+		rules, err = parseClashDomain(content)
+	case C.RuleSetFormatClashIPCIDR:
+		rules, err = parseClashIPCIDR(content)
+	default:
+		return option.PlainRuleSet{}, E.New("unknown rule list format: ", format)
+	}
+	if err != nil {
+		return option.PlainRuleSet{}, err
+	}
+	if len(rules) == 0 {
+		return option.PlainRuleSet{}, E.New("no supported rules found in ", format, " list")
+	}
+	return option.PlainRuleSet{Rules: rules}, nil
+}
+
+func readLines(content []byte) []string {
+	content = bytes.TrimPrefix(content, []byte("\xef\xbb\xbf"))
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(nil, len(content)+1)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func isDomainLabelChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '_'
+}
+
+func isDomain(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isDomainLabelChar(s[i]) && s[i] != '.' {
+			return false
+		}
+	}
+	return true
+}
+
+type stringSet struct {
+	values []string
+	seen   map[string]bool
+}
+
+func (s *stringSet) Add(value string) {
+	if s.seen == nil {
+		s.seen = make(map[string]bool)
+	}
+	if s.seen[value] {
+		return
+	}
+	s.seen[value] = true
+	s.values = append(s.values, value)
+}
+
+func (s *stringSet) Values() []string {
+	return s.values
+}
+
+type domainRuleBuilder struct {
+	domain        stringSet
+	domainSuffix  stringSet
+	domainKeyword stringSet
+	domainRegex   stringSet
+	ipCIDR        stringSet
+}
+
+func (b *domainRuleBuilder) IsEmpty() bool {
+	return len(b.domain.values) == 0 && len(b.domainSuffix.values) == 0 && len(b.domainKeyword.values) == 0 && len(b.domainRegex.values) == 0 && len(b.ipCIDR.values) == 0
+}
+
+func (b *domainRuleBuilder) Build() option.DefaultHeadlessRule {
+	return option.DefaultHeadlessRule{
+		Domain:        b.domain.Values(),
+		DomainSuffix:  b.domainSuffix.Values(),
+		DomainKeyword: b.domainKeyword.Values(),
+		DomainRegex:   b.domainRegex.Values(),
+		IPCIDR:        b.ipCIDR.Values(),
+	}
+}
+
+func newDefaultRule(options option.DefaultHeadlessRule) option.HeadlessRule {
+	return option.HeadlessRule{
+		Type:           C.RuleTypeDefault,
+		DefaultOptions: options,
+	}
+}
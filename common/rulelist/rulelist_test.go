@@ -0,0 +1,137 @@
+package rulelist_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/rulelist"
+	"github.com/sagernet/sing-box/common/srs"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/route"
+	M "github.com/sagernet/sing/common/metadata"
+
+	"github.com/stretchr/testify/require"
+)
+
+func matchRuleList(t *testing.T, format string, content string) func(destination string) bool {
+	plainRuleSet, err := rulelist.Parse(format, []byte(content))
+	require.NoError(t, err)
+	var buffer bytes.Buffer
+	require.NoError(t, srs.Write(&buffer, plainRuleSet, C.RuleSetVersion1))
+	compat, err := srs.Read(&buffer, false)
+	require.NoError(t, err)
+	plainRuleSet, err = compat.Upgrade()
+	require.NoError(t, err)
+	rules := make([]adapter.HeadlessRule, len(plainRuleSet.Rules))
+	for i, ruleOptions := range plainRuleSet.Rules {
+		rules[i], err = route.NewHeadlessRule(nil, ruleOptions)
+		require.NoError(t, err)
+	}
+	return func(destination string) bool {
+		var metadata adapter.InboundContext
+		if addr := M.ParseAddr(destination); addr.IsValid() {
+			metadata.Destination = M.SocksaddrFrom(addr, 443)
+		} else {
+			metadata.Domain = destination
+			metadata.Destination = M.ParseSocksaddrHostPort(destination, 443)
+		}
+		for _, rule := range rules {
+			if rule.Match(&metadata) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func TestAdGuard(t *testing.T) {
+	t.Parallel()
+	match := matchRuleList(t, C.RuleSetFormatAdGuard, `[Adblock Plus 2.0]
+! Title: test
+||ads.example.com^
+||tracker.example.org^$important
+|exact.example.net^
+||*.wild.example^
+/^banner[0-9]+\./
+0.0.0.0 hosts.example
+example.com##.banner
+||third.example^$third-party
+@@||good.ads.example.com^
+`)
+	require.True(t, match("ads.example.com"))
+	require.True(t, match("x.ads.example.com"))
+	require.False(t, match("good.ads.example.com"))
+	require.True(t, match("tracker.example.org"))
+	require.True(t, match("exact.example.net"))
+	require.False(t, match("sub.exact.example.net"))
+	require.True(t, match("a.wild.example"))
+	require.True(t, match("banner12.example"))
+	require.True(t, match("hosts.example"))
+	require.False(t, match("example.com"))
+	require.False(t, match("third.example"))
+}
+
+func TestHosts(t *testing.T) {
+	t.Parallel()
+	match := matchRuleList(t, C.RuleSetFormatHosts, `# comment
+127.0.0.1 localhost
+::1 localhost ip6-localhost
+0.0.0.0 0.0.0.0
+0.0.0.0 ads.example.com tracker.example.com # inline
+`)
+	require.True(t, match("ads.example.com"))
+	require.True(t, match("tracker.example.com"))
+	require.False(t, match("sub.ads.example.com"))
+	require.False(t, match("localhost"))
+}
+
+func TestClashDomain(t *testing.T) {
+	t.Parallel()
+	match := matchRuleList(t, C.RuleSetFormatClashDomain, `payload:
+  - 'exact.example.com'
+  - '+.suffix.example.com'
+  - '.sub.example.com'
+  - '*.single.example.com'
+`)
+	require.True(t, match("exact.example.com"))
+	require.True(t, match("suffix.example.com"))
+	require.True(t, match("a.b.suffix.example.com"))
+	require.False(t, match("sub.example.com"))
+	require.True(t, match("a.sub.example.com"))
+	require.True(t, match("a.single.example.com"))
+	require.False(t, match("a.b.single.example.com"))
+}
+
+func TestClashIPCIDR(t *testing.T) {
+	t.Parallel()
+	match := matchRuleList(t, C.RuleSetFormatClashIPCIDR, `# text layout
+10.0.0.0/8
+2001:db8::/32
+192.0.2.1
+`)
+	require.True(t, match("10.1.2.3"))
+	require.True(t, match("2001:db8::1"))
+	require.True(t, match("192.0.2.1"))
+	require.False(t, match("192.0.2.2"))
+}
+
+func TestClashClassical(t *testing.T) {
+	t.Parallel()
+	match := matchRuleList(t, C.RuleSetFormatClashClassical, `payload:
+  - DOMAIN-SUFFIX,example.com
+  - DOMAIN-KEYWORD,tracker
+  - IP-CIDR,198.51.100.0/24,no-resolve
+  - GEOIP,CN
+`)
+	require.True(t, match("www.example.com"))
+	require.True(t, match("tracker.example.org"))
+	require.True(t, match("198.51.100.7"))
+	require.False(t, match("example.org"))
+}
+
+func TestEmptyRuleList(t *testing.T) {
+	t.Parallel()
+	_, err := rulelist.Parse(C.RuleSetFormatHosts, []byte("# nothing here\n"))
+	require.Error(t, err)
+}
@@ -111,3 +111,96 @@ func UDPTracker(_ context.Context, packet []byte) (*adapter.InboundContext, erro
 		return nil, os.ErrInvalid
 	}
 }
+
+// DHT detects if the packet is a BitTorrent DHT KRPC message.
+// For the DHT protocol specification, see https://www.bittorrent.org/beps/bep_0005.html
+func DHT(_ context.Context, packet []byte) (*adapter.InboundContext, error) {
+	if len(packet) < 2 || packet[0] != 'd' || packet[len(packet)-1] != 'e' {
+		return nil, os.ErrInvalid
+	}
+	var (
+		hasTransactionID bool
+		messageType      []byte
+	)
+	index := 1
+	for index < len(packet) && packet[index] != 'e' {
+		key, next, err := readBencodeString(packet, index)
+		if err != nil {
+			return nil, err
+		}
+		valueIndex := next
+		next, err = skipBencodeValue(packet, valueIndex, 0)
+		if err != nil {
+			return nil, err
+		}
+		switch string(key) {
+		case "t":
+			hasTransactionID = true
+		case "y":
+			messageType, _, err = readBencodeString(packet, valueIndex)
+			if err != nil {
+				return nil, err
+			}
+		}
+		index = next
+	}
+	if index != len(packet)-1 || !hasTransactionID {
+		return nil, os.ErrInvalid
+	}
+	switch string(messageType) {
+	case "q", "r", "e":
+		return &adapter.InboundContext{
+			Protocol: C.ProtocolBitTorrent,
+		}, nil
+	default:
+		return nil, os.ErrInvalid
+	}
+}
+
+const bencodeMaxDepth = 8
+
+func readBencodeString(data []byte, index int) ([]byte, int, error) {
+	length := 0
+	start := index
+	for ; index < len(data) && data[index] != ':'; index++ {
+		if data[index] < '0' || data[index] > '9' || length > len(data) {
+			return nil, 0, os.ErrInvalid
+		}
+		length = length*10 + int(data[index]-'0')
+	}
+	if index == start || index >= len(data) || len(data)-index-1 < length {
+		return nil, 0, os.ErrInvalid
+	}
+	index++
+	return data[index : index+length], index + length, nil
+}
+
+func skipBencodeValue(data []byte, index int, depth int) (int, error) {
+	if index >= len(data) || depth > bencodeMaxDepth {
+		return 0, os.ErrInvalid
+	}
+	switch data[index] {
+	case 'i':
+		end := bytes.IndexByte(data[index:], 'e')
+		if end < 2 {
+			return 0, os.ErrInvalid
+		}
+		return index + end + 1, nil
+	case 'l', 'd':
+		index++
+		for index < len(data) && data[index] != 'e' {
+			var err error
+			index, err = skipBencodeValue(data, index, depth+1)
+			if err != nil {
+				return 0, err
+			}
+		}
+		if index >= len(data) {
+			return 0, os.ErrInvalid
+		}
+		return index + 1, nil
+	default:
+		_, next, err := readBencodeString(data, index)
+		return next, err
+	}
+}
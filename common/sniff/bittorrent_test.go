@@ -79,3 +79,42 @@ func TestSniffUDPTracker(t *testing.T) {
 		require.Equal(t, C.ProtocolBitTorrent, metadata.Protocol)
 	}
 }
+
+func TestSniffDHT(t *testing.T) {
+	t.Parallel()
+
+	packets := []string{
+		"d1:ad2:id20:abcdefghij0123456789e1:q4:ping1:t2:aa1:y1:qe",
+		"d1:rd2:id20:mnopqrstuvwxyz123456e1:t2:aa1:y1:re",
+		"d1:eli201e23:A Generic Error Ocurrede1:t2:aa1:y1:ee",
+		"d1:ad2:id20:abcdefghij01234567899:info_hash20:mnopqrstuvwxyz123456e1:q9:get_peers1:t2:aa1:y1:qe",
+	}
+
+	for _, pkt := range packets {
+		metadata, err := sniff.DHT(context.TODO(), []byte(pkt))
+		require.NoError(t, err)
+		require.Equal(t, C.ProtocolBitTorrent, metadata.Protocol)
+	}
+}
+
+func TestSniffNotDHT(t *testing.T) {
+	t.Parallel()
+
+	packets := []string{
+		"d1:ad2:id20:abcdefghij0123456789e1:q4:ping1:y1:qe",
+		"d1:t2:aa1:y1:xe",
+		"d1:t2:aa1:y1:q",
+		"d1:t20:aa1:y1:qe",
+	}
+
+	for _, pkt := range packets {
+		_, err := sniff.DHT(context.TODO(), []byte(pkt))
+		require.Error(t, err)
+	}
+}
+
+func FuzzSniffDHT(f *testing.F) {
+	f.Fuzz(func(t *testing.T, data []byte) {
+		sniff.DHT(context.TODO(), data)
+	})
+}
@@ -0,0 +1,87 @@
+package sniff
+
+import (
+	"context"
+	"encoding/binary"
+	"os"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	M "github.com/sagernet/sing/common/metadata"
+)
+
+const (
+	dtlsRecordHeaderSize    = 13
+	dtlsHandshakeHeaderSize = 12
+	dtlsVersion10           = 0xFEFF
+	dtlsVersion12           = 0xFEFD
+	tlsExtensionServerName  = 0
+)
+
+// DTLSClientHello detects if the packet is a DTLS ClientHello, and reads the server name, ALPN and fingerprint
+// if the message is not fragmented.
+// For the DTLS protocol specification, see https://www.rfc-editor.org/rfc/rfc6347#section-4.2
+func DTLSClientHello(_ context.Context, packet []byte) (*adapter.InboundContext, error) {
+	if len(packet) < dtlsRecordHeaderSize+dtlsHandshakeHeaderSize {
+		return nil, os.ErrInvalid
+	}
+	if packet[0] != tlsContentHandshake {
+		return nil, os.ErrInvalid
+	}
+	version := binary.BigEndian.Uint16(packet[1:3])
+	if version != dtlsVersion10 && version != dtlsVersion12 {
+		return nil, os.ErrInvalid
+	}
+	// ClientHello is sent in epoch 0
+	if binary.BigEndian.Uint16(packet[3:5]) != 0 {
+		return nil, os.ErrInvalid
+	}
+	recordLength := int(binary.BigEndian.Uint16(packet[11:13]))
+	if recordLength < dtlsHandshakeHeaderSize || len(packet) < dtlsRecordHeaderSize+recordLength {
+		return nil, os.ErrInvalid
+	}
+	handshake := packet[dtlsRecordHeaderSize : dtlsRecordHeaderSize+recordLength]
+	if handshake[0] != tlsHandshakeClientHello {
+		return nil, os.ErrInvalid
+	}
+	messageLength := uint24(handshake[1:4])
+	fragmentOffset := uint24(handshake[6:9])
+	fragmentLength := uint24(handshake[9:12])
+	if fragmentOffset+fragmentLength > messageLength || dtlsHandshakeHeaderSize+fragmentLength > len(handshake) {
+		return nil, os.ErrInvalid
+	}
+	metadata := &adapter.InboundContext{Protocol: C.ProtocolDTLS}
+	if fragmentOffset == 0 && fragmentLength == messageLength {
+		hello := parseClientHello(handshake[dtlsHandshakeHeaderSize:dtlsHandshakeHeaderSize+fragmentLength], true)
+		if hello != nil {
+			if M.IsDomainName(hello.serverName) {
+				metadata.Domain = hello.serverName
+			}
+			metadata.ClientALPN = hello.alpn
+			metadata.ClientFingerprint = hello.fingerprint('d')
+		}
+	}
+	return metadata, nil
+}
+
+func uint24(b []byte) int {
+	return int(b[0])<<16 | int(b[1])<<8 | int(b[2])
+}
+
+func skipBytes(b []byte, n int) []byte {
+	if len(b) < n {
+		return nil
+	}
+	return b[n:]
+}
+
+func skipVector(b []byte, lengthSize int) []byte {
+	if len(b) < lengthSize {
+		return nil
+	}
+	var length int
+	for _, l := range b[:lengthSize] {
+		length = length<<8 | int(l)
+	}
+	return skipBytes(b, lengthSize+length)
+}
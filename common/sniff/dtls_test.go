@@ -0,0 +1,37 @@
+package sniff_test
+
+import (
+	"context"
+	"encoding/hex"
+	"testing"
+
+	"github.com/sagernet/sing-box/common/sniff"
+	C "github.com/sagernet/sing-box/constant"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSniffDTLSClientHello(t *testing.T) {
+	t.Parallel()
+	pkt, err := hex.DecodeString("16feff000000000000000000d4010000c800000000000000c8fefdba0059e789d36412ddae646210c8bdb2d10e0332c141b58ec6cd4154bcd66c9700000038c02cc030009fcca9cca8ccaac02bc02f009ec024c028006bc023c0270067c00ac0140039c009c0130033009d009c003d003c0035002f00ff0100006600000010000e00000b6578616d706c652e636f6d000b000403000102000a000c000a001d0017001e00190018002300000016000000170000000d002a0028040305030603080708080809080a080b080408050806040105010601030303010302040205020602")
+	require.NoError(t, err)
+	metadata, err := sniff.DTLSClientHello(context.Background(), pkt)
+	require.NoError(t, err)
+	require.Equal(t, C.ProtocolDTLS, metadata.Protocol)
+	require.Equal(t, "example.com", metadata.Domain)
+	require.Equal(t, "dd2d", metadata.ClientFingerprint[:4])
+}
+
+func TestSniffDTLSApplicationData(t *testing.T) {
+	t.Parallel()
+	pkt, err := hex.DecodeString("17fefd000100000000000100200001000000000001c4e1f3d5a3b09e7f2b6c1d8e4f5a6b7c8d9e0f1a")
+	require.NoError(t, err)
+	_, err = sniff.DTLSClientHello(context.Background(), pkt)
+	require.Error(t, err)
+}
+
+func FuzzSniffDTLSClientHello(f *testing.F) {
+	f.Fuzz(func(t *testing.T, data []byte) {
+		sniff.DTLSClientHello(context.Background(), data)
+	})
+}
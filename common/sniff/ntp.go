@@ -0,0 +1,39 @@
+package sniff
+
+import (
+	"context"
+	"encoding/binary"
+	"os"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+)
+
+const (
+	ntpHeaderSize = 48
+	ntpModeClient = 3
+	// 16 seconds in the NTP short format
+	ntpMaxRootDistance = 16 << 16
+)
+
+// NTP detects if the packet is a NTP client request.
+// For the NTP protocol specification, see https://www.rfc-editor.org/rfc/rfc5905#section-7.3
+func NTP(_ context.Context, packet []byte) (*adapter.InboundContext, error) {
+	// Extension fields and MAC are 32-bit aligned
+	if len(packet) < ntpHeaderSize || len(packet)%4 != 0 {
+		return nil, os.ErrInvalid
+	}
+	version := (packet[0] >> 3) & 0x07
+	mode := packet[0] & 0x07
+	if version < 1 || version > 4 || mode != ntpModeClient {
+		return nil, os.ErrInvalid
+	}
+	// Stratum of the client is at most 16 (unsynchronized)
+	if packet[1] > 16 {
+		return nil, os.ErrInvalid
+	}
+	if binary.BigEndian.Uint32(packet[4:8]) > ntpMaxRootDistance || binary.BigEndian.Uint32(packet[8:12]) > ntpMaxRootDistance {
+		return nil, os.ErrInvalid
+	}
+	return &adapter.InboundContext{Protocol: C.ProtocolNTP}, nil
+}
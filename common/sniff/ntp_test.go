@@ -0,0 +1,35 @@
+package sniff_test
+
+import (
+	"context"
+	"encoding/hex"
+	"testing"
+
+	"github.com/sagernet/sing-box/common/sniff"
+	C "github.com/sagernet/sing-box/constant"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSniffNTP(t *testing.T) {
+	t.Parallel()
+	packets := []string{
+		"1b0000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000",
+		"230006e9000000000000000000000000000000000000000000000000000000000000000000000000e8da2a5e7c7a5a31",
+	}
+	for _, pkt := range packets {
+		pkt, err := hex.DecodeString(pkt)
+		require.NoError(t, err)
+		metadata, err := sniff.NTP(context.Background(), pkt)
+		require.NoError(t, err)
+		require.Equal(t, C.ProtocolNTP, metadata.Protocol)
+	}
+}
+
+func TestSniffNTPServerResponse(t *testing.T) {
+	t.Parallel()
+	pkt, err := hex.DecodeString("240203e800000b8c000001ebc0a80001e8da2a53a3d70a3de8da2a5e7c7a5a31e8da2a5e8a9fbe76e8da2a5e8aa3d70a")
+	require.NoError(t, err)
+	_, err = sniff.NTP(context.Background(), pkt)
+	require.Error(t, err)
+}
@@ -294,7 +294,7 @@ find:
 		}
 		return &adapter.InboundContext{Protocol: C.ProtocolQUIC}, E.New("bad fragments")
 	}
-	metadata, err := TLSClientHello(ctx, io.MultiReader(readers...))
+	metadata, err := tlsClientHello(ctx, io.MultiReader(readers...), 'q')
 	if err != nil {
 		return &adapter.InboundContext{Protocol: C.ProtocolQUIC}, err
 	}
@@ -17,6 +17,8 @@ func TestSniffQUICv1(t *testing.T) {
 	metadata, err := sniff.QUICClientHello(context.Background(), pkt)
 	require.NoError(t, err)
 	require.Equal(t, metadata.Domain, "cloudflare-quic.com")
+	require.Equal(t, []string{"h3"}, metadata.ClientALPN)
+	require.Equal(t, "q13d", metadata.ClientFingerprint[:4])
 }
 
 func TestSniffQUICFragment(t *testing.T) {
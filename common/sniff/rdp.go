@@ -0,0 +1,60 @@
+package sniff
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"os"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+)
+
+const (
+	tpktVersion            = 3
+	x224ConnectionRequest  = 0xE0
+	rdpNegotiationRequest  = 0x01
+	rdpNegotiationDataSize = 8
+)
+
+// RDP detects if the stream is a RDP connection by the X.224 Connection Request PDU.
+// For the RDP protocol specification, see https://learn.microsoft.com/en-us/openspecs/windows_protocols/ms-rdpbcgr/18a27ef9-6f9a-4501-b000-94b1fe3c2c10
+func RDP(_ context.Context, reader io.Reader) (*adapter.InboundContext, error) {
+	var header [11]byte
+	_, err := io.ReadFull(reader, header[:])
+	if err != nil {
+		return nil, err
+	}
+	// TPKT header
+	if header[0] != tpktVersion || header[1] != 0 {
+		return nil, os.ErrInvalid
+	}
+	packetLength := int(binary.BigEndian.Uint16(header[2:4]))
+	// X.224 Connection Request TPDU with zero destination reference and class 0
+	if packetLength < len(header) || int(header[4]) != packetLength-5 || header[5] != x224ConnectionRequest ||
+		binary.BigEndian.Uint16(header[6:8]) != 0 || header[10] != 0 {
+		return nil, os.ErrInvalid
+	}
+	data := make([]byte, packetLength-len(header))
+	_, err = io.ReadFull(reader, data)
+	if err != nil {
+		return nil, err
+	}
+	// Optional routing token or cookie, terminated by CRLF
+	if len(data) > 0 && data[0] != rdpNegotiationRequest {
+		index := bytes.Index(data, []byte("\r\n"))
+		if index < 0 {
+			return nil, os.ErrInvalid
+		}
+		data = data[index+2:]
+	}
+	// Optional RDP Negotiation Request
+	if len(data) > 0 {
+		if len(data) != rdpNegotiationDataSize || data[0] != rdpNegotiationRequest ||
+			binary.LittleEndian.Uint16(data[2:4]) != rdpNegotiationDataSize {
+			return nil, os.ErrInvalid
+		}
+	}
+	return &adapter.InboundContext{Protocol: C.ProtocolRDP}, nil
+}
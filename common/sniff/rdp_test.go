@@ -0,0 +1,42 @@
+package sniff_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"testing"
+
+	"github.com/sagernet/sing-box/common/sniff"
+	C "github.com/sagernet/sing-box/constant"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSniffRDP(t *testing.T) {
+	t.Parallel()
+	packets := []string{
+		"030000130ee000000000000100080003000000",
+		"0300002b26e00000000000436f6f6b69653a206d737473686173683d61646d696e0d0a0100080003000000",
+	}
+	for _, pkt := range packets {
+		pkt, err := hex.DecodeString(pkt)
+		require.NoError(t, err)
+		metadata, err := sniff.RDP(context.Background(), bytes.NewReader(pkt))
+		require.NoError(t, err)
+		require.Equal(t, C.ProtocolRDP, metadata.Protocol)
+	}
+}
+
+func TestSniffIncompleteRDP(t *testing.T) {
+	t.Parallel()
+	pkt, err := hex.DecodeString("0300002b26e00000000000436f6f6b69653a206d737473")
+	require.NoError(t, err)
+	_, err = sniff.RDP(context.Background(), bytes.NewReader(pkt))
+	require.Error(t, err)
+}
+
+func FuzzSniffRDP(f *testing.F) {
+	f.Fuzz(func(t *testing.T, data []byte) {
+		sniff.RDP(context.Background(), bytes.NewReader(data))
+	})
+}
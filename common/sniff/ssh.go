@@ -0,0 +1,27 @@
+package sniff
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+)
+
+// SSH detects if the stream is a SSH connection by the identification string sent by the client.
+// For the SSH protocol specification, see https://www.rfc-editor.org/rfc/rfc4253#section-4.2
+func SSH(_ context.Context, reader io.Reader) (*adapter.InboundContext, error) {
+	bReader := bufio.NewReaderSize(reader, 255)
+	line, err := bReader.ReadSlice('\n')
+	if err != nil {
+		return nil, err
+	}
+	identification := strings.TrimSuffix(strings.TrimSuffix(string(line), "\n"), "\r")
+	if !strings.HasPrefix(identification, "SSH-2.0-") && !strings.HasPrefix(identification, "SSH-1.99-") {
+		return nil, os.ErrInvalid
+	}
+	return &adapter.InboundContext{Protocol: C.ProtocolSSH}, nil
+}
@@ -1,6 +1,7 @@
 package sniff
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"io"
@@ -11,15 +12,26 @@ import (
 )
 
 func TLSClientHello(ctx context.Context, reader io.Reader) (*adapter.InboundContext, error) {
-	var clientHello *tls.ClientHelloInfo
-	err := tls.Server(bufio.NewReadOnlyConn(reader), &tls.Config{
+	return tlsClientHello(ctx, reader, 't')
+}
+
+func tlsClientHello(ctx context.Context, reader io.Reader, transport byte) (*adapter.InboundContext, error) {
+	var (
+		records     bytes.Buffer
+		clientHello *tls.ClientHelloInfo
+	)
+	err := tls.Server(bufio.NewReadOnlyConn(io.TeeReader(reader, &records)), &tls.Config{
 		GetConfigForClient: func(argHello *tls.ClientHelloInfo) (*tls.Config, error) {
 			clientHello = argHello
 			return nil, nil
 		},
 	}).HandshakeContext(ctx)
 	if clientHello != nil {
-		return &adapter.InboundContext{Protocol: C.ProtocolTLS, Domain: clientHello.ServerName}, nil
+		metadata := &adapter.InboundContext{Protocol: C.ProtocolTLS, Domain: clientHello.ServerName, ClientALPN: clientHello.SupportedProtos}
+		if hello := readTLSClientHello(records.Bytes()); hello != nil {
+			metadata.ClientFingerprint = hello.fingerprint(transport)
+		}
+		return metadata, nil
 	}
 	return nil, err
 }
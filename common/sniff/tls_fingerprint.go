@@ -0,0 +1,264 @@
+package sniff
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	tlsContentHandshake     = 22
+	tlsHandshakeClientHello = 1
+
+	tlsExtensionALPN                = 0x0010
+	tlsExtensionSignatureAlgorithms = 0x000d
+	tlsExtensionSupportedVersions   = 0x002b
+)
+
+type clientHello struct {
+	version             uint16
+	cipherSuites        []uint16
+	extensions          []uint16
+	serverName          string
+	alpn                []string
+	signatureAlgorithms []uint16
+	supportedVersions   []uint16
+}
+
+// readTLSClientHello reads the ClientHello message from TLS records.
+func readTLSClientHello(records []byte) *clientHello {
+	var message []byte
+	for len(records) >= 5 && records[0] == tlsContentHandshake {
+		recordLength := int(binary.BigEndian.Uint16(records[3:5]))
+		if len(records) < 5+recordLength {
+			break
+		}
+		message = append(message, records[5:5+recordLength]...)
+		records = records[5+recordLength:]
+	}
+	if len(message) < 4 || message[0] != tlsHandshakeClientHello {
+		return nil
+	}
+	messageLength := uint24(message[1:4])
+	if len(message) < 4+messageLength {
+		return nil
+	}
+	return parseClientHello(message[4:4+messageLength], false)
+}
+
+// parseClientHello parses the body of a ClientHello message, which contains an additional cookie field in DTLS.
+// For the message structure, see https://www.rfc-editor.org/rfc/rfc8446#section-4.1.2
+func parseClientHello(message []byte, dtls bool) *clientHello {
+	if len(message) < 2+32 {
+		return nil
+	}
+	hello := &clientHello{
+		version: binary.BigEndian.Uint16(message),
+	}
+	// random
+	message = skipBytes(message, 2+32)
+	// session_id
+	message = skipVector(message, 1)
+	if dtls {
+		// cookie
+		message = skipVector(message, 1)
+	}
+	if len(message) < 2 {
+		return nil
+	}
+	cipherSuitesLength := int(binary.BigEndian.Uint16(message))
+	if len(message) < 2+cipherSuitesLength {
+		return nil
+	}
+	hello.cipherSuites = readUint16List(message[2 : 2+cipherSuitesLength])
+	message = message[2+cipherSuitesLength:]
+	// compression_methods
+	message = skipVector(message, 1)
+	if len(message) < 2 {
+		// extensions are optional
+		return hello
+	}
+	extensions := message[2:]
+	if extensionsLength := int(binary.BigEndian.Uint16(message)); extensionsLength < len(extensions) {
+		extensions = extensions[:extensionsLength]
+	}
+	for len(extensions) >= 4 {
+		extensionType := binary.BigEndian.Uint16(extensions)
+		extensionLength := int(binary.BigEndian.Uint16(extensions[2:4]))
+		if len(extensions) < 4+extensionLength {
+			return nil
+		}
+		extension := extensions[4 : 4+extensionLength]
+		extensions = extensions[4+extensionLength:]
+		hello.extensions = append(hello.extensions, extensionType)
+		switch extensionType {
+		case tlsExtensionServerName:
+			// server_name_list with a single host_name entry
+			if len(extension) >= 5 && extension[2] == 0 {
+				nameLength := int(binary.BigEndian.Uint16(extension[3:5]))
+				if len(extension) >= 5+nameLength {
+					hello.serverName = string(extension[5 : 5+nameLength])
+				}
+			}
+		case tlsExtensionALPN:
+			if len(extension) >= 2 {
+				protocols := extension[2:]
+				for len(protocols) > 0 && len(protocols) >= 1+int(protocols[0]) {
+					hello.alpn = append(hello.alpn, string(protocols[1:1+protocols[0]]))
+					protocols = protocols[1+protocols[0]:]
+				}
+			}
+		case tlsExtensionSignatureAlgorithms:
+			if len(extension) >= 2 {
+				hello.signatureAlgorithms = readUint16List(extension[2:])
+			}
+		case tlsExtensionSupportedVersions:
+			if len(extension) >= 1 {
+				hello.supportedVersions = readUint16List(extension[1:])
+			}
+		}
+	}
+	return hello
+}
+
+// fingerprint returns the JA4 fingerprint of the ClientHello, with the transport protocol
+// t for TLS over TCP, q for QUIC or d for DTLS.
+// For the JA4 specification, see https://github.com/FoxIO-LLC/ja4/blob/main/technical_details/JA4.md
+func (h *clientHello) fingerprint(transport byte) string {
+	version := h.version
+	if supportedVersions := filterGREASE(h.supportedVersions); len(supportedVersions) > 0 {
+		version = supportedVersions[0]
+		for _, supportedVersion := range supportedVersions[1:] {
+			// DTLS versions are decreasing
+			if supportedVersion < 0xfe00 && supportedVersion > version || supportedVersion >= 0xfe00 && supportedVersion < version {
+				version = supportedVersion
+			}
+		}
+	}
+	cipherSuites := filterGREASE(h.cipherSuites)
+	extensions := filterGREASE(h.extensions)
+	var builder strings.Builder
+	builder.WriteByte(transport)
+	builder.WriteString(ja4Version(version))
+	if h.serverName != "" {
+		builder.WriteByte('d')
+	} else {
+		builder.WriteByte('i')
+	}
+	builder.WriteString(ja4Count(len(cipherSuites)))
+	builder.WriteString(ja4Count(len(extensions)))
+	builder.WriteString(ja4ALPN(h.alpn))
+	builder.WriteByte('_')
+	sort.Slice(cipherSuites, func(i, j int) bool {
+		return cipherSuites[i] < cipherSuites[j]
+	})
+	builder.WriteString(ja4Hash(ja4HexList(cipherSuites)))
+	builder.WriteByte('_')
+	var hashedExtensions []uint16
+	for _, extension := range extensions {
+		if extension != tlsExtensionServerName && extension != tlsExtensionALPN {
+			hashedExtensions = append(hashedExtensions, extension)
+		}
+	}
+	sort.Slice(hashedExtensions, func(i, j int) bool {
+		return hashedExtensions[i] < hashedExtensions[j]
+	})
+	extensionsString := ja4HexList(hashedExtensions)
+	if signatureAlgorithms := filterGREASE(h.signatureAlgorithms); len(signatureAlgorithms) > 0 {
+		extensionsString += "_" + ja4HexList(signatureAlgorithms)
+	}
+	builder.WriteString(ja4Hash(extensionsString))
+	return builder.String()
+}
+
+func ja4Version(version uint16) string {
+	switch version {
+	case 0x0304:
+		return "13"
+	case 0x0303:
+		return "12"
+	case 0x0302:
+		return "11"
+	case 0x0301:
+		return "10"
+	case 0x0300:
+		return "s3"
+	case 0xfeff:
+		return "d1"
+	case 0xfefd:
+		return "d2"
+	case 0xfefc:
+		return "d3"
+	default:
+		return "00"
+	}
+}
+
+func ja4Count(count int) string {
+	if count > 99 {
+		count = 99
+	}
+	if count < 10 {
+		return "0" + strconv.Itoa(count)
+	}
+	return strconv.Itoa(count)
+}
+
+func ja4ALPN(protocols []string) string {
+	if len(protocols) == 0 || protocols[0] == "" {
+		return "00"
+	}
+	protocol := protocols[0]
+	first, last := protocol[0], protocol[len(protocol)-1]
+	if !isAlphanumeric(first) || !isAlphanumeric(last) {
+		hexString := hex.EncodeToString([]byte(protocol))
+		return string([]byte{hexString[0], hexString[len(hexString)-1]})
+	}
+	return string([]byte{first, last})
+}
+
+func ja4HexList(values []uint16) string {
+	hexValues := make([]string, 0, len(values))
+	for _, value := range values {
+		hexValues = append(hexValues, hex.EncodeToString([]byte{byte(value >> 8), byte(value)}))
+	}
+	return strings.Join(hexValues, ",")
+}
+
+func ja4Hash(value string) string {
+	if value == "" {
+		return "000000000000"
+	}
+	hash := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(hash[:6])
+}
+
+func isAlphanumeric(b byte) bool {
+	return b >= '0' && b <= '9' || b >= 'A' && b <= 'Z' || b >= 'a' && b <= 'z'
+}
+
+func isGREASE(value uint16) bool {
+	return value&0x0f0f == 0x0a0a && value>>8 == value&0xff
+}
+
+func filterGREASE(values []uint16) []uint16 {
+	filtered := make([]uint16, 0, len(values))
+	for _, value := range values {
+		if !isGREASE(value) {
+			filtered = append(filtered, value)
+		}
+	}
+	return filtered
+}
+
+func readUint16List(b []byte) []uint16 {
+	values := make([]uint16, 0, len(b)/2)
+	for len(b) >= 2 {
+		values = append(values, binary.BigEndian.Uint16(b))
+		b = b[2:]
+	}
+	return values
+}
@@ -0,0 +1,25 @@
+package sniff_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"testing"
+
+	"github.com/sagernet/sing-box/common/sniff"
+	C "github.com/sagernet/sing-box/constant"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSniffTLSClientHello(t *testing.T) {
+	t.Parallel()
+	pkt, err := hex.DecodeString("160301014a010001460303d77c8208b79fb4424282361dca2cbdacfbbcb4656b53733ef5e3e0e704727b20201ffdb14cf4f16e9f298482216d8cd77458a9eabf31910f5213d3c62bf0e90cc0003e130213031301c02cc030009fcca9cca8ccaac02bc02f009ec024c028006bc023c0270067c00ac0140039c009c0130033009d009c003d003c0035002f00ff010000bf00000010000e00000b6578616d706c652e636f6d000b000403000102000a00160014001d0017001e0019001801000101010201030104002300000010000e000c02683208687474702f312e310016000000170000000d002a0028040305030603080708080809080a080b080408050806040105010601030303010302040205020602002b0009080304030303020301002d00020101003300260024001d002030670d93e05f42d8e5e6bae1d9245207d02a94f80a109ea9f48644bf9e3fc24b")
+	require.NoError(t, err)
+	metadata, err := sniff.TLSClientHello(context.Background(), bytes.NewReader(pkt))
+	require.NoError(t, err)
+	require.Equal(t, C.ProtocolTLS, metadata.Protocol)
+	require.Equal(t, "example.com", metadata.Domain)
+	require.Equal(t, []string{"h2", "http/1.1"}, metadata.ClientALPN)
+	require.Equal(t, "t13d3111h2_e8f1e7e78f70_1f22a2ca17c4", metadata.ClientFingerprint)
+}
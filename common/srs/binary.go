@@ -36,10 +36,22 @@ const (
 	ruleItemPackageName
 	ruleItemWIFISSID
 	ruleItemWIFIBSSID
+	ruleItemClientALPN
+	ruleItemClientFingerprint
 	ruleItemFinal uint8 = 0xFF
 )
 
-func Read(reader io.Reader, recover bool) (ruleSet option.PlainRuleSet, err error) {
+// ruleItemVersion returns the rule-set version introducing the item type.
+func ruleItemVersion(itemType uint8) uint8 {
+	switch itemType {
+	case ruleItemClientALPN, ruleItemClientFingerprint:
+		return C.RuleSetVersion2
+	default:
+		return C.RuleSetVersion1
+	}
+}
+
+func Read(reader io.Reader, recover bool) (ruleSet option.PlainRuleSetCompat, err error) {
 	var magicBytes [3]byte
 	_, err = io.ReadFull(reader, magicBytes[:])
 	if err != nil {
@@ -54,9 +66,10 @@ func Read(reader io.Reader, recover bool) (ruleSet option.PlainRuleSet, err erro
 	if err != nil {
 		return ruleSet, err
 	}
-	if version != 1 {
+	if version < C.RuleSetVersion1 || version > C.RuleSetVersionCurrent {
 		return ruleSet, E.New("unsupported version: ", version)
 	}
+	ruleSet.Version = int(version)
 	zReader, err := zlib.NewReader(reader)
 	if err != nil {
 		return
@@ -66,9 +79,9 @@ func Read(reader io.Reader, recover bool) (ruleSet option.PlainRuleSet, err erro
 	if err != nil {
 		return
 	}
-	ruleSet.Rules = make([]option.HeadlessRule, length)
+	ruleSet.Options.Rules = make([]option.HeadlessRule, length)
 	for i := uint64(0); i < length; i++ {
-		ruleSet.Rules[i], err = readRule(bReader, recover)
+		ruleSet.Options.Rules[i], err = readRule(bReader, version, recover)
 		if err != nil {
 			err = E.Cause(err, "read rule[", i, "]")
 			return
@@ -77,12 +90,16 @@ func Read(reader io.Reader, recover bool) (ruleSet option.PlainRuleSet, err erro
 	return
 }
 
-func Write(writer io.Writer, ruleSet option.PlainRuleSet) error {
+// Write writes the rule-set in the given version, rule items unsupported by the version are rejected.
+func Write(writer io.Writer, ruleSet option.PlainRuleSet, version uint8) error {
+	if version < C.RuleSetVersion1 || version > C.RuleSetVersionCurrent {
+		return E.New("unsupported version: ", version)
+	}
 	_, err := writer.Write(MagicBytes[:])
 	if err != nil {
 		return err
 	}
-	err = binary.Write(writer, binary.BigEndian, uint8(1))
+	err = binary.Write(writer, binary.BigEndian, version)
 	if err != nil {
 		return err
 	}
@@ -95,10 +112,10 @@ func Write(writer io.Writer, ruleSet option.PlainRuleSet) error {
 	if err != nil {
 		return err
 	}
-	for _, rule := range ruleSet.Rules {
-		err = writeRule(bWriter, rule)
+	for i, rule := range ruleSet.Rules {
+		err = writeRule(bWriter, rule, version)
 		if err != nil {
-			return err
+			return E.Cause(err, "write rule[", i, "]")
 		}
 	}
 	err = bWriter.Flush()
@@ -108,7 +125,7 @@ func Write(writer io.Writer, ruleSet option.PlainRuleSet) error {
 	return zWriter.Close()
 }
 
-func readRule(reader varbin.Reader, recover bool) (rule option.HeadlessRule, err error) {
+func readRule(reader varbin.Reader, version uint8, recover bool) (rule option.HeadlessRule, err error) {
 	var ruleType uint8
 	err = binary.Read(reader, binary.BigEndian, &ruleType)
 	if err != nil {
@@ -117,28 +134,28 @@ func readRule(reader varbin.Reader, recover bool) (rule option.HeadlessRule, err
 	switch ruleType {
 	case 0:
 		rule.Type = C.RuleTypeDefault
-		rule.DefaultOptions, err = readDefaultRule(reader, recover)
+		rule.DefaultOptions, err = readDefaultRule(reader, version, recover)
 	case 1:
 		rule.Type = C.RuleTypeLogical
-		rule.LogicalOptions, err = readLogicalRule(reader, recover)
+		rule.LogicalOptions, err = readLogicalRule(reader, version, recover)
 	default:
 		err = E.New("unknown rule type: ", ruleType)
 	}
 	return
 }
 
-func writeRule(writer varbin.Writer, rule option.HeadlessRule) error {
+func writeRule(writer varbin.Writer, rule option.HeadlessRule, version uint8) error {
 	switch rule.Type {
 	case C.RuleTypeDefault:
-		return writeDefaultRule(writer, rule.DefaultOptions)
+		return writeDefaultRule(writer, rule.DefaultOptions, version)
 	case C.RuleTypeLogical:
-		return writeLogicalRule(writer, rule.LogicalOptions)
+		return writeLogicalRule(writer, rule.LogicalOptions, version)
 	default:
 		panic("unknown rule type: " + rule.Type)
 	}
 }
 
-func readDefaultRule(reader varbin.Reader, recover bool) (rule option.DefaultHeadlessRule, err error) {
+func readDefaultRule(reader varbin.Reader, version uint8, recover bool) (rule option.DefaultHeadlessRule, err error) {
 	var lastItemType uint8
 	for {
 		var itemType uint8
@@ -146,6 +163,10 @@ func readDefaultRule(reader varbin.Reader, recover bool) (rule option.DefaultHea
 		if err != nil {
 			return
 		}
+		if itemType != ruleItemFinal && ruleItemVersion(itemType) > version {
+			err = E.New("rule item type ", itemType, " is not supported in version ", version)
+			return
+		}
 		switch itemType {
 		case ruleItemQueryType:
 			var rawQueryType []uint16
@@ -206,6 +227,10 @@ func readDefaultRule(reader varbin.Reader, recover bool) (rule option.DefaultHea
 			rule.WIFISSID, err = readRuleItemString(reader)
 		case ruleItemWIFIBSSID:
 			rule.WIFIBSSID, err = readRuleItemString(reader)
+		case ruleItemClientALPN:
+			rule.ClientALPN, err = readRuleItemString(reader)
+		case ruleItemClientFingerprint:
+			rule.ClientFingerprint, err = readRuleItemString(reader)
 		case ruleItemFinal:
 			err = binary.Read(reader, binary.BigEndian, &rule.Invert)
 			return
@@ -219,7 +244,7 @@ func readDefaultRule(reader varbin.Reader, recover bool) (rule option.DefaultHea
 	}
 }
 
-func writeDefaultRule(writer varbin.Writer, rule option.DefaultHeadlessRule) error {
+func writeDefaultRule(writer varbin.Writer, rule option.DefaultHeadlessRule, version uint8) error {
 	err := binary.Write(writer, binary.BigEndian, uint8(0))
 	if err != nil {
 		return err
@@ -326,6 +351,24 @@ func writeDefaultRule(writer varbin.Writer, rule option.DefaultHeadlessRule) err
 			return err
 		}
 	}
+	if len(rule.ClientALPN) > 0 {
+		if version < ruleItemVersion(ruleItemClientALPN) {
+			return E.New("client_alpn requires rule-set version ", ruleItemVersion(ruleItemClientALPN))
+		}
+		err = writeRuleItemString(writer, ruleItemClientALPN, rule.ClientALPN)
+		if err != nil {
+			return err
+		}
+	}
+	if len(rule.ClientFingerprint) > 0 {
+		if version < ruleItemVersion(ruleItemClientFingerprint) {
+			return E.New("client_fingerprint requires rule-set version ", ruleItemVersion(ruleItemClientFingerprint))
+		}
+		err = writeRuleItemString(writer, ruleItemClientFingerprint, rule.ClientFingerprint)
+		if err != nil {
+			return err
+		}
+	}
 	err = binary.Write(writer, binary.BigEndian, ruleItemFinal)
 	if err != nil {
 		return err
@@ -387,7 +430,7 @@ func writeRuleItemCIDR(writer varbin.Writer, itemType uint8, value []string) err
 	return writeIPSet(writer, ipSet)
 }
 
-func readLogicalRule(reader varbin.Reader, recovery bool) (logicalRule option.LogicalHeadlessRule, err error) {
+func readLogicalRule(reader varbin.Reader, version uint8, recovery bool) (logicalRule option.LogicalHeadlessRule, err error) {
 	mode, err := reader.ReadByte()
 	if err != nil {
 		return
@@ -407,7 +450,7 @@ func readLogicalRule(reader varbin.Reader, recovery bool) (logicalRule option.Lo
 	}
 	logicalRule.Rules = make([]option.HeadlessRule, length)
 	for i := uint64(0); i < length; i++ {
-		logicalRule.Rules[i], err = readRule(reader, recovery)
+		logicalRule.Rules[i], err = readRule(reader, version, recovery)
 		if err != nil {
 			err = E.Cause(err, "read logical rule [", i, "]")
 			return
@@ -420,7 +463,7 @@ func readLogicalRule(reader varbin.Reader, recovery bool) (logicalRule option.Lo
 	return
 }
 
-func writeLogicalRule(writer varbin.Writer, logicalRule option.LogicalHeadlessRule) error {
+func writeLogicalRule(writer varbin.Writer, logicalRule option.LogicalHeadlessRule, version uint8) error {
 	err := binary.Write(writer, binary.BigEndian, uint8(1))
 	if err != nil {
 		return err
@@ -441,7 +484,7 @@ func writeLogicalRule(writer varbin.Writer, logicalRule option.LogicalHeadlessRu
 		return err
 	}
 	for _, rule := range logicalRule.Rules {
-		err = writeRule(writer, rule)
+		err = writeRule(writer, rule, version)
 		if err != nil {
 			return err
 		}
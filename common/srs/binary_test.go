@@ -0,0 +1,106 @@
+package srs
+
+import (
+	"bytes"
+	"testing"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testRuleSet(clientItems bool) option.PlainRuleSet {
+	defaultRule := option.DefaultHeadlessRule{
+		QueryType:     option.Listable[option.DNSQueryType]{1, 28},
+		Network:       option.Listable[string]{"tcp"},
+		Domain:        option.Listable[string]{"example.com"},
+		DomainSuffix:  option.Listable[string]{".example.org"},
+		DomainKeyword: option.Listable[string]{"example"},
+		DomainRegex:   option.Listable[string]{`^example\.net$`},
+		IPCIDR:        option.Listable[string]{"10.0.0.0/8"},
+		Port:          option.Listable[uint16]{443},
+		PortRange:     option.Listable[string]{"1000:2000"},
+		ProcessName:   option.Listable[string]{"curl"},
+		Invert:        true,
+	}
+	if clientItems {
+		defaultRule.ClientALPN = option.Listable[string]{"h2", "http/1.1"}
+		defaultRule.ClientFingerprint = option.Listable[string]{"t13d1516h2"}
+	}
+	return option.PlainRuleSet{
+		Rules: []option.HeadlessRule{
+			{
+				Type:           C.RuleTypeDefault,
+				DefaultOptions: defaultRule,
+			},
+			{
+				Type: C.RuleTypeLogical,
+				LogicalOptions: option.LogicalHeadlessRule{
+					Mode: C.LogicalTypeOr,
+					Rules: []option.HeadlessRule{
+						{
+							Type:           C.RuleTypeDefault,
+							DefaultOptions: defaultRule,
+						},
+						{
+							Type: C.RuleTypeDefault,
+							DefaultOptions: option.DefaultHeadlessRule{
+								SourceIPCIDR: option.Listable[string]{"192.168.0.0/16"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		version     uint8
+		clientItems bool
+	}{
+		{C.RuleSetVersion1, false},
+		{C.RuleSetVersion2, false},
+		{C.RuleSetVersion2, true},
+	} {
+		ruleSet := testRuleSet(testCase.clientItems)
+		var buffer bytes.Buffer
+		require.NoError(t, Write(&buffer, ruleSet, testCase.version))
+		content := append([]byte(nil), buffer.Bytes()...)
+		compat, err := Read(&buffer, true)
+		require.NoError(t, err)
+		require.Equal(t, int(testCase.version), compat.Version)
+		// recovered domains and addresses are not in their original form, so compare the encoded form
+		var rewritten bytes.Buffer
+		require.NoError(t, Write(&rewritten, compat.Options, testCase.version))
+		require.Equal(t, content, rewritten.Bytes())
+		readRule := compat.Options.Rules[0].DefaultOptions
+		require.Equal(t, ruleSet.Rules[0].DefaultOptions.ClientALPN, readRule.ClientALPN)
+		require.Equal(t, ruleSet.Rules[0].DefaultOptions.ClientFingerprint, readRule.ClientFingerprint)
+		require.Equal(t, ruleSet.Rules[0].DefaultOptions.Port, readRule.Port)
+		require.True(t, readRule.Invert)
+		require.Len(t, compat.Options.Rules[1].LogicalOptions.Rules, 2)
+	}
+}
+
+func TestVersionRejectsUnsupportedItems(t *testing.T) {
+	t.Parallel()
+	ruleSet := testRuleSet(true)
+	var buffer bytes.Buffer
+	require.Error(t, Write(&buffer, ruleSet, C.RuleSetVersion1))
+	require.Error(t, Write(&buffer, ruleSet, C.RuleSetVersionCurrent+1))
+
+	buffer.Reset()
+	require.NoError(t, Write(&buffer, ruleSet, C.RuleSetVersion2))
+	content := buffer.Bytes()
+	content[len(MagicBytes)] = C.RuleSetVersion1
+	_, err := Read(bytes.NewReader(content), false)
+	require.Error(t, err)
+
+	content[len(MagicBytes)] = C.RuleSetVersionCurrent + 1
+	_, err = Read(bytes.NewReader(content), false)
+	require.Error(t, err)
+}
@@ -4,3 +4,21 @@ const (
 	DNSProviderAliDNS     = "alidns"
 	DNSProviderCloudflare = "cloudflare"
 )
+
+const (
+	DNSInboundProtocolDNS   = "dns"
+	DNSInboundProtocolTLS   = "tls"
+	DNSInboundProtocolHTTPS = "https"
+	DNSInboundProtocolHTTP  = "http"
+)
+
+const (
+	DNSGroupModeRace            = "race"
+	DNSGroupModeFastestIP       = "fastest_ip"
+	DNSGroupModeFallbackOnError = "fallback_on_error"
+)
+
+const (
+	DNSSECStatusSecure   = "secure"
+	DNSSECStatusInsecure = "insecure"
+)
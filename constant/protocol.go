@@ -7,4 +7,8 @@ const (
 	ProtocolDNS        = "dns"
 	ProtocolSTUN       = "stun"
 	ProtocolBitTorrent = "bittorrent"
+	ProtocolSSH        = "ssh"
+	ProtocolRDP        = "rdp"
+	ProtocolNTP        = "ntp"
+	ProtocolDTLS       = "dtls"
 )
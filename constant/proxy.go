@@ -26,8 +26,16 @@ const (
 )
 
 const (
-	TypeSelector = "selector"
-	TypeURLTest  = "urltest"
+	TypeSelector    = "selector"
+	TypeURLTest     = "urltest"
+	TypeLoadBalance = "load_balance"
+	TypeFallback    = "fallback"
+)
+
+const (
+	LoadBalanceStrategyRoundRobin        = "round_robin"
+	LoadBalanceStrategyConsistentHashing = "consistent_hashing"
+	LoadBalanceStrategyStickySessions    = "sticky_sessions"
 )
 
 func ProxyDisplayName(proxyType string) string {
@@ -80,6 +88,10 @@ func ProxyDisplayName(proxyType string) string {
 		return "Selector"
 	case TypeURLTest:
 		return "URLTest"
+	case TypeLoadBalance:
+		return "LoadBalance"
+	case TypeFallback:
+		return "Fallback"
 	default:
 		return "Unknown"
 	}
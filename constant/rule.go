@@ -11,10 +11,42 @@ const (
 )
 
 const (
-	RuleSetTypeInline   = "inline"
-	RuleSetTypeLocal    = "local"
-	RuleSetTypeRemote   = "remote"
-	RuleSetVersion1     = 1
-	RuleSetFormatSource = "source"
-	RuleSetFormatBinary = "binary"
+	RuleSetTypeInline     = "inline"
+	RuleSetTypeLocal      = "local"
+	RuleSetTypeRemote     = "remote"
+	RuleSetVersion1       = 1
+	RuleSetVersion2       = 2
+	RuleSetVersionCurrent = RuleSetVersion2
+	RuleSetFormatSource   = "source"
+	RuleSetFormatBinary   = "binary"
+)
+
+const (
+	RuleSetBehaviorDomain    = "domain"
+	RuleSetBehaviorIPCIDR    = "ipcidr"
+	RuleSetBehaviorClassical = "classical"
+)
+
+const (
+	RuleSetFormatAdGuard        = "adguard"
+	RuleSetFormatHosts          = "hosts"
+	RuleSetFormatClashClassical = "clash-classical"
+	RuleSetFormatClashDomain    = "clash-domain"
+	RuleSetFormatClashIPCIDR    = "clash-ipcidr"
+)
+
+const (
+	RuleActionTypeRoute        = "route"
+	RuleActionTypeRouteOptions = "route-options"
+	RuleActionTypeReject       = "reject"
+	RuleActionTypeHijackDNS    = "hijack-dns"
+	RuleActionTypeSniff        = "sniff"
+	RuleActionTypeResolve      = "resolve"
+)
+
+const (
+	RuleActionRejectMethodDefault  = "default"
+	RuleActionRejectMethodTCPReset = "tcp-reset"
+	RuleActionRejectMethodICMP     = "icmp"
+	RuleActionRejectMethodDrop     = "drop"
 )
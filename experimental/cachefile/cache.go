@@ -3,7 +3,6 @@ package cachefile
 import (
 	"context"
 	"errors"
-	"net/netip"
 	"os"
 	"strings"
 	"sync"
@@ -30,6 +29,8 @@ var (
 		string(bucketMode),
 		string(bucketRuleSet),
 		string(bucketRDRC),
+		string(bucketDNSCache),
+		string(bucketUserTraffic),
 	}
 
 	cacheIDDefault = []byte("default")
@@ -38,20 +39,22 @@ var (
 var _ adapter.CacheFile = (*CacheFile)(nil)
 
 type CacheFile struct {
-	ctx               context.Context
-	path              string
-	cacheID           []byte
-	storeFakeIP       bool
-	storeRDRC         bool
-	rdrcTimeout       time.Duration
-	DB                *bbolt.DB
-	saveMetadataTimer *time.Timer
-	saveFakeIPAccess  sync.RWMutex
-	saveDomain        map[netip.Addr]string
-	saveAddress4      map[string]netip.Addr
-	saveAddress6      map[string]netip.Addr
-	saveRDRCAccess    sync.RWMutex
-	saveRDRC          map[saveRDRCCacheKey]bool
+	ctx             context.Context
+	path            string
+	cacheID         []byte
+	storeFakeIP     bool
+	storeRDRC       bool
+	rdrcTimeout     time.Duration
+	storeDNS        bool
+	dnsStaleTimeout time.Duration
+	DB              *bbolt.DB
+	*fakeIPStorage
+	fakeIPAccess       sync.Mutex
+	namedFakeIPStorage map[string]*fakeIPStorage
+	saveRDRCAccess     sync.RWMutex
+	saveRDRC           map[saveRDRCCacheKey]bool
+	saveDNSAccess      sync.RWMutex
+	saveDNS            map[saveRDRCCacheKey]savedDNSResponse
 }
 
 type saveRDRCCacheKey struct {
@@ -79,18 +82,21 @@ func New(ctx context.Context, options option.CacheFileOptions) *CacheFile {
 			rdrcTimeout = 7 * 24 * time.Hour
 		}
 	}
-	return &CacheFile{
-		ctx:          ctx,
-		path:         filemanager.BasePath(ctx, path),
-		cacheID:      cacheIDBytes,
-		storeFakeIP:  options.StoreFakeIP,
-		storeRDRC:    options.StoreRDRC,
-		rdrcTimeout:  rdrcTimeout,
-		saveDomain:   make(map[netip.Addr]string),
-		saveAddress4: make(map[string]netip.Addr),
-		saveAddress6: make(map[string]netip.Addr),
-		saveRDRC:     make(map[saveRDRCCacheKey]bool),
+	cacheFile := &CacheFile{
+		ctx:                ctx,
+		path:               filemanager.BasePath(ctx, path),
+		cacheID:            cacheIDBytes,
+		storeFakeIP:        options.StoreFakeIP,
+		storeRDRC:          options.StoreRDRC,
+		rdrcTimeout:        rdrcTimeout,
+		storeDNS:           options.StoreDNS,
+		dnsStaleTimeout:    time.Duration(options.DNSStaleTimeout),
+		namedFakeIPStorage: make(map[string]*fakeIPStorage),
+		saveRDRC:           make(map[saveRDRCCacheKey]bool),
+		saveDNS:            make(map[saveRDRCCacheKey]savedDNSResponse),
 	}
+	cacheFile.fakeIPStorage = newFakeIPStorage(cacheFile, "")
+	return cacheFile
 }
 
 func (c *CacheFile) start() error {
@@ -148,6 +154,13 @@ func (c *CacheFile) start() error {
 		return err
 	}
 	c.DB = db
+	if c.storeDNS {
+		err = c.purgeDNSCache()
+		if err != nil {
+			db.Close()
+			return E.Cause(err, "purge DNS cache")
+		}
+	}
 	return nil
 }
 
@@ -0,0 +1,154 @@
+package cachefile
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/sagernet/bbolt"
+	"github.com/sagernet/sing/common/buf"
+	"github.com/sagernet/sing/common/logger"
+
+	"github.com/miekg/dns"
+)
+
+var bucketDNSCache = []byte("dns_cache")
+
+type savedDNSResponse struct {
+	response *dns.Msg
+	expireAt time.Time
+}
+
+func (c *CacheFile) StoreDNS() bool {
+	return c.storeDNS
+}
+
+// LoadDNSCache returns the saved response and its expiration time,
+// responses expired for longer than the stale timeout are deleted.
+func (c *CacheFile) LoadDNSCache(transportName string, qName string, qType uint16) (response *dns.Msg, expireAt time.Time, loaded bool) {
+	c.saveDNSAccess.RLock()
+	saved, cached := c.saveDNS[saveRDRCCacheKey{transportName, qName, qType}]
+	c.saveDNSAccess.RUnlock()
+	if cached && !time.Now().After(saved.expireAt.Add(c.dnsStaleTimeout)) {
+		return saved.response.Copy(), saved.expireAt, true
+	}
+	key := buf.Get(2 + len(qName))
+	binary.BigEndian.PutUint16(key, qType)
+	copy(key[2:], qName)
+	defer buf.Put(key)
+	var deleteCache bool
+	err := c.DB.View(func(tx *bbolt.Tx) error {
+		bucket := c.bucket(tx, bucketDNSCache)
+		if bucket == nil {
+			return nil
+		}
+		bucket = bucket.Bucket([]byte(transportName))
+		if bucket == nil {
+			return nil
+		}
+		content := bucket.Get(key)
+		if len(content) < 8 {
+			return nil
+		}
+		expireAt = time.Unix(int64(binary.BigEndian.Uint64(content)), 0)
+		if time.Now().After(expireAt.Add(c.dnsStaleTimeout)) {
+			deleteCache = true
+			return nil
+		}
+		response = new(dns.Msg)
+		if response.Unpack(content[8:]) != nil {
+			response = nil
+			deleteCache = true
+			return nil
+		}
+		loaded = true
+		return nil
+	})
+	if err != nil {
+		return nil, time.Time{}, false
+	}
+	if deleteCache {
+		c.DB.Update(func(tx *bbolt.Tx) error {
+			bucket := c.bucket(tx, bucketDNSCache)
+			if bucket == nil {
+				return nil
+			}
+			bucket = bucket.Bucket([]byte(transportName))
+			if bucket == nil {
+				return nil
+			}
+			return bucket.Delete(key)
+		})
+	}
+	return
+}
+
+func (c *CacheFile) SaveDNSCache(transportName string, qName string, qType uint16, response *dns.Msg, expireAt time.Time) error {
+	rawMessage, err := response.Pack()
+	if err != nil {
+		return err
+	}
+	return c.DB.Batch(func(tx *bbolt.Tx) error {
+		bucket, err := c.createBucket(tx, bucketDNSCache)
+		if err != nil {
+			return err
+		}
+		bucket, err = bucket.CreateBucketIfNotExists([]byte(transportName))
+		if err != nil {
+			return err
+		}
+		key := buf.Get(2 + len(qName))
+		binary.BigEndian.PutUint16(key, qType)
+		copy(key[2:], qName)
+		defer buf.Put(key)
+		content := make([]byte, 8+len(rawMessage))
+		binary.BigEndian.PutUint64(content, uint64(expireAt.Unix()))
+		copy(content[8:], rawMessage)
+		return bucket.Put(key, content)
+	})
+}
+
+func (c *CacheFile) SaveDNSCacheAsync(transportName string, qName string, qType uint16, response *dns.Msg, expireAt time.Time, logger logger.Logger) {
+	saveKey := saveRDRCCacheKey{transportName, qName, qType}
+	c.saveDNSAccess.Lock()
+	c.saveDNS[saveKey] = savedDNSResponse{response, expireAt}
+	c.saveDNSAccess.Unlock()
+	go func() {
+		err := c.SaveDNSCache(transportName, qName, qType, response, expireAt)
+		if err != nil {
+			logger.Warn("save DNS cache: ", err)
+		}
+		c.saveDNSAccess.Lock()
+		delete(c.saveDNS, saveKey)
+		c.saveDNSAccess.Unlock()
+	}()
+}
+
+func (c *CacheFile) purgeDNSCache() error {
+	purgeBefore := uint64(time.Now().Add(-c.dnsStaleTimeout).Unix())
+	return c.DB.Update(func(tx *bbolt.Tx) error {
+		bucket := c.bucket(tx, bucketDNSCache)
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEachBucket(func(name []byte) error {
+			transportBucket := bucket.Bucket(name)
+			var expiredKeys [][]byte
+			err := transportBucket.ForEach(func(key, content []byte) error {
+				if len(content) < 8 || binary.BigEndian.Uint64(content) < purgeBefore {
+					expiredKeys = append(expiredKeys, append([]byte(nil), key...))
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			for _, key := range expiredKeys {
+				err = transportBucket.Delete(key)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
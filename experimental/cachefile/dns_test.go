@@ -0,0 +1,138 @@
+package cachefile
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sagernet/bbolt"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+func openDNSCacheFile(t *testing.T, path string, staleTimeout time.Duration) *CacheFile {
+	cacheFile := New(context.Background(), option.CacheFileOptions{
+		Enabled:         true,
+		Path:            path,
+		StoreDNS:        true,
+		DNSStaleTimeout: option.Duration(staleTimeout),
+	})
+	require.NoError(t, cacheFile.PreStart())
+	return cacheFile
+}
+
+func newTestResponse(name string, address string) *dns.Msg {
+	message := new(dns.Msg)
+	message.SetQuestion(name, dns.TypeA)
+	response := new(dns.Msg)
+	response.SetReply(message)
+	record, err := dns.NewRR(name + " 60 IN A " + address)
+	if err != nil {
+		panic(err)
+	}
+	response.Answer = []dns.RR{record}
+	return response
+}
+
+func answerStrings(response *dns.Msg) []string {
+	answers := make([]string, 0, len(response.Answer))
+	for _, record := range response.Answer {
+		answers = append(answers, record.String())
+	}
+	return answers
+}
+
+func storedDNSCacheKeys(t *testing.T, cacheFile *CacheFile, transportName string) int {
+	var count int
+	require.NoError(t, cacheFile.DB.View(func(tx *bbolt.Tx) error {
+		bucket := cacheFile.bucket(tx, bucketDNSCache)
+		if bucket == nil {
+			return nil
+		}
+		bucket = bucket.Bucket([]byte(transportName))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(key, content []byte) error {
+			count++
+			return nil
+		})
+	}))
+	return count
+}
+
+func TestDNSCachePersistence(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "cache.db")
+	expireAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	cacheFile := openDNSCacheFile(t, path, 0)
+	require.True(t, cacheFile.StoreDNS())
+	require.NoError(t, cacheFile.SaveDNSCache("remote", "example.com.", dns.TypeA, newTestResponse("example.com.", "10.0.0.1"), expireAt))
+	require.NoError(t, cacheFile.Close())
+
+	cacheFile = openDNSCacheFile(t, path, 0)
+	defer cacheFile.Close()
+	response, loadedExpireAt, loaded := cacheFile.LoadDNSCache("remote", "example.com.", dns.TypeA)
+	require.True(t, loaded)
+	require.True(t, expireAt.Equal(loadedExpireAt))
+	require.Equal(t, []string{"example.com.\t60\tIN\tA\t10.0.0.1"}, answerStrings(response))
+
+	// responses are keyed by transport, name and type
+	_, _, loaded = cacheFile.LoadDNSCache("local", "example.com.", dns.TypeA)
+	require.False(t, loaded)
+	_, _, loaded = cacheFile.LoadDNSCache("remote", "example.com.", dns.TypeAAAA)
+	require.False(t, loaded)
+	_, _, loaded = cacheFile.LoadDNSCache("remote", "example.org.", dns.TypeA)
+	require.False(t, loaded)
+}
+
+func TestDNSCacheSaveAsync(t *testing.T) {
+	t.Parallel()
+	cacheFile := openDNSCacheFile(t, filepath.Join(t.TempDir(), "cache.db"), 0)
+	defer cacheFile.Close()
+	response := newTestResponse("example.com.", "10.0.0.1")
+	cacheFile.SaveDNSCacheAsync("remote", "example.com.", dns.TypeA, response, time.Now().Add(time.Hour), log.NewNOPFactory().Logger())
+
+	// pending responses are loaded before they are written
+	loadedResponse, _, loaded := cacheFile.LoadDNSCache("remote", "example.com.", dns.TypeA)
+	require.True(t, loaded)
+	require.Equal(t, answerStrings(response), answerStrings(loadedResponse))
+	// loaded responses are copies
+	loadedResponse.Answer = nil
+	require.Eventually(t, func() bool {
+		return storedDNSCacheKeys(t, cacheFile, "remote") == 1
+	}, time.Second, 10*time.Millisecond)
+	loadedResponse, _, loaded = cacheFile.LoadDNSCache("remote", "example.com.", dns.TypeA)
+	require.True(t, loaded)
+	require.Equal(t, answerStrings(response), answerStrings(loadedResponse))
+}
+
+func TestDNSCacheStaleTimeout(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "cache.db")
+	cacheFile := openDNSCacheFile(t, path, time.Hour)
+	staleExpireAt := time.Now().Add(-10 * time.Minute).Truncate(time.Second)
+	require.NoError(t, cacheFile.SaveDNSCache("remote", "stale.example.com.", dns.TypeA, newTestResponse("stale.example.com.", "10.0.0.1"), staleExpireAt))
+	require.NoError(t, cacheFile.SaveDNSCache("remote", "expired.example.com.", dns.TypeA, newTestResponse("expired.example.com.", "10.0.0.2"), time.Now().Add(-2*time.Hour)))
+	require.NoError(t, cacheFile.SaveDNSCache("remote", "purged.example.com.", dns.TypeA, newTestResponse("purged.example.com.", "10.0.0.3"), time.Now().Add(-2*time.Hour)))
+
+	// expired responses are loaded until the stale timeout has passed
+	_, expireAt, loaded := cacheFile.LoadDNSCache("remote", "stale.example.com.", dns.TypeA)
+	require.True(t, loaded)
+	require.True(t, staleExpireAt.Equal(expireAt))
+	_, _, loaded = cacheFile.LoadDNSCache("remote", "expired.example.com.", dns.TypeA)
+	require.False(t, loaded)
+	require.Equal(t, 2, storedDNSCacheKeys(t, cacheFile, "remote"))
+	require.NoError(t, cacheFile.Close())
+
+	// the rest is purged when the cache file is opened again
+	cacheFile = openDNSCacheFile(t, path, time.Hour)
+	defer cacheFile.Close()
+	require.Equal(t, 1, storedDNSCacheKeys(t, cacheFile, "remote"))
+	_, _, loaded = cacheFile.LoadDNSCache("remote", "stale.example.com.", dns.TypeA)
+	require.True(t, loaded)
+}
@@ -1,11 +1,14 @@
 package cachefile
 
 import (
+	"errors"
 	"net/netip"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/sagernet/bbolt"
+	bboltErrors "github.com/sagernet/bbolt/errors"
 	"github.com/sagernet/sing-box/adapter"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing/common/logger"
@@ -14,17 +17,58 @@ import (
 
 const fakeipBucketPrefix = "fakeip_"
 
-var (
-	bucketFakeIP        = []byte(fakeipBucketPrefix + "address")
-	bucketFakeIPDomain4 = []byte(fakeipBucketPrefix + "domain4")
-	bucketFakeIPDomain6 = []byte(fakeipBucketPrefix + "domain6")
-	keyMetadata         = []byte(fakeipBucketPrefix + "metadata")
-)
+var keyMetadata = []byte(fakeipBucketPrefix + "metadata")
+
+var _ adapter.FakeIPStorage = (*fakeIPStorage)(nil)
+
+// fakeIPStorage stores FakeIP mappings in buckets named by the prefix,
+// the default storage uses fakeip_address, fakeip_domain4 and fakeip_domain6.
+type fakeIPStorage struct {
+	cacheFile         *CacheFile
+	bucketAddress     []byte
+	bucketDomain4     []byte
+	bucketDomain6     []byte
+	saveMetadataTimer *time.Timer
+	saveAccess        sync.RWMutex
+	saveDomain        map[netip.Addr]string
+	saveAddress4      map[string]netip.Addr
+	saveAddress6      map[string]netip.Addr
+}
+
+func newFakeIPStorage(cacheFile *CacheFile, name string) *fakeIPStorage {
+	prefix := fakeipBucketPrefix
+	if name != "" {
+		prefix += name + "_"
+	}
+	return &fakeIPStorage{
+		cacheFile:     cacheFile,
+		bucketAddress: []byte(prefix + "address"),
+		bucketDomain4: []byte(prefix + "domain4"),
+		bucketDomain6: []byte(prefix + "domain6"),
+		saveDomain:    make(map[netip.Addr]string),
+		saveAddress4:  make(map[string]netip.Addr),
+		saveAddress6:  make(map[string]netip.Addr),
+	}
+}
 
-func (c *CacheFile) FakeIPMetadata() *adapter.FakeIPMetadata {
+func (c *CacheFile) NamedFakeIPStorage(name string) adapter.FakeIPStorage {
+	if name == "" {
+		return c.fakeIPStorage
+	}
+	c.fakeIPAccess.Lock()
+	defer c.fakeIPAccess.Unlock()
+	storage, loaded := c.namedFakeIPStorage[name]
+	if !loaded {
+		storage = newFakeIPStorage(c, name)
+		c.namedFakeIPStorage[name] = storage
+	}
+	return storage
+}
+
+func (s *fakeIPStorage) FakeIPMetadata() *adapter.FakeIPMetadata {
 	var metadata adapter.FakeIPMetadata
-	err := c.DB.Batch(func(tx *bbolt.Tx) error {
-		bucket := tx.Bucket(bucketFakeIP)
+	err := s.cacheFile.DB.Batch(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(s.bucketAddress)
 		if bucket == nil {
 			return os.ErrNotExist
 		}
@@ -44,9 +88,9 @@ func (c *CacheFile) FakeIPMetadata() *adapter.FakeIPMetadata {
 	return &metadata
 }
 
-func (c *CacheFile) FakeIPSaveMetadata(metadata *adapter.FakeIPMetadata) error {
-	return c.DB.Batch(func(tx *bbolt.Tx) error {
-		bucket, err := tx.CreateBucketIfNotExists(bucketFakeIP)
+func (s *fakeIPStorage) FakeIPSaveMetadata(metadata *adapter.FakeIPMetadata) error {
+	return s.cacheFile.DB.Batch(func(tx *bbolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(s.bucketAddress)
 		if err != nil {
 			return err
 		}
@@ -58,19 +102,19 @@ func (c *CacheFile) FakeIPSaveMetadata(metadata *adapter.FakeIPMetadata) error {
 	})
 }
 
-func (c *CacheFile) FakeIPSaveMetadataAsync(metadata *adapter.FakeIPMetadata) {
-	if c.saveMetadataTimer == nil {
-		c.saveMetadataTimer = time.AfterFunc(C.FakeIPMetadataSaveInterval, func() {
-			_ = c.FakeIPSaveMetadata(metadata)
+func (s *fakeIPStorage) FakeIPSaveMetadataAsync(metadata *adapter.FakeIPMetadata) {
+	if s.saveMetadataTimer == nil {
+		s.saveMetadataTimer = time.AfterFunc(C.FakeIPMetadataSaveInterval, func() {
+			_ = s.FakeIPSaveMetadata(metadata)
 		})
 	} else {
-		c.saveMetadataTimer.Reset(C.FakeIPMetadataSaveInterval)
+		s.saveMetadataTimer.Reset(C.FakeIPMetadataSaveInterval)
 	}
 }
 
-func (c *CacheFile) FakeIPStore(address netip.Addr, domain string) error {
-	return c.DB.Batch(func(tx *bbolt.Tx) error {
-		bucket, err := tx.CreateBucketIfNotExists(bucketFakeIP)
+func (s *fakeIPStorage) FakeIPStore(address netip.Addr, domain string) error {
+	return s.cacheFile.DB.Batch(func(tx *bbolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(s.bucketAddress)
 		if err != nil {
 			return err
 		}
@@ -80,9 +124,9 @@ func (c *CacheFile) FakeIPStore(address netip.Addr, domain string) error {
 			return err
 		}
 		if address.Is4() {
-			bucket, err = tx.CreateBucketIfNotExists(bucketFakeIPDomain4)
+			bucket, err = tx.CreateBucketIfNotExists(s.bucketDomain4)
 		} else {
-			bucket, err = tx.CreateBucketIfNotExists(bucketFakeIPDomain6)
+			bucket, err = tx.CreateBucketIfNotExists(s.bucketDomain6)
 		}
 		if err != nil {
 			return err
@@ -96,48 +140,48 @@ func (c *CacheFile) FakeIPStore(address netip.Addr, domain string) error {
 	})
 }
 
-func (c *CacheFile) FakeIPStoreAsync(address netip.Addr, domain string, logger logger.Logger) {
-	c.saveFakeIPAccess.Lock()
-	if oldDomain, loaded := c.saveDomain[address]; loaded {
+func (s *fakeIPStorage) FakeIPStoreAsync(address netip.Addr, domain string, logger logger.Logger) {
+	s.saveAccess.Lock()
+	if oldDomain, loaded := s.saveDomain[address]; loaded {
 		if address.Is4() {
-			delete(c.saveAddress4, oldDomain)
+			delete(s.saveAddress4, oldDomain)
 		} else {
-			delete(c.saveAddress6, oldDomain)
+			delete(s.saveAddress6, oldDomain)
 		}
 	}
-	c.saveDomain[address] = domain
+	s.saveDomain[address] = domain
 	if address.Is4() {
-		c.saveAddress4[domain] = address
+		s.saveAddress4[domain] = address
 	} else {
-		c.saveAddress6[domain] = address
+		s.saveAddress6[domain] = address
 	}
-	c.saveFakeIPAccess.Unlock()
+	s.saveAccess.Unlock()
 	go func() {
-		err := c.FakeIPStore(address, domain)
+		err := s.FakeIPStore(address, domain)
 		if err != nil {
 			logger.Warn("save FakeIP cache: ", err)
 		}
-		c.saveFakeIPAccess.Lock()
-		delete(c.saveDomain, address)
+		s.saveAccess.Lock()
+		delete(s.saveDomain, address)
 		if address.Is4() {
-			delete(c.saveAddress4, domain)
+			delete(s.saveAddress4, domain)
 		} else {
-			delete(c.saveAddress6, domain)
+			delete(s.saveAddress6, domain)
 		}
-		c.saveFakeIPAccess.Unlock()
+		s.saveAccess.Unlock()
 	}()
 }
 
-func (c *CacheFile) FakeIPLoad(address netip.Addr) (string, bool) {
-	c.saveFakeIPAccess.RLock()
-	cachedDomain, cached := c.saveDomain[address]
-	c.saveFakeIPAccess.RUnlock()
+func (s *fakeIPStorage) FakeIPLoad(address netip.Addr) (string, bool) {
+	s.saveAccess.RLock()
+	cachedDomain, cached := s.saveDomain[address]
+	s.saveAccess.RUnlock()
 	if cached {
 		return cachedDomain, true
 	}
 	var domain string
-	_ = c.DB.View(func(tx *bbolt.Tx) error {
-		bucket := tx.Bucket(bucketFakeIP)
+	_ = s.cacheFile.DB.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(s.bucketAddress)
 		if bucket == nil {
 			return nil
 		}
@@ -147,28 +191,28 @@ func (c *CacheFile) FakeIPLoad(address netip.Addr) (string, bool) {
 	return domain, domain != ""
 }
 
-func (c *CacheFile) FakeIPLoadDomain(domain string, isIPv6 bool) (netip.Addr, bool) {
+func (s *fakeIPStorage) FakeIPLoadDomain(domain string, isIPv6 bool) (netip.Addr, bool) {
 	var (
 		cachedAddress netip.Addr
 		cached        bool
 	)
-	c.saveFakeIPAccess.RLock()
+	s.saveAccess.RLock()
 	if !isIPv6 {
-		cachedAddress, cached = c.saveAddress4[domain]
+		cachedAddress, cached = s.saveAddress4[domain]
 	} else {
-		cachedAddress, cached = c.saveAddress6[domain]
+		cachedAddress, cached = s.saveAddress6[domain]
 	}
-	c.saveFakeIPAccess.RUnlock()
+	s.saveAccess.RUnlock()
 	if cached {
 		return cachedAddress, true
 	}
 	var address netip.Addr
-	_ = c.DB.View(func(tx *bbolt.Tx) error {
+	_ = s.cacheFile.DB.View(func(tx *bbolt.Tx) error {
 		var bucket *bbolt.Bucket
 		if isIPv6 {
-			bucket = tx.Bucket(bucketFakeIPDomain6)
+			bucket = tx.Bucket(s.bucketDomain6)
 		} else {
-			bucket = tx.Bucket(bucketFakeIPDomain4)
+			bucket = tx.Bucket(s.bucketDomain4)
 		}
 		if bucket == nil {
 			return nil
@@ -179,16 +223,37 @@ func (c *CacheFile) FakeIPLoadDomain(domain string, isIPv6 bool) (netip.Addr, bo
 	return address, address.IsValid()
 }
 
-func (c *CacheFile) FakeIPReset() error {
-	return c.DB.Batch(func(tx *bbolt.Tx) error {
-		err := tx.DeleteBucket(bucketFakeIP)
-		if err != nil {
-			return err
+func (s *fakeIPStorage) FakeIPAddresses() []netip.Addr {
+	s.saveAccess.RLock()
+	addresses := make([]netip.Addr, 0, len(s.saveDomain))
+	for address := range s.saveDomain {
+		addresses = append(addresses, address)
+	}
+	s.saveAccess.RUnlock()
+	_ = s.cacheFile.DB.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(s.bucketAddress)
+		if bucket == nil {
+			return nil
 		}
-		err = tx.DeleteBucket(bucketFakeIPDomain4)
-		if err != nil {
-			return err
+		return bucket.ForEach(func(key, value []byte) error {
+			if len(key) != 4 && len(key) != 16 {
+				return nil
+			}
+			addresses = append(addresses, M.AddrFromIP(key))
+			return nil
+		})
+	})
+	return addresses
+}
+
+func (s *fakeIPStorage) FakeIPReset() error {
+	return s.cacheFile.DB.Batch(func(tx *bbolt.Tx) error {
+		for _, bucketName := range [][]byte{s.bucketAddress, s.bucketDomain4, s.bucketDomain6} {
+			err := tx.DeleteBucket(bucketName)
+			if err != nil && !errors.Is(err, bboltErrors.ErrBucketNotFound) {
+				return err
+			}
 		}
-		return tx.DeleteBucket(bucketFakeIPDomain6)
+		return nil
 	})
 }
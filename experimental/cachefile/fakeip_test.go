@@ -0,0 +1,111 @@
+package cachefile
+
+import (
+	"context"
+	"net/netip"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-box/transport/fakeip"
+	"github.com/sagernet/sing/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+func openTestCacheFile(t *testing.T, path string) *CacheFile {
+	cacheFile := New(context.Background(), option.CacheFileOptions{
+		Enabled:     true,
+		Path:        path,
+		StoreFakeIP: true,
+	})
+	require.NoError(t, cacheFile.PreStart())
+	return cacheFile
+}
+
+func TestNamedFakeIPStoragePersistence(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "cache.db")
+	defaultAddress := netip.MustParseAddr("198.18.0.2")
+	namedAddress := netip.MustParseAddr("198.19.0.2")
+
+	cacheFile := openTestCacheFile(t, path)
+	require.NoError(t, cacheFile.NamedFakeIPStorage("").FakeIPStore(defaultAddress, "example.com"))
+	named := cacheFile.NamedFakeIPStorage("remote")
+	require.Same(t, named, cacheFile.NamedFakeIPStorage("remote"))
+	require.NoError(t, named.FakeIPStore(namedAddress, "example.com"))
+	require.NoError(t, named.FakeIPSaveMetadata(&adapter.FakeIPMetadata{
+		Inet4Range:   netip.MustParsePrefix("198.19.0.0/16"),
+		Inet4Current: namedAddress,
+	}))
+	require.NoError(t, cacheFile.Close())
+
+	// fakeip_remote_ buckets are kept when the cache file is opened again
+	cacheFile = openTestCacheFile(t, path)
+	defer cacheFile.Close()
+	named = cacheFile.NamedFakeIPStorage("remote")
+	domain, loaded := named.FakeIPLoad(namedAddress)
+	require.True(t, loaded)
+	require.Equal(t, "example.com", domain)
+	address, loaded := named.FakeIPLoadDomain("example.com", false)
+	require.True(t, loaded)
+	require.Equal(t, namedAddress, address)
+	require.Equal(t, []netip.Addr{namedAddress}, named.FakeIPAddresses())
+	metadata := named.FakeIPMetadata()
+	require.NotNil(t, metadata)
+	require.Equal(t, namedAddress, metadata.Inet4Current)
+
+	// storages are isolated from each other
+	address, loaded = cacheFile.NamedFakeIPStorage("").FakeIPLoadDomain("example.com", false)
+	require.True(t, loaded)
+	require.Equal(t, defaultAddress, address)
+	_, loaded = cacheFile.NamedFakeIPStorage("local").FakeIPLoad(namedAddress)
+	require.False(t, loaded)
+	require.NoError(t, named.FakeIPReset())
+	_, loaded = named.FakeIPLoad(namedAddress)
+	require.False(t, loaded)
+	_, loaded = cacheFile.NamedFakeIPStorage("").FakeIPLoad(defaultAddress)
+	require.True(t, loaded)
+}
+
+func TestNamedFakeIPStoreRestore(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "cache.db")
+	inet4Range := netip.MustParsePrefix("198.19.0.0/29")
+	newStore := func(cacheFile *CacheFile) *fakeip.Store {
+		ctx := service.ContextWith[adapter.CacheFile](context.Background(), cacheFile)
+		store := fakeip.NewStore(ctx, log.NewNOPFactory().Logger(), "remote", inet4Range, netip.Prefix{})
+		require.NoError(t, store.Start())
+		return store
+	}
+
+	cacheFile := openTestCacheFile(t, path)
+	store := newStore(cacheFile)
+	firstAddress, err := store.Create("example.com", false)
+	require.NoError(t, err)
+	secondAddress, err := store.Create("example.org", false)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return len(cacheFile.NamedFakeIPStorage("remote").FakeIPAddresses()) == 2
+	}, time.Second, 10*time.Millisecond)
+	require.NoError(t, store.Close())
+	require.NoError(t, cacheFile.Close())
+
+	cacheFile = openTestCacheFile(t, path)
+	defer cacheFile.Close()
+	store = newStore(cacheFile)
+	defer store.Close()
+	address, err := store.Create("example.com", false)
+	require.NoError(t, err)
+	require.Equal(t, firstAddress, address)
+	domain, loaded := store.Lookup(secondAddress)
+	require.True(t, loaded)
+	require.Equal(t, "example.org", domain)
+	address, err = store.Create("example.net", false)
+	require.NoError(t, err)
+	require.NotEqual(t, firstAddress, address)
+	require.NotEqual(t, secondAddress, address)
+}
@@ -0,0 +1,59 @@
+package cachefile
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/sagernet/bbolt"
+	bboltErrors "github.com/sagernet/bbolt/errors"
+)
+
+var bucketUserTraffic = []byte("user_traffic")
+
+func (c *CacheFile) LoadUserTraffic(inbound string) map[string]uint64 {
+	traffic := make(map[string]uint64)
+	c.DB.View(func(tx *bbolt.Tx) error {
+		bucket := c.bucket(tx, bucketUserTraffic)
+		if bucket == nil {
+			return nil
+		}
+		bucket = bucket.Bucket([]byte(inbound))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			if len(v) == 8 {
+				traffic[string(k)] = binary.BigEndian.Uint64(v)
+			}
+			return nil
+		})
+	})
+	return traffic
+}
+
+func (c *CacheFile) StoreUserTraffic(inbound string, traffic map[string]uint64) error {
+	return c.DB.Batch(func(tx *bbolt.Tx) error {
+		bucket, err := c.createBucket(tx, bucketUserTraffic)
+		if err != nil {
+			return err
+		}
+		err = bucket.DeleteBucket([]byte(inbound))
+		if err != nil && !errors.Is(err, bboltErrors.ErrBucketNotFound) {
+			return err
+		}
+		if len(traffic) == 0 {
+			return nil
+		}
+		bucket, err = bucket.CreateBucket([]byte(inbound))
+		if err != nil {
+			return err
+		}
+		for name, value := range traffic {
+			err = bucket.Put([]byte(name), binary.BigEndian.AppendUint64(nil, value))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -5,28 +5,63 @@ import (
 	"net/http"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
 	"github.com/sagernet/sing/service"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
 )
 
-func cacheRouter(ctx context.Context) http.Handler {
+func cacheRouter(ctx context.Context, router adapter.Router) http.Handler {
 	r := chi.NewRouter()
-	r.Post("/fakeip/flush", flushFakeip(ctx))
+	r.Get("/fakeip", getFakeIPUsage(router))
+	r.Post("/fakeip/flush", flushFakeip(ctx, router))
 	return r
 }
 
-func flushFakeip(ctx context.Context) func(w http.ResponseWriter, r *http.Request) {
+func getFakeIPUsage(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		cacheFile := service.FromContext[adapter.CacheFile](ctx)
-		if cacheFile != nil {
-			err := cacheFile.FakeIPReset()
-			if err != nil {
-				render.Status(r, http.StatusInternalServerError)
-				render.JSON(w, r, newError(err.Error()))
-				return
+		fakeIPStores := router.FakeIPStores()
+		if len(fakeIPStores) == 0 {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, ErrNotFound)
+			return
+		}
+		var pools []render.M
+		for _, usage := range common.FlatMap(fakeIPStores, adapter.FakeIPStore.Usage) {
+			pool := render.M{
+				"range":  usage.Range.String(),
+				"size":   usage.Size,
+				"used":   usage.Used,
+				"active": usage.Active,
+			}
+			if usage.Name != "" {
+				pool["server"] = usage.Name
+			}
+			if !usage.LeastRecentlyUsed.IsZero() {
+				pool["least_recently_used"] = usage.LeastRecentlyUsed
 			}
+			pools = append(pools, pool)
+		}
+		render.JSON(w, r, render.M{
+			"pools": pools,
+		})
+	}
+}
+
+func flushFakeip(ctx context.Context, router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		if fakeIPStores := router.FakeIPStores(); len(fakeIPStores) > 0 {
+			err = E.Errors(common.Map(fakeIPStores, adapter.FakeIPStore.Reset)...)
+		} else if cacheFile := service.FromContext[adapter.CacheFile](ctx); cacheFile != nil {
+			err = cacheFile.FakeIPReset()
+		}
+		if err != nil {
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, newError(err.Error()))
+			return
 		}
 		render.NoContent(w, r)
 	}
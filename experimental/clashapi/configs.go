@@ -2,8 +2,14 @@ package clashapi
 
 import (
 	"net/http"
+	"os"
+	"path/filepath"
 
+	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/json"
+	"github.com/sagernet/sing/service"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
@@ -12,7 +18,7 @@ import (
 func configRouter(server *Server, logFactory log.Factory) http.Handler {
 	r := chi.NewRouter()
 	r.Get("/", getConfigs(server, logFactory))
-	r.Put("/", updateConfigs)
+	r.Put("/", updateConfigs(server))
 	r.Patch("/", patchConfigs(server))
 	return r
 }
@@ -63,6 +69,58 @@ func patchConfigs(server *Server) func(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func updateConfigs(w http.ResponseWriter, r *http.Request) {
-	render.NoContent(w, r)
+type updateConfigRequest struct {
+	Path    string `json:"path"`
+	Payload string `json:"payload"`
+}
+
+func updateConfigs(server *Server) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request updateConfigRequest
+		err := render.DecodeJSON(r.Body, &request)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, ErrBadRequest)
+			return
+		}
+		reloader := service.FromContext[adapter.ConfigReloader](server.ctx)
+		if reloader == nil {
+			render.Status(r, http.StatusNotImplemented)
+			render.JSON(w, r, newError("config reload is not supported"))
+			return
+		}
+		var content []byte
+		if request.Payload != "" {
+			content = []byte(request.Payload)
+		} else if request.Path != "" {
+			if !filepath.IsAbs(request.Path) {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, newError("path is not an absolute path"))
+				return
+			}
+			content, err = os.ReadFile(request.Path)
+			if err != nil {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, newError(err.Error()))
+				return
+			}
+		} else {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, newError("missing path or payload"))
+			return
+		}
+		options, err := json.UnmarshalExtended[option.Options](content)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, newError(err.Error()))
+			return
+		}
+		err = reloader.Reload(options)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, newError(err.Error()))
+			return
+		}
+		render.NoContent(w, r)
+	}
 }
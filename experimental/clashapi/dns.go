@@ -1,21 +1,30 @@
 package clashapi
 
 import (
+	"bytes"
 	"context"
+	"net"
 	"net/http"
+	"strconv"
 
 	"github.com/sagernet/sing-box/adapter"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/json"
+	"github.com/sagernet/ws"
+	"github.com/sagernet/ws/wsutil"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
 	"github.com/miekg/dns"
 )
 
-func dnsRouter(router adapter.Router) http.Handler {
+func dnsRouter(router adapter.Router, queryLog *DNSQueryLog) http.Handler {
 	r := chi.NewRouter()
 	r.Get("/query", queryDNS(router))
+	r.Get("/logs", getDNSLogs(queryLog))
+	r.Get("/stats", getDNSStats(queryLog))
+	r.Delete("/stats", resetDNSStats(queryLog))
 	return r
 }
 
@@ -80,3 +89,76 @@ func queryDNS(router adapter.Router) func(w http.ResponseWriter, r *http.Request
 		render.JSON(w, r, responseData)
 	}
 }
+
+func getDNSLogs(queryLog *DNSQueryLog) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		subscription, done, err := queryLog.Subscribe()
+		if err != nil {
+			render.Status(r, http.StatusNoContent)
+			return
+		}
+		defer queryLog.UnSubscribe(subscription)
+
+		var conn net.Conn
+		if r.Header.Get("Upgrade") == "websocket" {
+			conn, _, _, err = ws.UpgradeHTTP(r, w)
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+
+		if conn == nil {
+			w.Header().Set("Content-Type", "application/json")
+			render.Status(r, http.StatusOK)
+		}
+
+		buf := &bytes.Buffer{}
+		var query DNSQuery
+		for {
+			select {
+			case <-done:
+				return
+			case query = <-subscription:
+			}
+			buf.Reset()
+			err = json.NewEncoder(buf).Encode(query)
+			if err != nil {
+				break
+			}
+			if conn == nil {
+				_, err = w.Write(buf.Bytes())
+				w.(http.Flusher).Flush()
+			} else {
+				err = wsutil.WriteServerText(conn, buf.Bytes())
+			}
+
+			if err != nil {
+				break
+			}
+		}
+	}
+}
+
+func getDNSStats(queryLog *DNSQueryLog) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		top := 10
+		if topStr := r.URL.Query().Get("top"); topStr != "" {
+			var err error
+			top, err = strconv.Atoi(topStr)
+			if err != nil || top < 0 {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, ErrBadRequest)
+				return
+			}
+		}
+		render.JSON(w, r, queryLog.Statistics(top))
+	}
+}
+
+func resetDNSStats(queryLog *DNSQueryLog) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		queryLog.Reset()
+		render.NoContent(w, r)
+	}
+}
@@ -0,0 +1,244 @@
+package clashapi
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/observable"
+
+	"github.com/miekg/dns"
+)
+
+// dnsMaxTrackedDomains limits the number of domains counted for statistics,
+// the least queried dnsEvictedDomains are dropped at once when it is exceeded,
+// so that eviction runs only once per that many new domains.
+const (
+	dnsMaxTrackedDomains = 8192
+	dnsEvictedDomains    = dnsMaxTrackedDomains / 4
+)
+
+type DNSQuery struct {
+	Time     time.Time `json:"time"`
+	Inbound  string    `json:"inbound,omitempty"`
+	Client   string    `json:"client,omitempty"`
+	Domain   string    `json:"domain"`
+	Type     string    `json:"type"`
+	Rule     string    `json:"rule,omitempty"`
+	Server   string    `json:"server,omitempty"`
+	RCode    string    `json:"rcode"`
+	Answers  []string  `json:"answers"`
+	Cached   bool      `json:"cached"`
+	Blocked  bool      `json:"blocked"`
+	Duration int64     `json:"duration"`
+	Error    string    `json:"error,omitempty"`
+}
+
+type DNSDomainCount struct {
+	Domain string `json:"domain"`
+	Count  uint64 `json:"count"`
+}
+
+type DNSServerStatistics struct {
+	Server         string  `json:"server"`
+	Queries        uint64  `json:"queries"`
+	Failed         uint64  `json:"failed"`
+	AverageLatency float64 `json:"average_latency"`
+}
+
+type DNSStatistics struct {
+	Since      time.Time             `json:"since"`
+	Queries    uint64                `json:"queries"`
+	Cached     uint64                `json:"cached"`
+	Blocked    uint64                `json:"blocked"`
+	Failed     uint64                `json:"failed"`
+	TopDomains []DNSDomainCount      `json:"top_domains"`
+	TopBlocked []DNSDomainCount      `json:"top_blocked"`
+	Servers    []DNSServerStatistics `json:"servers"`
+}
+
+type dnsServerCounter struct {
+	queries  uint64
+	failed   uint64
+	duration time.Duration
+}
+
+type DNSQueryLog struct {
+	subscriber *observable.Subscriber[DNSQuery]
+	observer   *observable.Observer[DNSQuery]
+
+	access         sync.Mutex
+	since          time.Time
+	queries        uint64
+	cached         uint64
+	blocked        uint64
+	failed         uint64
+	domains        map[string]uint64
+	blockedDomains map[string]uint64
+	servers        map[string]*dnsServerCounter
+}
+
+func NewDNSQueryLog() *DNSQueryLog {
+	subscriber := observable.NewSubscriber[DNSQuery](128)
+	queryLog := &DNSQueryLog{
+		subscriber: subscriber,
+		observer:   observable.NewObserver[DNSQuery](subscriber, 64),
+	}
+	queryLog.Reset()
+	return queryLog
+}
+
+func (l *DNSQueryLog) Subscribe() (subscription observable.Subscription[DNSQuery], done <-chan struct{}, err error) {
+	return l.observer.Subscribe()
+}
+
+func (l *DNSQueryLog) UnSubscribe(subscription observable.Subscription[DNSQuery]) {
+	l.observer.UnSubscribe(subscription)
+}
+
+func (l *DNSQueryLog) Add(event adapter.DNSQueryEvent) {
+	query := DNSQuery{
+		Time:    event.Time,
+		Inbound: event.Inbound,
+		Domain:  event.Domain,
+		Type: strings.Join(common.Map(event.QueryTypes, func(it uint16) string {
+			return dns.TypeToString[it]
+		}), ","),
+		Server:   event.Transport,
+		RCode:    dns.RcodeToString[event.RCode],
+		Answers:  event.Answers,
+		Cached:   event.Cached,
+		Blocked:  event.Blocked,
+		Duration: event.Duration.Milliseconds(),
+	}
+	if event.Source.IsValid() {
+		query.Client = event.Source.String()
+	}
+	if event.Rule != nil {
+		query.Rule = event.Rule.String()
+	} else if !event.Cached {
+		query.Rule = "final"
+	}
+	if query.Answers == nil {
+		query.Answers = []string{}
+	}
+	if event.Error != nil {
+		query.Error = event.Error.Error()
+	}
+	l.subscriber.Emit(query)
+
+	l.access.Lock()
+	defer l.access.Unlock()
+	l.queries++
+	if event.Cached {
+		l.cached++
+	}
+	if event.Error != nil {
+		l.failed++
+	}
+	if event.Domain != "" {
+		l.domains[event.Domain]++
+		if len(l.domains) > dnsMaxTrackedDomains {
+			evictDomains(l.domains)
+		}
+	}
+	if event.Blocked {
+		l.blocked++
+		if event.Domain != "" {
+			l.blockedDomains[event.Domain]++
+			if len(l.blockedDomains) > dnsMaxTrackedDomains {
+				evictDomains(l.blockedDomains)
+			}
+		}
+	}
+	if !event.Cached && event.Transport != "" {
+		counter := l.servers[event.Transport]
+		if counter == nil {
+			counter = &dnsServerCounter{}
+			l.servers[event.Transport] = counter
+		}
+		counter.queries++
+		counter.duration += event.Duration
+		if event.Error != nil {
+			counter.failed++
+		}
+	}
+}
+
+func (l *DNSQueryLog) Statistics(top int) DNSStatistics {
+	l.access.Lock()
+	defer l.access.Unlock()
+	statistics := DNSStatistics{
+		Since:      l.since,
+		Queries:    l.queries,
+		Cached:     l.cached,
+		Blocked:    l.blocked,
+		Failed:     l.failed,
+		TopDomains: topDomains(l.domains, top),
+		TopBlocked: topDomains(l.blockedDomains, top),
+		Servers:    make([]DNSServerStatistics, 0, len(l.servers)),
+	}
+	for server, counter := range l.servers {
+		statistics.Servers = append(statistics.Servers, DNSServerStatistics{
+			Server:         server,
+			Queries:        counter.queries,
+			Failed:         counter.failed,
+			AverageLatency: float64(counter.duration.Microseconds()) / float64(counter.queries) / 1000,
+		})
+	}
+	sort.Slice(statistics.Servers, func(i, j int) bool {
+		return statistics.Servers[i].Server < statistics.Servers[j].Server
+	})
+	return statistics
+}
+
+func (l *DNSQueryLog) Reset() {
+	l.access.Lock()
+	defer l.access.Unlock()
+	l.since = time.Now()
+	l.queries = 0
+	l.cached = 0
+	l.blocked = 0
+	l.failed = 0
+	l.domains = make(map[string]uint64)
+	l.blockedDomains = make(map[string]uint64)
+	l.servers = make(map[string]*dnsServerCounter)
+}
+
+func (l *DNSQueryLog) Close() error {
+	l.subscriber.Close()
+	return nil
+}
+
+func evictDomains(domains map[string]uint64) {
+	counts := make([]DNSDomainCount, 0, len(domains))
+	for domain, count := range domains {
+		counts = append(counts, DNSDomainCount{domain, count})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		return counts[i].Count < counts[j].Count
+	})
+	for _, count := range counts[:len(counts)-dnsMaxTrackedDomains+dnsEvictedDomains] {
+		delete(domains, count.Domain)
+	}
+}
+
+func topDomains(domains map[string]uint64, top int) []DNSDomainCount {
+	counts := make([]DNSDomainCount, 0, len(domains))
+	for domain, count := range domains {
+		counts = append(counts, DNSDomainCount{domain, count})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Domain < counts[j].Domain
+	})
+	if len(counts) > top {
+		counts = counts[:top]
+	}
+	return counts
+}
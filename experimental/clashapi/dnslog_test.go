@@ -0,0 +1,81 @@
+package clashapi
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	F "github.com/sagernet/sing/common/format"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDNSQueryLogStatistics(t *testing.T) {
+	t.Parallel()
+	queryLog := NewDNSQueryLog()
+	defer queryLog.Close()
+	for i := 0; i < 3; i++ {
+		queryLog.Add(adapter.DNSQueryEvent{Domain: "example.com", QueryTypes: []uint16{dns.TypeA}, Transport: "local", Duration: 10 * time.Millisecond})
+	}
+	queryLog.Add(adapter.DNSQueryEvent{Domain: "example.com", Cached: true})
+	queryLog.Add(adapter.DNSQueryEvent{Domain: "ads.example.org", Transport: "block", Blocked: true})
+	queryLog.Add(adapter.DNSQueryEvent{Domain: "example.net", Transport: "local", Duration: 30 * time.Millisecond, Error: errors.New("timeout")})
+
+	statistics := queryLog.Statistics(2)
+	require.Equal(t, uint64(6), statistics.Queries)
+	require.Equal(t, uint64(1), statistics.Cached)
+	require.Equal(t, uint64(1), statistics.Blocked)
+	require.Equal(t, uint64(1), statistics.Failed)
+	require.Equal(t, []DNSDomainCount{{"example.com", 4}, {"ads.example.org", 1}}, statistics.TopDomains)
+	require.Equal(t, []DNSDomainCount{{"ads.example.org", 1}}, statistics.TopBlocked)
+	require.Equal(t, []DNSServerStatistics{
+		{Server: "block", Queries: 1},
+		{Server: "local", Queries: 4, Failed: 1, AverageLatency: 15},
+	}, statistics.Servers)
+
+	queryLog.Reset()
+	statistics = queryLog.Statistics(2)
+	require.Zero(t, statistics.Queries)
+	require.Empty(t, statistics.TopDomains)
+}
+
+func TestDNSQueryLogEvictsLeastQueried(t *testing.T) {
+	t.Parallel()
+	queryLog := NewDNSQueryLog()
+	defer queryLog.Close()
+	for i := 0; i < dnsMaxTrackedDomains; i++ {
+		domain := F.ToString("domain-", i, ".example.com")
+		queryLog.Add(adapter.DNSQueryEvent{Domain: domain})
+		if i%2 == 0 {
+			queryLog.Add(adapter.DNSQueryEvent{Domain: domain})
+		}
+	}
+	require.Len(t, queryLog.domains, dnsMaxTrackedDomains)
+
+	queryLog.Add(adapter.DNSQueryEvent{Domain: "new.example.com"})
+	require.Len(t, queryLog.domains, dnsMaxTrackedDomains-dnsEvictedDomains)
+	for i := 0; i < dnsMaxTrackedDomains; i += 2 {
+		require.Equal(t, uint64(2), queryLog.domains[F.ToString("domain-", i, ".example.com")])
+	}
+
+	// no eviction happens until the limit is exceeded again
+	for i := 0; i < dnsEvictedDomains; i++ {
+		queryLog.Add(adapter.DNSQueryEvent{Domain: F.ToString("other-", i, ".example.com")})
+	}
+	require.Len(t, queryLog.domains, dnsMaxTrackedDomains)
+}
+
+func TestEvictDomainsWithFrequentDomains(t *testing.T) {
+	t.Parallel()
+	domains := make(map[string]uint64)
+	for i := 0; i <= dnsMaxTrackedDomains; i++ {
+		domains[F.ToString("domain-", i)] = uint64(100 + i)
+	}
+	evictDomains(domains)
+	require.Len(t, domains, dnsMaxTrackedDomains-dnsEvictedDomains)
+	for domain, count := range domains {
+		require.Greater(t, count, uint64(100+dnsEvictedDomains), domain)
+	}
+}
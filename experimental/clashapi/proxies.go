@@ -130,6 +130,14 @@ func getProxies(server *Server, router adapter.Router) func(w http.ResponseWrite
 			}
 			proxyMap.Put(tag, proxyInfo(server, detour))
 		}
+		for _, provider := range router.OutboundProviders() {
+			for _, detour := range provider.Outbounds() {
+				if proxyMap.ContainsKey(detour.Tag()) {
+					continue
+				}
+				proxyMap.Put(detour.Tag(), proxyInfo(server, detour))
+			}
+		}
 		var responseMap badjson.JSONObject
 		responseMap.Put("proxies", &proxyMap)
 		response, err := responseMap.MarshalJSON()
@@ -1,58 +1,104 @@
 package clashapi
 
 import (
+	"context"
 	"net/http"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing/common/json/badjson"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
 )
 
-func ruleProviderRouter() http.Handler {
+func ruleProviderRouter(router adapter.Router) http.Handler {
 	r := chi.NewRouter()
-	r.Get("/", getRuleProviders)
+	r.Get("/", getRuleProviders(router))
 
 	r.Route("/{name}", func(r chi.Router) {
-		r.Use(parseProviderName, findRuleProviderByName)
+		r.Use(parseProviderName, findRuleProviderByName(router))
 		r.Get("/", getRuleProvider)
 		r.Put("/", updateRuleProvider)
 	})
 	return r
 }
 
-func getRuleProviders(w http.ResponseWriter, r *http.Request) {
-	render.JSON(w, r, render.M{
-		"providers": []string{},
-	})
+func ruleProviderInfo(ruleSet adapter.RuleSet) *badjson.JSONObject {
+	var info badjson.JSONObject
+	info.Put("name", ruleSet.Name())
+	info.Put("type", "Rule")
+	info.Put("behavior", ruleSet.Behavior())
+	info.Put("format", ruleSet.Format())
+	info.Put("ruleCount", ruleSet.RuleCount())
+	var vehicleType string
+	switch ruleSet.Type() {
+	case C.RuleSetTypeRemote:
+		vehicleType = "HTTP"
+	case C.RuleSetTypeLocal:
+		vehicleType = "File"
+	default:
+		vehicleType = "Inline"
+	}
+	info.Put("vehicleType", vehicleType)
+	if updatedAt := ruleSet.UpdatedAt(); !updatedAt.IsZero() {
+		info.Put("updatedAt", updatedAt.Format(time.RFC3339Nano))
+	}
+	return &info
+}
+
+func getRuleProviders(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var providerMap badjson.JSONObject
+		for _, ruleSet := range router.RuleSets() {
+			providerMap.Put(ruleSet.Name(), ruleProviderInfo(ruleSet))
+		}
+		var responseMap badjson.JSONObject
+		responseMap.Put("providers", &providerMap)
+		response, err := responseMap.MarshalJSON()
+		if err != nil {
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, newError(err.Error()))
+			return
+		}
+		w.Write(response)
+	}
 }
 
 func getRuleProvider(w http.ResponseWriter, r *http.Request) {
-	// provider := r.Context().Value(CtxKeyProvider).(provider.RuleProvider)
-	// render.JSON(w, r, provider)
-	render.NoContent(w, r)
+	ruleSet := r.Context().Value(CtxKeyProvider).(adapter.RuleSet)
+	response, err := ruleProviderInfo(ruleSet).MarshalJSON()
+	if err != nil {
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, newError(err.Error()))
+		return
+	}
+	w.Write(response)
 }
 
 func updateRuleProvider(w http.ResponseWriter, r *http.Request) {
-	/*provider := r.Context().Value(CtxKeyProvider).(provider.RuleProvider)
-	if err := provider.Update(); err != nil {
+	ruleSet := r.Context().Value(CtxKeyProvider).(adapter.RuleSet)
+	if err := ruleSet.Update(r.Context()); err != nil {
 		render.Status(r, http.StatusServiceUnavailable)
 		render.JSON(w, r, newError(err.Error()))
 		return
-	}*/
+	}
 	render.NoContent(w, r)
 }
 
-func findRuleProviderByName(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		/*name := r.Context().Value(CtxKeyProviderName).(string)
-		providers := tunnel.RuleProviders()
-		provider, exist := providers[name]
-		if !exist {*/
-		render.Status(r, http.StatusNotFound)
-		render.JSON(w, r, ErrNotFound)
-		//return
-		//}
-
-		// ctx := context.WithValue(r.Context(), CtxKeyProvider, provider)
-		// next.ServeHTTP(w, r.WithContext(ctx))
-	})
+func findRuleProviderByName(router adapter.Router) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			name := r.Context().Value(CtxKeyProviderName).(string)
+			ruleSet, exist := router.RuleSet(name)
+			if !exist {
+				render.Status(r, http.StatusNotFound)
+				render.JSON(w, r, ErrNotFound)
+				return
+			}
+			ctx := context.WithValue(r.Context(), CtxKeyProvider, ruleSet)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
 }
@@ -0,0 +1,153 @@
+package clashapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testRuleProviderRouter struct {
+	adapter.Router
+	ruleSets []adapter.RuleSet
+}
+
+func (r *testRuleProviderRouter) RuleSets() []adapter.RuleSet {
+	return r.ruleSets
+}
+
+func (r *testRuleProviderRouter) RuleSet(tag string) (adapter.RuleSet, bool) {
+	for _, ruleSet := range r.ruleSets {
+		if ruleSet.Name() == tag {
+			return ruleSet, true
+		}
+	}
+	return nil, false
+}
+
+type testRuleSet struct {
+	adapter.RuleSet
+	name        string
+	ruleSetType string
+	format      string
+	behavior    string
+	ruleCount   int
+	updatedAt   time.Time
+	updateErr   error
+	updates     int
+}
+
+func (s *testRuleSet) Name() string {
+	return s.name
+}
+
+func (s *testRuleSet) Type() string {
+	return s.ruleSetType
+}
+
+func (s *testRuleSet) Format() string {
+	return s.format
+}
+
+func (s *testRuleSet) Behavior() string {
+	return s.behavior
+}
+
+func (s *testRuleSet) RuleCount() int {
+	return s.ruleCount
+}
+
+func (s *testRuleSet) UpdatedAt() time.Time {
+	return s.updatedAt
+}
+
+func (s *testRuleSet) Update(ctx context.Context) error {
+	s.updates++
+	return s.updateErr
+}
+
+type testRuleProviderInfo struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Behavior    string `json:"behavior"`
+	Format      string `json:"format"`
+	RuleCount   int    `json:"ruleCount"`
+	VehicleType string `json:"vehicleType"`
+	UpdatedAt   string `json:"updatedAt"`
+}
+
+func serveRuleProviders(handler http.Handler, method string, path string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(method, path, nil))
+	return recorder
+}
+
+func TestRuleProviders(t *testing.T) {
+	t.Parallel()
+	updatedAt := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+	remote := &testRuleSet{
+		name:        "geosite-cn",
+		ruleSetType: C.RuleSetTypeRemote,
+		format:      C.RuleSetFormatBinary,
+		behavior:    C.RuleSetBehaviorDomain,
+		ruleCount:   2,
+		updatedAt:   updatedAt,
+	}
+	inline := &testRuleSet{
+		name:        "private",
+		ruleSetType: C.RuleSetTypeInline,
+		behavior:    C.RuleSetBehaviorIPCIDR,
+		ruleCount:   1,
+		updateErr:   errors.New("inline rule-set cannot be updated"),
+	}
+	handler := ruleProviderRouter(&testRuleProviderRouter{ruleSets: []adapter.RuleSet{remote, inline}})
+
+	recorder := serveRuleProviders(handler, http.MethodGet, "/")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var response struct {
+		Providers map[string]testRuleProviderInfo `json:"providers"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	require.Equal(t, map[string]testRuleProviderInfo{
+		"geosite-cn": {
+			Name:        "geosite-cn",
+			Type:        "Rule",
+			Behavior:    C.RuleSetBehaviorDomain,
+			Format:      C.RuleSetFormatBinary,
+			RuleCount:   2,
+			VehicleType: "HTTP",
+			UpdatedAt:   updatedAt.Format(time.RFC3339Nano),
+		},
+		"private": {
+			Name:        "private",
+			Type:        "Rule",
+			Behavior:    C.RuleSetBehaviorIPCIDR,
+			RuleCount:   1,
+			VehicleType: "Inline",
+		},
+	}, response.Providers)
+
+	recorder = serveRuleProviders(handler, http.MethodGet, "/geosite-cn")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var info testRuleProviderInfo
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &info))
+	require.Equal(t, "geosite-cn", info.Name)
+	require.Equal(t, http.StatusNotFound, serveRuleProviders(handler, http.MethodGet, "/missing").Code)
+
+	recorder = serveRuleProviders(handler, http.MethodPut, "/geosite-cn")
+	require.Equal(t, http.StatusNoContent, recorder.Code)
+	require.Equal(t, 1, remote.updates)
+	recorder = serveRuleProviders(handler, http.MethodPut, "/private")
+	require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	require.Contains(t, recorder.Body.String(), "inline rule-set cannot be updated")
+	require.Equal(t, 1, inline.updates)
+	require.Equal(t, http.StatusNotFound, serveRuleProviders(handler, http.MethodPut, "/missing").Code)
+}
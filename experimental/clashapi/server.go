@@ -45,6 +45,7 @@ type Server struct {
 	logger         log.Logger
 	httpServer     *http.Server
 	trafficManager *trafficontrol.Manager
+	dnsQueryLog    *DNSQueryLog
 	urlTestHistory *urltest.HistoryStorage
 	mode           string
 	modeList       []string
@@ -68,6 +69,7 @@ func NewServer(ctx context.Context, router adapter.Router, logFactory log.Observ
 			Handler: chiRouter,
 		},
 		trafficManager:           trafficManager,
+		dnsQueryLog:              NewDNSQueryLog(),
 		modeList:                 options.ModeList,
 		externalController:       options.ExternalController != "",
 		externalUIDownloadURL:    options.ExternalUIDownloadURL,
@@ -107,12 +109,13 @@ func NewServer(ctx context.Context, router adapter.Router, logFactory log.Observ
 		r.Mount("/proxies", proxyRouter(server, router))
 		r.Mount("/rules", ruleRouter(router))
 		r.Mount("/connections", connectionRouter(router, trafficManager))
-		r.Mount("/providers/proxies", proxyProviderRouter())
-		r.Mount("/providers/rules", ruleProviderRouter())
+		r.Mount("/providers/proxies", proxyProviderRouter(server, router))
+		r.Mount("/providers/rules", ruleProviderRouter(router))
 		r.Mount("/script", scriptRouter())
 		r.Mount("/profile", profileRouter())
-		r.Mount("/cache", cacheRouter(ctx))
-		r.Mount("/dns", dnsRouter(router))
+		r.Mount("/cache", cacheRouter(ctx, router))
+		r.Mount("/dns", dnsRouter(router, server.dnsQueryLog))
+		r.Mount("/users", userRouter(router))
 
 		server.setupMetaAPI(r)
 	})
@@ -175,6 +178,7 @@ func (s *Server) Close() error {
 	return common.Close(
 		common.PtrOrNil(s.httpServer),
 		s.trafficManager,
+		s.dnsQueryLog,
 		s.urlTestHistory,
 	)
 }
@@ -239,6 +243,14 @@ func (s *Server) RoutedPacketConnection(ctx context.Context, conn N.PacketConn,
 	return tracker, tracker
 }
 
+func (s *Server) DNSQueried(event adapter.DNSQueryEvent) {
+	s.dnsQueryLog.Add(event)
+}
+
+func (s *Server) DNSQueryLog() *DNSQueryLog {
+	return s.dnsQueryLog
+}
+
 func authentication(serverSecret string) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		fn := func(w http.ResponseWriter, r *http.Request) {
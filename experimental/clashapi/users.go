@@ -0,0 +1,107 @@
+package clashapi
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing/common"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+func userRouter(router adapter.Router) http.Handler {
+	r := chi.NewRouter()
+	r.Route("/{tag}", func(r chi.Router) {
+		r.Use(findManagedUserInbound(router))
+		r.Get("/", getUsers)
+		r.Post("/", addUsers)
+		r.Put("/", replaceUsers)
+		r.Delete("/{name}", removeUser)
+	})
+	return r
+}
+
+type updateUsersRequest struct {
+	Users []adapter.InboundUser `json:"users"`
+}
+
+func getUsers(w http.ResponseWriter, r *http.Request) {
+	inbound := r.Context().Value(CtxKeyInbound).(adapter.ManagedUserInbound)
+	users := common.Map(inbound.Users(), adapter.InboundUser.Redacted)
+	if users == nil {
+		users = []adapter.InboundUser{}
+	}
+	render.JSON(w, r, render.M{
+		"users": users,
+	})
+}
+
+func addUsers(w http.ResponseWriter, r *http.Request) {
+	inbound := r.Context().Value(CtxKeyInbound).(adapter.ManagedUserInbound)
+	var request updateUsersRequest
+	err := render.DecodeJSON(r.Body, &request)
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, ErrBadRequest)
+		return
+	}
+	err = inbound.AddUsers(request.Users)
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, newError(err.Error()))
+		return
+	}
+	render.NoContent(w, r)
+}
+
+func replaceUsers(w http.ResponseWriter, r *http.Request) {
+	inbound := r.Context().Value(CtxKeyInbound).(adapter.ManagedUserInbound)
+	var request updateUsersRequest
+	err := render.DecodeJSON(r.Body, &request)
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, ErrBadRequest)
+		return
+	}
+	err = inbound.ReplaceUsers(request.Users)
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, newError(err.Error()))
+		return
+	}
+	render.NoContent(w, r)
+}
+
+func removeUser(w http.ResponseWriter, r *http.Request) {
+	inbound := r.Context().Value(CtxKeyInbound).(adapter.ManagedUserInbound)
+	err := inbound.RemoveUsers([]string{getEscapeParam(r, "name")})
+	if err != nil {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, newError(err.Error()))
+		return
+	}
+	render.NoContent(w, r)
+}
+
+func findManagedUserInbound(router adapter.Router) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			inbound, loaded := router.Inbound(getEscapeParam(r, "tag"))
+			if !loaded {
+				render.Status(r, http.StatusNotFound)
+				render.JSON(w, r, ErrNotFound)
+				return
+			}
+			managedInbound, isManaged := inbound.(adapter.ManagedUserInbound)
+			if !isManaged {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, newError("inbound does not support user management"))
+				return
+			}
+			ctx := context.WithValue(r.Context(), CtxKeyInbound, managedInbound)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
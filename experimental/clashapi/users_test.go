@@ -0,0 +1,61 @@
+package clashapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testUserRouter struct {
+	adapter.Router
+	inbound adapter.Inbound
+}
+
+func (r *testUserRouter) Inbound(tag string) (adapter.Inbound, bool) {
+	if tag != r.inbound.Tag() {
+		return nil, false
+	}
+	return r.inbound, true
+}
+
+type testManagedUserInbound struct {
+	adapter.ManagedUserInbound
+	users []adapter.InboundUser
+}
+
+func (i *testManagedUserInbound) Tag() string {
+	return "vless-in"
+}
+
+func (i *testManagedUserInbound) Users() []adapter.InboundUser {
+	return i.users
+}
+
+func TestGetUsersRedactsCredentials(t *testing.T) {
+	t.Parallel()
+	inbound := &testManagedUserInbound{users: []adapter.InboundUser{
+		{Name: "uuid", UUID: "b831381d-6324-4d53-ad4f-8cda48b30811", Flow: "xtls-rprx-vision"},
+		{Name: "password", Password: "password", UserLimitOptions: option.UserLimitOptions{MaxConnections: 2}},
+	}}
+	handler := userRouter(&testUserRouter{inbound: inbound})
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/vless-in", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.NotContains(t, recorder.Body.String(), "b831381d")
+	require.NotContains(t, recorder.Body.String(), `"password":`)
+	var response struct {
+		Users []adapter.InboundUser `json:"users"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	require.Equal(t, []adapter.InboundUser{
+		{Name: "uuid", Flow: "xtls-rprx-vision"},
+		{Name: "password", UserLimitOptions: option.UserLimitOptions{MaxConnections: 2}},
+	}, response.Users)
+	require.Equal(t, "password", inbound.users[1].Password)
+}
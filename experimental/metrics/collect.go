@@ -0,0 +1,138 @@
+package metrics
+
+import "sort"
+
+var dnsDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+
+func (s *Server) writeTraffic(writer *Writer) {
+	s.trafficAccess.Lock()
+	defer s.trafficAccess.Unlock()
+	writeTrafficCounters(writer, "sing_box_inbound_traffic_bytes_total", "Traffic of connections accepted by the inbound.", "inbound", s.inbounds)
+	writeTrafficCounters(writer, "sing_box_outbound_traffic_bytes_total", "Traffic of connections routed to the outbound.", "outbound", s.outbounds)
+	writeTrafficCounters(writer, "sing_box_user_traffic_bytes_total", "Traffic of connections of the user.", "user", s.users)
+}
+
+func writeTrafficCounters(writer *Writer, name string, help string, label string, counters map[string]*trafficCounter) {
+	writer.Header(name, "counter", help)
+	for _, key := range sortedKeys(counters) {
+		counter := counters[key]
+		writer.Sample(name, []string{label, key, "direction", "uplink"}, float64(counter.uplink.Load()))
+		writer.Sample(name, []string{label, key, "direction", "downlink"}, float64(counter.downlink.Load()))
+	}
+}
+
+type connectionKey struct {
+	inbound  string
+	outbound string
+	network  string
+}
+
+func (s *Server) writeConnections(writer *Writer) {
+	connections := make(map[connectionKey]int)
+	for _, connection := range s.trafficManager.Connections() {
+		connections[connectionKey{
+			inbound:  connection.Metadata.Inbound,
+			outbound: connection.Outbound,
+			network:  connection.Metadata.Network,
+		}]++
+	}
+	keys := make([]connectionKey, 0, len(connections))
+	for key := range connections {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].inbound != keys[j].inbound {
+			return keys[i].inbound < keys[j].inbound
+		}
+		if keys[i].outbound != keys[j].outbound {
+			return keys[i].outbound < keys[j].outbound
+		}
+		return keys[i].network < keys[j].network
+	})
+	writer.Header("sing_box_connections", "gauge", "Active connections.")
+	for _, key := range keys {
+		writer.Sample("sing_box_connections", []string{"inbound", key.inbound, "outbound", key.outbound, "network", key.network}, float64(connections[key]))
+	}
+	upload, download := s.trafficManager.Total()
+	writer.Header("sing_box_traffic_bytes_total", "counter", "Traffic of all routed connections.")
+	writer.Sample("sing_box_traffic_bytes_total", []string{"direction", "uplink"}, float64(upload))
+	writer.Sample("sing_box_traffic_bytes_total", []string{"direction", "downlink"}, float64(download))
+}
+
+func (s *Server) writeDNS(writer *Writer) {
+	queries := s.dnsQueries.Load()
+	cacheHits := s.dnsCacheHits.Load()
+	writer.Header("sing_box_dns_queries_total", "counter", "DNS queries handled by the router.")
+	writer.Sample("sing_box_dns_queries_total", nil, float64(queries))
+	writer.Header("sing_box_dns_cache_hits_total", "counter", "DNS queries answered from the cache.")
+	writer.Sample("sing_box_dns_cache_hits_total", nil, float64(cacheHits))
+	writer.Header("sing_box_dns_cache_hit_ratio", "gauge", "Ratio of DNS queries answered from the cache.")
+	var cacheHitRatio float64
+	if queries > 0 {
+		cacheHitRatio = float64(cacheHits) / float64(queries)
+	}
+	writer.Sample("sing_box_dns_cache_hit_ratio", nil, cacheHitRatio)
+	s.dnsAccess.Lock()
+	defer s.dnsAccess.Unlock()
+	servers := sortedKeys(s.dnsServers)
+	writer.Header("sing_box_dns_query_failures_total", "counter", "Failed DNS queries sent to the server.")
+	for _, server := range servers {
+		writer.Sample("sing_box_dns_query_failures_total", []string{"server", server}, float64(s.dnsServers[server].failures))
+	}
+	writer.Header("sing_box_dns_query_duration_seconds", "histogram", "Latency of successful DNS queries sent to the server.")
+	for _, server := range servers {
+		writer.Histogram("sing_box_dns_query_duration_seconds", []string{"server", server}, s.dnsServers[server].duration)
+	}
+}
+
+func (s *Server) writeRuleSets(writer *Writer) {
+	ruleSets := s.router.RuleSets()
+	writer.Header("sing_box_rule_set_rules", "gauge", "Rules in the rule-set.")
+	for _, ruleSet := range ruleSets {
+		writer.Sample("sing_box_rule_set_rules", []string{"rule_set", ruleSet.Name(), "type", ruleSet.Type()}, float64(ruleSet.RuleCount()))
+	}
+	writer.Header("sing_box_rule_set_updated_timestamp_seconds", "gauge", "Time of the last successful update of the rule-set.")
+	for _, ruleSet := range ruleSets {
+		updatedAt := ruleSet.UpdatedAt()
+		if updatedAt.IsZero() {
+			continue
+		}
+		writer.Sample("sing_box_rule_set_updated_timestamp_seconds", []string{"rule_set", ruleSet.Name(), "type", ruleSet.Type()}, float64(updatedAt.Unix()))
+	}
+}
+
+func (s *Server) writeURLTest(writer *Writer) {
+	if s.urlTestHistory == nil {
+		return
+	}
+	type urlTestResult struct {
+		outbound string
+		delay    uint16
+		testedAt int64
+	}
+	var results []urlTestResult
+	for _, outbound := range s.router.Outbounds() {
+		history := s.urlTestHistory.LoadURLTestHistory(outbound.Tag())
+		if history == nil {
+			continue
+		}
+		results = append(results, urlTestResult{outbound.Tag(), history.Delay, history.Time.Unix()})
+	}
+	writer.Header("sing_box_urltest_delay_milliseconds", "gauge", "Delay of the last URL test of the outbound.")
+	for _, result := range results {
+		writer.Sample("sing_box_urltest_delay_milliseconds", []string{"outbound", result.outbound}, float64(result.delay))
+	}
+	writer.Header("sing_box_urltest_timestamp_seconds", "gauge", "Time of the last URL test of the outbound.")
+	for _, result := range results {
+		writer.Sample("sing_box_urltest_timestamp_seconds", []string{"outbound", result.outbound}, float64(result.testedAt))
+	}
+}
+
+func sortedKeys[T any](values map[string]T) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
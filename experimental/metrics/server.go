@@ -0,0 +1,225 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/urltest"
+	"github.com/sagernet/sing-box/experimental/clashapi/trafficontrol"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/atomic"
+	"github.com/sagernet/sing/common/bufio"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/service"
+)
+
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+var _ adapter.MetricsServer = (*Server)(nil)
+
+type Server struct {
+	ctx            context.Context
+	router         adapter.Router
+	logger         log.Logger
+	listen         string
+	path           string
+	secret         string
+	httpServer     *http.Server
+	tcpListener    net.Listener
+	urlTestHistory *urltest.HistoryStorage
+
+	// trafficManager is shared with the Clash API if enabled, connections are then tracked only once.
+	trafficManager     *trafficontrol.Manager
+	ownsTrafficManager bool
+
+	trafficAccess sync.Mutex
+	inbounds      map[string]*trafficCounter
+	outbounds     map[string]*trafficCounter
+	users         map[string]*trafficCounter
+
+	dnsQueries   atomic.Int64
+	dnsCacheHits atomic.Int64
+	dnsAccess    sync.Mutex
+	dnsServers   map[string]*dnsServerCounter
+}
+
+type trafficCounter struct {
+	uplink   atomic.Int64
+	downlink atomic.Int64
+}
+
+type dnsServerCounter struct {
+	failures int64
+	duration *histogram
+}
+
+func NewServer(ctx context.Context, router adapter.Router, logger log.Logger, options option.MetricsOptions) (*Server, error) {
+	path := options.Path
+	if path == "" {
+		path = "/metrics"
+	} else if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	server := &Server{
+		ctx:            ctx,
+		router:         router,
+		logger:         logger,
+		listen:         options.Listen,
+		path:           path,
+		secret:         options.Secret,
+		urlTestHistory: service.PtrFromContext[urltest.HistoryStorage](ctx),
+		inbounds:       make(map[string]*trafficCounter),
+		outbounds:      make(map[string]*trafficCounter),
+		users:          make(map[string]*trafficCounter),
+		dnsServers:     make(map[string]*dnsServerCounter),
+	}
+	if clashServer, isTracked := router.ClashServer().(trafficManagerServer); isTracked {
+		server.trafficManager = clashServer.TrafficManager()
+	} else {
+		server.trafficManager = trafficontrol.NewManager()
+		server.ownsTrafficManager = true
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(path, server.serveMetrics)
+	server.httpServer = &http.Server{
+		Handler: mux,
+	}
+	return server, nil
+}
+
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", s.listen)
+	if err != nil {
+		return err
+	}
+	s.logger.Info("metrics server started at ", listener.Addr())
+	s.tcpListener = listener
+	go func() {
+		err = s.httpServer.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Error("metrics server serve error: ", err)
+		}
+	}()
+	return nil
+}
+
+func (s *Server) Close() error {
+	var trafficManager *trafficontrol.Manager
+	if s.ownsTrafficManager {
+		trafficManager = s.trafficManager
+	}
+	return common.Close(
+		common.PtrOrNil(s.httpServer),
+		s.tcpListener,
+		common.PtrOrNil(trafficManager),
+	)
+}
+
+func (s *Server) RoutedConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext, matchedRule adapter.Rule, chain []adapter.Outbound) (net.Conn, adapter.Tracker) {
+	readCounters, writeCounters := s.loadTrafficCounters(metadata, chain)
+	conn = bufio.NewInt64CounterConn(conn, readCounters, writeCounters)
+	if !s.ownsTrafficManager {
+		return conn, nopTracker{}
+	}
+	tracker := trafficontrol.NewTCPTracker(conn, s.trafficManager, metadata, s.router, matchedRule)
+	return tracker, tracker
+}
+
+func (s *Server) RoutedPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext, matchedRule adapter.Rule, chain []adapter.Outbound) (N.PacketConn, adapter.Tracker) {
+	readCounters, writeCounters := s.loadTrafficCounters(metadata, chain)
+	conn = bufio.NewInt64CounterPacketConn(conn, readCounters, writeCounters)
+	if !s.ownsTrafficManager {
+		return conn, nopTracker{}
+	}
+	tracker := trafficontrol.NewUDPTracker(conn, s.trafficManager, metadata, s.router, matchedRule)
+	return tracker, tracker
+}
+
+type trafficManagerServer interface {
+	TrafficManager() *trafficontrol.Manager
+}
+
+type nopTracker struct{}
+
+func (nopTracker) Leave() {
+}
+
+// loadTrafficCounters returns the counters of the inbound, the user and every
+// outbound in the chain, so traffic through a group is also counted for the
+// outbound it selected.
+func (s *Server) loadTrafficCounters(metadata adapter.InboundContext, chain []adapter.Outbound) ([]*atomic.Int64, []*atomic.Int64) {
+	var counters []*trafficCounter
+	s.trafficAccess.Lock()
+	if metadata.Inbound != "" {
+		counters = append(counters, loadTrafficCounter(s.inbounds, metadata.Inbound))
+	}
+	if metadata.User != "" {
+		counters = append(counters, loadTrafficCounter(s.users, metadata.User))
+	}
+	for _, outbound := range chain {
+		counters = append(counters, loadTrafficCounter(s.outbounds, outbound.Tag()))
+	}
+	s.trafficAccess.Unlock()
+	readCounters := make([]*atomic.Int64, 0, len(counters))
+	writeCounters := make([]*atomic.Int64, 0, len(counters))
+	for _, counter := range counters {
+		readCounters = append(readCounters, &counter.uplink)
+		writeCounters = append(writeCounters, &counter.downlink)
+	}
+	return readCounters, writeCounters
+}
+
+func loadTrafficCounter(counters map[string]*trafficCounter, name string) *trafficCounter {
+	counter, loaded := counters[name]
+	if !loaded {
+		counter = new(trafficCounter)
+		counters[name] = counter
+	}
+	return counter
+}
+
+func (s *Server) DNSExchanged(server string, cached bool, duration time.Duration, err error) {
+	s.dnsQueries.Add(1)
+	if cached {
+		s.dnsCacheHits.Add(1)
+		return
+	}
+	if server == "" {
+		return
+	}
+	s.dnsAccess.Lock()
+	defer s.dnsAccess.Unlock()
+	counter, loaded := s.dnsServers[server]
+	if !loaded {
+		counter = &dnsServerCounter{duration: newHistogram(dnsDurationBuckets)}
+		s.dnsServers[server] = counter
+	}
+	if err != nil {
+		counter.failures++
+		return
+	}
+	counter.duration.observe(duration.Seconds())
+}
+
+func (s *Server) serveMetrics(writer http.ResponseWriter, request *http.Request) {
+	if s.secret != "" && request.Header.Get("Authorization") != "Bearer "+s.secret {
+		writer.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	var metricsWriter Writer
+	s.writeTraffic(&metricsWriter)
+	s.writeConnections(&metricsWriter)
+	s.writeDNS(&metricsWriter)
+	s.writeRuleSets(&metricsWriter)
+	s.writeURLTest(&metricsWriter)
+	writer.Header().Set("Content-Type", contentType)
+	_, _ = writer.Write(metricsWriter.Bytes())
+}
@@ -0,0 +1,142 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/outbound"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestServer() *Server {
+	return &Server{
+		inbounds:   make(map[string]*trafficCounter),
+		outbounds:  make(map[string]*trafficCounter),
+		users:      make(map[string]*trafficCounter),
+		dnsServers: make(map[string]*dnsServerCounter),
+	}
+}
+
+func TestWriterEscaping(t *testing.T) {
+	t.Parallel()
+	var writer Writer
+	writer.Header("sing_box_test", "gauge", "Help with \\ and\nnew line.")
+	writer.Sample("sing_box_test", []string{"name", "a\"b\\c\nd", "type", "plain"}, 1.5)
+	writer.Sample("sing_box_test", nil, 0)
+	require.Equal(t, `# HELP sing_box_test Help with \\ and\nnew line.
+# TYPE sing_box_test gauge
+sing_box_test{name="a\"b\\c\nd",type="plain"} 1.5
+sing_box_test 0
+`, string(writer.Bytes()))
+}
+
+func TestWriteTraffic(t *testing.T) {
+	t.Parallel()
+	server := newTestServer()
+	logger := log.NewNOPFactory().Logger()
+	chain := []adapter.Outbound{outbound.NewBlock(logger, "group"), outbound.NewBlock(logger, "member")}
+	readCounters, writeCounters := server.loadTrafficCounters(adapter.InboundContext{Inbound: "mixed-in", User: "alice"}, chain)
+	for _, counter := range readCounters {
+		counter.Add(100)
+	}
+	for _, counter := range writeCounters {
+		counter.Add(2048)
+	}
+	var writer Writer
+	server.writeTraffic(&writer)
+	require.Equal(t, `# HELP sing_box_inbound_traffic_bytes_total Traffic of connections accepted by the inbound.
+# TYPE sing_box_inbound_traffic_bytes_total counter
+sing_box_inbound_traffic_bytes_total{inbound="mixed-in",direction="uplink"} 100
+sing_box_inbound_traffic_bytes_total{inbound="mixed-in",direction="downlink"} 2048
+# HELP sing_box_outbound_traffic_bytes_total Traffic of connections routed to the outbound.
+# TYPE sing_box_outbound_traffic_bytes_total counter
+sing_box_outbound_traffic_bytes_total{outbound="group",direction="uplink"} 100
+sing_box_outbound_traffic_bytes_total{outbound="group",direction="downlink"} 2048
+sing_box_outbound_traffic_bytes_total{outbound="member",direction="uplink"} 100
+sing_box_outbound_traffic_bytes_total{outbound="member",direction="downlink"} 2048
+# HELP sing_box_user_traffic_bytes_total Traffic of connections of the user.
+# TYPE sing_box_user_traffic_bytes_total counter
+sing_box_user_traffic_bytes_total{user="alice",direction="uplink"} 100
+sing_box_user_traffic_bytes_total{user="alice",direction="downlink"} 2048
+`, string(writer.Bytes()))
+}
+
+func TestWriteDNS(t *testing.T) {
+	t.Parallel()
+	server := newTestServer()
+	server.DNSExchanged("", true, 0, nil)
+	server.DNSExchanged("local", false, 20*time.Millisecond, nil)
+	server.DNSExchanged("local", false, 3*time.Second, nil)
+	server.DNSExchanged("remote", false, time.Second, errors.New("timeout"))
+	var writer Writer
+	server.writeDNS(&writer)
+	require.Equal(t, `# HELP sing_box_dns_queries_total DNS queries handled by the router.
+# TYPE sing_box_dns_queries_total counter
+sing_box_dns_queries_total 4
+# HELP sing_box_dns_cache_hits_total DNS queries answered from the cache.
+# TYPE sing_box_dns_cache_hits_total counter
+sing_box_dns_cache_hits_total 1
+# HELP sing_box_dns_cache_hit_ratio Ratio of DNS queries answered from the cache.
+# TYPE sing_box_dns_cache_hit_ratio gauge
+sing_box_dns_cache_hit_ratio 0.25
+# HELP sing_box_dns_query_failures_total Failed DNS queries sent to the server.
+# TYPE sing_box_dns_query_failures_total counter
+sing_box_dns_query_failures_total{server="local"} 0
+sing_box_dns_query_failures_total{server="remote"} 1
+# HELP sing_box_dns_query_duration_seconds Latency of successful DNS queries sent to the server.
+# TYPE sing_box_dns_query_duration_seconds histogram
+sing_box_dns_query_duration_seconds_bucket{server="local",le="0.005"} 0
+sing_box_dns_query_duration_seconds_bucket{server="local",le="0.01"} 0
+sing_box_dns_query_duration_seconds_bucket{server="local",le="0.025"} 1
+sing_box_dns_query_duration_seconds_bucket{server="local",le="0.05"} 1
+sing_box_dns_query_duration_seconds_bucket{server="local",le="0.1"} 1
+sing_box_dns_query_duration_seconds_bucket{server="local",le="0.25"} 1
+sing_box_dns_query_duration_seconds_bucket{server="local",le="0.5"} 1
+sing_box_dns_query_duration_seconds_bucket{server="local",le="1"} 1
+sing_box_dns_query_duration_seconds_bucket{server="local",le="2.5"} 1
+sing_box_dns_query_duration_seconds_bucket{server="local",le="5"} 2
+sing_box_dns_query_duration_seconds_bucket{server="local",le="+Inf"} 2
+sing_box_dns_query_duration_seconds_sum{server="local"} 3.02
+sing_box_dns_query_duration_seconds_count{server="local"} 2
+sing_box_dns_query_duration_seconds_bucket{server="remote",le="0.005"} 0
+sing_box_dns_query_duration_seconds_bucket{server="remote",le="0.01"} 0
+sing_box_dns_query_duration_seconds_bucket{server="remote",le="0.025"} 0
+sing_box_dns_query_duration_seconds_bucket{server="remote",le="0.05"} 0
+sing_box_dns_query_duration_seconds_bucket{server="remote",le="0.1"} 0
+sing_box_dns_query_duration_seconds_bucket{server="remote",le="0.25"} 0
+sing_box_dns_query_duration_seconds_bucket{server="remote",le="0.5"} 0
+sing_box_dns_query_duration_seconds_bucket{server="remote",le="1"} 0
+sing_box_dns_query_duration_seconds_bucket{server="remote",le="2.5"} 0
+sing_box_dns_query_duration_seconds_bucket{server="remote",le="5"} 0
+sing_box_dns_query_duration_seconds_bucket{server="remote",le="+Inf"} 0
+sing_box_dns_query_duration_seconds_sum{server="remote"} 0
+sing_box_dns_query_duration_seconds_count{server="remote"} 0
+`, string(writer.Bytes()))
+}
+
+func TestRoutedConnectionCountsTraffic(t *testing.T) {
+	t.Parallel()
+	// connections are tracked by the Clash API if its traffic manager is shared
+	server := newTestServer()
+	logger := log.NewNOPFactory().Logger()
+	client, peer := net.Pipe()
+	defer peer.Close()
+	conn, tracker := server.RoutedConnection(context.Background(), client, adapter.InboundContext{Inbound: "in"}, nil, []adapter.Outbound{outbound.NewBlock(logger, "out")})
+	require.Equal(t, nopTracker{}, tracker)
+	go peer.Write([]byte("uploaded"))
+	buffer := make([]byte, 16)
+	n, err := conn.Read(buffer)
+	require.NoError(t, err)
+	require.Equal(t, 8, n)
+	go peer.Read(buffer)
+	_, err = conn.Write([]byte("got"))
+	require.NoError(t, err)
+	require.Equal(t, int64(8), server.inbounds["in"].uplink.Load())
+	require.Equal(t, int64(3), server.outbounds["out"].downlink.Load())
+}
@@ -0,0 +1,105 @@
+package metrics
+
+import (
+	"bytes"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Writer writes metrics in the Prometheus text exposition format.
+type Writer struct {
+	buffer bytes.Buffer
+}
+
+func (w *Writer) Header(name string, metricType string, help string) {
+	w.buffer.WriteString("# HELP ")
+	w.buffer.WriteString(name)
+	w.buffer.WriteByte(' ')
+	w.buffer.WriteString(helpEscaper.Replace(help))
+	w.buffer.WriteString("\n# TYPE ")
+	w.buffer.WriteString(name)
+	w.buffer.WriteByte(' ')
+	w.buffer.WriteString(metricType)
+	w.buffer.WriteByte('\n')
+}
+
+// Sample writes a sample with labels given as name and value pairs.
+func (w *Writer) Sample(name string, labels []string, value float64) {
+	w.buffer.WriteString(name)
+	if len(labels) > 0 {
+		w.buffer.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				w.buffer.WriteByte(',')
+			}
+			w.buffer.WriteString(labels[i])
+			w.buffer.WriteString(`="`)
+			w.buffer.WriteString(labelEscaper.Replace(labels[i+1]))
+			w.buffer.WriteByte('"')
+		}
+		w.buffer.WriteByte('}')
+	}
+	w.buffer.WriteByte(' ')
+	w.buffer.WriteString(formatValue(value))
+	w.buffer.WriteByte('\n')
+}
+
+func (w *Writer) Histogram(name string, labels []string, histogram *histogram) {
+	bucketLabels := append(append([]string(nil), labels...), "le", "")
+	for i, upperBound := range histogram.upperBounds {
+		bucketLabels[len(bucketLabels)-1] = formatValue(upperBound)
+		w.Sample(name+"_bucket", bucketLabels, float64(histogram.counts[i]))
+	}
+	bucketLabels[len(bucketLabels)-1] = "+Inf"
+	w.Sample(name+"_bucket", bucketLabels, float64(histogram.count))
+	w.Sample(name+"_sum", labels, histogram.sum)
+	w.Sample(name+"_count", labels, float64(histogram.count))
+}
+
+func (w *Writer) Bytes() []byte {
+	return w.buffer.Bytes()
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
+
+// histogram keeps cumulative bucket counts. It is not safe for concurrent use.
+type histogram struct {
+	upperBounds []float64
+	counts      []uint64
+	count       uint64
+	sum         float64
+}
+
+func newHistogram(upperBounds []float64) *histogram {
+	return &histogram{
+		upperBounds: upperBounds,
+		counts:      make([]uint64, len(upperBounds)),
+	}
+}
+
+func (h *histogram) observe(value float64) {
+	for i, upperBound := range h.upperBounds {
+		if value <= upperBound {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += value
+}
@@ -0,0 +1,125 @@
+package v2rayapi
+
+import (
+	"context"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var _ HandlerServiceServer = (*HandlerService)(nil)
+
+type HandlerService struct {
+	router adapter.Router
+}
+
+func NewHandlerService(router adapter.Router, options option.V2RayHandlerServiceOptions) *HandlerService {
+	if !options.Enabled {
+		return nil
+	}
+	return &HandlerService{router: router}
+}
+
+func (s *HandlerService) ListUsers(ctx context.Context, request *ListUsersRequest) (*ListUsersResponse, error) {
+	inbound, err := s.managedInbound(request.Tag)
+	if err != nil {
+		return nil, err
+	}
+	return &ListUsersResponse{Users: common.Map(common.Map(inbound.Users(), adapter.InboundUser.Redacted), toUser)}, nil
+}
+
+func (s *HandlerService) AddUsers(ctx context.Context, request *AddUsersRequest) (*AddUsersResponse, error) {
+	inbound, err := s.managedInbound(request.Tag)
+	if err != nil {
+		return nil, err
+	}
+	err = inbound.AddUsers(common.Map(request.Users, fromUser))
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &AddUsersResponse{}, nil
+}
+
+func (s *HandlerService) RemoveUsers(ctx context.Context, request *RemoveUsersRequest) (*RemoveUsersResponse, error) {
+	inbound, err := s.managedInbound(request.Tag)
+	if err != nil {
+		return nil, err
+	}
+	err = inbound.RemoveUsers(request.Names)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &RemoveUsersResponse{}, nil
+}
+
+func (s *HandlerService) ReplaceUsers(ctx context.Context, request *ReplaceUsersRequest) (*ReplaceUsersResponse, error) {
+	inbound, err := s.managedInbound(request.Tag)
+	if err != nil {
+		return nil, err
+	}
+	err = inbound.ReplaceUsers(common.Map(request.Users, fromUser))
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &ReplaceUsersResponse{}, nil
+}
+
+func (s *HandlerService) managedInbound(tag string) (adapter.ManagedUserInbound, error) {
+	inbound, loaded := s.router.Inbound(tag)
+	if !loaded {
+		return nil, status.Error(codes.NotFound, "inbound not found: "+tag)
+	}
+	managedInbound, isManaged := inbound.(adapter.ManagedUserInbound)
+	if !isManaged {
+		return nil, status.Error(codes.FailedPrecondition, "inbound does not support user management: "+tag)
+	}
+	return managedInbound, nil
+}
+
+func (s *HandlerService) mustEmbedUnimplementedHandlerServiceServer() {
+}
+
+func toUser(user adapter.InboundUser) *User {
+	bandwidthLimit := common.PtrValueOrDefault(user.BandwidthLimit)
+	return &User{
+		Name:           user.Name,
+		Uuid:           user.UUID,
+		Password:       user.Password,
+		AlterId:        int32(user.AlterId),
+		Flow:           user.Flow,
+		TrafficQuota:   uint64(user.TrafficQuota),
+		ExpireAt:       user.ExpireAt,
+		MaxConnections: int32(user.MaxConnections),
+		MaxIps:         int32(user.MaxIPs),
+		UpMbps:         int32(bandwidthLimit.UpMbps),
+		DownMbps:       int32(bandwidthLimit.DownMbps),
+	}
+}
+
+func fromUser(user *User) adapter.InboundUser {
+	var bandwidthLimit *option.BandwidthLimitOptions
+	if user.UpMbps != 0 || user.DownMbps != 0 {
+		bandwidthLimit = &option.BandwidthLimitOptions{
+			UpMbps:   int(user.UpMbps),
+			DownMbps: int(user.DownMbps),
+		}
+	}
+	return adapter.InboundUser{
+		Name:     user.Name,
+		UUID:     user.Uuid,
+		Password: user.Password,
+		AlterId:  int(user.AlterId),
+		Flow:     user.Flow,
+		UserLimitOptions: option.UserLimitOptions{
+			TrafficQuota:   option.MemoryBytes(user.TrafficQuota),
+			ExpireAt:       user.ExpireAt,
+			MaxConnections: int(user.MaxConnections),
+			MaxIPs:         int(user.MaxIps),
+			BandwidthLimit: bandwidthLimit,
+		},
+	}
+}
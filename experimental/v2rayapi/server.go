@@ -29,12 +29,16 @@ type Server struct {
 	statsService *StatsService
 }
 
-func NewServer(logger log.Logger, options option.V2RayAPIOptions) (adapter.V2RayServer, error) {
+func NewServer(router adapter.Router, logger log.Logger, options option.V2RayAPIOptions) (adapter.V2RayServer, error) {
 	grpcServer := grpc.NewServer(grpc.Creds(insecure.NewCredentials()))
 	statsService := NewStatsService(common.PtrValueOrDefault(options.Stats))
 	if statsService != nil {
 		RegisterStatsServiceServer(grpcServer, statsService)
 	}
+	handlerService := NewHandlerService(router, common.PtrValueOrDefault(options.Handler))
+	if handlerService != nil {
+		RegisterHandlerServiceServer(grpcServer, handlerService)
+	}
 	server := &Server{
 		logger:       logger,
 		listen:       options.Listen,
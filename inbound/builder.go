@@ -48,6 +48,12 @@ func New(ctx context.Context, router adapter.Router, logger log.ContextLogger, t
 		return NewTUIC(ctx, router, logger, tag, options.TUICOptions)
 	case C.TypeHysteria2:
 		return NewHysteria2(ctx, router, logger, tag, options.Hysteria2Options)
+	case C.TypeWireGuard:
+		return NewWireGuard(ctx, router, logger, tag, options.WireGuardOptions)
+	case C.TypeSSH:
+		return NewSSH(ctx, router, logger, tag, options.SSHOptions)
+	case C.TypeDNS:
+		return NewDNS(ctx, router, logger, tag, options.DNSOptions)
 	default:
 		return nil, E.New("unknown inbound type: ", options.Type)
 	}
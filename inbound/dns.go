@@ -0,0 +1,292 @@
+package inbound
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/tls"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-dns"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/buf"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	mDNS "github.com/miekg/dns"
+)
+
+const dnsMessageContentType = "application/dns-message"
+
+var _ adapter.Inbound = (*DNS)(nil)
+
+type DNS struct {
+	myInboundAdapter
+	dnsRouter      adapter.Router
+	serverProtocol string
+	path           string
+	tlsConfig      tls.ServerConfig
+	httpServer     *http.Server
+}
+
+func NewDNS(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.DNSInboundOptions) (*DNS, error) {
+	inbound := &DNS{
+		myInboundAdapter: myInboundAdapter{
+			protocol:      C.TypeDNS,
+			network:       []string{N.NetworkTCP},
+			ctx:           ctx,
+			router:        router,
+			logger:        logger,
+			tag:           tag,
+			listenOptions: options.ListenOptions,
+		},
+		dnsRouter:      router,
+		serverProtocol: options.Protocol,
+		path:           options.Path,
+	}
+	tlsEnabled := options.TLS != nil && options.TLS.Enabled
+	switch options.Protocol {
+	case "", C.DNSInboundProtocolDNS:
+		inbound.serverProtocol = C.DNSInboundProtocolDNS
+		inbound.network = options.Network.Build()
+		if tlsEnabled {
+			return nil, E.New("TLS is only available for tls and https protocol")
+		}
+	case C.DNSInboundProtocolTLS, C.DNSInboundProtocolHTTPS:
+		if !tlsEnabled {
+			return nil, E.New("TLS is required for ", options.Protocol, " protocol")
+		}
+	case C.DNSInboundProtocolHTTP:
+		if tlsEnabled {
+			return nil, E.New("TLS is not available for http protocol, use https instead")
+		}
+	default:
+		return nil, E.New("unknown dns protocol: ", options.Protocol)
+	}
+	switch inbound.serverProtocol {
+	case C.DNSInboundProtocolHTTPS, C.DNSInboundProtocolHTTP:
+		if inbound.path == "" {
+			inbound.path = "/dns-query"
+		}
+	default:
+		if inbound.path != "" {
+			return nil, E.New("path is only available for https and http protocol")
+		}
+	}
+	if tlsEnabled {
+		tlsConfig, err := tls.NewServer(ctx, logger, common.PtrValueOrDefault(options.TLS))
+		if err != nil {
+			return nil, err
+		}
+		inbound.tlsConfig = tlsConfig
+	}
+	inbound.connHandler = inbound
+	inbound.packetHandler = inbound
+	return inbound, nil
+}
+
+func (d *DNS) Start() error {
+	var tlsConfig *tls.STDConfig
+	if d.tlsConfig != nil {
+		err := d.tlsConfig.Start()
+		if err != nil {
+			return E.Cause(err, "create TLS config")
+		}
+		tlsConfig, err = d.tlsConfig.Config()
+		if err != nil {
+			return err
+		}
+	}
+	switch d.serverProtocol {
+	case C.DNSInboundProtocolHTTPS, C.DNSInboundProtocolHTTP:
+		tcpListener, err := d.ListenTCP()
+		if err != nil {
+			return err
+		}
+		d.httpServer = &http.Server{
+			Handler:   d,
+			TLSConfig: tlsConfig,
+			BaseContext: func(listener net.Listener) context.Context {
+				return d.ctx
+			},
+		}
+		go func() {
+			var sErr error
+			if tlsConfig != nil {
+				sErr = d.httpServer.ServeTLS(tcpListener, "", "")
+			} else {
+				sErr = d.httpServer.Serve(tcpListener)
+			}
+			if sErr != nil && !E.IsClosedOrCanceled(sErr) && !errors.Is(sErr, http.ErrServerClosed) {
+				d.logger.Error("http server serve error: ", sErr)
+			}
+		}()
+		return nil
+	default:
+		return d.myInboundAdapter.Start()
+	}
+}
+
+func (d *DNS) Close() error {
+	return common.Close(
+		&d.myInboundAdapter,
+		common.PtrOrNil(d.httpServer),
+		d.tlsConfig,
+	)
+}
+
+func (d *DNS) NewConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
+	if d.tlsConfig != nil {
+		var err error
+		conn, err = tls.ServerHandshake(ctx, conn, d.tlsConfig)
+		if err != nil {
+			return err
+		}
+	}
+	defer conn.Close()
+	var writeAccess sync.Mutex
+	for {
+		err := conn.SetReadDeadline(time.Now().Add(C.DNSTimeout))
+		if err != nil {
+			return err
+		}
+		var queryLength uint16
+		err = binary.Read(conn, binary.BigEndian, &queryLength)
+		if err != nil {
+			if E.IsTimeout(err) || errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if queryLength == 0 {
+			return dns.RCodeFormatError
+		}
+		buffer := buf.NewSize(int(queryLength))
+		_, err = buffer.ReadFullFrom(conn, int(queryLength))
+		if err != nil {
+			buffer.Release()
+			return err
+		}
+		var message mDNS.Msg
+		err = message.Unpack(buffer.Bytes())
+		buffer.Release()
+		if err != nil {
+			return err
+		}
+		go func() {
+			response := d.exchange(ctx, &message, metadata)
+			responseBuffer := buf.NewPacket()
+			defer responseBuffer.Release()
+			responseBuffer.Resize(2, 0)
+			rawResponse, err := response.PackBuffer(responseBuffer.FreeBytes())
+			if err != nil {
+				d.logger.ErrorContext(ctx, E.Cause(err, "pack dns response"))
+				return
+			}
+			responseBuffer.Truncate(len(rawResponse))
+			binary.BigEndian.PutUint16(responseBuffer.ExtendHeader(2), uint16(len(rawResponse)))
+			writeAccess.Lock()
+			_, err = conn.Write(responseBuffer.Bytes())
+			writeAccess.Unlock()
+			if err != nil {
+				d.NewError(ctx, E.Cause(err, "write dns response"))
+			}
+		}()
+	}
+}
+
+func (d *DNS) NewPacket(ctx context.Context, conn N.PacketConn, buffer *buf.Buffer, metadata adapter.InboundContext) error {
+	var message mDNS.Msg
+	err := message.Unpack(buffer.Bytes())
+	if err != nil {
+		return err
+	}
+	ctx = log.ContextWithNewID(ctx)
+	go func() {
+		response := d.exchange(ctx, &message, metadata)
+		responseBuffer, err := dns.TruncateDNSMessage(&message, response, 1024)
+		if err != nil {
+			d.logger.ErrorContext(ctx, E.Cause(err, "pack dns response"))
+			return
+		}
+		err = conn.WritePacket(responseBuffer, metadata.Source)
+		if err != nil {
+			d.NewError(ctx, E.Cause(err, "write dns response"))
+		}
+	}()
+	return nil
+}
+
+func (d *DNS) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	ctx := log.ContextWithNewID(request.Context())
+	if request.URL.Path != d.path {
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+	var (
+		rawMessage []byte
+		err        error
+	)
+	switch request.Method {
+	case http.MethodGet:
+		rawMessage, err = base64.RawURLEncoding.DecodeString(request.URL.Query().Get("dns"))
+	case http.MethodPost:
+		if request.Header.Get("Content-Type") != dnsMessageContentType {
+			writer.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		rawMessage, err = io.ReadAll(io.LimitReader(request.Body, mDNS.MaxMsgSize))
+	default:
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var message mDNS.Msg
+	if err == nil {
+		err = message.Unpack(rawMessage)
+	}
+	if err != nil {
+		d.logger.DebugContext(ctx, E.Cause(err, "process dns request from ", request.RemoteAddr))
+		writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	var metadata adapter.InboundContext
+	metadata.Inbound = d.tag
+	metadata.InboundType = d.protocol
+	metadata.InboundDetour = d.listenOptions.Detour
+	metadata.InboundOptions = d.listenOptions.InboundOptions
+	metadata.Source = M.ParseSocksaddr(request.RemoteAddr).Unwrap()
+	response := d.exchange(ctx, &message, metadata)
+	rawResponse, err := response.Pack()
+	if err != nil {
+		d.logger.ErrorContext(ctx, E.Cause(err, "pack dns response"))
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	writer.Header().Set("Content-Type", dnsMessageContentType)
+	_, _ = writer.Write(rawResponse)
+}
+
+func (d *DNS) exchange(ctx context.Context, message *mDNS.Msg, metadata adapter.InboundContext) *mDNS.Msg {
+	metadata.Destination = M.Socksaddr{}
+	d.logger.DebugContext(ctx, "inbound dns query from ", metadata.Source)
+	response, err := d.dnsRouter.Exchange(adapter.WithContext(ctx, &metadata), message)
+	if err == nil {
+		return response
+	}
+	var rcode dns.RCodeError
+	if errors.As(err, &rcode) {
+		return new(mDNS.Msg).SetRcode(message, int(rcode))
+	}
+	d.NewError(ctx, E.Cause(err, "exchange dns query from ", metadata.Source))
+	return new(mDNS.Msg).SetRcode(message, mDNS.RcodeServerFailure)
+}
@@ -23,13 +23,16 @@ import (
 	N "github.com/sagernet/sing/common/network"
 )
 
-var _ adapter.Inbound = (*Hysteria2)(nil)
+var (
+	_ adapter.Inbound            = (*Hysteria2)(nil)
+	_ adapter.ManagedUserInbound = (*Hysteria2)(nil)
+)
 
 type Hysteria2 struct {
 	myInboundAdapter
-	tlsConfig    tls.ServerConfig
-	service      *hysteria2.Service[int]
-	userNameList []string
+	*userManager
+	tlsConfig tls.ServerConfig
+	service   *hysteria2.Service[int]
 }
 
 func NewHysteria2(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.Hysteria2InboundOptions) (*Hysteria2, error) {
@@ -110,17 +113,18 @@ func NewHysteria2(ctx context.Context, router adapter.Router, logger log.Context
 	if err != nil {
 		return nil, err
 	}
-	userList := make([]int, 0, len(options.Users))
-	userNameList := make([]string, 0, len(options.Users))
-	userPasswordList := make([]string, 0, len(options.Users))
-	for index, user := range options.Users {
-		userList = append(userList, index)
-		userNameList = append(userNameList, user.Name)
-		userPasswordList = append(userPasswordList, user.Password)
+	inbound.userManager, err = newUserManager(ctx, logger, tag, common.Map(options.Users, func(it option.Hysteria2User) adapter.InboundUser {
+		return adapter.InboundUser{Name: it.Name, Password: it.Password, UserLimitOptions: it.UserLimitOptions}
+	}), func(indexes []int, users []adapter.InboundUser) error {
+		service.UpdateUsers(indexes, common.Map(users, func(it adapter.InboundUser) string {
+			return it.Password
+		}))
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	service.UpdateUsers(userList, userPasswordList)
 	inbound.service = service
-	inbound.userNameList = userNameList
 	return inbound, nil
 }
 
@@ -129,13 +133,17 @@ func (h *Hysteria2) newConnection(ctx context.Context, conn net.Conn, metadata a
 	metadata = h.createMetadata(conn, metadata)
 	h.logger.InfoContext(ctx, "inbound connection from ", metadata.Source)
 	userID, _ := auth.UserFromContext[int](ctx)
-	if userName := h.userNameList[userID]; userName != "" {
+	user, loaded := h.User(userID)
+	if !loaded {
+		return E.New("user ", userID, " removed")
+	}
+	if userName := user.Name; userName != "" {
 		metadata.User = userName
 		h.logger.InfoContext(ctx, "[", userName, "] inbound connection to ", metadata.Destination)
 	} else {
 		h.logger.InfoContext(ctx, "inbound connection to ", metadata.Destination)
 	}
-	return h.router.RouteConnection(ctx, conn, metadata)
+	return h.routeConnection(ctx, h.router, user, conn, metadata)
 }
 
 func (h *Hysteria2) newPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext) error {
@@ -143,16 +151,24 @@ func (h *Hysteria2) newPacketConnection(ctx context.Context, conn N.PacketConn,
 	metadata = h.createPacketMetadata(conn, metadata)
 	h.logger.InfoContext(ctx, "inbound packet connection from ", metadata.Source)
 	userID, _ := auth.UserFromContext[int](ctx)
-	if userName := h.userNameList[userID]; userName != "" {
+	user, loaded := h.User(userID)
+	if !loaded {
+		return E.New("user ", userID, " removed")
+	}
+	if userName := user.Name; userName != "" {
 		metadata.User = userName
 		h.logger.InfoContext(ctx, "[", userName, "] inbound packet connection to ", metadata.Destination)
 	} else {
 		h.logger.InfoContext(ctx, "inbound packet connection to ", metadata.Destination)
 	}
-	return h.router.RoutePacketConnection(ctx, conn, metadata)
+	return h.routePacketConnection(ctx, h.router, user, conn, metadata)
 }
 
 func (h *Hysteria2) Start() error {
+	err := h.userManager.Start()
+	if err != nil {
+		return err
+	}
 	if h.tlsConfig != nil {
 		err := h.tlsConfig.Start()
 		if err != nil {
@@ -169,6 +185,7 @@ func (h *Hysteria2) Start() error {
 func (h *Hysteria2) Close() error {
 	return common.Close(
 		&h.myInboundAdapter,
+		h.userManager,
 		h.tlsConfig,
 		common.PtrOrNil(h.service),
 	)
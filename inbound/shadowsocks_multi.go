@@ -25,14 +25,15 @@ import (
 )
 
 var (
-	_ adapter.Inbound           = (*ShadowsocksMulti)(nil)
-	_ adapter.InjectableInbound = (*ShadowsocksMulti)(nil)
+	_ adapter.Inbound            = (*ShadowsocksMulti)(nil)
+	_ adapter.InjectableInbound  = (*ShadowsocksMulti)(nil)
+	_ adapter.ManagedUserInbound = (*ShadowsocksMulti)(nil)
 )
 
 type ShadowsocksMulti struct {
 	myInboundAdapter
+	*userManager
 	service shadowsocks.MultiService[int]
-	users   []option.ShadowsocksUser
 }
 
 func newShadowsocksMulti(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.ShadowsocksInboundOptions) (*ShadowsocksMulti, error) {
@@ -80,20 +81,36 @@ func newShadowsocksMulti(ctx context.Context, router adapter.Router, logger log.
 	if err != nil {
 		return nil, err
 	}
-	err = service.UpdateUsersWithPasswords(common.MapIndexed(options.Users, func(index int, user option.ShadowsocksUser) int {
-		return index
-	}), common.Map(options.Users, func(user option.ShadowsocksUser) string {
-		return user.Password
-	}))
+	inbound.userManager, err = newUserManager(ctx, logger, tag, common.Map(options.Users, func(user option.ShadowsocksUser) adapter.InboundUser {
+		return adapter.InboundUser{Name: user.Name, Password: user.Password, UserLimitOptions: user.UserLimitOptions}
+	}), func(indexes []int, users []adapter.InboundUser) error {
+		return service.UpdateUsersWithPasswords(indexes, common.Map(users, func(user adapter.InboundUser) string {
+			return user.Password
+		}))
+	})
 	if err != nil {
 		return nil, err
 	}
 	inbound.service = service
 	inbound.packetUpstream = service
-	inbound.users = options.Users
 	return inbound, err
 }
 
+func (h *ShadowsocksMulti) Start() error {
+	err := h.userManager.Start()
+	if err != nil {
+		return err
+	}
+	return h.myInboundAdapter.Start()
+}
+
+func (h *ShadowsocksMulti) Close() error {
+	return common.Close(
+		&h.myInboundAdapter,
+		h.userManager,
+	)
+}
+
 func (h *ShadowsocksMulti) NewConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
 	return h.service.NewConnection(adapter.WithContext(log.ContextWithNewID(ctx), &metadata), conn, adapter.UpstreamMetadata(metadata))
 }
@@ -111,14 +128,18 @@ func (h *ShadowsocksMulti) newConnection(ctx context.Context, conn net.Conn, met
 	if !loaded {
 		return os.ErrInvalid
 	}
-	user := h.users[userIndex].Name
+	userOptions, loaded := h.User(userIndex)
+	if !loaded {
+		return E.New("user ", userIndex, " removed")
+	}
+	user := userOptions.Name
 	if user == "" {
 		user = F.ToString(userIndex)
 	} else {
 		metadata.User = user
 	}
 	h.logger.InfoContext(ctx, "[", user, "] inbound connection to ", metadata.Destination)
-	return h.router.RouteConnection(ctx, conn, metadata)
+	return h.routeConnection(ctx, h.router, userOptions, conn, metadata)
 }
 
 func (h *ShadowsocksMulti) newPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext) error {
@@ -126,7 +147,11 @@ func (h *ShadowsocksMulti) newPacketConnection(ctx context.Context, conn N.Packe
 	if !loaded {
 		return os.ErrInvalid
 	}
-	user := h.users[userIndex].Name
+	userOptions, loaded := h.User(userIndex)
+	if !loaded {
+		return E.New("user ", userIndex, " removed")
+	}
+	user := userOptions.Name
 	if user == "" {
 		user = F.ToString(userIndex)
 	} else {
@@ -135,5 +160,5 @@ func (h *ShadowsocksMulti) newPacketConnection(ctx context.Context, conn N.Packe
 	ctx = log.ContextWithNewID(ctx)
 	h.logger.InfoContext(ctx, "[", user, "] inbound packet connection from ", metadata.Source)
 	h.logger.InfoContext(ctx, "[", user, "] inbound packet connection to ", metadata.Destination)
-	return h.router.RoutePacketConnection(ctx, conn, metadata)
+	return h.routePacketConnection(ctx, h.router, userOptions, conn, metadata)
 }
@@ -0,0 +1,227 @@
+package inbound
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/auth"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	"golang.org/x/crypto/ssh"
+)
+
+var (
+	_ adapter.Inbound           = (*SSH)(nil)
+	_ adapter.InjectableInbound = (*SSH)(nil)
+)
+
+type SSH struct {
+	myInboundAdapter
+	config *ssh.ServerConfig
+	users  map[string]option.SSHUser
+	keys   map[string][]ssh.PublicKey
+}
+
+func NewSSH(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.SSHInboundOptions) (*SSH, error) {
+	inbound := &SSH{
+		myInboundAdapter: myInboundAdapter{
+			protocol:      C.TypeSSH,
+			network:       []string{N.NetworkTCP},
+			ctx:           ctx,
+			router:        router,
+			logger:        logger,
+			tag:           tag,
+			listenOptions: options.ListenOptions,
+		},
+		users: make(map[string]option.SSHUser),
+		keys:  make(map[string][]ssh.PublicKey),
+	}
+	if len(options.Users) == 0 {
+		return nil, E.New("missing users")
+	}
+	for index, user := range options.Users {
+		if user.Name == "" {
+			return nil, E.New("missing name for user ", index)
+		}
+		if user.Password == "" && len(user.AuthorizedKey) == 0 {
+			return nil, E.New("missing password or authorized key for user ", user.Name)
+		}
+		for _, authorizedKey := range user.AuthorizedKey {
+			key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(authorizedKey))
+			if err != nil {
+				return nil, E.Cause(err, "parse authorized key for user ", user.Name)
+			}
+			inbound.keys[user.Name] = append(inbound.keys[user.Name], key)
+		}
+		inbound.users[user.Name] = user
+	}
+	config := &ssh.ServerConfig{
+		ServerVersion: options.ServerVersion,
+	}
+	if common.Any(options.Users, func(it option.SSHUser) bool {
+		return it.Password != ""
+	}) {
+		config.PasswordCallback = inbound.passwordCallback
+	}
+	if len(inbound.keys) > 0 {
+		config.PublicKeyCallback = inbound.publicKeyCallback
+	}
+	var hostKey []byte
+	if len(options.HostKey) > 0 {
+		hostKey = []byte(strings.Join(options.HostKey, "\n"))
+	} else if options.HostKeyPath != "" {
+		var err error
+		hostKey, err = os.ReadFile(os.ExpandEnv(options.HostKeyPath))
+		if err != nil {
+			return nil, E.Cause(err, "read host key")
+		}
+	} else {
+		return nil, E.New("missing host key")
+	}
+	signer, err := ssh.ParsePrivateKey(hostKey)
+	if err != nil {
+		return nil, E.Cause(err, "parse host key")
+	}
+	config.AddHostKey(signer)
+	inbound.config = config
+	inbound.connHandler = inbound
+	return inbound, nil
+}
+
+func (h *SSH) passwordCallback(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+	user, loaded := h.users[conn.User()]
+	if loaded && user.Password != "" && subtle.ConstantTimeCompare([]byte(user.Password), password) == 1 {
+		return nil, nil
+	}
+	return nil, E.New("password rejected for ", conn.User())
+}
+
+func (h *SSH) publicKeyCallback(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+	clientKey := key.Marshal()
+	for _, authorizedKey := range h.keys[conn.User()] {
+		if bytes.Equal(clientKey, authorizedKey.Marshal()) {
+			return nil, nil
+		}
+	}
+	return nil, E.New("unknown public key for ", conn.User())
+}
+
+func (h *SSH) NewConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
+	serverConn, channels, requests, err := ssh.NewServerConn(conn, h.config)
+	if err != nil {
+		return E.Cause(err, "process ssh handshake")
+	}
+	defer serverConn.Close()
+	go ssh.DiscardRequests(requests)
+	user := serverConn.User()
+	h.logger.InfoContext(ctx, "[", user, "] ssh session established")
+	ctx = auth.ContextWithUser(ctx, user)
+	for newChannel := range channels {
+		switch newChannel.ChannelType() {
+		case "session":
+			channel, channelRequests, err := newChannel.Accept()
+			if err != nil {
+				continue
+			}
+			go h.serveSession(channel, channelRequests)
+			continue
+		case "direct-tcpip":
+		default:
+			newChannel.Reject(ssh.Prohibited, "only port forwarding is supported")
+			continue
+		}
+		var request struct {
+			DestinationAddress string
+			DestinationPort    uint32
+			OriginAddress      string
+			OriginPort         uint32
+		}
+		err = ssh.Unmarshal(newChannel.ExtraData(), &request)
+		if err != nil {
+			newChannel.Reject(ssh.ConnectionFailed, "invalid direct-tcpip request")
+			continue
+		}
+		channel, channelRequests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+		go ssh.DiscardRequests(channelRequests)
+		channelMetadata := metadata
+		channelMetadata.Destination = M.ParseSocksaddrHostPort(request.DestinationAddress, uint16(request.DestinationPort))
+		go func() {
+			channelCtx := log.ContextWithNewID(ctx)
+			hErr := h.newUserConnection(channelCtx, &sshChannelConn{channel, conn.LocalAddr(), conn.RemoteAddr()}, channelMetadata)
+			if hErr != nil {
+				h.NewError(channelCtx, hErr)
+			}
+		}()
+	}
+	return nil
+}
+
+// serveSession keeps the session channel opened by clients such as ssh -L and -D
+// until they close it. Terminal and shell requests succeed without running anything.
+func (h *SSH) serveSession(channel ssh.Channel, requests <-chan *ssh.Request) {
+	defer channel.Close()
+	go io.Copy(io.Discard, channel)
+	for request := range requests {
+		switch request.Type {
+		case "pty-req", "shell", "env":
+			request.Reply(true, nil)
+		default:
+			request.Reply(false, nil)
+		}
+	}
+}
+
+func (h *SSH) NewPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext) error {
+	return os.ErrInvalid
+}
+
+var _ net.Conn = (*sshChannelConn)(nil)
+
+type sshChannelConn struct {
+	ssh.Channel
+	localAddr  net.Addr
+	remoteAddr net.Addr
+}
+
+func (c *sshChannelConn) LocalAddr() net.Addr {
+	return c.localAddr
+}
+
+func (c *sshChannelConn) RemoteAddr() net.Addr {
+	return c.remoteAddr
+}
+
+// SSH channels have no deadlines, read deadlines are handled by the wrapper
+// requested through NeedAdditionalReadDeadline.
+
+func (c *sshChannelConn) SetDeadline(t time.Time) error {
+	return nil
+}
+
+func (c *sshChannelConn) SetReadDeadline(t time.Time) error {
+	return nil
+}
+
+func (c *sshChannelConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
+
+func (c *sshChannelConn) NeedAdditionalReadDeadline() bool {
+	return true
+}
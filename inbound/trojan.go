@@ -22,14 +22,15 @@ import (
 )
 
 var (
-	_ adapter.Inbound           = (*Trojan)(nil)
-	_ adapter.InjectableInbound = (*Trojan)(nil)
+	_ adapter.Inbound            = (*Trojan)(nil)
+	_ adapter.InjectableInbound  = (*Trojan)(nil)
+	_ adapter.ManagedUserInbound = (*Trojan)(nil)
 )
 
 type Trojan struct {
 	myInboundAdapter
+	*userManager
 	service                  *trojan.Service[int]
-	users                    []option.TrojanUser
 	tlsConfig                tls.ServerConfig
 	fallbackAddr             M.Socksaddr
 	fallbackAddrTLSNextProto map[string]M.Socksaddr
@@ -47,7 +48,6 @@ func NewTrojan(ctx context.Context, router adapter.Router, logger log.ContextLog
 			tag:           tag,
 			listenOptions: options.ListenOptions,
 		},
-		users: options.Users,
 	}
 	if options.TLS != nil {
 		tlsConfig, err := tls.NewServer(ctx, logger, common.PtrValueOrDefault(options.TLS))
@@ -81,11 +81,14 @@ func NewTrojan(ctx context.Context, router adapter.Router, logger log.ContextLog
 		fallbackHandler = adapter.NewUpstreamContextHandler(inbound.fallbackConnection, nil, nil)
 	}
 	service := trojan.NewService[int](adapter.NewUpstreamContextHandler(inbound.newConnection, inbound.newPacketConnection, inbound), fallbackHandler)
-	err := service.UpdateUsers(common.MapIndexed(options.Users, func(index int, it option.TrojanUser) int {
-		return index
-	}), common.Map(options.Users, func(it option.TrojanUser) string {
-		return it.Password
-	}))
+	var err error
+	inbound.userManager, err = newUserManager(ctx, logger, tag, common.Map(options.Users, func(it option.TrojanUser) adapter.InboundUser {
+		return adapter.InboundUser{Name: it.Name, Password: it.Password, UserLimitOptions: it.UserLimitOptions}
+	}), func(indexes []int, users []adapter.InboundUser) error {
+		return service.UpdateUsers(indexes, common.Map(users, func(it adapter.InboundUser) string {
+			return it.Password
+		}))
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -105,6 +108,10 @@ func NewTrojan(ctx context.Context, router adapter.Router, logger log.ContextLog
 }
 
 func (h *Trojan) Start() error {
+	err := h.userManager.Start()
+	if err != nil {
+		return err
+	}
 	if h.tlsConfig != nil {
 		err := h.tlsConfig.Start()
 		if err != nil {
@@ -144,6 +151,7 @@ func (h *Trojan) Start() error {
 func (h *Trojan) Close() error {
 	return common.Close(
 		&h.myInboundAdapter,
+		h.userManager,
 		h.tlsConfig,
 		h.transport,
 	)
@@ -174,14 +182,18 @@ func (h *Trojan) newConnection(ctx context.Context, conn net.Conn, metadata adap
 	if !loaded {
 		return os.ErrInvalid
 	}
-	user := h.users[userIndex].Name
+	userOptions, loaded := h.User(userIndex)
+	if !loaded {
+		return E.New("user ", userIndex, " removed")
+	}
+	user := userOptions.Name
 	if user == "" {
 		user = F.ToString(userIndex)
 	} else {
 		metadata.User = user
 	}
 	h.logger.InfoContext(ctx, "[", user, "] inbound connection to ", metadata.Destination)
-	return h.router.RouteConnection(ctx, conn, metadata)
+	return h.routeConnection(ctx, h.router, userOptions, conn, metadata)
 }
 
 func (h *Trojan) fallbackConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
@@ -212,14 +224,18 @@ func (h *Trojan) newPacketConnection(ctx context.Context, conn N.PacketConn, met
 	if !loaded {
 		return os.ErrInvalid
 	}
-	user := h.users[userIndex].Name
+	userOptions, loaded := h.User(userIndex)
+	if !loaded {
+		return E.New("user ", userIndex, " removed")
+	}
+	user := userOptions.Name
 	if user == "" {
 		user = F.ToString(userIndex)
 	} else {
 		metadata.User = user
 	}
 	h.logger.InfoContext(ctx, "[", user, "] inbound packet connection to ", metadata.Destination)
-	return h.router.RoutePacketConnection(ctx, conn, metadata)
+	return h.routePacketConnection(ctx, h.router, userOptions, conn, metadata)
 }
 
 var _ adapter.V2RayServerTransportHandler = (*trojanTransportHandler)(nil)
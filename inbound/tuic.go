@@ -22,13 +22,16 @@ import (
 	"github.com/gofrs/uuid/v5"
 )
 
-var _ adapter.Inbound = (*TUIC)(nil)
+var (
+	_ adapter.Inbound            = (*TUIC)(nil)
+	_ adapter.ManagedUserInbound = (*TUIC)(nil)
+)
 
 type TUIC struct {
 	myInboundAdapter
-	tlsConfig    tls.ServerConfig
-	server       *tuic.Service[int]
-	userNameList []string
+	*userManager
+	tlsConfig tls.ServerConfig
+	server    *tuic.Service[int]
 }
 
 func NewTUIC(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.TUICInboundOptions) (*TUIC, error) {
@@ -72,26 +75,29 @@ func NewTUIC(ctx context.Context, router adapter.Router, logger log.ContextLogge
 	if err != nil {
 		return nil, err
 	}
-	var userList []int
-	var userNameList []string
-	var userUUIDList [][16]byte
-	var userPasswordList []string
-	for index, user := range options.Users {
-		if user.UUID == "" {
-			return nil, E.New("missing uuid for user ", index)
-		}
-		userUUID, err := uuid.FromString(user.UUID)
-		if err != nil {
-			return nil, E.Cause(err, "invalid uuid for user ", index)
+	inbound.userManager, err = newUserManager(ctx, logger, tag, common.Map(options.Users, func(it option.TUICUser) adapter.InboundUser {
+		return adapter.InboundUser{Name: it.Name, UUID: it.UUID, Password: it.Password, UserLimitOptions: it.UserLimitOptions}
+	}), func(indexes []int, users []adapter.InboundUser) error {
+		userUUIDList := make([][16]byte, 0, len(users))
+		for i, user := range users {
+			if user.UUID == "" {
+				return E.New("missing uuid for user ", indexes[i])
+			}
+			userUUID, err := uuid.FromString(user.UUID)
+			if err != nil {
+				return E.Cause(err, "invalid uuid for user ", indexes[i])
+			}
+			userUUIDList = append(userUUIDList, userUUID)
 		}
-		userList = append(userList, index)
-		userNameList = append(userNameList, user.Name)
-		userUUIDList = append(userUUIDList, userUUID)
-		userPasswordList = append(userPasswordList, user.Password)
+		service.UpdateUsers(indexes, userUUIDList, common.Map(users, func(it adapter.InboundUser) string {
+			return it.Password
+		}))
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	service.UpdateUsers(userList, userUUIDList, userPasswordList)
 	inbound.server = service
-	inbound.userNameList = userNameList
 	return inbound, nil
 }
 
@@ -100,13 +106,17 @@ func (h *TUIC) newConnection(ctx context.Context, conn net.Conn, metadata adapte
 	metadata = h.createMetadata(conn, metadata)
 	h.logger.InfoContext(ctx, "inbound connection from ", metadata.Source)
 	userID, _ := auth.UserFromContext[int](ctx)
-	if userName := h.userNameList[userID]; userName != "" {
+	user, loaded := h.User(userID)
+	if !loaded {
+		return E.New("user ", userID, " removed")
+	}
+	if userName := user.Name; userName != "" {
 		metadata.User = userName
 		h.logger.InfoContext(ctx, "[", userName, "] inbound connection to ", metadata.Destination)
 	} else {
 		h.logger.InfoContext(ctx, "inbound connection to ", metadata.Destination)
 	}
-	return h.router.RouteConnection(ctx, conn, metadata)
+	return h.routeConnection(ctx, h.router, user, conn, metadata)
 }
 
 func (h *TUIC) newPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext) error {
@@ -114,16 +124,24 @@ func (h *TUIC) newPacketConnection(ctx context.Context, conn N.PacketConn, metad
 	metadata = h.createPacketMetadata(conn, metadata)
 	h.logger.InfoContext(ctx, "inbound packet connection from ", metadata.Source)
 	userID, _ := auth.UserFromContext[int](ctx)
-	if userName := h.userNameList[userID]; userName != "" {
+	user, loaded := h.User(userID)
+	if !loaded {
+		return E.New("user ", userID, " removed")
+	}
+	if userName := user.Name; userName != "" {
 		metadata.User = userName
 		h.logger.InfoContext(ctx, "[", userName, "] inbound packet connection to ", metadata.Destination)
 	} else {
 		h.logger.InfoContext(ctx, "inbound packet connection to ", metadata.Destination)
 	}
-	return h.router.RoutePacketConnection(ctx, conn, metadata)
+	return h.routePacketConnection(ctx, h.router, user, conn, metadata)
 }
 
 func (h *TUIC) Start() error {
+	err := h.userManager.Start()
+	if err != nil {
+		return err
+	}
 	if h.tlsConfig != nil {
 		err := h.tlsConfig.Start()
 		if err != nil {
@@ -140,6 +158,7 @@ func (h *TUIC) Start() error {
 func (h *TUIC) Close() error {
 	return common.Close(
 		&h.myInboundAdapter,
+		h.userManager,
 		h.tlsConfig,
 		common.PtrOrNil(h.server),
 	)
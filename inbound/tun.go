@@ -18,6 +18,7 @@ import (
 	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing-tun"
 	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/buf"
 	E "github.com/sagernet/sing/common/exceptions"
 	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
@@ -27,7 +28,7 @@ import (
 	"go4.org/netipx"
 )
 
-var _ adapter.Inbound = (*Tun)(nil)
+var _ adapter.PortUnreachableInbound = (*Tun)(nil)
 
 type Tun struct {
 	tag                         string
@@ -430,6 +431,17 @@ func (t *Tun) NewPacketConnection(ctx context.Context, conn N.PacketConn, upstre
 	return nil
 }
 
+func (t *Tun) WritePortUnreachable(source netip.AddrPort, destination netip.AddrPort) error {
+	if t.tunIf == nil {
+		return os.ErrInvalid
+	}
+	packet := portUnreachablePacket(source, destination)
+	if packet == nil {
+		return E.New("mismatched address family: ", source, " and ", destination)
+	}
+	return t.tunIf.WriteVectorised([]*buf.Buffer{buf.As(packet)})
+}
+
 func (t *Tun) NewError(ctx context.Context, err error) {
 	NewError(t.logger, ctx, err)
 }
@@ -0,0 +1,92 @@
+package inbound
+
+import (
+	"encoding/binary"
+	"net/netip"
+)
+
+const (
+	ipv4HeaderLen = 20
+	ipv6HeaderLen = 40
+	udpHeaderLen  = 8
+	icmpHeaderLen = 8
+)
+
+// portUnreachablePacket builds an ICMP port unreachable packet sent from destination back to source,
+// quoting the IP and UDP headers of the rejected packet. It returns nil if the address families differ.
+func portUnreachablePacket(source netip.AddrPort, destination netip.AddrPort) []byte {
+	sourceAddr, destinationAddr := source.Addr().Unmap(), destination.Addr().Unmap()
+	switch {
+	case sourceAddr.Is4() && destinationAddr.Is4():
+		packet := make([]byte, ipv4HeaderLen+icmpHeaderLen+ipv4HeaderLen+udpHeaderLen)
+		writeIPv4Header(packet, destinationAddr, sourceAddr, 1, len(packet))
+		message := packet[ipv4HeaderLen:]
+		message[0] = 3 // destination unreachable
+		message[1] = 3 // port unreachable
+		quoted := message[icmpHeaderLen:]
+		writeIPv4Header(quoted, sourceAddr, destinationAddr, 17, len(quoted))
+		writeUDPHeader(quoted[ipv4HeaderLen:], source.Port(), destination.Port())
+		binary.BigEndian.PutUint16(message[2:], finishChecksum(checksum(0, message)))
+		return packet
+	case sourceAddr.Is6() && destinationAddr.Is6():
+		packet := make([]byte, ipv6HeaderLen+icmpHeaderLen+ipv6HeaderLen+udpHeaderLen)
+		writeIPv6Header(packet, destinationAddr, sourceAddr, 58, len(packet)-ipv6HeaderLen)
+		message := packet[ipv6HeaderLen:]
+		message[0] = 1 // destination unreachable
+		message[1] = 4 // port unreachable
+		quoted := message[icmpHeaderLen:]
+		writeIPv6Header(quoted, sourceAddr, destinationAddr, 17, udpHeaderLen)
+		writeUDPHeader(quoted[ipv6HeaderLen:], source.Port(), destination.Port())
+		// ICMPv6 checksums cover a pseudo header of the addresses, length and next header.
+		sum := checksum(0, packet[8:ipv6HeaderLen])
+		sum += uint32(len(message)) + 58
+		binary.BigEndian.PutUint16(message[2:], finishChecksum(checksum(sum, message)))
+		return packet
+	default:
+		return nil
+	}
+}
+
+func writeIPv4Header(header []byte, source netip.Addr, destination netip.Addr, protocol byte, totalLen int) {
+	header[0] = 0x45
+	binary.BigEndian.PutUint16(header[2:], uint16(totalLen))
+	header[8] = 64
+	header[9] = protocol
+	sourceBytes, destinationBytes := source.As4(), destination.As4()
+	copy(header[12:], sourceBytes[:])
+	copy(header[16:], destinationBytes[:])
+	binary.BigEndian.PutUint16(header[10:], finishChecksum(checksum(0, header[:ipv4HeaderLen])))
+}
+
+func writeIPv6Header(header []byte, source netip.Addr, destination netip.Addr, nextHeader byte, payloadLen int) {
+	header[0] = 0x60
+	binary.BigEndian.PutUint16(header[4:], uint16(payloadLen))
+	header[6] = nextHeader
+	header[7] = 64
+	sourceBytes, destinationBytes := source.As16(), destination.As16()
+	copy(header[8:], sourceBytes[:])
+	copy(header[24:], destinationBytes[:])
+}
+
+func writeUDPHeader(header []byte, sourcePort uint16, destinationPort uint16) {
+	binary.BigEndian.PutUint16(header, sourcePort)
+	binary.BigEndian.PutUint16(header[2:], destinationPort)
+	binary.BigEndian.PutUint16(header[4:], udpHeaderLen)
+}
+
+func checksum(sum uint32, data []byte) uint32 {
+	for ; len(data) >= 2; data = data[2:] {
+		sum += uint32(binary.BigEndian.Uint16(data))
+	}
+	if len(data) == 1 {
+		sum += uint32(data[0]) << 8
+	}
+	return sum
+}
+
+func finishChecksum(sum uint32) uint16 {
+	for sum > 0xffff {
+		sum = sum&0xffff + sum>>16
+	}
+	return ^uint16(sum)
+}
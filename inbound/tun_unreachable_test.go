@@ -0,0 +1,76 @@
+package inbound
+
+import (
+	"encoding/binary"
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+func TestPortUnreachablePacketIPv4(t *testing.T) {
+	t.Parallel()
+	source := netip.MustParseAddrPort("172.19.0.2:40000")
+	destination := netip.MustParseAddrPort("[::ffff:198.18.0.1]:53")
+	packet := portUnreachablePacket(source, destination)
+	header, err := ipv4.ParseHeader(packet)
+	require.NoError(t, err)
+	require.Equal(t, len(packet), header.TotalLen)
+	require.Equal(t, 1, header.Protocol)
+	require.True(t, header.Src.Equal(net.ParseIP("198.18.0.1")))
+	require.True(t, header.Dst.Equal(net.ParseIP("172.19.0.2")))
+	require.Zero(t, finishChecksum(checksum(0, packet[:ipv4HeaderLen])))
+	message, err := icmp.ParseMessage(1, packet[ipv4HeaderLen:])
+	require.NoError(t, err)
+	require.Equal(t, ipv4.ICMPTypeDestinationUnreachable, message.Type)
+	require.Equal(t, 3, message.Code)
+	// Marshal computes the checksum independently.
+	marshaled, err := message.Marshal(nil)
+	require.NoError(t, err)
+	require.Equal(t, packet[ipv4HeaderLen:], marshaled)
+	quoted := message.Body.(*icmp.DstUnreach).Data
+	quotedHeader, err := ipv4.ParseHeader(quoted)
+	require.NoError(t, err)
+	require.Equal(t, 17, quotedHeader.Protocol)
+	require.True(t, quotedHeader.Src.Equal(net.ParseIP("172.19.0.2")))
+	require.True(t, quotedHeader.Dst.Equal(net.ParseIP("198.18.0.1")))
+	require.Equal(t, uint16(40000), binary.BigEndian.Uint16(quoted[ipv4HeaderLen:]))
+	require.Equal(t, uint16(53), binary.BigEndian.Uint16(quoted[ipv4HeaderLen+2:]))
+}
+
+func TestPortUnreachablePacketIPv6(t *testing.T) {
+	t.Parallel()
+	source := netip.MustParseAddrPort("[fdfe:dcba:9876::2]:40000")
+	destination := netip.MustParseAddrPort("[2001:db8::1]:443")
+	packet := portUnreachablePacket(source, destination)
+	header, err := ipv6.ParseHeader(packet)
+	require.NoError(t, err)
+	require.Equal(t, len(packet)-ipv6HeaderLen, header.PayloadLen)
+	require.Equal(t, 58, header.NextHeader)
+	require.True(t, header.Src.Equal(net.ParseIP("2001:db8::1")))
+	require.True(t, header.Dst.Equal(net.ParseIP("fdfe:dcba:9876::2")))
+	message, err := icmp.ParseMessage(58, packet[ipv6HeaderLen:])
+	require.NoError(t, err)
+	require.Equal(t, ipv6.ICMPTypeDestinationUnreachable, message.Type)
+	require.Equal(t, 4, message.Code)
+	marshaled, err := message.Marshal(icmp.IPv6PseudoHeader(header.Src, header.Dst))
+	require.NoError(t, err)
+	require.Equal(t, packet[ipv6HeaderLen:], marshaled)
+	quoted := message.Body.(*icmp.DstUnreach).Data
+	quotedHeader, err := ipv6.ParseHeader(quoted)
+	require.NoError(t, err)
+	require.Equal(t, 17, quotedHeader.NextHeader)
+	require.True(t, quotedHeader.Src.Equal(net.ParseIP("fdfe:dcba:9876::2")))
+	require.True(t, quotedHeader.Dst.Equal(net.ParseIP("2001:db8::1")))
+	require.Equal(t, uint16(40000), binary.BigEndian.Uint16(quoted[ipv6HeaderLen:]))
+	require.Equal(t, uint16(443), binary.BigEndian.Uint16(quoted[ipv6HeaderLen+2:]))
+}
+
+func TestPortUnreachablePacketMismatchedFamily(t *testing.T) {
+	t.Parallel()
+	require.Nil(t, portUnreachablePacket(netip.MustParseAddrPort("172.19.0.2:40000"), netip.MustParseAddrPort("[2001:db8::1]:53")))
+}
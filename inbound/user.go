@@ -0,0 +1,576 @@
+package inbound
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/netip"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/ratelimit"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/atomic"
+	"github.com/sagernet/sing/common/bufio"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/service"
+)
+
+const userLimitCheckInterval = 30 * time.Second
+
+type userUpdateFunc func(indexes []int, users []adapter.InboundUser) error
+
+// userManager keeps the user list of a multi-user inbound. Every user gets a
+// stable index that is never reused, so connections authenticated against
+// an older user list can not be attributed to a different user.
+//
+// Connections of named users are tracked to enforce the user limits, and the
+// traffic of each user is persisted in the cache file under the inbound tag.
+type userManager struct {
+	inboundCtx    context.Context
+	inboundLogger log.ContextLogger
+	inboundTag    string
+	access        sync.RWMutex
+	users         map[int]adapter.InboundUser
+	nextIndex     int
+	update        userUpdateFunc
+	connAccess    sync.Mutex
+	conns         map[string]map[io.Closer]struct{}
+	sources       map[string]map[netip.Addr]int
+	traffic       map[string]*atomic.Int64
+	limiters      map[string]*userLimiter
+	cacheFile     adapter.CacheFile
+	done          chan struct{}
+	closeOnce     sync.Once
+}
+
+func newUserManager(ctx context.Context, logger log.ContextLogger, tag string, users []adapter.InboundUser, update userUpdateFunc) (*userManager, error) {
+	manager := &userManager{
+		inboundCtx:    ctx,
+		inboundLogger: logger,
+		inboundTag:    tag,
+		users:         make(map[int]adapter.InboundUser),
+		update:        update,
+		conns:         make(map[string]map[io.Closer]struct{}),
+		sources:       make(map[string]map[netip.Addr]int),
+		traffic:       make(map[string]*atomic.Int64),
+		limiters:      make(map[string]*userLimiter),
+		done:          make(chan struct{}),
+	}
+	for i, user := range users {
+		err := validateUserLimits(user)
+		if err != nil {
+			return nil, E.Cause(err, "parse user[", i, "]")
+		}
+		manager.users[manager.nextIndex] = user
+		manager.nextIndex++
+	}
+	err := manager.push(manager.users)
+	if err != nil {
+		return nil, err
+	}
+	return manager, nil
+}
+
+func validateUserLimits(user adapter.InboundUser) error {
+	if user.UserLimitOptions == (option.UserLimitOptions{}) {
+		return nil
+	}
+	if user.Name == "" {
+		return E.New("missing user name for user limits")
+	}
+	if user.MaxConnections < 0 {
+		return E.New("invalid max_connections: ", user.MaxConnections)
+	}
+	if user.MaxIPs < 0 {
+		return E.New("invalid max_ips: ", user.MaxIPs)
+	}
+	if user.BandwidthLimit != nil {
+		if user.BandwidthLimit.UpMbps < 0 {
+			return E.New("invalid bandwidth_limit.up_mbps: ", user.BandwidthLimit.UpMbps)
+		}
+		if user.BandwidthLimit.DownMbps < 0 {
+			return E.New("invalid bandwidth_limit.down_mbps: ", user.BandwidthLimit.DownMbps)
+		}
+	}
+	_, err := userExpireAt(user)
+	return err
+}
+
+func userExpireAt(user adapter.InboundUser) (time.Time, error) {
+	if user.ExpireAt == "" {
+		return time.Time{}, nil
+	}
+	expireAt, err := time.Parse(time.RFC3339, user.ExpireAt)
+	if err != nil {
+		return time.Time{}, E.Cause(err, "parse expire_at")
+	}
+	return expireAt, nil
+}
+
+func (m *userManager) Start() error {
+	cacheFile := service.FromContext[adapter.CacheFile](m.inboundCtx)
+	if cacheFile != nil && m.inboundTag != "" {
+		m.cacheFile = cacheFile
+		savedTraffic := cacheFile.LoadUserTraffic(m.inboundTag)
+		m.access.RLock()
+		m.connAccess.Lock()
+		for _, user := range m.users {
+			if value, loaded := savedTraffic[user.Name]; loaded && user.Name != "" {
+				m.loadTraffic(user.Name).Store(int64(value))
+			}
+		}
+		m.connAccess.Unlock()
+		m.access.RUnlock()
+	}
+	go m.loopLimits()
+	return nil
+}
+
+func (m *userManager) Close() error {
+	m.closeOnce.Do(func() {
+		close(m.done)
+	})
+	return m.storeTraffic()
+}
+
+func (m *userManager) loopLimits() {
+	ticker := time.NewTicker(userLimitCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			m.closeExceeded()
+			err := m.storeTraffic()
+			if err != nil {
+				m.inboundLogger.Warn("store user traffic: ", err)
+			}
+		case <-m.done:
+			return
+		}
+	}
+}
+
+func (m *userManager) storeTraffic() error {
+	if m.cacheFile == nil {
+		return nil
+	}
+	traffic := make(map[string]uint64)
+	m.connAccess.Lock()
+	for name, counter := range m.traffic {
+		if value := counter.Load(); value > 0 {
+			traffic[name] = uint64(value)
+		}
+	}
+	m.connAccess.Unlock()
+	return m.cacheFile.StoreUserTraffic(m.inboundTag, traffic)
+}
+
+func (m *userManager) closeExceeded() {
+	now := time.Now()
+	var exceeded []string
+	for _, user := range m.Users() {
+		if user.Name == "" {
+			continue
+		}
+		m.connAccess.Lock()
+		err := m.checkLimits(user, now)
+		active := len(m.conns[user.Name]) > 0
+		m.connAccess.Unlock()
+		if err != nil && active {
+			m.inboundLogger.Info(err, ", closing connections")
+			exceeded = append(exceeded, user.Name)
+		}
+	}
+	m.closeConnections(exceeded)
+}
+
+// checkLimits reports whether the user is expired or out of traffic.
+// Must be called with connAccess held.
+func (m *userManager) checkLimits(user adapter.InboundUser, now time.Time) error {
+	expireAt, _ := userExpireAt(user)
+	if !expireAt.IsZero() && now.After(expireAt) {
+		return E.New("user ", user.Name, " expired")
+	}
+	if user.TrafficQuota > 0 && uint64(m.loadTraffic(user.Name).Load()) >= uint64(user.TrafficQuota) {
+		return E.New("user ", user.Name, " exceeded traffic quota")
+	}
+	return nil
+}
+
+func (m *userManager) loadTraffic(name string) *atomic.Int64 {
+	counter, loaded := m.traffic[name]
+	if !loaded {
+		counter = new(atomic.Int64)
+		m.traffic[name] = counter
+	}
+	return counter
+}
+
+func (m *userManager) push(users map[int]adapter.InboundUser) error {
+	indexes := make([]int, 0, len(users))
+	for index := range users {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	return m.update(indexes, common.Map(indexes, func(index int) adapter.InboundUser {
+		return users[index]
+	}))
+}
+
+func (m *userManager) User(index int) (adapter.InboundUser, bool) {
+	m.access.RLock()
+	defer m.access.RUnlock()
+	user, loaded := m.users[index]
+	return user, loaded
+}
+
+func (m *userManager) Users() []adapter.InboundUser {
+	m.access.RLock()
+	defer m.access.RUnlock()
+	indexes := make([]int, 0, len(m.users))
+	for index := range m.users {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	return common.Map(indexes, func(index int) adapter.InboundUser {
+		return m.users[index]
+	})
+}
+
+func (m *userManager) AddUsers(users []adapter.InboundUser) error {
+	m.access.Lock()
+	defer m.access.Unlock()
+	names := make(map[string]bool)
+	for _, user := range m.users {
+		names[user.Name] = true
+	}
+	newUsers := make(map[int]adapter.InboundUser, len(m.users)+len(users))
+	for index, user := range m.users {
+		newUsers[index] = user
+	}
+	nextIndex := m.nextIndex
+	for _, user := range users {
+		if user.Name == "" {
+			return E.New("missing user name")
+		}
+		if names[user.Name] {
+			return E.New("user already exists: ", user.Name)
+		}
+		err := validateUserLimits(user)
+		if err != nil {
+			return E.Cause(err, "user ", user.Name)
+		}
+		names[user.Name] = true
+		newUsers[nextIndex] = user
+		nextIndex++
+	}
+	err := m.push(newUsers)
+	if err != nil {
+		return err
+	}
+	m.users = newUsers
+	m.nextIndex = nextIndex
+	return nil
+}
+
+func (m *userManager) RemoveUsers(names []string) error {
+	m.access.Lock()
+	defer m.access.Unlock()
+	removed := make(map[string]bool)
+	for _, name := range names {
+		removed[name] = true
+	}
+	newUsers := make(map[int]adapter.InboundUser, len(m.users))
+	for index, user := range m.users {
+		if removed[user.Name] {
+			delete(removed, user.Name)
+			continue
+		}
+		newUsers[index] = user
+	}
+	for name := range removed {
+		return E.New("user not found: ", name)
+	}
+	err := m.push(newUsers)
+	if err != nil {
+		return err
+	}
+	m.users = newUsers
+	m.removeUserState(names)
+	return nil
+}
+
+func (m *userManager) ReplaceUsers(users []adapter.InboundUser) error {
+	m.access.Lock()
+	defer m.access.Unlock()
+	indexByName := make(map[string]int)
+	for index, user := range m.users {
+		if user.Name != "" {
+			indexByName[user.Name] = index
+		}
+	}
+	newUsers := make(map[int]adapter.InboundUser, len(users))
+	names := make(map[string]bool)
+	var changed []string
+	nextIndex := m.nextIndex
+	for _, user := range users {
+		if user.Name == "" {
+			return E.New("missing user name")
+		}
+		if names[user.Name] {
+			return E.New("duplicate user name: ", user.Name)
+		}
+		err := validateUserLimits(user)
+		if err != nil {
+			return E.Cause(err, "user ", user.Name)
+		}
+		names[user.Name] = true
+		if index, loaded := indexByName[user.Name]; loaded {
+			newUsers[index] = user
+			if !sameCredentials(m.users[index], user) {
+				changed = append(changed, user.Name)
+			}
+		} else {
+			newUsers[nextIndex] = user
+			nextIndex++
+		}
+	}
+	err := m.push(newUsers)
+	if err != nil {
+		return err
+	}
+	var removed []string
+	for _, user := range m.users {
+		if user.Name != "" && !names[user.Name] {
+			removed = append(removed, user.Name)
+		}
+	}
+	m.users = newUsers
+	m.nextIndex = nextIndex
+	m.removeUserState(removed)
+	// Connections authenticated with revoked credentials are closed, the traffic is kept.
+	m.closeConnections(changed)
+	return nil
+}
+
+func sameCredentials(user adapter.InboundUser, newUser adapter.InboundUser) bool {
+	return user.UUID == newUser.UUID && user.Password == newUser.Password && user.AlterId == newUser.AlterId
+}
+
+// removeUserState drops the traffic of removed users and closes their
+// connections, so that a user added again later starts from zero.
+func (m *userManager) removeUserState(names []string) {
+	m.connAccess.Lock()
+	for _, name := range names {
+		delete(m.traffic, name)
+		delete(m.limiters, name)
+	}
+	m.connAccess.Unlock()
+	m.closeConnections(names)
+}
+
+func (m *userManager) closeConnections(names []string) {
+	var conns []io.Closer
+	m.connAccess.Lock()
+	for _, name := range names {
+		for conn := range m.conns[name] {
+			conns = append(conns, conn)
+		}
+	}
+	m.connAccess.Unlock()
+	for _, conn := range conns {
+		conn.Close()
+	}
+}
+
+func (m *userManager) track(user adapter.InboundUser, conn io.Closer, source netip.Addr) error {
+	m.connAccess.Lock()
+	defer m.connAccess.Unlock()
+	err := m.checkLimits(user, time.Now())
+	if err != nil {
+		return err
+	}
+	userConns := m.conns[user.Name]
+	if user.MaxConnections > 0 && len(userConns) >= user.MaxConnections {
+		return E.New("user ", user.Name, " exceeded max connections")
+	}
+	userSources := m.sources[user.Name]
+	if user.MaxIPs > 0 && source.IsValid() && userSources[source] == 0 && len(userSources) >= user.MaxIPs {
+		return E.New("user ", user.Name, " exceeded max source IPs")
+	}
+	if userConns == nil {
+		userConns = make(map[io.Closer]struct{})
+		m.conns[user.Name] = userConns
+	}
+	userConns[conn] = struct{}{}
+	if source.IsValid() {
+		if userSources == nil {
+			userSources = make(map[netip.Addr]int)
+			m.sources[user.Name] = userSources
+		}
+		userSources[source]++
+	}
+	return nil
+}
+
+func (m *userManager) untrack(name string, conn io.Closer, source netip.Addr) {
+	m.connAccess.Lock()
+	defer m.connAccess.Unlock()
+	userConns := m.conns[name]
+	if _, loaded := userConns[conn]; !loaded {
+		return
+	}
+	delete(userConns, conn)
+	if len(userConns) == 0 {
+		delete(m.conns, name)
+	}
+	if source.IsValid() {
+		userSources := m.sources[name]
+		userSources[source]--
+		if userSources[source] <= 0 {
+			delete(userSources, source)
+		}
+		if len(userSources) == 0 {
+			delete(m.sources, name)
+		}
+	}
+}
+
+type userLimiter struct {
+	options option.BandwidthLimitOptions
+	limiter *ratelimit.Limiter
+}
+
+// userState returns the traffic counters and the bandwidth limiter shared by
+// all connections of the user. A limiter whose limited directions are unchanged
+// is updated in place, so that rate changes also apply to open connections.
+func (m *userManager) userState(user adapter.InboundUser) ([]*atomic.Int64, *ratelimit.Limiter) {
+	m.connAccess.Lock()
+	defer m.connAccess.Unlock()
+	counters := []*atomic.Int64{m.loadTraffic(user.Name)}
+	if user.BandwidthLimit == nil {
+		delete(m.limiters, user.Name)
+		return counters, nil
+	}
+	options := *user.BandwidthLimit
+	current, loaded := m.limiters[user.Name]
+	if loaded && (current.options.UpMbps > 0) == (options.UpMbps > 0) && (current.options.DownMbps > 0) == (options.DownMbps > 0) {
+		if current.options != options {
+			current.limiter.Update(options)
+			current.options = options
+		}
+		return counters, current.limiter
+	}
+	current = &userLimiter{options, ratelimit.NewLimiter(options)}
+	m.limiters[user.Name] = current
+	return counters, current.limiter
+}
+
+// TrackConn checks the limits of a named user and registers the connection,
+// so that its traffic is counted and shaped, and it is closed when the user is
+// removed, expires or runs out of traffic.
+func (m *userManager) TrackConn(user adapter.InboundUser, conn net.Conn, source M.Socksaddr) (net.Conn, error) {
+	if user.Name == "" {
+		return conn, nil
+	}
+	counters, limiter := m.userState(user)
+	trackedConn := &userConn{
+		Conn:    limiter.NewConn(bufio.NewInt64CounterConn(conn, counters, counters)),
+		manager: m,
+		name:    user.Name,
+		source:  source.Addr.Unmap(),
+	}
+	err := m.track(user, trackedConn, trackedConn.source)
+	if err != nil {
+		return nil, err
+	}
+	return trackedConn, nil
+}
+
+func (m *userManager) TrackPacketConn(user adapter.InboundUser, conn N.PacketConn, source M.Socksaddr) (N.PacketConn, error) {
+	if user.Name == "" {
+		return conn, nil
+	}
+	counters, limiter := m.userState(user)
+	trackedConn := &userPacketConn{
+		PacketConn: limiter.NewPacketConn(bufio.NewInt64CounterPacketConn(conn, counters, counters)),
+		manager:    m,
+		name:       user.Name,
+		source:     source.Addr.Unmap(),
+	}
+	err := m.track(user, trackedConn, trackedConn.source)
+	if err != nil {
+		return nil, err
+	}
+	return trackedConn, nil
+}
+
+// routeConnection tracks the connection of the user and routes it. On error the
+// router and the inbound only close the original connection, so the tracked one
+// is untracked here to release its slot in the user limits.
+func (m *userManager) routeConnection(ctx context.Context, router adapter.ConnectionRouter, user adapter.InboundUser, conn net.Conn, metadata adapter.InboundContext) error {
+	trackedConn, err := m.TrackConn(user, conn, metadata.Source)
+	if err != nil {
+		return err
+	}
+	err = router.RouteConnection(ctx, trackedConn, metadata)
+	if err != nil {
+		if userConn, isUserConn := trackedConn.(*userConn); isUserConn {
+			m.untrack(userConn.name, userConn, userConn.source)
+		}
+	}
+	return err
+}
+
+func (m *userManager) routePacketConnection(ctx context.Context, router adapter.ConnectionRouter, user adapter.InboundUser, conn N.PacketConn, metadata adapter.InboundContext) error {
+	trackedConn, err := m.TrackPacketConn(user, conn, metadata.Source)
+	if err != nil {
+		return err
+	}
+	err = router.RoutePacketConnection(ctx, trackedConn, metadata)
+	if err != nil {
+		if userConn, isUserConn := trackedConn.(*userPacketConn); isUserConn {
+			m.untrack(userConn.name, userConn, userConn.source)
+		}
+	}
+	return err
+}
+
+type userConn struct {
+	net.Conn
+	manager *userManager
+	name    string
+	source  netip.Addr
+}
+
+func (c *userConn) Close() error {
+	c.manager.untrack(c.name, c, c.source)
+	return c.Conn.Close()
+}
+
+func (c *userConn) Upstream() any {
+	return c.Conn
+}
+
+type userPacketConn struct {
+	N.PacketConn
+	manager *userManager
+	name    string
+	source  netip.Addr
+}
+
+func (c *userPacketConn) Close() error {
+	c.manager.untrack(c.name, c, c.source)
+	return c.PacketConn.Close()
+}
+
+func (c *userPacketConn) Upstream() any {
+	return c.PacketConn
+}
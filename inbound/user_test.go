@@ -0,0 +1,184 @@
+package inbound
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/bufio"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestUserManager(t *testing.T, users ...adapter.InboundUser) *userManager {
+	manager, err := newUserManager(context.Background(), log.NewNOPFactory().Logger(), "test", users, func(indexes []int, users []adapter.InboundUser) error {
+		return nil
+	})
+	require.NoError(t, err)
+	return manager
+}
+
+func newTestConn(t *testing.T) net.Conn {
+	conn, peer := net.Pipe()
+	t.Cleanup(func() {
+		conn.Close()
+		peer.Close()
+	})
+	return conn
+}
+
+func testSource(address string) M.Socksaddr {
+	return M.SocksaddrFrom(netip.MustParseAddr(address), 443)
+}
+
+func (m *userManager) trackedConnections(name string) (int, int) {
+	m.connAccess.Lock()
+	defer m.connAccess.Unlock()
+	return len(m.conns[name]), len(m.sources[name])
+}
+
+func TestUserMaxConnections(t *testing.T) {
+	t.Parallel()
+	user := adapter.InboundUser{Name: "user", UserLimitOptions: option.UserLimitOptions{MaxConnections: 2}}
+	manager := newTestUserManager(t, user)
+	conn1, err := manager.TrackConn(user, newTestConn(t), testSource("10.0.0.1"))
+	require.NoError(t, err)
+	_, err = manager.TrackConn(user, newTestConn(t), testSource("10.0.0.1"))
+	require.NoError(t, err)
+	_, err = manager.TrackConn(user, newTestConn(t), testSource("10.0.0.1"))
+	require.Error(t, err)
+	conn1.Close()
+	_, err = manager.TrackConn(user, newTestConn(t), testSource("10.0.0.1"))
+	require.NoError(t, err)
+}
+
+func TestUserMaxIPs(t *testing.T) {
+	t.Parallel()
+	user := adapter.InboundUser{Name: "user", UserLimitOptions: option.UserLimitOptions{MaxIPs: 2}}
+	manager := newTestUserManager(t, user)
+	conn1, err := manager.TrackConn(user, newTestConn(t), testSource("10.0.0.1"))
+	require.NoError(t, err)
+	conn2, err := manager.TrackConn(user, newTestConn(t), testSource("10.0.0.1"))
+	require.NoError(t, err)
+	_, err = manager.TrackConn(user, newTestConn(t), testSource("::ffff:10.0.0.2"))
+	require.NoError(t, err)
+	_, err = manager.TrackConn(user, newTestConn(t), testSource("10.0.0.3"))
+	require.Error(t, err)
+	conn1.Close()
+	_, err = manager.TrackConn(user, newTestConn(t), testSource("10.0.0.3"))
+	require.Error(t, err)
+	conn2.Close()
+	_, err = manager.TrackConn(user, newTestConn(t), testSource("10.0.0.3"))
+	require.NoError(t, err)
+	connections, sources := manager.trackedConnections("user")
+	require.Equal(t, 2, connections)
+	require.Equal(t, 2, sources)
+}
+
+func TestUserTrafficQuota(t *testing.T) {
+	t.Parallel()
+	user := adapter.InboundUser{Name: "user", UserLimitOptions: option.UserLimitOptions{TrafficQuota: 1024}}
+	manager := newTestUserManager(t, user)
+	conn, err := manager.TrackConn(user, newTestConn(t), testSource("10.0.0.1"))
+	require.NoError(t, err)
+	manager.connAccess.Lock()
+	manager.loadTraffic("user").Store(1024)
+	manager.connAccess.Unlock()
+	_, err = manager.TrackConn(user, newTestConn(t), testSource("10.0.0.1"))
+	require.Error(t, err)
+	manager.closeExceeded()
+	_, err = conn.Write([]byte{0})
+	require.Error(t, err)
+	connections, _ := manager.trackedConnections("user")
+	require.Zero(t, connections)
+}
+
+func TestUserExpireAt(t *testing.T) {
+	t.Parallel()
+	expiredUser := adapter.InboundUser{Name: "expired", UserLimitOptions: option.UserLimitOptions{ExpireAt: time.Now().Add(-time.Hour).Format(time.RFC3339)}}
+	activeUser := adapter.InboundUser{Name: "active", UserLimitOptions: option.UserLimitOptions{ExpireAt: time.Now().Add(time.Hour).Format(time.RFC3339)}}
+	manager := newTestUserManager(t, expiredUser, activeUser)
+	_, err := manager.TrackConn(expiredUser, newTestConn(t), testSource("10.0.0.1"))
+	require.Error(t, err)
+	_, err = manager.TrackConn(activeUser, newTestConn(t), testSource("10.0.0.1"))
+	require.NoError(t, err)
+	_, err = newUserManager(context.Background(), log.NewNOPFactory().Logger(), "test", []adapter.InboundUser{
+		{Name: "invalid", UserLimitOptions: option.UserLimitOptions{ExpireAt: "tomorrow"}},
+	}, func(indexes []int, users []adapter.InboundUser) error {
+		return nil
+	})
+	require.Error(t, err)
+}
+
+type failedRouter struct{}
+
+func (r *failedRouter) RouteConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
+	return E.New("route failed")
+}
+
+func (r *failedRouter) RoutePacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext) error {
+	return E.New("route failed")
+}
+
+func TestUserRouteFailureUntracks(t *testing.T) {
+	t.Parallel()
+	user := adapter.InboundUser{Name: "user", UserLimitOptions: option.UserLimitOptions{MaxConnections: 1, MaxIPs: 1}}
+	manager := newTestUserManager(t, user)
+	metadata := adapter.InboundContext{Source: testSource("10.0.0.1")}
+	for i := 0; i < 2; i++ {
+		err := manager.routeConnection(context.Background(), &failedRouter{}, user, newTestConn(t), metadata)
+		require.EqualError(t, err, "route failed")
+	}
+	packetConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer packetConn.Close()
+	err = manager.routePacketConnection(context.Background(), &failedRouter{}, user, bufio.NewPacketConn(packetConn), metadata)
+	require.EqualError(t, err, "route failed")
+	connections, sources := manager.trackedConnections("user")
+	require.Zero(t, connections)
+	require.Zero(t, sources)
+	_, err = manager.TrackConn(user, newTestConn(t), testSource("10.0.0.2"))
+	require.NoError(t, err)
+}
+
+func TestReplaceUsersClosesChangedCredentials(t *testing.T) {
+	t.Parallel()
+	users := []adapter.InboundUser{
+		{Name: "uuid", UUID: "b831381d-6324-4d53-ad4f-8cda48b30811"},
+		{Name: "password", Password: "password"},
+		{Name: "limits", Password: "password"},
+	}
+	manager := newTestUserManager(t, users...)
+	for _, user := range users {
+		_, err := manager.TrackConn(user, newTestConn(t), testSource("10.0.0.1"))
+		require.NoError(t, err)
+	}
+	manager.connAccess.Lock()
+	manager.loadTraffic("uuid").Store(1024)
+	manager.connAccess.Unlock()
+
+	newUsers := []adapter.InboundUser{
+		{Name: "uuid", UUID: "4b0c7e8a-4b19-4c62-9bd1-3a3b2a1d6f55"},
+		{Name: "password", Password: "new-password"},
+		{Name: "limits", Password: "password", UserLimitOptions: option.UserLimitOptions{MaxConnections: 2}},
+	}
+	require.NoError(t, manager.ReplaceUsers(newUsers))
+	for _, name := range []string{"uuid", "password"} {
+		connections, _ := manager.trackedConnections(name)
+		require.Zero(t, connections, name)
+	}
+	connections, _ := manager.trackedConnections("limits")
+	require.Equal(t, 1, connections)
+	require.Equal(t, newUsers, manager.Users())
+	manager.connAccess.Lock()
+	require.Equal(t, int64(1024), manager.loadTraffic("uuid").Load())
+	manager.connAccess.Unlock()
+}
@@ -25,14 +25,15 @@ import (
 )
 
 var (
-	_ adapter.Inbound           = (*VLESS)(nil)
-	_ adapter.InjectableInbound = (*VLESS)(nil)
+	_ adapter.Inbound            = (*VLESS)(nil)
+	_ adapter.InjectableInbound  = (*VLESS)(nil)
+	_ adapter.ManagedUserInbound = (*VLESS)(nil)
 )
 
 type VLESS struct {
 	myInboundAdapter
+	*userManager
 	ctx       context.Context
-	users     []option.VLESSUser
 	service   *vless.Service[int]
 	tlsConfig tls.ServerConfig
 	transport adapter.V2RayServerTransport
@@ -49,8 +50,7 @@ func NewVLESS(ctx context.Context, router adapter.Router, logger log.ContextLogg
 			tag:           tag,
 			listenOptions: options.ListenOptions,
 		},
-		ctx:   ctx,
-		users: options.Users,
+		ctx: ctx,
 	}
 	var err error
 	inbound.router, err = mux.NewRouterWithOptions(inbound.router, logger, common.PtrValueOrDefault(options.Multiplex))
@@ -58,13 +58,19 @@ func NewVLESS(ctx context.Context, router adapter.Router, logger log.ContextLogg
 		return nil, err
 	}
 	service := vless.NewService[int](logger, adapter.NewUpstreamContextHandler(inbound.newConnection, inbound.newPacketConnection, inbound))
-	service.UpdateUsers(common.MapIndexed(inbound.users, func(index int, _ option.VLESSUser) int {
-		return index
-	}), common.Map(inbound.users, func(it option.VLESSUser) string {
-		return it.UUID
-	}), common.Map(inbound.users, func(it option.VLESSUser) string {
-		return it.Flow
-	}))
+	inbound.userManager, err = newUserManager(ctx, logger, tag, common.Map(options.Users, func(it option.VLESSUser) adapter.InboundUser {
+		return adapter.InboundUser{Name: it.Name, UUID: it.UUID, Flow: it.Flow, UserLimitOptions: it.UserLimitOptions}
+	}), func(indexes []int, users []adapter.InboundUser) error {
+		service.UpdateUsers(indexes, common.Map(users, func(it adapter.InboundUser) string {
+			return it.UUID
+		}), common.Map(users, func(it adapter.InboundUser) string {
+			return it.Flow
+		}))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
 	inbound.service = service
 	if options.TLS != nil {
 		inbound.tlsConfig, err = tls.NewServer(ctx, logger, common.PtrValueOrDefault(options.TLS))
@@ -83,6 +89,10 @@ func NewVLESS(ctx context.Context, router adapter.Router, logger log.ContextLogg
 }
 
 func (h *VLESS) Start() error {
+	err := h.userManager.Start()
+	if err != nil {
+		return err
+	}
 	if h.tlsConfig != nil {
 		err := h.tlsConfig.Start()
 		if err != nil {
@@ -123,6 +133,7 @@ func (h *VLESS) Close() error {
 	return common.Close(
 		h.service,
 		&h.myInboundAdapter,
+		h.userManager,
 		h.tlsConfig,
 		h.transport,
 	)
@@ -153,14 +164,18 @@ func (h *VLESS) newConnection(ctx context.Context, conn net.Conn, metadata adapt
 	if !loaded {
 		return os.ErrInvalid
 	}
-	user := h.users[userIndex].Name
+	userOptions, loaded := h.User(userIndex)
+	if !loaded {
+		return E.New("user ", userIndex, " removed")
+	}
+	user := userOptions.Name
 	if user == "" {
 		user = F.ToString(userIndex)
 	} else {
 		metadata.User = user
 	}
 	h.logger.InfoContext(ctx, "[", user, "] inbound connection to ", metadata.Destination)
-	return h.router.RouteConnection(ctx, conn, metadata)
+	return h.routeConnection(ctx, h.router, userOptions, conn, metadata)
 }
 
 func (h *VLESS) newPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext) error {
@@ -168,7 +183,11 @@ func (h *VLESS) newPacketConnection(ctx context.Context, conn N.PacketConn, meta
 	if !loaded {
 		return os.ErrInvalid
 	}
-	user := h.users[userIndex].Name
+	userOptions, loaded := h.User(userIndex)
+	if !loaded {
+		return E.New("user ", userIndex, " removed")
+	}
+	user := userOptions.Name
 	if user == "" {
 		user = F.ToString(userIndex)
 	} else {
@@ -181,7 +200,7 @@ func (h *VLESS) newPacketConnection(ctx context.Context, conn N.PacketConn, meta
 	} else {
 		h.logger.InfoContext(ctx, "[", user, "] inbound packet connection to ", metadata.Destination)
 	}
-	return h.router.RoutePacketConnection(ctx, conn, metadata)
+	return h.routePacketConnection(ctx, h.router, userOptions, conn, metadata)
 }
 
 var _ adapter.V2RayServerTransportHandler = (*vlessTransportHandler)(nil)
@@ -25,15 +25,16 @@ import (
 )
 
 var (
-	_ adapter.Inbound           = (*VMess)(nil)
-	_ adapter.InjectableInbound = (*VMess)(nil)
+	_ adapter.Inbound            = (*VMess)(nil)
+	_ adapter.InjectableInbound  = (*VMess)(nil)
+	_ adapter.ManagedUserInbound = (*VMess)(nil)
 )
 
 type VMess struct {
 	myInboundAdapter
+	*userManager
 	ctx       context.Context
 	service   *vmess.Service[int]
-	users     []option.VMessUser
 	tlsConfig tls.ServerConfig
 	transport adapter.V2RayServerTransport
 }
@@ -49,8 +50,7 @@ func NewVMess(ctx context.Context, router adapter.Router, logger log.ContextLogg
 			tag:           tag,
 			listenOptions: options.ListenOptions,
 		},
-		ctx:   ctx,
-		users: options.Users,
+		ctx: ctx,
 	}
 	var err error
 	inbound.router, err = mux.NewRouterWithOptions(inbound.router, logger, common.PtrValueOrDefault(options.Multiplex))
@@ -66,13 +66,15 @@ func NewVMess(ctx context.Context, router adapter.Router, logger log.ContextLogg
 	}
 	service := vmess.NewService[int](adapter.NewUpstreamContextHandler(inbound.newConnection, inbound.newPacketConnection, inbound), serviceOptions...)
 	inbound.service = service
-	err = service.UpdateUsers(common.MapIndexed(options.Users, func(index int, it option.VMessUser) int {
-		return index
-	}), common.Map(options.Users, func(it option.VMessUser) string {
-		return it.UUID
-	}), common.Map(options.Users, func(it option.VMessUser) int {
-		return it.AlterId
-	}))
+	inbound.userManager, err = newUserManager(ctx, logger, tag, common.Map(options.Users, func(it option.VMessUser) adapter.InboundUser {
+		return adapter.InboundUser{Name: it.Name, UUID: it.UUID, AlterId: it.AlterId, UserLimitOptions: it.UserLimitOptions}
+	}), func(indexes []int, users []adapter.InboundUser) error {
+		return service.UpdateUsers(indexes, common.Map(users, func(it adapter.InboundUser) string {
+			return it.UUID
+		}), common.Map(users, func(it adapter.InboundUser) int {
+			return it.AlterId
+		}))
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -97,6 +99,10 @@ func (h *VMess) Start() error {
 	if err != nil {
 		return err
 	}
+	err = h.userManager.Start()
+	if err != nil {
+		return err
+	}
 	if h.tlsConfig != nil {
 		err = h.tlsConfig.Start()
 		if err != nil {
@@ -137,6 +143,7 @@ func (h *VMess) Close() error {
 	return common.Close(
 		h.service,
 		&h.myInboundAdapter,
+		h.userManager,
 		h.tlsConfig,
 		h.transport,
 	)
@@ -167,14 +174,18 @@ func (h *VMess) newConnection(ctx context.Context, conn net.Conn, metadata adapt
 	if !loaded {
 		return os.ErrInvalid
 	}
-	user := h.users[userIndex].Name
+	userOptions, loaded := h.User(userIndex)
+	if !loaded {
+		return E.New("user ", userIndex, " removed")
+	}
+	user := userOptions.Name
 	if user == "" {
 		user = F.ToString(userIndex)
 	} else {
 		metadata.User = user
 	}
 	h.logger.InfoContext(ctx, "[", user, "] inbound connection to ", metadata.Destination)
-	return h.router.RouteConnection(ctx, conn, metadata)
+	return h.routeConnection(ctx, h.router, userOptions, conn, metadata)
 }
 
 func (h *VMess) newPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext) error {
@@ -182,7 +193,11 @@ func (h *VMess) newPacketConnection(ctx context.Context, conn N.PacketConn, meta
 	if !loaded {
 		return os.ErrInvalid
 	}
-	user := h.users[userIndex].Name
+	userOptions, loaded := h.User(userIndex)
+	if !loaded {
+		return E.New("user ", userIndex, " removed")
+	}
+	user := userOptions.Name
 	if user == "" {
 		user = F.ToString(userIndex)
 	} else {
@@ -195,7 +210,7 @@ func (h *VMess) newPacketConnection(ctx context.Context, conn N.PacketConn, meta
 	} else {
 		h.logger.InfoContext(ctx, "[", user, "] inbound packet connection to ", metadata.Destination)
 	}
-	return h.router.RoutePacketConnection(ctx, conn, metadata)
+	return h.routePacketConnection(ctx, h.router, userOptions, conn, metadata)
 }
 
 var _ adapter.V2RayServerTransportHandler = (*vmessTransportHandler)(nil)
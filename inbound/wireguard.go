@@ -0,0 +1,146 @@
+//go:build with_wireguard
+
+package inbound
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-box/transport/wireguard"
+	"github.com/sagernet/sing-tun"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/wireguard-go/device"
+)
+
+var _ adapter.Inbound = (*WireGuard)(nil)
+
+type WireGuard struct {
+	myInboundAdapter
+	workers    int
+	peers      []wireguard.PeerConfig
+	ipcConf    string
+	udpTimeout int64
+	tunDevice  *wireguard.ServerDevice
+	tunStack   tun.Stack
+	device     *device.Device
+}
+
+func NewWireGuard(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.WireGuardInboundOptions) (*WireGuard, error) {
+	inbound := &WireGuard{
+		myInboundAdapter: myInboundAdapter{
+			protocol:      C.TypeWireGuard,
+			network:       []string{N.NetworkUDP},
+			ctx:           ctx,
+			router:        router,
+			logger:        logger,
+			tag:           tag,
+			listenOptions: options.ListenOptions,
+		},
+		workers: options.Workers,
+	}
+	peers, err := wireguard.ParseInboundPeers(options.Peers)
+	if err != nil {
+		return nil, err
+	}
+	inbound.peers = peers
+	var privateKey string
+	{
+		bytes, err := base64.StdEncoding.DecodeString(options.PrivateKey)
+		if err != nil {
+			return nil, E.Cause(err, "decode private key")
+		}
+		privateKey = hex.EncodeToString(bytes)
+	}
+	inbound.ipcConf = "private_key=" + privateKey
+	var udpTimeout time.Duration
+	if options.UDPTimeout != 0 {
+		udpTimeout = time.Duration(options.UDPTimeout)
+	} else {
+		udpTimeout = C.UDPTimeout
+	}
+	inbound.udpTimeout = int64(udpTimeout.Seconds())
+	mtu := options.MTU
+	if mtu == 0 {
+		mtu = 1408
+	}
+	tunDevice, err := wireguard.NewServerDevice(mtu)
+	if err != nil {
+		return nil, E.Cause(err, "create WireGuard device")
+	}
+	inbound.tunDevice = tunDevice
+	return inbound, nil
+}
+
+func (w *WireGuard) Start() error {
+	tunStack, err := tun.NewStack("gvisor", tun.StackOptions{
+		Context:    w.ctx,
+		Tun:        w.tunDevice.Tun(),
+		UDPTimeout: w.udpTimeout,
+		Handler:    adapter.NewUpstreamHandler(adapter.InboundContext{}, w.newConnection, w.newPacketConnection, w),
+		Logger:     w.logger,
+	})
+	if err != nil {
+		return err
+	}
+	err = tunStack.Start()
+	w.tunStack = tunStack
+	if err != nil {
+		return err
+	}
+	wgDevice := device.NewDevice(w.tunDevice, wireguard.NewServerBind(w.myInboundAdapter.ListenUDP), &device.Logger{
+		Verbosef: func(format string, args ...interface{}) {
+			w.logger.Debug(fmt.Sprintf(strings.ToLower(format), args...))
+		},
+		Errorf: func(format string, args ...interface{}) {
+			w.logger.Error(fmt.Sprintf(strings.ToLower(format), args...))
+		},
+	}, w.workers)
+	w.device = wgDevice
+	ipcConf := w.ipcConf
+	for _, peer := range w.peers {
+		ipcConf += peer.GenerateIpcLines()
+	}
+	err = wgDevice.IpcSet(ipcConf)
+	if err != nil {
+		return E.Cause(err, "setup wireguard")
+	}
+	return w.tunDevice.Start()
+}
+
+func (w *WireGuard) Close() error {
+	if w.device != nil {
+		w.device.Close()
+	}
+	return common.Close(
+		w.tunStack,
+		common.PtrOrNil(w.tunDevice),
+		&w.myInboundAdapter,
+	)
+}
+
+func (w *WireGuard) newConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
+	ctx = log.ContextWithNewID(ctx)
+	metadata = w.createMetadata(conn, metadata)
+	w.logger.InfoContext(ctx, "inbound connection from ", metadata.Source)
+	w.logger.InfoContext(ctx, "inbound connection to ", metadata.Destination)
+	return w.router.RouteConnection(ctx, conn, metadata)
+}
+
+func (w *WireGuard) newPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext) error {
+	ctx = log.ContextWithNewID(ctx)
+	metadata = w.createPacketMetadata(conn, metadata)
+	w.logger.InfoContext(ctx, "inbound packet connection from ", metadata.Source)
+	w.logger.InfoContext(ctx, "inbound packet connection to ", metadata.Destination)
+	return w.router.RoutePacketConnection(ctx, conn, metadata)
+}
@@ -11,7 +11,7 @@ import (
 )
 
 func init() {
-	experimental.RegisterV2RayServerConstructor(func(logger log.Logger, options option.V2RayAPIOptions) (adapter.V2RayServer, error) {
+	experimental.RegisterV2RayServerConstructor(func(router adapter.Router, logger log.Logger, options option.V2RayAPIOptions) (adapter.V2RayServer, error) {
 		return nil, E.New(`v2ray api is not included in this build, rebuild with -tags with_v2ray_api`)
 	})
 }
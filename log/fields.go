@@ -0,0 +1,52 @@
+package log
+
+import (
+	"context"
+
+	"github.com/sagernet/sing/common/atomic"
+)
+
+// Fields are the structured fields of a connection. They are shared by all
+// messages logged with the connection ID, including those logged before the
+// fields were known.
+type Fields struct {
+	Inbound     string
+	Source      string
+	Destination string
+	Domain      string
+	Rule        string
+	Outbound    string
+	Upload      *atomic.Int64
+	Download    *atomic.Int64
+}
+
+type fieldsHolder struct {
+	fields atomic.Pointer[Fields]
+}
+
+// UpdateFields replaces the fields of the connection ID in the context.
+func UpdateFields(ctx context.Context, fields Fields) {
+	id, loaded := IDFromContext(ctx)
+	if !loaded || id.fields == nil {
+		return
+	}
+	id.fields.fields.Store(&fields)
+}
+
+func FieldsFromContext(ctx context.Context) (Fields, bool) {
+	id, loaded := IDFromContext(ctx)
+	if !loaded || id.fields == nil {
+		return Fields{}, false
+	}
+	fields := id.fields.fields.Load()
+	if fields == nil {
+		return Fields{}, false
+	}
+	return *fields, true
+}
+
+// FieldsFactory is implemented by factories that write connection fields,
+// so that callers can skip collecting them otherwise.
+type FieldsFactory interface {
+	NeedFields() bool
+}
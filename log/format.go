@@ -18,9 +18,13 @@ type Formatter struct {
 	FullTimestamp    bool
 	TimestampFormat  string
 	DisableLineBreak bool
+	JSON             bool
 }
 
 func (f Formatter) Format(ctx context.Context, level Level, tag string, message string, timestamp time.Time) string {
+	if f.JSON {
+		return f.formatJSON(ctx, level, tag, message, timestamp)
+	}
 	levelString := strings.ToUpper(FormatLevel(level))
 	if !f.DisableColors {
 		switch level {
@@ -90,6 +94,9 @@ func (f Formatter) Format(ctx context.Context, level Level, tag string, message
 }
 
 func (f Formatter) FormatWithSimple(ctx context.Context, level Level, tag string, message string, timestamp time.Time) (string, string) {
+	if f.JSON {
+		return f.formatJSON(ctx, level, tag, message, timestamp), formatSimple(ctx, tag, message)
+	}
 	levelString := strings.ToUpper(FormatLevel(level))
 	if !f.DisableColors {
 		switch level {
@@ -155,6 +162,18 @@ func (f Formatter) FormatWithSimple(ctx context.Context, level Level, tag string
 	return message, messageSimple
 }
 
+func formatSimple(ctx context.Context, tag string, message string) string {
+	if tag != "" {
+		message = tag + ": " + message
+	}
+	if ctx != nil {
+		if id, hasId := IDFromContext(ctx); hasId {
+			message = F.ToString("[", id.ID, " ", FormatDuration(time.Since(id.CreatedAt)), "] ", message)
+		}
+	}
+	return message
+}
+
 func xd(value int, x int) string {
 	message := strconv.Itoa(value)
 	for len(message) < x {
@@ -0,0 +1,72 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"time"
+
+	"github.com/sagernet/sing/common/json"
+)
+
+type jsonEntry struct {
+	Time        string `json:"time"`
+	Level       string `json:"level"`
+	Tag         string `json:"tag,omitempty"`
+	Message     string `json:"message"`
+	ID          uint32 `json:"id,omitempty"`
+	Duration    *int64 `json:"duration,omitempty"`
+	Inbound     string `json:"inbound,omitempty"`
+	Source      string `json:"source,omitempty"`
+	Destination string `json:"destination,omitempty"`
+	Domain      string `json:"domain,omitempty"`
+	Rule        string `json:"rule,omitempty"`
+	Outbound    string `json:"outbound,omitempty"`
+	Upload      *int64 `json:"upload,omitempty"`
+	Download    *int64 `json:"download,omitempty"`
+}
+
+// formatJSON formats the message as a single line JSON object, with the
+// connection ID, its age in milliseconds and the connection fields if any.
+func (f Formatter) formatJSON(ctx context.Context, level Level, tag string, message string, timestamp time.Time) string {
+	entry := jsonEntry{
+		Time:    timestamp.Format(time.RFC3339Nano),
+		Level:   FormatLevel(level),
+		Tag:     tag,
+		Message: strings.TrimSuffix(message, "\n"),
+	}
+	if ctx != nil {
+		if id, hasId := IDFromContext(ctx); hasId {
+			duration := time.Since(id.CreatedAt).Milliseconds()
+			entry.ID = id.ID
+			entry.Duration = &duration
+		}
+		if fields, loaded := FieldsFromContext(ctx); loaded {
+			entry.Inbound = fields.Inbound
+			entry.Source = fields.Source
+			entry.Destination = fields.Destination
+			entry.Domain = fields.Domain
+			entry.Rule = fields.Rule
+			entry.Outbound = fields.Outbound
+			if fields.Upload != nil {
+				upload := fields.Upload.Load()
+				entry.Upload = &upload
+			}
+			if fields.Download != nil {
+				download := fields.Download.Load()
+				entry.Download = &download
+			}
+		}
+	}
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	err := encoder.Encode(entry)
+	if err != nil {
+		return err.Error() + "\n"
+	}
+	if f.DisableLineBreak {
+		buffer.Truncate(buffer.Len() - 1)
+	}
+	return buffer.String()
+}
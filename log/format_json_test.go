@@ -0,0 +1,76 @@
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing/common/atomic"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatJSON(t *testing.T) {
+	t.Parallel()
+	timestamp := time.Date(2024, 1, 2, 3, 4, 5, 600000000, time.UTC)
+	formatter := Formatter{JSON: true}
+	require.Equal(t, `{"time":"2024-01-02T03:04:05.6Z","level":"info","tag":"router","message":"started"}`+"\n",
+		formatter.Format(context.Background(), LevelInfo, "router", "started\n", timestamp))
+	require.Equal(t, `{"time":"2024-01-02T03:04:05.6Z","level":"warn","message":"quote \" backslash \\ <tag> & new\nline\ttab \u0001"}`,
+		Formatter{JSON: true, DisableLineBreak: true}.Format(nil, LevelWarn, "", "quote \" backslash \\ <tag> & new\nline\ttab \x01", timestamp))
+}
+
+func TestFormatJSONConnection(t *testing.T) {
+	t.Parallel()
+	timestamp := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	ctx := context.WithValue(context.Background(), (*idKey)(nil), ID{
+		ID:        42,
+		CreatedAt: time.Now().Add(-1500 * time.Millisecond),
+		fields:    new(fieldsHolder),
+	})
+	formatter := Formatter{JSON: true, DisableLineBreak: true}
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal([]byte(formatter.Format(ctx, LevelDebug, "inbound/mixed[in]", "connection", timestamp)), &entry))
+	duration := entry["duration"]
+	require.GreaterOrEqual(t, duration, float64(1500))
+	delete(entry, "duration")
+	require.Equal(t, map[string]any{
+		"time":    "2024-01-02T03:04:05Z",
+		"level":   "debug",
+		"tag":     "inbound/mixed[in]",
+		"message": "connection",
+		"id":      float64(42),
+	}, entry)
+
+	// fields are shared by messages logged before and after they are updated
+	var upload, download atomic.Int64
+	upload.Store(1024)
+	UpdateFields(ctx, Fields{
+		Inbound:     "in",
+		Source:      "127.0.0.1:1234",
+		Destination: "[2001:db8::1]:443",
+		Domain:      "example.com",
+		Rule:        `domain_suffix=[".com"] => route(out)`,
+		Outbound:    "out",
+		Upload:      &upload,
+		Download:    &download,
+	})
+	entry = nil
+	require.NoError(t, json.Unmarshal([]byte(formatter.Format(ctx, LevelInfo, "", "closed", timestamp)), &entry))
+	delete(entry, "duration")
+	require.Equal(t, map[string]any{
+		"time":        "2024-01-02T03:04:05Z",
+		"level":       "info",
+		"message":     "closed",
+		"id":          float64(42),
+		"inbound":     "in",
+		"source":      "127.0.0.1:1234",
+		"destination": "[2001:db8::1]:443",
+		"domain":      "example.com",
+		"rule":        `domain_suffix=[".com"] => route(out)`,
+		"outbound":    "out",
+		"upload":      float64(1024),
+		"download":    float64(0),
+	}, entry)
+}
@@ -17,12 +17,14 @@ type idKey struct{}
 type ID struct {
 	ID        uint32
 	CreatedAt time.Time
+	fields    *fieldsHolder
 }
 
 func ContextWithNewID(ctx context.Context) context.Context {
 	return context.WithValue(ctx, (*idKey)(nil), ID{
 		ID:        rand.Uint32(),
 		CreatedAt: time.Now(),
+		fields:    new(fieldsHolder),
 	})
 }
 
@@ -49,6 +49,13 @@ func New(options Options) (Factory, error) {
 		FullTimestamp:    logOptions.Timestamp,
 		TimestampFormat:  "-0700 2006-01-02 15:04:05",
 	}
+	switch logOptions.Format {
+	case "", "text":
+	case "json":
+		logFormatter.JSON = true
+	default:
+		return nil, E.New("unknown log format: ", logOptions.Format)
+	}
 	factory := NewDefaultFactory(
 		options.Context,
 		logFormatter,
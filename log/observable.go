@@ -12,7 +12,10 @@ import (
 	"github.com/sagernet/sing/service/filemanager"
 )
 
-var _ Factory = (*defaultFactory)(nil)
+var (
+	_ Factory       = (*defaultFactory)(nil)
+	_ FieldsFactory = (*defaultFactory)(nil)
+)
 
 type defaultFactory struct {
 	ctx               context.Context
@@ -78,6 +81,10 @@ func (f *defaultFactory) Close() error {
 	)
 }
 
+func (f *defaultFactory) NeedFields() bool {
+	return f.formatter.JSON
+}
+
 func (f *defaultFactory) Level() Level {
 	return f.level
 }
@@ -14,6 +14,7 @@ type _Options struct {
 	NTP          *NTPOptions          `json:"ntp,omitempty"`
 	Inbounds     []Inbound            `json:"inbounds,omitempty"`
 	Outbounds    []Outbound           `json:"outbounds,omitempty"`
+	Providers    []OutboundProvider   `json:"providers,omitempty"`
 	Route        *RouteOptions        `json:"route,omitempty"`
 	Experimental *ExperimentalOptions `json:"experimental,omitempty"`
 }
@@ -36,5 +37,6 @@ type LogOptions struct {
 	Level        string `json:"level,omitempty"`
 	Output       string `json:"output,omitempty"`
 	Timestamp    bool   `json:"timestamp,omitempty"`
+	Format       string `json:"format,omitempty"`
 	DisableColor bool   `json:"-"`
 }
@@ -8,18 +8,40 @@ type DNSOptions struct {
 	Final          string             `json:"final,omitempty"`
 	ReverseMapping bool               `json:"reverse_mapping,omitempty"`
 	FakeIP         *DNSFakeIPOptions  `json:"fakeip,omitempty"`
+	TrustAnchors   Listable[string]   `json:"trust_anchors,omitempty"`
 	DNSClientOptions
 }
 
 type DNSServerOptions struct {
-	Tag                  string         `json:"tag,omitempty"`
-	Address              string         `json:"address"`
-	AddressResolver      string         `json:"address_resolver,omitempty"`
-	AddressStrategy      DomainStrategy `json:"address_strategy,omitempty"`
-	AddressFallbackDelay Duration       `json:"address_fallback_delay,omitempty"`
-	Strategy             DomainStrategy `json:"strategy,omitempty"`
-	Detour               string         `json:"detour,omitempty"`
-	ClientSubnet         *AddrPrefix    `json:"client_subnet,omitempty"`
+	Tag                  string                  `json:"tag,omitempty"`
+	Address              string                  `json:"address"`
+	AddressResolver      string                  `json:"address_resolver,omitempty"`
+	AddressStrategy      DomainStrategy          `json:"address_strategy,omitempty"`
+	AddressFallbackDelay Duration                `json:"address_fallback_delay,omitempty"`
+	Strategy             DomainStrategy          `json:"strategy,omitempty"`
+	Detour               string                  `json:"detour,omitempty"`
+	ClientSubnet         *AddrPrefix             `json:"client_subnet,omitempty"`
+	Hosts                *DNSHostsOptions        `json:"hosts,omitempty"`
+	Group                *DNSGroupOptions        `json:"group,omitempty"`
+	FakeIP               *DNSFakeIPServerOptions `json:"fakeip,omitempty"`
+	DNSSEC               bool                    `json:"dnssec,omitempty"`
+}
+
+type DNSGroupOptions struct {
+	Servers      Listable[string] `json:"servers"`
+	Mode         string           `json:"mode,omitempty"`
+	ProbePort    uint16           `json:"probe_port,omitempty"`
+	ProbeTimeout Duration         `json:"probe_timeout,omitempty"`
+}
+
+type DNSHostsOptions struct {
+	Path       Listable[string]                `json:"path,omitempty"`
+	Predefined map[string]Listable[netip.Addr] `json:"predefined,omitempty"`
+}
+
+type DNSPredefinedOptions struct {
+	RCode  string           `json:"rcode,omitempty"`
+	Answer Listable[string] `json:"answer,omitempty"`
 }
 
 type DNSClientOptions struct {
@@ -35,3 +57,18 @@ type DNSFakeIPOptions struct {
 	Inet4Range *netip.Prefix `json:"inet4_range,omitempty"`
 	Inet6Range *netip.Prefix `json:"inet6_range,omitempty"`
 }
+
+type DNSFakeIPServerOptions struct {
+	Inet4Range       *netip.Prefix          `json:"inet4_range,omitempty"`
+	Inet6Range       *netip.Prefix          `json:"inet6_range,omitempty"`
+	ExcludeRuleSet   Listable[string]       `json:"exclude_rule_set,omitempty"`
+	ExcludeQueryType Listable[DNSQueryType] `json:"exclude_query_type,omitempty"`
+}
+
+type DNSInboundOptions struct {
+	ListenOptions
+	Network  NetworkList `json:"network,omitempty"`
+	Protocol string      `json:"protocol,omitempty"`
+	Path     string      `json:"path,omitempty"`
+	InboundTLSOptionsContainer
+}
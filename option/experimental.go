@@ -4,16 +4,19 @@ type ExperimentalOptions struct {
 	CacheFile *CacheFileOptions `json:"cache_file,omitempty"`
 	ClashAPI  *ClashAPIOptions  `json:"clash_api,omitempty"`
 	V2RayAPI  *V2RayAPIOptions  `json:"v2ray_api,omitempty"`
+	Metrics   *MetricsOptions   `json:"metrics,omitempty"`
 	Debug     *DebugOptions     `json:"debug,omitempty"`
 }
 
 type CacheFileOptions struct {
-	Enabled     bool     `json:"enabled,omitempty"`
-	Path        string   `json:"path,omitempty"`
-	CacheID     string   `json:"cache_id,omitempty"`
-	StoreFakeIP bool     `json:"store_fakeip,omitempty"`
-	StoreRDRC   bool     `json:"store_rdrc,omitempty"`
-	RDRCTimeout Duration `json:"rdrc_timeout,omitempty"`
+	Enabled         bool     `json:"enabled,omitempty"`
+	Path            string   `json:"path,omitempty"`
+	CacheID         string   `json:"cache_id,omitempty"`
+	StoreFakeIP     bool     `json:"store_fakeip,omitempty"`
+	StoreRDRC       bool     `json:"store_rdrc,omitempty"`
+	RDRCTimeout     Duration `json:"rdrc_timeout,omitempty"`
+	StoreDNS        bool     `json:"store_dns,omitempty"`
+	DNSStaleTimeout Duration `json:"dns_stale_timeout,omitempty"`
 }
 
 type ClashAPIOptions struct {
@@ -38,8 +41,9 @@ type ClashAPIOptions struct {
 }
 
 type V2RayAPIOptions struct {
-	Listen string                    `json:"listen,omitempty"`
-	Stats  *V2RayStatsServiceOptions `json:"stats,omitempty"`
+	Listen  string                      `json:"listen,omitempty"`
+	Stats   *V2RayStatsServiceOptions   `json:"stats,omitempty"`
+	Handler *V2RayHandlerServiceOptions `json:"handler,omitempty"`
 }
 
 type V2RayStatsServiceOptions struct {
@@ -48,3 +52,13 @@ type V2RayStatsServiceOptions struct {
 	Outbounds []string `json:"outbounds,omitempty"`
 	Users     []string `json:"users,omitempty"`
 }
+
+type V2RayHandlerServiceOptions struct {
+	Enabled bool `json:"enabled,omitempty"`
+}
+
+type MetricsOptions struct {
+	Listen string `json:"listen,omitempty"`
+	Path   string `json:"path,omitempty"`
+	Secret string `json:"secret,omitempty"`
+}
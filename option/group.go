@@ -1,16 +1,37 @@
 package option
 
 type SelectorOutboundOptions struct {
-	Outbounds                 []string `json:"outbounds"`
+	Outbounds                 []string `json:"outbounds,omitempty"`
+	Providers                 []string `json:"providers,omitempty"`
 	Default                   string   `json:"default,omitempty"`
 	InterruptExistConnections bool     `json:"interrupt_exist_connections,omitempty"`
 }
 
 type URLTestOutboundOptions struct {
-	Outbounds                 []string `json:"outbounds"`
+	Outbounds                 []string `json:"outbounds,omitempty"`
+	Providers                 []string `json:"providers,omitempty"`
 	URL                       string   `json:"url,omitempty"`
 	Interval                  Duration `json:"interval,omitempty"`
 	Tolerance                 uint16   `json:"tolerance,omitempty"`
 	IdleTimeout               Duration `json:"idle_timeout,omitempty"`
 	InterruptExistConnections bool     `json:"interrupt_exist_connections,omitempty"`
 }
+
+type LoadBalanceOutboundOptions struct {
+	Outbounds   []string `json:"outbounds,omitempty"`
+	Providers   []string `json:"providers,omitempty"`
+	Strategy    string   `json:"strategy,omitempty"`
+	StickyTTL   Duration `json:"sticky_ttl,omitempty"`
+	URL         string   `json:"url,omitempty"`
+	Interval    Duration `json:"interval,omitempty"`
+	IdleTimeout Duration `json:"idle_timeout,omitempty"`
+}
+
+type FallbackOutboundOptions struct {
+	Outbounds   []string `json:"outbounds,omitempty"`
+	Providers   []string `json:"providers,omitempty"`
+	Timeout     Duration `json:"timeout,omitempty"`
+	URL         string   `json:"url,omitempty"`
+	Interval    Duration `json:"interval,omitempty"`
+	IdleTimeout Duration `json:"idle_timeout,omitempty"`
+}
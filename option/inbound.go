@@ -27,6 +27,9 @@ type _Inbound struct {
 	VLESSOptions       VLESSInboundOptions       `json:"-"`
 	TUICOptions        TUICInboundOptions        `json:"-"`
 	Hysteria2Options   Hysteria2InboundOptions   `json:"-"`
+	WireGuardOptions   WireGuardInboundOptions   `json:"-"`
+	SSHOptions         SSHInboundOptions         `json:"-"`
+	DNSOptions         DNSInboundOptions         `json:"-"`
 }
 
 type Inbound _Inbound
@@ -66,6 +69,12 @@ func (h *Inbound) RawOptions() (any, error) {
 		rawOptionsPtr = &h.TUICOptions
 	case C.TypeHysteria2:
 		rawOptionsPtr = &h.Hysteria2Options
+	case C.TypeWireGuard:
+		rawOptionsPtr = &h.WireGuardOptions
+	case C.TypeSSH:
+		rawOptionsPtr = &h.SSHOptions
+	case C.TypeDNS:
+		rawOptionsPtr = &h.DNSOptions
 	case "":
 		return nil, E.New("missing inbound type")
 	default:
@@ -73,3 +73,16 @@ func UnmarshallExcluded(inputContent []byte, parentObject any, object any) error
 	decoder.DisallowUnknownFields()
 	return decoder.Decode(object)
 }
+
+// Equal reports whether both options marshal to the same JSON.
+func Equal(oldOptions any, newOptions any) bool {
+	oldContent, err := json.Marshal(oldOptions)
+	if err != nil {
+		return false
+	}
+	newContent, err := json.Marshal(newOptions)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(oldContent, newContent)
+}
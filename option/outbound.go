@@ -10,6 +10,7 @@ import (
 type _Outbound struct {
 	Type                string                      `json:"type"`
 	Tag                 string                      `json:"tag,omitempty"`
+	BandwidthLimit      *BandwidthLimitOptions      `json:"bandwidth_limit,omitempty"`
 	DirectOptions       DirectOutboundOptions       `json:"-"`
 	SocksOptions        SocksOutboundOptions        `json:"-"`
 	HTTPOptions         HTTPOutboundOptions         `json:"-"`
@@ -27,6 +28,8 @@ type _Outbound struct {
 	Hysteria2Options    Hysteria2OutboundOptions    `json:"-"`
 	SelectorOptions     SelectorOutboundOptions     `json:"-"`
 	URLTestOptions      URLTestOutboundOptions      `json:"-"`
+	LoadBalanceOptions  LoadBalanceOutboundOptions  `json:"-"`
+	FallbackOptions     FallbackOutboundOptions     `json:"-"`
 }
 
 type Outbound _Outbound
@@ -70,6 +73,10 @@ func (h *Outbound) RawOptions() (any, error) {
 		rawOptionsPtr = &h.SelectorOptions
 	case C.TypeURLTest:
 		rawOptionsPtr = &h.URLTestOptions
+	case C.TypeLoadBalance:
+		rawOptionsPtr = &h.LoadBalanceOptions
+	case C.TypeFallback:
+		rawOptionsPtr = &h.FallbackOptions
 	case "":
 		return nil, E.New("missing outbound type")
 	default:
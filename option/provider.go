@@ -0,0 +1,19 @@
+package option
+
+type OutboundProvider struct {
+	Tag            string                       `json:"tag"`
+	URL            string                       `json:"url"`
+	Path           string                       `json:"path,omitempty"`
+	UserAgent      string                       `json:"user_agent,omitempty"`
+	DownloadDetour string                       `json:"download_detour,omitempty"`
+	UpdateInterval Duration                     `json:"update_interval,omitempty"`
+	Include        string                       `json:"include,omitempty"`
+	Exclude        string                       `json:"exclude,omitempty"`
+	HealthCheck    *OutboundProviderHealthCheck `json:"health_check,omitempty"`
+}
+
+type OutboundProviderHealthCheck struct {
+	Enabled  bool     `json:"enabled,omitempty"`
+	URL      string   `json:"url,omitempty"`
+	Interval Duration `json:"interval,omitempty"`
+}
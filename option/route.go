@@ -1,16 +1,31 @@
 package option
 
 type RouteOptions struct {
-	GeoIP               *GeoIPOptions   `json:"geoip,omitempty"`
-	Geosite             *GeositeOptions `json:"geosite,omitempty"`
-	Rules               []Rule          `json:"rules,omitempty"`
-	RuleSet             []RuleSet       `json:"rule_set,omitempty"`
-	Final               string          `json:"final,omitempty"`
-	FindProcess         bool            `json:"find_process,omitempty"`
-	AutoDetectInterface bool            `json:"auto_detect_interface,omitempty"`
-	OverrideAndroidVPN  bool            `json:"override_android_vpn,omitempty"`
-	DefaultInterface    string          `json:"default_interface,omitempty"`
-	DefaultMark         uint32          `json:"default_mark,omitempty"`
+	GeoIP               *GeoIPOptions     `json:"geoip,omitempty"`
+	Geosite             *GeositeOptions   `json:"geosite,omitempty"`
+	Rules               []Rule            `json:"rules,omitempty"`
+	RuleSet             []RuleSet         `json:"rule_set,omitempty"`
+	Final               string            `json:"final,omitempty"`
+	FindProcess         bool              `json:"find_process,omitempty"`
+	AutoDetectInterface bool              `json:"auto_detect_interface,omitempty"`
+	OverrideAndroidVPN  bool              `json:"override_android_vpn,omitempty"`
+	DefaultInterface    string            `json:"default_interface,omitempty"`
+	DefaultMark         uint32            `json:"default_mark,omitempty"`
+	AccessLog           *AccessLogOptions `json:"access_log,omitempty"`
+}
+
+type AccessLogOptions struct {
+	Enabled    bool                    `json:"enabled,omitempty"`
+	Output     string                  `json:"output,omitempty"`
+	MaxSize    MemoryBytes             `json:"max_size,omitempty"`
+	MaxBackups int                     `json:"max_backups,omitempty"`
+	Syslog     *AccessLogSyslogOptions `json:"syslog,omitempty"`
+}
+
+type AccessLogSyslogOptions struct {
+	Network string `json:"network,omitempty"`
+	Address string `json:"address,omitempty"`
+	Tag     string `json:"tag,omitempty"`
 }
 
 type GeoIPOptions struct {
@@ -70,6 +70,8 @@ type _DefaultRule struct {
 	Network                  Listable[string] `json:"network,omitempty"`
 	AuthUser                 Listable[string] `json:"auth_user,omitempty"`
 	Protocol                 Listable[string] `json:"protocol,omitempty"`
+	ClientALPN               Listable[string] `json:"client_alpn,omitempty"`
+	ClientFingerprint        Listable[string] `json:"client_fingerprint,omitempty"`
 	Domain                   Listable[string] `json:"domain,omitempty"`
 	DomainSuffix             Listable[string] `json:"domain_suffix,omitempty"`
 	DomainKeyword            Listable[string] `json:"domain_keyword,omitempty"`
@@ -97,6 +99,7 @@ type _DefaultRule struct {
 	RuleSetIPCIDRMatchSource bool             `json:"rule_set_ip_cidr_match_source,omitempty"`
 	Invert                   bool             `json:"invert,omitempty"`
 	Outbound                 string           `json:"outbound,omitempty"`
+	RuleAction
 
 	// Deprecated: renamed to rule_set_ip_cidr_match_source
 	Deprecated_RulesetIPCIDRMatchSource bool `json:"rule_set_ipcidr_match_source,omitempty"`
@@ -122,6 +125,7 @@ func (r *DefaultRule) IsValid() bool {
 	var defaultValue DefaultRule
 	defaultValue.Invert = r.Invert
 	defaultValue.Outbound = r.Outbound
+	defaultValue.RuleAction = r.RuleAction
 	return !reflect.DeepEqual(r, defaultValue)
 }
 
@@ -130,6 +134,7 @@ type LogicalRule struct {
 	Rules    []Rule `json:"rules,omitempty"`
 	Invert   bool   `json:"invert,omitempty"`
 	Outbound string `json:"outbound,omitempty"`
+	RuleAction
 }
 
 func (r LogicalRule) IsValid() bool {
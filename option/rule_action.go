@@ -0,0 +1,23 @@
+package option
+
+type RuleAction struct {
+	Action string `json:"action,omitempty"`
+
+	// route and route-options
+	OverrideAddress           string                 `json:"override_address,omitempty"`
+	OverridePort              uint16                 `json:"override_port,omitempty"`
+	UDPDisableDomainUnmapping bool                   `json:"udp_disable_domain_unmapping,omitempty"`
+	BandwidthLimit            *BandwidthLimitOptions `json:"bandwidth_limit,omitempty"`
+
+	// reject
+	Method string `json:"method,omitempty"`
+
+	// sniff
+	Sniffer             Listable[string] `json:"sniffer,omitempty"`
+	Timeout             Duration         `json:"timeout,omitempty"`
+	OverrideDestination bool             `json:"override_destination,omitempty"`
+
+	// resolve
+	Strategy DomainStrategy `json:"strategy,omitempty"`
+	Server   string         `json:"server,omitempty"`
+}
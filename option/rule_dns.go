@@ -98,11 +98,14 @@ type _DefaultDNSRule struct {
 	RuleSet                  Listable[string]       `json:"rule_set,omitempty"`
 	RuleSetIPCIDRMatchSource bool                   `json:"rule_set_ip_cidr_match_source,omitempty"`
 	RuleSetIPCIDRAcceptEmpty bool                   `json:"rule_set_ip_cidr_accept_empty,omitempty"`
+	DNSSECStatus             Listable[string]       `json:"dnssec_status,omitempty"`
 	Invert                   bool                   `json:"invert,omitempty"`
 	Server                   string                 `json:"server,omitempty"`
 	DisableCache             bool                   `json:"disable_cache,omitempty"`
 	RewriteTTL               *uint32                `json:"rewrite_ttl,omitempty"`
 	ClientSubnet             *AddrPrefix            `json:"client_subnet,omitempty"`
+	Predefined               *DNSPredefinedOptions  `json:"predefined,omitempty"`
+	DNSSEC                   bool                   `json:"dnssec,omitempty"`
 
 	// Deprecated: renamed to rule_set_ip_cidr_match_source
 	Deprecated_RulesetIPCIDRMatchSource bool `json:"rule_set_ipcidr_match_source,omitempty"`
@@ -131,17 +134,21 @@ func (r *DefaultDNSRule) IsValid() bool {
 	defaultValue.DisableCache = r.DisableCache
 	defaultValue.RewriteTTL = r.RewriteTTL
 	defaultValue.ClientSubnet = r.ClientSubnet
+	defaultValue.Predefined = r.Predefined
+	defaultValue.DNSSEC = r.DNSSEC
 	return !reflect.DeepEqual(r, defaultValue)
 }
 
 type LogicalDNSRule struct {
-	Mode         string      `json:"mode"`
-	Rules        []DNSRule   `json:"rules,omitempty"`
-	Invert       bool        `json:"invert,omitempty"`
-	Server       string      `json:"server,omitempty"`
-	DisableCache bool        `json:"disable_cache,omitempty"`
-	RewriteTTL   *uint32     `json:"rewrite_ttl,omitempty"`
-	ClientSubnet *AddrPrefix `json:"client_subnet,omitempty"`
+	Mode         string                `json:"mode"`
+	Rules        []DNSRule             `json:"rules,omitempty"`
+	Invert       bool                  `json:"invert,omitempty"`
+	Server       string                `json:"server,omitempty"`
+	DisableCache bool                  `json:"disable_cache,omitempty"`
+	RewriteTTL   *uint32               `json:"rewrite_ttl,omitempty"`
+	ClientSubnet *AddrPrefix           `json:"client_subnet,omitempty"`
+	Predefined   *DNSPredefinedOptions `json:"predefined,omitempty"`
+	DNSSEC       bool                  `json:"dnssec,omitempty"`
 }
 
 func (r LogicalDNSRule) IsValid() bool {
@@ -53,6 +53,7 @@ func (r *RuleSet) UnmarshalJSON(bytes []byte) error {
 		case "":
 			return E.New("missing format")
 		case C.RuleSetFormatSource, C.RuleSetFormatBinary:
+		case C.RuleSetFormatAdGuard, C.RuleSetFormatHosts, C.RuleSetFormatClashClassical, C.RuleSetFormatClashDomain, C.RuleSetFormatClashIPCIDR:
 		default:
 			return E.New("unknown rule-set format: " + r.Format)
 		}
@@ -144,24 +145,26 @@ func (r HeadlessRule) IsValid() bool {
 }
 
 type DefaultHeadlessRule struct {
-	QueryType       Listable[DNSQueryType] `json:"query_type,omitempty"`
-	Network         Listable[string]       `json:"network,omitempty"`
-	Domain          Listable[string]       `json:"domain,omitempty"`
-	DomainSuffix    Listable[string]       `json:"domain_suffix,omitempty"`
-	DomainKeyword   Listable[string]       `json:"domain_keyword,omitempty"`
-	DomainRegex     Listable[string]       `json:"domain_regex,omitempty"`
-	SourceIPCIDR    Listable[string]       `json:"source_ip_cidr,omitempty"`
-	IPCIDR          Listable[string]       `json:"ip_cidr,omitempty"`
-	SourcePort      Listable[uint16]       `json:"source_port,omitempty"`
-	SourcePortRange Listable[string]       `json:"source_port_range,omitempty"`
-	Port            Listable[uint16]       `json:"port,omitempty"`
-	PortRange       Listable[string]       `json:"port_range,omitempty"`
-	ProcessName     Listable[string]       `json:"process_name,omitempty"`
-	ProcessPath     Listable[string]       `json:"process_path,omitempty"`
-	PackageName     Listable[string]       `json:"package_name,omitempty"`
-	WIFISSID        Listable[string]       `json:"wifi_ssid,omitempty"`
-	WIFIBSSID       Listable[string]       `json:"wifi_bssid,omitempty"`
-	Invert          bool                   `json:"invert,omitempty"`
+	QueryType         Listable[DNSQueryType] `json:"query_type,omitempty"`
+	Network           Listable[string]       `json:"network,omitempty"`
+	Domain            Listable[string]       `json:"domain,omitempty"`
+	DomainSuffix      Listable[string]       `json:"domain_suffix,omitempty"`
+	DomainKeyword     Listable[string]       `json:"domain_keyword,omitempty"`
+	DomainRegex       Listable[string]       `json:"domain_regex,omitempty"`
+	SourceIPCIDR      Listable[string]       `json:"source_ip_cidr,omitempty"`
+	IPCIDR            Listable[string]       `json:"ip_cidr,omitempty"`
+	SourcePort        Listable[uint16]       `json:"source_port,omitempty"`
+	SourcePortRange   Listable[string]       `json:"source_port_range,omitempty"`
+	Port              Listable[uint16]       `json:"port,omitempty"`
+	PortRange         Listable[string]       `json:"port_range,omitempty"`
+	ProcessName       Listable[string]       `json:"process_name,omitempty"`
+	ProcessPath       Listable[string]       `json:"process_path,omitempty"`
+	PackageName       Listable[string]       `json:"package_name,omitempty"`
+	WIFISSID          Listable[string]       `json:"wifi_ssid,omitempty"`
+	WIFIBSSID         Listable[string]       `json:"wifi_bssid,omitempty"`
+	ClientALPN        Listable[string]       `json:"client_alpn,omitempty"`
+	ClientFingerprint Listable[string]       `json:"client_fingerprint,omitempty"`
+	Invert            bool                   `json:"invert,omitempty"`
 
 	DomainMatcher *domain.Matcher `json:"-"`
 	SourceIPSet   *netipx.IPSet   `json:"-"`
@@ -194,7 +197,7 @@ type PlainRuleSetCompat _PlainRuleSetCompat
 func (r PlainRuleSetCompat) MarshalJSON() ([]byte, error) {
 	var v any
 	switch r.Version {
-	case C.RuleSetVersion1:
+	case C.RuleSetVersion1, C.RuleSetVersion2:
 		v = r.Options
 	default:
 		return nil, E.New("unknown rule-set version: ", r.Version)
@@ -209,7 +212,7 @@ func (r *PlainRuleSetCompat) UnmarshalJSON(bytes []byte) error {
 	}
 	var v any
 	switch r.Version {
-	case C.RuleSetVersion1:
+	case C.RuleSetVersion1, C.RuleSetVersion2:
 		v = &r.Options
 	case 0:
 		return E.New("missing rule-set version")
@@ -225,13 +228,39 @@ func (r *PlainRuleSetCompat) UnmarshalJSON(bytes []byte) error {
 
 func (r PlainRuleSetCompat) Upgrade() (PlainRuleSet, error) {
 	switch r.Version {
-	case C.RuleSetVersion1:
+	case C.RuleSetVersion1, C.RuleSetVersion2:
 	default:
 		return PlainRuleSet{}, E.New("unknown rule-set version: " + F.ToString(r.Version))
 	}
+	if requiredVersion := r.Options.RequiredVersion(); requiredVersion > r.Version {
+		return PlainRuleSet{}, E.New("client_alpn and client_fingerprint require rule-set version ", requiredVersion)
+	}
 	return r.Options, nil
 }
 
 type PlainRuleSet struct {
 	Rules []HeadlessRule `json:"rules,omitempty"`
 }
+
+// RequiredVersion returns the lowest rule-set version supporting all rule items used.
+func (r PlainRuleSet) RequiredVersion() int {
+	version := C.RuleSetVersion1
+	for _, rule := range r.Rules {
+		if ruleVersion := rule.requiredVersion(); ruleVersion > version {
+			version = ruleVersion
+		}
+	}
+	return version
+}
+
+func (r HeadlessRule) requiredVersion() int {
+	switch r.Type {
+	case C.RuleTypeLogical:
+		return PlainRuleSet{Rules: r.LogicalOptions.Rules}.RequiredVersion()
+	default:
+		if len(r.DefaultOptions.ClientALPN) > 0 || len(r.DefaultOptions.ClientFingerprint) > 0 {
+			return C.RuleSetVersion2
+		}
+		return C.RuleSetVersion1
+	}
+}
@@ -14,6 +14,7 @@ type TUICUser struct {
 	Name     string `json:"name,omitempty"`
 	UUID     string `json:"uuid,omitempty"`
 	Password string `json:"password,omitempty"`
+	UserLimitOptions
 }
 
 type TUICOutboundOptions struct {
@@ -0,0 +1,14 @@
+package option
+
+type UserLimitOptions struct {
+	TrafficQuota   MemoryBytes            `json:"traffic_quota,omitempty"`
+	ExpireAt       string                 `json:"expire_at,omitempty"`
+	MaxConnections int                    `json:"max_connections,omitempty"`
+	MaxIPs         int                    `json:"max_ips,omitempty"`
+	BandwidthLimit *BandwidthLimitOptions `json:"bandwidth_limit,omitempty"`
+}
+
+type BandwidthLimitOptions struct {
+	UpMbps   int `json:"up_mbps,omitempty"`
+	DownMbps int `json:"down_mbps,omitempty"`
+}
@@ -26,3 +26,11 @@ type WireGuardPeer struct {
 	AllowedIPs   Listable[string] `json:"allowed_ips,omitempty"`
 	Reserved     []uint8          `json:"reserved,omitempty"`
 }
+
+type WireGuardInboundOptions struct {
+	ListenOptions
+	PrivateKey string          `json:"private_key"`
+	Peers      []WireGuardPeer `json:"peers,omitempty"`
+	Workers    int             `json:"workers,omitempty"`
+	MTU        uint32          `json:"mtu,omitempty"`
+}
@@ -4,13 +4,33 @@ import (
 	"context"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/ratelimit"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
 	E "github.com/sagernet/sing/common/exceptions"
 )
 
+type bandwidthLimitSetter interface {
+	setBandwidthLimiter(limiter *ratelimit.Limiter)
+}
+
 func New(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.Outbound) (adapter.Outbound, error) {
+	outbound, err := newOutbound(ctx, router, logger, tag, options)
+	if err != nil {
+		return nil, err
+	}
+	if options.BandwidthLimit != nil {
+		setter, isSetter := outbound.(bandwidthLimitSetter)
+		if !isSetter {
+			return nil, E.New("bandwidth limit is not supported by outbound type: ", options.Type)
+		}
+		setter.setBandwidthLimiter(ratelimit.NewLimiter(*options.BandwidthLimit))
+	}
+	return outbound, nil
+}
+
+func newOutbound(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.Outbound) (adapter.Outbound, error) {
 	var metadata *adapter.InboundContext
 	if tag != "" {
 		ctx, metadata = adapter.AppendContext(ctx)
@@ -59,6 +79,10 @@ func New(ctx context.Context, router adapter.Router, logger log.ContextLogger, t
 		return NewSelector(ctx, router, logger, tag, options.SelectorOptions)
 	case C.TypeURLTest:
 		return NewURLTest(ctx, router, logger, tag, options.URLTestOptions)
+	case C.TypeLoadBalance:
+		return NewLoadBalance(ctx, router, logger, tag, options.LoadBalanceOptions)
+	case C.TypeFallback:
+		return NewFallback(ctx, router, logger, tag, options.FallbackOptions)
 	default:
 		return nil, E.New("unknown outbound type: ", options.Type)
 	}
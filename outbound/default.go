@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/ratelimit"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
@@ -22,12 +23,13 @@ import (
 )
 
 type myOutboundAdapter struct {
-	protocol     string
-	network      []string
-	router       adapter.Router
-	logger       log.ContextLogger
-	tag          string
-	dependencies []string
+	protocol         string
+	network          []string
+	router           adapter.Router
+	logger           log.ContextLogger
+	tag              string
+	dependencies     []string
+	bandwidthLimiter *ratelimit.Limiter
 }
 
 func (a *myOutboundAdapter) Type() string {
@@ -46,6 +48,14 @@ func (a *myOutboundAdapter) Dependencies() []string {
 	return a.dependencies
 }
 
+func (a *myOutboundAdapter) BandwidthLimiter() *ratelimit.Limiter {
+	return a.bandwidthLimiter
+}
+
+func (a *myOutboundAdapter) setBandwidthLimiter(limiter *ratelimit.Limiter) {
+	a.bandwidthLimiter = limiter
+}
+
 func (a *myOutboundAdapter) NewError(ctx context.Context, err error) {
 	NewError(a.logger, ctx, err)
 }
@@ -0,0 +1,174 @@
+package outbound
+
+import (
+	"context"
+	"io"
+	"net"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+var (
+	_ adapter.Outbound                   = (*Fallback)(nil)
+	_ adapter.URLTestGroup               = (*Fallback)(nil)
+	_ adapter.PerConnectionOutboundGroup = (*Fallback)(nil)
+	_ adapter.InterfaceUpdateListener    = (*Fallback)(nil)
+)
+
+type Fallback struct {
+	groupAdapter
+	timeout time.Duration
+}
+
+func NewFallback(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.FallbackOutboundOptions) (*Fallback, error) {
+	groupAdapter, err := newGroupAdapter(ctx, router, logger, C.TypeFallback, tag, options.Outbounds, options.Providers)
+	if err != nil {
+		return nil, err
+	}
+	groupAdapter.link = options.URL
+	groupAdapter.interval = time.Duration(options.Interval)
+	groupAdapter.idleTimeout = time.Duration(options.IdleTimeout)
+	outbound := &Fallback{
+		groupAdapter: groupAdapter,
+		timeout:      time.Duration(options.Timeout),
+	}
+	if outbound.timeout == 0 {
+		outbound.timeout = C.TCPTimeout
+	}
+	return outbound, nil
+}
+
+func (s *Fallback) SelectPerConnection() {
+}
+
+func (s *Fallback) Now() string {
+	if s.group == nil {
+		return ""
+	}
+	outbounds := s.orderedOutbounds(N.NetworkTCP)
+	if len(outbounds) == 0 {
+		return ""
+	}
+	return outbounds[0].Tag()
+}
+
+func (s *Fallback) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	s.group.Touch()
+	outbounds := s.orderedOutbounds(N.NetworkName(network))
+	if len(outbounds) == 0 {
+		return nil, E.New("missing supported outbound")
+	}
+	var errors []error
+	for _, outbound := range outbounds {
+		detour := outbound
+		conn, err := dialWithTimeout(ctx, s.timeout, func() (net.Conn, error) {
+			return detour.DialContext(ctx, network, destination)
+		})
+		if err == nil {
+			return s.limitDialedConn(outbound, conn), nil
+		}
+		errors = append(errors, s.markFailed(ctx, outbound, err))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, E.Errors(errors...)
+}
+
+func (s *Fallback) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
+	s.group.Touch()
+	outbounds := s.orderedOutbounds(N.NetworkUDP)
+	if len(outbounds) == 0 {
+		return nil, E.New("missing supported outbound")
+	}
+	var errors []error
+	for _, outbound := range outbounds {
+		detour := outbound
+		conn, err := dialWithTimeout(ctx, s.timeout, func() (net.PacketConn, error) {
+			return detour.ListenPacket(ctx, destination)
+		})
+		if err == nil {
+			return s.limitDialedPacketConn(outbound, conn), nil
+		}
+		errors = append(errors, s.markFailed(ctx, outbound, err))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, E.Errors(errors...)
+}
+
+func (s *Fallback) NewConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
+	return NewConnection(ctx, s, conn, metadata)
+}
+
+func (s *Fallback) NewPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext) error {
+	return NewPacketConnection(ctx, s, conn, metadata)
+}
+
+// orderedOutbounds returns members supporting the network in configured order,
+// with members that passed the last check before the others.
+func (s *Fallback) orderedOutbounds(network string) []adapter.Outbound {
+	var availableOutbounds, unavailableOutbounds []adapter.Outbound
+	for _, detour := range s.group.Outbounds() {
+		if !common.Contains(detour.Network(), network) {
+			continue
+		}
+		if s.group.history.LoadURLTestHistory(RealTag(detour)) != nil {
+			availableOutbounds = append(availableOutbounds, detour)
+		} else {
+			unavailableOutbounds = append(unavailableOutbounds, detour)
+		}
+	}
+	return append(availableOutbounds, unavailableOutbounds...)
+}
+
+func (s *Fallback) markFailed(ctx context.Context, outbound adapter.Outbound, err error) error {
+	err = E.Cause(err, "outbound/", outbound.Type(), "[", outbound.Tag(), "]")
+	s.logger.DebugContext(ctx, err)
+	s.group.history.DeleteURLTestHistory(RealTag(outbound))
+	return err
+}
+
+type dialResult[T io.Closer] struct {
+	conn T
+	err  error
+}
+
+// dialWithTimeout waits for dial for at most timeout without cancelling its context,
+// since some outbounds bind the lifetime of the connection to it.
+// A connection established after the timeout is closed.
+func dialWithTimeout[T io.Closer](ctx context.Context, timeout time.Duration, dial func() (T, error)) (T, error) {
+	resultChan := make(chan dialResult[T], 1)
+	go func() {
+		conn, err := dial()
+		resultChan <- dialResult[T]{conn, err}
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	var err error
+	select {
+	case result := <-resultChan:
+		return result.conn, result.err
+	case <-timer.C:
+		err = E.New("dial timeout after ", timeout)
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	go func() {
+		result := <-resultChan
+		if result.err == nil {
+			result.conn.Close()
+		}
+	}()
+	var zero T
+	return zero, err
+}
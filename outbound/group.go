@@ -0,0 +1,188 @@
+package outbound
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/ratelimit"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/common/x/list"
+)
+
+// groupAdapter is shared by groups testing their members with a URLTestGroup.
+// Members are the configured outbounds followed by the outbounds of providers,
+// and are updated when a provider is updated.
+type groupAdapter struct {
+	myOutboundAdapter
+	ctx                          context.Context
+	tags                         []string
+	providerTags                 []string
+	providers                    []adapter.OutboundProvider
+	providerCallbacks            []*list.Element[adapter.OutboundProviderUpdateCallback]
+	link                         string
+	interval                     time.Duration
+	tolerance                    uint16
+	idleTimeout                  time.Duration
+	interruptExternalConnections bool
+	group                        *URLTestGroup
+}
+
+func newGroupAdapter(ctx context.Context, router adapter.Router, logger log.ContextLogger, protocol string, tag string, outbounds []string, providers []string) (groupAdapter, error) {
+	if len(outbounds) == 0 && len(providers) == 0 {
+		return groupAdapter{}, E.New("missing tags")
+	}
+	return groupAdapter{
+		myOutboundAdapter: myOutboundAdapter{
+			protocol:     protocol,
+			network:      []string{N.NetworkTCP, N.NetworkUDP},
+			router:       router,
+			logger:       logger,
+			tag:          tag,
+			dependencies: outbounds,
+		},
+		ctx:          ctx,
+		tags:         outbounds,
+		providerTags: providers,
+	}, nil
+}
+
+func (s *groupAdapter) Start() error {
+	outbounds := make([]adapter.Outbound, 0, len(s.tags))
+	for i, tag := range s.tags {
+		detour, loaded := s.router.Outbound(tag)
+		if !loaded {
+			return E.New("outbound ", i, " not found: ", tag)
+		}
+		outbounds = append(outbounds, detour)
+	}
+	for i, tag := range s.providerTags {
+		provider, loaded := s.router.OutboundProvider(tag)
+		if !loaded {
+			return E.New("provider ", i, " not found: ", tag)
+		}
+		s.providers = append(s.providers, provider)
+	}
+	outbounds = s.appendProviderOutbounds(outbounds)
+	group, err := NewURLTestGroup(
+		s.ctx,
+		s.router,
+		s.logger,
+		outbounds,
+		s.link,
+		s.interval,
+		s.tolerance,
+		s.idleTimeout,
+		s.interruptExternalConnections,
+	)
+	if err != nil {
+		return err
+	}
+	s.group = group
+	for _, provider := range s.providers {
+		s.providerCallbacks = append(s.providerCallbacks, provider.RegisterCallback(s.providerUpdated))
+	}
+	return nil
+}
+
+func (s *groupAdapter) PostStart() error {
+	s.group.PostStart()
+	return nil
+}
+
+func (s *groupAdapter) Close() error {
+	for i, provider := range s.providers {
+		provider.UnregisterCallback(s.providerCallbacks[i])
+	}
+	return common.Close(
+		common.PtrOrNil(s.group),
+	)
+}
+
+func (s *groupAdapter) appendProviderOutbounds(outbounds []adapter.Outbound) []adapter.Outbound {
+	for _, provider := range s.providers {
+		for _, detour := range provider.Outbounds() {
+			if common.Any(outbounds, func(it adapter.Outbound) bool {
+				return it.Tag() == detour.Tag()
+			}) {
+				continue
+			}
+			outbounds = append(outbounds, detour)
+		}
+	}
+	return outbounds
+}
+
+func (s *groupAdapter) providerUpdated(_ adapter.OutboundProvider) {
+	outbounds := make([]adapter.Outbound, 0, len(s.tags))
+	for _, tag := range s.tags {
+		detour, loaded := s.router.Outbound(tag)
+		if loaded {
+			outbounds = append(outbounds, detour)
+		}
+	}
+	s.group.SetOutbounds(s.appendProviderOutbounds(outbounds))
+}
+
+// memberBandwidthLimiters returns the limiters of the member selected for a connection
+// and of the outbounds currently selected through it.
+func (s *groupAdapter) memberBandwidthLimiters(member adapter.Outbound) []*ratelimit.Limiter {
+	var limiters []*ratelimit.Limiter
+	for {
+		if limitedOutbound, isLimited := member.(adapter.BandwidthLimitedOutbound); isLimited {
+			if limiter := limitedOutbound.BandwidthLimiter(); limiter != nil {
+				limiters = append(limiters, limiter)
+			}
+		}
+		if _, isPerConnection := member.(adapter.PerConnectionOutboundGroup); isPerConnection {
+			return limiters
+		}
+		group, isGroup := member.(adapter.OutboundGroup)
+		if !isGroup {
+			return limiters
+		}
+		next, loaded := s.router.Outbound(group.Now())
+		if !loaded || next == member {
+			return limiters
+		}
+		member = next
+	}
+}
+
+func (s *groupAdapter) limitDialedConn(member adapter.Outbound, conn net.Conn) net.Conn {
+	for _, limiter := range s.memberBandwidthLimiters(member) {
+		conn = limiter.NewDialedConn(conn)
+	}
+	return conn
+}
+
+func (s *groupAdapter) limitDialedPacketConn(member adapter.Outbound, conn net.PacketConn) net.PacketConn {
+	for _, limiter := range s.memberBandwidthLimiters(member) {
+		conn = limiter.NewDialedPacketConn(conn)
+	}
+	return conn
+}
+
+func (s *groupAdapter) All() []string {
+	if s.group == nil {
+		return s.tags
+	}
+	return common.Map(s.group.Outbounds(), adapter.Outbound.Tag)
+}
+
+func (s *groupAdapter) URLTest(ctx context.Context) (map[string]uint16, error) {
+	return s.group.URLTest(ctx)
+}
+
+func (s *groupAdapter) CheckOutbounds() {
+	s.group.CheckOutbounds(true)
+}
+
+func (s *groupAdapter) InterfaceUpdated() {
+	go s.group.CheckOutbounds(true)
+	return
+}
@@ -0,0 +1,200 @@
+package outbound
+
+import (
+	"context"
+	"hash/fnv"
+	"net"
+	"net/netip"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/atomic"
+	"github.com/sagernet/sing/common/cache"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+var (
+	_ adapter.Outbound                   = (*LoadBalance)(nil)
+	_ adapter.URLTestGroup               = (*LoadBalance)(nil)
+	_ adapter.PerConnectionOutboundGroup = (*LoadBalance)(nil)
+	_ adapter.InterfaceUpdateListener    = (*LoadBalance)(nil)
+)
+
+type LoadBalance struct {
+	groupAdapter
+	strategy        string
+	roundRobinIndex atomic.Uint32
+	stickyCache     *cache.LruCache[netip.Addr, string]
+	lastSelected    atomic.TypedValue[string]
+}
+
+func NewLoadBalance(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.LoadBalanceOutboundOptions) (*LoadBalance, error) {
+	groupAdapter, err := newGroupAdapter(ctx, router, logger, C.TypeLoadBalance, tag, options.Outbounds, options.Providers)
+	if err != nil {
+		return nil, err
+	}
+	groupAdapter.link = options.URL
+	groupAdapter.interval = time.Duration(options.Interval)
+	groupAdapter.idleTimeout = time.Duration(options.IdleTimeout)
+	outbound := &LoadBalance{
+		groupAdapter: groupAdapter,
+		strategy:     options.Strategy,
+	}
+	switch outbound.strategy {
+	case "":
+		outbound.strategy = C.LoadBalanceStrategyRoundRobin
+	case C.LoadBalanceStrategyRoundRobin, C.LoadBalanceStrategyConsistentHashing:
+	case C.LoadBalanceStrategyStickySessions:
+		stickyTTL := time.Duration(options.StickyTTL)
+		if stickyTTL == 0 {
+			stickyTTL = C.DefaultLoadBalanceStickyTTL
+		}
+		outbound.stickyCache = cache.New[netip.Addr, string](
+			cache.WithAge[netip.Addr, string](int64(stickyTTL.Seconds())),
+			cache.WithUpdateAgeOnGet[netip.Addr, string](),
+		)
+	default:
+		return nil, E.New("unknown load balance strategy: ", outbound.strategy)
+	}
+	return outbound, nil
+}
+
+func (s *LoadBalance) SelectPerConnection() {
+}
+
+func (s *LoadBalance) Now() string {
+	if selected := s.lastSelected.Load(); selected != "" {
+		return selected
+	}
+	if s.group == nil {
+		return ""
+	}
+	outbounds := s.availableOutbounds(N.NetworkTCP)
+	if len(outbounds) == 0 {
+		return ""
+	}
+	return outbounds[0].Tag()
+}
+
+func (s *LoadBalance) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	s.group.Touch()
+	outbound, err := s.selectOutbound(ctx, N.NetworkName(network), destination)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := outbound.DialContext(ctx, network, destination)
+	if err == nil {
+		return s.limitDialedConn(outbound, conn), nil
+	}
+	s.logger.ErrorContext(ctx, err)
+	s.group.history.DeleteURLTestHistory(RealTag(outbound))
+	return nil, err
+}
+
+func (s *LoadBalance) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
+	s.group.Touch()
+	outbound, err := s.selectOutbound(ctx, N.NetworkUDP, destination)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := outbound.ListenPacket(ctx, destination)
+	if err == nil {
+		return s.limitDialedPacketConn(outbound, conn), nil
+	}
+	s.logger.ErrorContext(ctx, err)
+	s.group.history.DeleteURLTestHistory(RealTag(outbound))
+	return nil, err
+}
+
+func (s *LoadBalance) NewConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
+	return NewConnection(ctx, s, conn, metadata)
+}
+
+func (s *LoadBalance) NewPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext) error {
+	return NewPacketConnection(ctx, s, conn, metadata)
+}
+
+// availableOutbounds returns members supporting the network that passed the last check,
+// or all members supporting the network if none did.
+func (s *LoadBalance) availableOutbounds(network string) []adapter.Outbound {
+	outbounds := common.Filter(s.group.Outbounds(), func(it adapter.Outbound) bool {
+		return common.Contains(it.Network(), network)
+	})
+	availableOutbounds := common.Filter(outbounds, func(it adapter.Outbound) bool {
+		return s.group.history.LoadURLTestHistory(RealTag(it)) != nil
+	})
+	if len(availableOutbounds) > 0 {
+		return availableOutbounds
+	}
+	return outbounds
+}
+
+func (s *LoadBalance) selectOutbound(ctx context.Context, network string, destination M.Socksaddr) (adapter.Outbound, error) {
+	outbounds := s.availableOutbounds(network)
+	if len(outbounds) == 0 {
+		return nil, E.New("missing supported outbound")
+	}
+	var selected adapter.Outbound
+	metadata := adapter.ContextFrom(ctx)
+	switch s.strategy {
+	case C.LoadBalanceStrategyConsistentHashing:
+		var key string
+		if metadata != nil && metadata.Domain != "" {
+			key = metadata.Domain
+		} else if destination.IsFqdn() {
+			key = destination.Fqdn
+		} else {
+			key = destination.Addr.String()
+		}
+		selected = selectByHash(outbounds, key)
+	case C.LoadBalanceStrategyStickySessions:
+		if metadata != nil && metadata.Source.Addr.IsValid() {
+			selected = s.selectSticky(outbounds, metadata.Source.Addr)
+		}
+	}
+	if selected == nil {
+		selected = outbounds[int(s.roundRobinIndex.Add(1)-1)%len(outbounds)]
+	}
+	s.lastSelected.Store(selected.Tag())
+	return selected, nil
+}
+
+func (s *LoadBalance) selectSticky(outbounds []adapter.Outbound, source netip.Addr) adapter.Outbound {
+	if tag, loaded := s.stickyCache.Load(source); loaded {
+		for _, detour := range outbounds {
+			if detour.Tag() == tag {
+				return detour
+			}
+		}
+	}
+	selected := selectByHash(outbounds, source.String())
+	s.stickyCache.Store(source, selected.Tag())
+	return selected
+}
+
+// selectByHash picks the member with the highest hash of key and tag (rendezvous hashing),
+// so that only keys of a removed member are moved to other members.
+func selectByHash(outbounds []adapter.Outbound, key string) adapter.Outbound {
+	var (
+		selected adapter.Outbound
+		maxScore uint64
+	)
+	for _, detour := range outbounds {
+		hash := fnv.New64a()
+		hash.Write([]byte(key))
+		hash.Write([]byte{0})
+		hash.Write([]byte(detour.Tag()))
+		score := hash.Sum64()
+		if selected == nil || score > maxScore {
+			selected = detour
+			maxScore = score
+		}
+	}
+	return selected
+}
@@ -0,0 +1,200 @@
+package outbound
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/ratelimit"
+	"github.com/sagernet/sing-box/common/urltest"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	F "github.com/sagernet/sing/common/format"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestMembers(count int) []adapter.Outbound {
+	members := make([]adapter.Outbound, 0, count)
+	for i := 0; i < count; i++ {
+		members = append(members, NewBlock(log.NewNOPFactory().Logger(), F.ToString("member-", i)))
+	}
+	return members
+}
+
+func newTestLoadBalance(t *testing.T, strategy string, members []adapter.Outbound) *LoadBalance {
+	ctx := service.ContextWithPtr(context.Background(), urltest.NewHistoryStorage())
+	tags := make([]string, 0, len(members))
+	for _, member := range members {
+		tags = append(tags, member.Tag())
+	}
+	loadBalance, err := NewLoadBalance(ctx, nil, log.NewNOPFactory().Logger(), "load-balance", option.LoadBalanceOutboundOptions{
+		Outbounds: tags,
+		Strategy:  strategy,
+	})
+	require.NoError(t, err)
+	loadBalance.group, err = NewURLTestGroup(ctx, nil, log.NewNOPFactory().Logger(), members, "", 0, 0, 0, false)
+	require.NoError(t, err)
+	return loadBalance
+}
+
+type pipeOutbound struct {
+	myOutboundAdapter
+	dialed net.Conn
+}
+
+func newPipeOutbound(tag string, limit option.BandwidthLimitOptions) *pipeOutbound {
+	outbound := &pipeOutbound{myOutboundAdapter: myOutboundAdapter{
+		protocol: "pipe",
+		network:  []string{N.NetworkTCP},
+		tag:      tag,
+	}}
+	outbound.setBandwidthLimiter(ratelimit.NewLimiter(limit))
+	return outbound
+}
+
+func (h *pipeOutbound) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	conn, _ := net.Pipe()
+	h.dialed = conn
+	return conn, nil
+}
+
+func (h *pipeOutbound) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
+	return nil, os.ErrInvalid
+}
+
+func (h *pipeOutbound) NewConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
+	return os.ErrInvalid
+}
+
+func (h *pipeOutbound) NewPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext) error {
+	return os.ErrInvalid
+}
+
+func contextWithSource(source string) context.Context {
+	return adapter.WithContext(context.Background(), &adapter.InboundContext{
+		Source: M.SocksaddrFrom(netip.MustParseAddr(source), 10000),
+	})
+}
+
+func TestSelectByHashStability(t *testing.T) {
+	t.Parallel()
+	members := newTestMembers(5)
+	const keyCount = 1000
+	selected := make([]adapter.Outbound, keyCount)
+	counts := make(map[adapter.Outbound]int)
+	for i := range selected {
+		selected[i] = selectByHash(members, F.ToString("key-", i))
+		counts[selected[i]]++
+		require.Equal(t, selected[i], selectByHash(members, F.ToString("key-", i)))
+	}
+	for _, member := range members {
+		require.Greater(t, counts[member], keyCount/len(members)/2, member.Tag())
+	}
+	removed := members[2]
+	remaining := append(append([]adapter.Outbound(nil), members[:2]...), members[3:]...)
+	for i := range selected {
+		reselected := selectByHash(remaining, F.ToString("key-", i))
+		if selected[i] != removed {
+			require.Equal(t, selected[i], reselected, "key-", i)
+		} else {
+			require.NotEqual(t, removed, reselected)
+		}
+	}
+}
+
+func TestLoadBalanceRoundRobin(t *testing.T) {
+	t.Parallel()
+	members := newTestMembers(3)
+	loadBalance := newTestLoadBalance(t, "", members)
+	destination := M.ParseSocksaddrHostPort("example.com", 443)
+	for i := 0; i < 6; i++ {
+		selected, err := loadBalance.selectOutbound(context.Background(), N.NetworkTCP, destination)
+		require.NoError(t, err)
+		require.Equal(t, members[i%len(members)], selected)
+		require.Equal(t, selected.Tag(), loadBalance.Now())
+	}
+
+	// members that passed the last check are preferred
+	loadBalance.group.history.StoreURLTestHistory(members[1].Tag(), &urltest.History{Time: time.Now(), Delay: 100})
+	for i := 0; i < 3; i++ {
+		selected, err := loadBalance.selectOutbound(context.Background(), N.NetworkTCP, destination)
+		require.NoError(t, err)
+		require.Equal(t, members[1], selected)
+	}
+}
+
+func TestLoadBalanceStickySessions(t *testing.T) {
+	t.Parallel()
+	members := newTestMembers(4)
+	loadBalance := newTestLoadBalance(t, C.LoadBalanceStrategyStickySessions, members)
+	sources := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4", "10.0.0.5", "10.0.0.6"}
+	selected := make(map[string]adapter.Outbound)
+	for _, source := range sources {
+		for _, destination := range []string{"example.com", "example.org"} {
+			outbound, err := loadBalance.selectOutbound(contextWithSource(source), N.NetworkTCP, M.ParseSocksaddrHostPort(destination, 443))
+			require.NoError(t, err)
+			if selected[source] == nil {
+				selected[source] = outbound
+			}
+			require.Equal(t, selected[source], outbound, source)
+		}
+	}
+
+	// a source stays on its member after more members become available
+	sticky := selected[sources[0]]
+	for _, member := range members {
+		if member != sticky {
+			loadBalance.group.history.StoreURLTestHistory(member.Tag(), &urltest.History{Time: time.Now(), Delay: 100})
+		}
+	}
+	loadBalance.group.history.StoreURLTestHistory(sticky.Tag(), &urltest.History{Time: time.Now(), Delay: 100})
+	outbound, err := loadBalance.selectOutbound(contextWithSource(sources[0]), N.NetworkTCP, M.ParseSocksaddrHostPort("example.net", 443))
+	require.NoError(t, err)
+	require.Equal(t, sticky, outbound)
+
+	// and is moved once its member fails
+	loadBalance.group.history.DeleteURLTestHistory(sticky.Tag())
+	outbound, err = loadBalance.selectOutbound(contextWithSource(sources[0]), N.NetworkTCP, M.ParseSocksaddrHostPort("example.net", 443))
+	require.NoError(t, err)
+	require.NotEqual(t, sticky, outbound)
+}
+
+func TestLoadBalanceConsistentHashing(t *testing.T) {
+	t.Parallel()
+	members := newTestMembers(4)
+	loadBalance := newTestLoadBalance(t, C.LoadBalanceStrategyConsistentHashing, members)
+	ctx := adapter.WithContext(context.Background(), &adapter.InboundContext{Domain: "example.com"})
+	first, err := loadBalance.selectOutbound(ctx, N.NetworkTCP, M.ParseSocksaddrHostPort("93.184.216.34", 443))
+	require.NoError(t, err)
+	second, err := loadBalance.selectOutbound(context.Background(), N.NetworkUDP, M.ParseSocksaddrHostPort("example.com", 443))
+	require.NoError(t, err)
+	require.Equal(t, first, second)
+	require.Equal(t, selectByHash(members, "example.com"), first)
+}
+
+func TestLoadBalanceMemberBandwidthLimit(t *testing.T) {
+	t.Parallel()
+	limited := newPipeOutbound("limited", option.BandwidthLimitOptions{UpMbps: 10})
+	unlimited := newPipeOutbound("unlimited", option.BandwidthLimitOptions{})
+	loadBalance := newTestLoadBalance(t, "", []adapter.Outbound{limited, unlimited})
+	destination := M.ParseSocksaddrHostPort("example.com", 443)
+
+	// limits of the member selected for the connection are applied, not of the last selected one
+	conn, err := loadBalance.DialContext(context.Background(), N.NetworkTCP, destination)
+	require.NoError(t, err)
+	require.NotEqual(t, limited.dialed, conn)
+	require.Equal(t, limited.dialed, common.Top(conn))
+	conn, err = loadBalance.DialContext(context.Background(), N.NetworkTCP, destination)
+	require.NoError(t, err)
+	require.Equal(t, unlimited.dialed, conn)
+}
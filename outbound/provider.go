@@ -0,0 +1,492 @@
+package outbound
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/urltest"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/atomic"
+	"github.com/sagernet/sing/common/batch"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+	"github.com/sagernet/sing/common/json"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/common/x/list"
+	"github.com/sagernet/sing/service"
+	"github.com/sagernet/sing/service/filemanager"
+	"github.com/sagernet/sing/service/pause"
+)
+
+var _ adapter.OutboundProvider = (*Provider)(nil)
+
+type Provider struct {
+	ctx                 context.Context
+	cancel              context.CancelFunc
+	router              adapter.Router
+	logFactory          log.Factory
+	logger              log.ContextLogger
+	options             option.OutboundProvider
+	path                string
+	updateInterval      time.Duration
+	healthCheckURL      string
+	healthCheckInterval time.Duration
+	include             *regexp.Regexp
+	exclude             *regexp.Regexp
+	dialer              N.Dialer
+	history             *urltest.HistoryStorage
+	pauseManager        pause.Manager
+	access              sync.RWMutex
+	outbounds           []adapter.Outbound
+	outboundByTag       map[string]adapter.Outbound
+	outboundOptions     map[string]option.Outbound
+	started             bool
+	lastUpdated         time.Time
+	lastEtag            string
+	updating            atomic.Bool
+	callbackAccess      sync.Mutex
+	callbacks           list.List[adapter.OutboundProviderUpdateCallback]
+}
+
+func NewProvider(ctx context.Context, router adapter.Router, logFactory log.Factory, options option.OutboundProvider) (*Provider, error) {
+	if options.Tag == "" {
+		return nil, E.New("missing tag")
+	}
+	if options.URL == "" && options.Path == "" {
+		return nil, E.New("missing url or path")
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	provider := &Provider{
+		ctx:             ctx,
+		cancel:          cancel,
+		router:          router,
+		logFactory:      logFactory,
+		logger:          logFactory.NewLogger(F.ToString("provider[", options.Tag, "]")),
+		options:         options,
+		pauseManager:    service.FromContext[pause.Manager](ctx),
+		outboundByTag:   make(map[string]adapter.Outbound),
+		outboundOptions: make(map[string]option.Outbound),
+	}
+	if options.Path != "" {
+		provider.path = filemanager.BasePath(ctx, os.ExpandEnv(options.Path))
+	}
+	if options.UpdateInterval > 0 {
+		provider.updateInterval = time.Duration(options.UpdateInterval)
+	} else {
+		provider.updateInterval = 24 * time.Hour
+	}
+	if options.HealthCheck != nil && options.HealthCheck.Enabled {
+		provider.healthCheckURL = options.HealthCheck.URL
+		if options.HealthCheck.Interval > 0 {
+			provider.healthCheckInterval = time.Duration(options.HealthCheck.Interval)
+		} else {
+			provider.healthCheckInterval = C.DefaultURLTestInterval
+		}
+	}
+	var err error
+	if options.Include != "" {
+		provider.include, err = regexp.Compile(options.Include)
+		if err != nil {
+			return nil, E.Cause(err, "parse include")
+		}
+	}
+	if options.Exclude != "" {
+		provider.exclude, err = regexp.Compile(options.Exclude)
+		if err != nil {
+			return nil, E.Cause(err, "parse exclude")
+		}
+	}
+	return provider, nil
+}
+
+func (p *Provider) Tag() string {
+	return p.options.Tag
+}
+
+func (p *Provider) Outbounds() []adapter.Outbound {
+	p.access.RLock()
+	defer p.access.RUnlock()
+	return p.outbounds
+}
+
+func (p *Provider) Outbound(tag string) (adapter.Outbound, bool) {
+	p.access.RLock()
+	defer p.access.RUnlock()
+	outbound, loaded := p.outboundByTag[tag]
+	return outbound, loaded
+}
+
+func (p *Provider) UpdatedAt() time.Time {
+	p.access.RLock()
+	defer p.access.RUnlock()
+	return p.lastUpdated
+}
+
+func (p *Provider) Start() error {
+	if p.path == "" {
+		return nil
+	}
+	content, err := os.ReadFile(p.path)
+	if err != nil {
+		if os.IsNotExist(err) && p.options.URL != "" {
+			return nil
+		}
+		return E.Cause(err, "read provider file")
+	}
+	err = p.loadBytes(content)
+	if err != nil {
+		return E.Cause(err, "load provider file")
+	}
+	fileInfo, err := os.Stat(p.path)
+	if err == nil {
+		p.lastUpdated = fileInfo.ModTime()
+	}
+	return nil
+}
+
+func (p *Provider) PostStart() error {
+	if p.history = service.PtrFromContext[urltest.HistoryStorage](p.ctx); p.history != nil {
+	} else if clashServer := p.router.ClashServer(); clashServer != nil {
+		p.history = clashServer.HistoryStorage()
+	} else {
+		p.history = urltest.NewHistoryStorage()
+	}
+	p.access.Lock()
+	p.started = true
+	outbounds := p.outbounds
+	p.access.Unlock()
+	for _, outbound := range outbounds {
+		if lateOutbound, isLateOutbound := outbound.(adapter.PostStarter); isLateOutbound {
+			err := lateOutbound.PostStart()
+			if err != nil {
+				p.logger.Error("post-start outbound[", outbound.Tag(), "]: ", err)
+			}
+		}
+	}
+	if p.healthCheckInterval > 0 {
+		go p.loopHealthCheck()
+	}
+	if p.options.URL == "" {
+		return nil
+	}
+	if p.options.DownloadDetour != "" {
+		outbound, loaded := p.router.Outbound(p.options.DownloadDetour)
+		if !loaded {
+			return E.New("download_detour not found: ", p.options.DownloadDetour)
+		}
+		p.dialer = outbound
+	} else {
+		outbound, err := p.router.DefaultOutbound(N.NetworkTCP)
+		if err != nil {
+			return err
+		}
+		p.dialer = outbound
+	}
+	if len(p.Outbounds()) == 0 {
+		err := p.fetchOnce(p.ctx)
+		if err != nil {
+			p.logger.Error("initial provider ", p.options.Tag, ": ", err)
+		}
+	}
+	go p.loopUpdate()
+	return nil
+}
+
+func (p *Provider) RegisterCallback(callback adapter.OutboundProviderUpdateCallback) *list.Element[adapter.OutboundProviderUpdateCallback] {
+	p.callbackAccess.Lock()
+	defer p.callbackAccess.Unlock()
+	return p.callbacks.PushBack(callback)
+}
+
+func (p *Provider) UnregisterCallback(element *list.Element[adapter.OutboundProviderUpdateCallback]) {
+	p.callbackAccess.Lock()
+	defer p.callbackAccess.Unlock()
+	p.callbacks.Remove(element)
+}
+
+func (p *Provider) Update(ctx context.Context) error {
+	if p.options.URL == "" {
+		return E.New("provider ", p.options.Tag, " has no url to update from")
+	}
+	if p.dialer == nil {
+		return E.New("provider ", p.options.Tag, " not started")
+	}
+	return p.fetchOnce(ctx)
+}
+
+func (p *Provider) HealthCheck(ctx context.Context) (map[string]uint16, error) {
+	result := make(map[string]uint16)
+	if p.history == nil {
+		return result, nil
+	}
+	b, _ := batch.New(ctx, batch.WithConcurrencyNum[any](10))
+	var resultAccess sync.Mutex
+	for _, detour := range p.Outbounds() {
+		tag := detour.Tag()
+		detour := detour
+		b.Go(tag, func() (any, error) {
+			testCtx, cancel := context.WithTimeout(ctx, C.TCPTimeout)
+			defer cancel()
+			t, err := urltest.URLTest(testCtx, p.healthCheckURL, detour)
+			if err != nil {
+				p.logger.Debug("outbound ", tag, " unavailable: ", err)
+				p.history.DeleteURLTestHistory(tag)
+			} else {
+				p.logger.Debug("outbound ", tag, " available: ", t, "ms")
+				p.history.StoreURLTestHistory(tag, &urltest.History{
+					Time:  time.Now(),
+					Delay: t,
+				})
+				resultAccess.Lock()
+				result[tag] = t
+				resultAccess.Unlock()
+			}
+			return nil, nil
+		})
+	}
+	b.Wait()
+	return result, nil
+}
+
+func (p *Provider) loopUpdate() {
+	if time.Since(p.UpdatedAt()) > p.updateInterval {
+		err := p.fetchOnce(p.ctx)
+		if err != nil {
+			p.logger.Error("fetch provider ", p.options.Tag, ": ", err)
+		}
+	}
+	ticker := time.NewTicker(p.updateInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-ticker.C:
+			p.pauseManager.WaitActive()
+			err := p.fetchOnce(p.ctx)
+			if err != nil {
+				p.logger.Error("fetch provider ", p.options.Tag, ": ", err)
+			}
+		}
+	}
+}
+
+func (p *Provider) loopHealthCheck() {
+	ticker := time.NewTicker(p.healthCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-ticker.C:
+			p.pauseManager.WaitActive()
+			_, _ = p.HealthCheck(p.ctx)
+		}
+	}
+}
+
+func (p *Provider) fetchOnce(ctx context.Context) error {
+	if p.updating.Swap(true) {
+		return E.New("provider ", p.options.Tag, " is updating")
+	}
+	defer p.updating.Store(false)
+	p.logger.Debug("updating provider ", p.options.Tag, " from URL: ", p.options.URL)
+	httpClient := &http.Client{
+		Transport: &http.Transport{
+			ForceAttemptHTTP2:   true,
+			TLSHandshakeTimeout: C.TCPTimeout,
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return p.dialer.DialContext(ctx, network, M.ParseSocksaddr(addr))
+			},
+		},
+	}
+	defer httpClient.CloseIdleConnections()
+	request, err := http.NewRequest("GET", p.options.URL, nil)
+	if err != nil {
+		return err
+	}
+	if p.options.UserAgent != "" {
+		request.Header.Set("User-Agent", p.options.UserAgent)
+	} else {
+		request.Header.Set("User-Agent", "sing-box "+C.Version)
+	}
+	if p.lastEtag != "" {
+		request.Header.Set("If-None-Match", p.lastEtag)
+	}
+	response, err := httpClient.Do(request.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	switch response.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		p.access.Lock()
+		p.lastUpdated = time.Now()
+		p.access.Unlock()
+		p.logger.Info("update provider ", p.options.Tag, ": not modified")
+		return nil
+	default:
+		return E.New("unexpected status: ", response.Status)
+	}
+	content, err := io.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+	err = p.loadBytes(content)
+	if err != nil {
+		return err
+	}
+	p.lastEtag = response.Header.Get("Etag")
+	if p.path != "" {
+		err = filemanager.WriteFile(p.ctx, p.path, content, 0o644)
+		if err != nil {
+			p.logger.Error("save provider file: ", err)
+		}
+	}
+	p.logger.Info("updated provider ", p.options.Tag, ", ", len(p.Outbounds()), " outbounds")
+	return nil
+}
+
+func (p *Provider) loadBytes(content []byte) error {
+	outboundOptions, err := parseProviderContent(p.logger, content)
+	if err != nil {
+		return err
+	}
+	var (
+		outbounds          []adapter.Outbound
+		outboundByTag      = make(map[string]adapter.Outbound)
+		newOutboundOptions = make(map[string]option.Outbound)
+	)
+	p.access.RLock()
+	started := p.started
+	oldOutboundByTag := p.outboundByTag
+	oldOutboundOptions := p.outboundOptions
+	p.access.RUnlock()
+	for i, options := range outboundOptions {
+		switch options.Type {
+		case C.TypeDirect, C.TypeBlock, C.TypeDNS, C.TypeSelector, C.TypeURLTest, C.TypeLoadBalance, C.TypeFallback:
+			continue
+		}
+		if options.Tag == "" {
+			options.Tag = F.ToString(p.options.Tag, "/", i)
+		}
+		if p.include != nil && !p.include.MatchString(options.Tag) {
+			continue
+		}
+		if p.exclude != nil && p.exclude.MatchString(options.Tag) {
+			continue
+		}
+		if _, exists := outboundByTag[options.Tag]; exists {
+			p.logger.Warn("skip duplicate outbound tag: ", options.Tag)
+			continue
+		}
+		if oldOutbound, loaded := oldOutboundByTag[options.Tag]; loaded && option.Equal(oldOutboundOptions[options.Tag], options) {
+			// keep unchanged outbounds, so that groups selecting them are not interrupted
+			outbounds = append(outbounds, oldOutbound)
+			outboundByTag[options.Tag] = oldOutbound
+			newOutboundOptions[options.Tag] = options
+			continue
+		}
+		outbound, err := New(p.ctx, p.router, p.logFactory.NewLogger(F.ToString("outbound/", options.Type, "[", options.Tag, "]")), options.Tag, options)
+		if err != nil {
+			p.logger.Warn("skip outbound[", options.Tag, "]: ", err)
+			continue
+		}
+		if starter, isStarter := outbound.(interface {
+			Start() error
+		}); isStarter {
+			err = starter.Start()
+			if err != nil {
+				common.Close(outbound)
+				p.logger.Warn("skip outbound[", options.Tag, "]: ", err)
+				continue
+			}
+		}
+		if lateOutbound, isLateOutbound := outbound.(adapter.PostStarter); isLateOutbound && started {
+			err = lateOutbound.PostStart()
+			if err != nil {
+				common.Close(outbound)
+				p.logger.Warn("skip outbound[", options.Tag, "]: ", err)
+				continue
+			}
+		}
+		outbounds = append(outbounds, outbound)
+		outboundByTag[options.Tag] = outbound
+		newOutboundOptions[options.Tag] = options
+	}
+	p.access.Lock()
+	oldOutbounds := p.outbounds
+	p.outbounds = outbounds
+	p.outboundByTag = outboundByTag
+	p.outboundOptions = newOutboundOptions
+	p.lastUpdated = time.Now()
+	p.access.Unlock()
+	p.callbackAccess.Lock()
+	callbacks := p.callbacks.Array()
+	p.callbackAccess.Unlock()
+	for _, callback := range callbacks {
+		callback(p)
+	}
+	for _, outbound := range oldOutbounds {
+		if outboundByTag[outbound.Tag()] != outbound {
+			common.Close(outbound)
+		}
+	}
+	return nil
+}
+
+func (p *Provider) Close() error {
+	p.cancel()
+	p.access.Lock()
+	outbounds := p.outbounds
+	p.outbounds = nil
+	p.outboundByTag = make(map[string]adapter.Outbound)
+	p.outboundOptions = make(map[string]option.Outbound)
+	p.access.Unlock()
+	var err error
+	for _, outbound := range outbounds {
+		err = E.Append(err, common.Close(outbound), func(err error) error {
+			return E.Cause(err, "close outbound/", outbound.Type(), "[", outbound.Tag(), "]")
+		})
+	}
+	return err
+}
+
+type providerContent struct {
+	Outbounds []option.Outbound `json:"outbounds"`
+}
+
+func parseProviderContent(logger log.ContextLogger, content []byte) ([]option.Outbound, error) {
+	content = bytes.TrimSpace(content)
+	if decoded, err := decodeBase64(string(content)); err == nil {
+		content = bytes.TrimSpace(decoded)
+	}
+	if len(content) == 0 {
+		return nil, E.New("empty provider content")
+	}
+	if firstLine, _, _ := bytes.Cut(content, []byte("\n")); isShareLink(string(firstLine)) {
+		return parseShareLinks(logger, content)
+	}
+	if content[0] == '{' {
+		var plainContent providerContent
+		err := json.Unmarshal(content, &plainContent)
+		if err != nil {
+			return nil, E.Cause(err, "decode sing-box outbounds")
+		}
+		return plainContent.Outbounds, nil
+	}
+	return parseClashProxies(logger, content)
+}
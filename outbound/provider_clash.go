@@ -0,0 +1,332 @@
+package outbound
+
+import (
+	"strconv"
+	"strings"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	N "github.com/sagernet/sing/common/network"
+
+	"gopkg.in/yaml.v3"
+)
+
+type clashProxy map[string]any
+
+type clashConfig struct {
+	Proxies []clashProxy `yaml:"proxies"`
+}
+
+func parseClashProxies(logger log.ContextLogger, content []byte) ([]option.Outbound, error) {
+	var config clashConfig
+	err := yaml.Unmarshal(content, &config)
+	if err != nil {
+		return nil, E.Cause(err, "decode clash proxies")
+	}
+	if len(config.Proxies) == 0 {
+		return nil, E.New("missing proxies")
+	}
+	var outbounds []option.Outbound
+	for i, proxy := range config.Proxies {
+		outbound, err := proxy.build()
+		if err != nil {
+			logger.Warn("skip proxies[", i, "]: ", err)
+			continue
+		}
+		if outbound == nil {
+			continue
+		}
+		outbounds = append(outbounds, *outbound)
+	}
+	return outbounds, nil
+}
+
+func (p clashProxy) build() (*option.Outbound, error) {
+	outbound := option.Outbound{
+		Tag: p.string("name"),
+	}
+	if outbound.Tag == "" {
+		return nil, E.New("missing name")
+	}
+	serverOptions := option.ServerOptions{
+		Server:     p.string("server"),
+		ServerPort: uint16(p.int("port")),
+	}
+	var network option.NetworkList
+	if !p.bool("udp") {
+		network = N.NetworkTCP
+	}
+	switch p.string("type") {
+	case "ss":
+		outbound.Type = C.TypeShadowsocks
+		outbound.ShadowsocksOptions = option.ShadowsocksOutboundOptions{
+			ServerOptions: serverOptions,
+			Method:        p.string("cipher"),
+			Password:      p.string("password"),
+			Network:       network,
+		}
+		pluginOptions := p.object("plugin-opts")
+		switch p.string("plugin") {
+		case "":
+		case "obfs":
+			outbound.ShadowsocksOptions.Plugin = "obfs-local"
+			outbound.ShadowsocksOptions.PluginOptions = "obfs=" + pluginOptions.string("mode")
+			if host := pluginOptions.string("host"); host != "" {
+				outbound.ShadowsocksOptions.PluginOptions += ";obfs-host=" + host
+			}
+		case "v2ray-plugin":
+			pluginArgs := []string{"mode=" + pluginOptions.string("mode")}
+			if host := pluginOptions.string("host"); host != "" {
+				pluginArgs = append(pluginArgs, "host="+host)
+			}
+			if path := pluginOptions.string("path"); path != "" {
+				pluginArgs = append(pluginArgs, "path="+path)
+			}
+			if pluginOptions.bool("tls") {
+				pluginArgs = append(pluginArgs, "tls")
+			}
+			outbound.ShadowsocksOptions.Plugin = "v2ray-plugin"
+			outbound.ShadowsocksOptions.PluginOptions = strings.Join(pluginArgs, ";")
+		default:
+			return nil, E.New("unsupported shadowsocks plugin: ", p.string("plugin"))
+		}
+	case "vmess":
+		outbound.Type = C.TypeVMess
+		outbound.VMessOptions = option.VMessOutboundOptions{
+			ServerOptions:               serverOptions,
+			UUID:                        p.string("uuid"),
+			Security:                    p.string("cipher"),
+			AlterId:                     p.int("alterId"),
+			Network:                     network,
+			OutboundTLSOptionsContainer: p.tlsOptions(false),
+			Transport:                   p.transportOptions(),
+		}
+		if outbound.VMessOptions.Security == "" {
+			outbound.VMessOptions.Security = "auto"
+		}
+	case "vless":
+		outbound.Type = C.TypeVLESS
+		outbound.VLESSOptions = option.VLESSOutboundOptions{
+			ServerOptions:               serverOptions,
+			UUID:                        p.string("uuid"),
+			Flow:                        p.string("flow"),
+			Network:                     network,
+			OutboundTLSOptionsContainer: p.tlsOptions(false),
+			Transport:                   p.transportOptions(),
+		}
+	case "trojan":
+		outbound.Type = C.TypeTrojan
+		outbound.TrojanOptions = option.TrojanOutboundOptions{
+			ServerOptions:               serverOptions,
+			Password:                    p.string("password"),
+			Network:                     network,
+			OutboundTLSOptionsContainer: p.tlsOptions(true),
+			Transport:                   p.transportOptions(),
+		}
+	case "socks5":
+		outbound.Type = C.TypeSOCKS
+		outbound.SocksOptions = option.SocksOutboundOptions{
+			ServerOptions: serverOptions,
+			Username:      p.string("username"),
+			Password:      p.string("password"),
+			Network:       network,
+		}
+	case "http":
+		outbound.Type = C.TypeHTTP
+		outbound.HTTPOptions = option.HTTPOutboundOptions{
+			ServerOptions:               serverOptions,
+			Username:                    p.string("username"),
+			Password:                    p.string("password"),
+			OutboundTLSOptionsContainer: p.tlsOptions(false),
+		}
+	case "hysteria2":
+		outbound.Type = C.TypeHysteria2
+		outbound.Hysteria2Options = option.Hysteria2OutboundOptions{
+			ServerOptions:               serverOptions,
+			UpMbps:                      p.mbps("up"),
+			DownMbps:                    p.mbps("down"),
+			Password:                    p.string("password"),
+			OutboundTLSOptionsContainer: p.tlsOptions(true),
+		}
+		if obfsType := p.string("obfs"); obfsType != "" {
+			outbound.Hysteria2Options.Obfs = &option.Hysteria2Obfs{
+				Type:     obfsType,
+				Password: p.string("obfs-password"),
+			}
+		}
+	case "tuic":
+		outbound.Type = C.TypeTUIC
+		outbound.TUICOptions = option.TUICOutboundOptions{
+			ServerOptions:               serverOptions,
+			UUID:                        p.string("uuid"),
+			Password:                    p.string("password"),
+			CongestionControl:           p.string("congestion-controller"),
+			UDPRelayMode:                p.string("udp-relay-mode"),
+			ZeroRTTHandshake:            p.bool("reduce-rtt"),
+			OutboundTLSOptionsContainer: p.tlsOptions(true),
+		}
+	default:
+		return nil, nil
+	}
+	return &outbound, nil
+}
+
+func (p clashProxy) tlsOptions(force bool) option.OutboundTLSOptionsContainer {
+	if !force && !p.bool("tls") {
+		return option.OutboundTLSOptionsContainer{}
+	}
+	tlsOptions := &option.OutboundTLSOptions{
+		Enabled:    true,
+		ServerName: p.string("servername"),
+		Insecure:   p.bool("skip-cert-verify"),
+		ALPN:       p.strings("alpn"),
+	}
+	if tlsOptions.ServerName == "" {
+		tlsOptions.ServerName = p.string("sni")
+	}
+	if fingerprint := p.string("client-fingerprint"); fingerprint != "" {
+		tlsOptions.UTLS = &option.OutboundUTLSOptions{
+			Enabled:     true,
+			Fingerprint: fingerprint,
+		}
+	}
+	if realityOptions := p.object("reality-opts"); realityOptions != nil {
+		tlsOptions.Reality = &option.OutboundRealityOptions{
+			Enabled:   true,
+			PublicKey: realityOptions.string("public-key"),
+			ShortID:   realityOptions.string("short-id"),
+		}
+	}
+	return option.OutboundTLSOptionsContainer{TLS: tlsOptions}
+}
+
+func (p clashProxy) transportOptions() *option.V2RayTransportOptions {
+	switch p.string("network") {
+	case "ws":
+		wsOptions := p.object("ws-opts")
+		return &option.V2RayTransportOptions{
+			Type: C.V2RayTransportTypeWebsocket,
+			WebsocketOptions: option.V2RayWebsocketOptions{
+				Path:                wsOptions.string("path"),
+				Headers:             wsOptions.headers("headers"),
+				MaxEarlyData:        uint32(wsOptions.int("max-early-data")),
+				EarlyDataHeaderName: wsOptions.string("early-data-header-name"),
+			},
+		}
+	case "grpc":
+		return &option.V2RayTransportOptions{
+			Type: C.V2RayTransportTypeGRPC,
+			GRPCOptions: option.V2RayGRPCOptions{
+				ServiceName: p.object("grpc-opts").string("grpc-service-name"),
+			},
+		}
+	case "h2":
+		h2Options := p.object("h2-opts")
+		return &option.V2RayTransportOptions{
+			Type: C.V2RayTransportTypeHTTP,
+			HTTPOptions: option.V2RayHTTPOptions{
+				Host: h2Options.strings("host"),
+				Path: h2Options.string("path"),
+			},
+		}
+	case "http":
+		httpOptions := p.object("http-opts")
+		var path string
+		if paths := httpOptions.strings("path"); len(paths) > 0 {
+			path = paths[0]
+		}
+		return &option.V2RayTransportOptions{
+			Type: C.V2RayTransportTypeHTTP,
+			HTTPOptions: option.V2RayHTTPOptions{
+				Method:  httpOptions.string("method"),
+				Path:    path,
+				Headers: httpOptions.headers("headers"),
+			},
+		}
+	default:
+		return nil
+	}
+}
+
+func (p clashProxy) string(key string) string {
+	switch value := p[key].(type) {
+	case string:
+		return value
+	case int:
+		return strconv.Itoa(value)
+	default:
+		return ""
+	}
+}
+
+func (p clashProxy) int(key string) int {
+	switch value := p[key].(type) {
+	case int:
+		return value
+	case string:
+		intValue, _ := strconv.Atoi(value)
+		return intValue
+	default:
+		return 0
+	}
+}
+
+func (p clashProxy) bool(key string) bool {
+	value, _ := p[key].(bool)
+	return value
+}
+
+func (p clashProxy) strings(key string) option.Listable[string] {
+	switch value := p[key].(type) {
+	case string:
+		return option.Listable[string]{value}
+	case []any:
+		var values option.Listable[string]
+		for _, item := range value {
+			if stringItem, isString := item.(string); isString {
+				values = append(values, stringItem)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+func (p clashProxy) object(key string) clashProxy {
+	// yaml.v3 decodes nested mappings into the type of the parent map
+	switch value := p[key].(type) {
+	case clashProxy:
+		return value
+	case map[string]any:
+		return value
+	default:
+		return nil
+	}
+}
+
+func (p clashProxy) headers(key string) option.HTTPHeader {
+	object := p.object(key)
+	if len(object) == 0 {
+		return nil
+	}
+	headers := make(option.HTTPHeader)
+	for name := range object {
+		headers[name] = object.strings(name)
+	}
+	return headers
+}
+
+func (p clashProxy) mbps(key string) int {
+	value := strings.TrimSpace(p.string(key))
+	end := strings.IndexFunc(value, func(r rune) bool {
+		return r < '0' || r > '9'
+	})
+	if end >= 0 {
+		value = value[:end]
+	}
+	mbps, _ := strconv.Atoi(value)
+	return mbps
+}
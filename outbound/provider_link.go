@@ -0,0 +1,308 @@
+package outbound
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+	"github.com/sagernet/sing/common/json"
+)
+
+// decodeBase64 decodes subscriptions served as base64, which are commonly wrapped and unpadded.
+func decodeBase64(content string) ([]byte, error) {
+	content = strings.NewReplacer("\r", "", "\n", "").Replace(strings.TrimSpace(content))
+	var err error
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		var decoded []byte
+		decoded, err = encoding.DecodeString(content)
+		if err == nil {
+			return decoded, nil
+		}
+	}
+	return nil, err
+}
+
+func isShareLink(line string) bool {
+	index := strings.Index(line, "://")
+	if index <= 0 {
+		return false
+	}
+	for _, r := range line[:index] {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// parseShareLinks parses a list of proxy share links, one per line.
+// Links are converted to Clash proxies, so that both formats share the same options.
+func parseShareLinks(logger log.ContextLogger, content []byte) ([]option.Outbound, error) {
+	var outbounds []option.Outbound
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for i := 0; scanner.Scan(); i++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		proxy, err := parseShareLink(line)
+		if err == nil && proxy == nil {
+			err = E.New("unsupported scheme")
+		}
+		var outbound *option.Outbound
+		if err == nil {
+			outbound, err = proxy.build()
+		}
+		if err != nil {
+			logger.Warn("skip links[", i, "]: ", err)
+			continue
+		}
+		if outbound == nil {
+			continue
+		}
+		outbounds = append(outbounds, *outbound)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, E.Cause(err, "read share links")
+	}
+	if len(outbounds) == 0 {
+		return nil, E.New("missing supported share links")
+	}
+	return outbounds, nil
+}
+
+func parseShareLink(link string) (clashProxy, error) {
+	scheme := link[:strings.Index(link, "://")]
+	if scheme == "vmess" {
+		return parseVMessLink(link)
+	}
+	linkURL, err := url.Parse(link)
+	if err != nil {
+		return nil, err
+	}
+	query := linkURL.Query()
+	proxy := clashProxy{
+		"name":   linkURL.Fragment,
+		"server": linkURL.Hostname(),
+		"port":   linkURL.Port(),
+		"udp":    true,
+	}
+	switch scheme {
+	case "ss":
+		proxy["type"] = "ss"
+		err = parseShadowsocksUserInfo(proxy, linkURL)
+		if err != nil {
+			return nil, err
+		}
+		if plugin := query.Get("plugin"); plugin != "" {
+			parseShadowsocksPlugin(proxy, plugin)
+		}
+	case "trojan":
+		proxy["type"] = "trojan"
+		proxy["password"] = linkURL.User.Username()
+		parseLinkTransport(proxy, query)
+	case "vless":
+		proxy["type"] = "vless"
+		proxy["uuid"] = linkURL.User.Username()
+		proxy["flow"] = query.Get("flow")
+		switch query.Get("security") {
+		case "tls":
+			proxy["tls"] = true
+		case "reality":
+			proxy["tls"] = true
+			proxy["reality-opts"] = map[string]any{
+				"public-key": query.Get("pbk"),
+				"short-id":   query.Get("sid"),
+			}
+		}
+		parseLinkTransport(proxy, query)
+	case "hysteria2", "hy2":
+		proxy["type"] = "hysteria2"
+		proxy["password"] = linkURL.User.String()
+		proxy["sni"] = query.Get("sni")
+		proxy["skip-cert-verify"] = query.Get("insecure") == "1"
+		proxy["obfs"] = query.Get("obfs")
+		proxy["obfs-password"] = query.Get("obfs-password")
+	default:
+		return nil, nil
+	}
+	if proxy.string("name") == "" {
+		proxy["name"] = net.JoinHostPort(proxy.string("server"), proxy.string("port"))
+	}
+	return proxy, nil
+}
+
+// parseShadowsocksUserInfo parses both SIP002 links with base64 encoded or plain user info,
+// and legacy links with the whole address base64 encoded.
+func parseShadowsocksUserInfo(proxy clashProxy, linkURL *url.URL) error {
+	if linkURL.User == nil {
+		decoded, err := decodeBase64(linkURL.Host)
+		if err != nil {
+			return E.Cause(err, "decode legacy shadowsocks link")
+		}
+		legacyURL, err := url.Parse("ss://" + string(decoded))
+		if err != nil {
+			return err
+		}
+		proxy["server"] = legacyURL.Hostname()
+		proxy["port"] = legacyURL.Port()
+		linkURL = legacyURL
+	}
+	method := linkURL.User.Username()
+	password, hasPassword := linkURL.User.Password()
+	if !hasPassword {
+		decoded, err := decodeBase64(method)
+		if err != nil {
+			return E.Cause(err, "decode shadowsocks user info")
+		}
+		var found bool
+		method, password, found = strings.Cut(string(decoded), ":")
+		if !found {
+			return E.New("missing shadowsocks password")
+		}
+	}
+	proxy["cipher"] = method
+	proxy["password"] = password
+	return nil
+}
+
+// parseShadowsocksPlugin converts SIP003 plugin arguments to Clash plugin options.
+func parseShadowsocksPlugin(proxy clashProxy, plugin string) {
+	arguments := strings.Split(plugin, ";")
+	pluginOptions := make(map[string]any)
+	for _, argument := range arguments[1:] {
+		key, value, _ := strings.Cut(argument, "=")
+		switch key {
+		case "obfs":
+			pluginOptions["mode"] = value
+		case "obfs-host":
+			pluginOptions["host"] = value
+		case "tls":
+			pluginOptions["tls"] = true
+		default:
+			pluginOptions[key] = value
+		}
+	}
+	switch arguments[0] {
+	case "obfs-local", "simple-obfs":
+		proxy["plugin"] = "obfs"
+	default:
+		proxy["plugin"] = arguments[0]
+	}
+	proxy["plugin-opts"] = pluginOptions
+}
+
+func parseLinkTransport(proxy clashProxy, query url.Values) {
+	if serverName := query.Get("sni"); serverName != "" {
+		proxy["servername"] = serverName
+	}
+	if query.Get("allowInsecure") == "1" || query.Get("insecure") == "1" {
+		proxy["skip-cert-verify"] = true
+	}
+	if alpn := query.Get("alpn"); alpn != "" {
+		proxy["alpn"] = stringsToAny(strings.Split(alpn, ","))
+	}
+	if fingerprint := query.Get("fp"); fingerprint != "" {
+		proxy["client-fingerprint"] = fingerprint
+	}
+	network := query.Get("type")
+	proxy["network"] = network
+	switch network {
+	case "ws":
+		wsOptions := map[string]any{"path": query.Get("path")}
+		if host := query.Get("host"); host != "" {
+			wsOptions["headers"] = map[string]any{"Host": host}
+		}
+		proxy["ws-opts"] = wsOptions
+	case "grpc":
+		proxy["grpc-opts"] = map[string]any{"grpc-service-name": query.Get("serviceName")}
+	case "h2":
+		proxy["h2-opts"] = map[string]any{
+			"host": stringsToAny(strings.Split(query.Get("host"), ",")),
+			"path": query.Get("path"),
+		}
+	}
+}
+
+type vmessLink struct {
+	Name        string `json:"ps"`
+	Server      string `json:"add"`
+	Port        any    `json:"port"`
+	UUID        string `json:"id"`
+	AlterID     any    `json:"aid"`
+	Security    string `json:"scy"`
+	Network     string `json:"net"`
+	Host        string `json:"host"`
+	Path        string `json:"path"`
+	TLS         string `json:"tls"`
+	ServerName  string `json:"sni"`
+	ALPN        string `json:"alpn"`
+	Fingerprint string `json:"fp"`
+}
+
+// parseVMessLink parses the base64 encoded JSON links of V2RayN.
+func parseVMessLink(link string) (clashProxy, error) {
+	decoded, err := decodeBase64(strings.TrimPrefix(link, "vmess://"))
+	if err != nil {
+		return nil, E.Cause(err, "decode vmess link")
+	}
+	var vmess vmessLink
+	err = json.Unmarshal(decoded, &vmess)
+	if err != nil {
+		return nil, E.Cause(err, "decode vmess link")
+	}
+	query := url.Values{}
+	query.Set("type", vmess.Network)
+	query.Set("host", vmess.Host)
+	query.Set("path", vmess.Path)
+	query.Set("serviceName", vmess.Path)
+	query.Set("sni", vmess.ServerName)
+	query.Set("alpn", vmess.ALPN)
+	query.Set("fp", vmess.Fingerprint)
+	proxy := clashProxy{
+		"type":    "vmess",
+		"name":    vmess.Name,
+		"server":  vmess.Server,
+		"port":    jsonNumberString(vmess.Port),
+		"uuid":    vmess.UUID,
+		"alterId": jsonNumberString(vmess.AlterID),
+		"cipher":  vmess.Security,
+		"tls":     vmess.TLS == "tls",
+		"udp":     true,
+	}
+	parseLinkTransport(proxy, query)
+	if proxy.string("name") == "" {
+		proxy["name"] = net.JoinHostPort(vmess.Server, proxy.string("port"))
+	}
+	return proxy, nil
+}
+
+// jsonNumberString returns numbers that V2RayN links encode either as strings or numbers.
+func jsonNumberString(value any) string {
+	switch number := value.(type) {
+	case string:
+		return number
+	case float64:
+		return F.ToString(int(number))
+	default:
+		return ""
+	}
+}
+
+func stringsToAny(values []string) []any {
+	result := make([]any, 0, len(values))
+	for _, value := range values {
+		if value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
@@ -0,0 +1,203 @@
+package outbound
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testClashContent = `
+mixed-port: 7890
+allow-lan: false
+proxies:
+  - name: "HK 01"
+    type: ss
+    server: hk01.example.com
+    port: 8388
+    cipher: aes-256-gcm
+    password: "p@ss"
+    udp: true
+    plugin: obfs
+    plugin-opts:
+      mode: tls
+      host: bing.com
+  - name: "HK 02"
+    type: ss
+    server: hk02.example.com
+    port: 8388
+    cipher: aes-128-gcm
+    password: secret
+    plugin: shadow-tls
+    plugin-opts:
+      host: cloud.tencent.com
+      password: secret
+  - name: "JP 01"
+    type: vmess
+    server: jp01.example.com
+    port: 443
+    uuid: b831381d-6324-4d53-ad4f-8cda48b30811
+    alterId: 0
+    cipher: auto
+    tls: true
+    servername: jp01.example.com
+    network: ws
+    ws-opts:
+      path: /ray
+      headers:
+        Host: jp01.example.com
+  - name: "US 01"
+    type: trojan
+    server: us01.example.com
+    port: 443
+    password: trojan-password
+    sni: us01.example.com
+    skip-cert-verify: true
+    network: grpc
+    grpc-opts:
+      grpc-service-name: grpc
+  - name: "SG 01"
+    type: vless
+    server: sg01.example.com
+    port: 443
+    uuid: b831381d-6324-4d53-ad4f-8cda48b30811
+    flow: xtls-rprx-vision
+    tls: true
+    servername: www.microsoft.com
+    client-fingerprint: chrome
+    reality-opts:
+      public-key: Z84J2IelR9ch3k8VtlVhhs5ycBUlXA7wHBWcBrjqnAw
+      short-id: 6ba85179e30d4fc2
+  - name: "Snell"
+    type: snell
+    server: snell.example.com
+    port: 443
+proxy-groups:
+  - name: Proxy
+    type: select
+    proxies: ["HK 01", "JP 01"]
+`
+
+func TestParseClashProxies(t *testing.T) {
+	t.Parallel()
+	outbounds, err := parseProviderContent(log.NewNOPFactory().Logger(), []byte(testClashContent))
+	require.NoError(t, err)
+	require.Equal(t, []string{"HK 01", "JP 01", "US 01", "SG 01"}, outboundTags(outbounds))
+
+	shadowsocks := outbounds[0].ShadowsocksOptions
+	require.Equal(t, C.TypeShadowsocks, outbounds[0].Type)
+	require.Equal(t, "hk01.example.com", shadowsocks.Server)
+	require.Equal(t, uint16(8388), shadowsocks.ServerPort)
+	require.Equal(t, "aes-256-gcm", shadowsocks.Method)
+	require.Equal(t, "p@ss", shadowsocks.Password)
+	require.Equal(t, "obfs-local", shadowsocks.Plugin)
+	require.Equal(t, "obfs=tls;obfs-host=bing.com", shadowsocks.PluginOptions)
+	require.Empty(t, shadowsocks.Network)
+
+	vmess := outbounds[1].VMessOptions
+	require.Equal(t, C.TypeVMess, outbounds[1].Type)
+	require.Equal(t, "auto", vmess.Security)
+	require.Equal(t, option.NetworkList("tcp"), vmess.Network)
+	require.True(t, vmess.TLS.Enabled)
+	require.Equal(t, "jp01.example.com", vmess.TLS.ServerName)
+	require.Equal(t, C.V2RayTransportTypeWebsocket, vmess.Transport.Type)
+	require.Equal(t, "/ray", vmess.Transport.WebsocketOptions.Path)
+	require.Equal(t, option.HTTPHeader{"Host": {"jp01.example.com"}}, vmess.Transport.WebsocketOptions.Headers)
+
+	trojan := outbounds[2].TrojanOptions
+	require.Equal(t, "trojan-password", trojan.Password)
+	require.True(t, trojan.TLS.Enabled)
+	require.True(t, trojan.TLS.Insecure)
+	require.Equal(t, "us01.example.com", trojan.TLS.ServerName)
+	require.Equal(t, C.V2RayTransportTypeGRPC, trojan.Transport.Type)
+	require.Equal(t, "grpc", trojan.Transport.GRPCOptions.ServiceName)
+
+	vless := outbounds[3].VLESSOptions
+	require.Equal(t, "xtls-rprx-vision", vless.Flow)
+	require.Equal(t, "www.microsoft.com", vless.TLS.ServerName)
+	require.Equal(t, "chrome", vless.TLS.UTLS.Fingerprint)
+	require.True(t, vless.TLS.Reality.Enabled)
+	require.Equal(t, "Z84J2IelR9ch3k8VtlVhhs5ycBUlXA7wHBWcBrjqnAw", vless.TLS.Reality.PublicKey)
+	require.Equal(t, "6ba85179e30d4fc2", vless.TLS.Reality.ShortID)
+}
+
+func TestParseShareLinks(t *testing.T) {
+	t.Parallel()
+	vmessLink := base64.StdEncoding.EncodeToString([]byte(`{"v":"2","ps":"JP 01","add":"jp01.example.com","port":"443","id":"b831381d-6324-4d53-ad4f-8cda48b30811","aid":"0","scy":"auto","net":"ws","type":"none","host":"jp01.example.com","path":"/ray","tls":"tls","sni":"jp01.example.com"}`))
+	links := strings.Join([]string{
+		"ss://" + base64.RawURLEncoding.EncodeToString([]byte("aes-256-gcm:p@ss")) + "@hk01.example.com:8388?plugin=obfs-local%3Bobfs%3Dhttp%3Bobfs-host%3Dbing.com#HK%2001",
+		"ss://" + base64.StdEncoding.EncodeToString([]byte("chacha20-ietf-poly1305:secret@hk03.example.com:8389")) + "#HK%2003",
+		"ss://2022-blake3-aes-128-gcm:c2VjcmV0c2VjcmV0c2VjcmV0@hk04.example.com:8390?plugin=kcptun#HK%2004",
+		"vmess://" + vmessLink,
+		"trojan://trojan-password@us01.example.com:443?sni=us01.example.com&allowInsecure=1&type=grpc&serviceName=grpc#US%2001",
+		"vless://b831381d-6324-4d53-ad4f-8cda48b30811@sg01.example.com:443?encryption=none&flow=xtls-rprx-vision&security=reality&sni=www.microsoft.com&fp=chrome&pbk=Z84J2IelR9ch3k8VtlVhhs5ycBUlXA7wHBWcBrjqnAw&sid=6ba85179e30d4fc2&type=tcp#SG%2001",
+		"hysteria2://hy2-password@kr01.example.com:8443?sni=kr01.example.com&obfs=salamander&obfs-password=obfs#KR%2001",
+		"ssr://aGsuZXhhbXBsZS5jb206ODM4ODpvcmlnaW46YWVzLTI1Ni1jZmI6cGxhaW46Y0dGemN3",
+	}, "\r\n")
+	// subscriptions are served base64 encoded and wrapped
+	content := base64.StdEncoding.EncodeToString([]byte(links))
+	content = content[:76] + "\n" + content[76:]
+	outbounds, err := parseProviderContent(log.NewNOPFactory().Logger(), []byte(content))
+	require.NoError(t, err)
+	require.Equal(t, []string{"HK 01", "HK 03", "JP 01", "US 01", "SG 01", "KR 01"}, outboundTags(outbounds))
+
+	shadowsocks := outbounds[0].ShadowsocksOptions
+	require.Equal(t, "aes-256-gcm", shadowsocks.Method)
+	require.Equal(t, "p@ss", shadowsocks.Password)
+	require.Equal(t, "obfs-local", shadowsocks.Plugin)
+	require.Equal(t, "obfs=http;obfs-host=bing.com", shadowsocks.PluginOptions)
+
+	legacyShadowsocks := outbounds[1].ShadowsocksOptions
+	require.Equal(t, "hk03.example.com", legacyShadowsocks.Server)
+	require.Equal(t, uint16(8389), legacyShadowsocks.ServerPort)
+	require.Equal(t, "chacha20-ietf-poly1305", legacyShadowsocks.Method)
+	require.Equal(t, "secret", legacyShadowsocks.Password)
+
+	vmess := outbounds[2].VMessOptions
+	require.Equal(t, uint16(443), vmess.ServerPort)
+	require.Equal(t, "b831381d-6324-4d53-ad4f-8cda48b30811", vmess.UUID)
+	require.True(t, vmess.TLS.Enabled)
+	require.Equal(t, "/ray", vmess.Transport.WebsocketOptions.Path)
+	require.Equal(t, option.HTTPHeader{"Host": {"jp01.example.com"}}, vmess.Transport.WebsocketOptions.Headers)
+
+	trojan := outbounds[3].TrojanOptions
+	require.True(t, trojan.TLS.Insecure)
+	require.Equal(t, "grpc", trojan.Transport.GRPCOptions.ServiceName)
+
+	vless := outbounds[4].VLESSOptions
+	require.Equal(t, "xtls-rprx-vision", vless.Flow)
+	require.Equal(t, "6ba85179e30d4fc2", vless.TLS.Reality.ShortID)
+	require.Nil(t, vless.Transport)
+
+	hysteria2 := outbounds[5].Hysteria2Options
+	require.Equal(t, "hy2-password", hysteria2.Password)
+	require.Equal(t, "kr01.example.com", hysteria2.TLS.ServerName)
+	require.Equal(t, "salamander", hysteria2.Obfs.Type)
+}
+
+func TestParseProviderContent(t *testing.T) {
+	t.Parallel()
+	content := `{"outbounds":[{"type":"direct","tag":"direct"},{"type":"socks","tag":"socks","server":"127.0.0.1","server_port":1080}]}`
+	for _, encoded := range []string{content, base64.StdEncoding.EncodeToString([]byte(content))} {
+		outbounds, err := parseProviderContent(log.NewNOPFactory().Logger(), []byte(encoded))
+		require.NoError(t, err)
+		require.Equal(t, []string{"direct", "socks"}, outboundTags(outbounds))
+	}
+	_, err := parseProviderContent(log.NewNOPFactory().Logger(), []byte(" \n"))
+	require.Error(t, err)
+	_, err = parseProviderContent(log.NewNOPFactory().Logger(), []byte("proxies: []"))
+	require.Error(t, err)
+}
+
+func outboundTags(outbounds []option.Outbound) []string {
+	tags := make([]string, 0, len(outbounds))
+	for _, outbound := range outbounds {
+		tags = append(tags, outbound.Tag)
+	}
+	return tags
+}
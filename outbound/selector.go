@@ -3,6 +3,7 @@ package outbound
 import (
 	"context"
 	"net"
+	"sync"
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/common/interrupt"
@@ -12,6 +13,7 @@ import (
 	E "github.com/sagernet/sing/common/exceptions"
 	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/common/x/list"
 	"github.com/sagernet/sing/service"
 )
 
@@ -23,9 +25,14 @@ var (
 type Selector struct {
 	myOutboundAdapter
 	ctx                          context.Context
+	outboundTags                 []string
+	providerTags                 []string
 	tags                         []string
 	defaultTag                   string
+	access                       sync.RWMutex
 	outbounds                    map[string]adapter.Outbound
+	providers                    []adapter.OutboundProvider
+	providerCallbacks            []*list.Element[adapter.OutboundProviderUpdateCallback]
 	selected                     adapter.Outbound
 	interruptGroup               *interrupt.Group
 	interruptExternalConnections bool
@@ -41,78 +48,107 @@ func NewSelector(ctx context.Context, router adapter.Router, logger log.ContextL
 			dependencies: options.Outbounds,
 		},
 		ctx:                          ctx,
-		tags:                         options.Outbounds,
+		outboundTags:                 options.Outbounds,
+		providerTags:                 options.Providers,
 		defaultTag:                   options.Default,
 		outbounds:                    make(map[string]adapter.Outbound),
 		interruptGroup:               interrupt.NewGroup(),
 		interruptExternalConnections: options.InterruptExistConnections,
 	}
-	if len(outbound.tags) == 0 {
+	if len(outbound.outboundTags) == 0 && len(outbound.providerTags) == 0 {
 		return nil, E.New("missing tags")
 	}
 	return outbound, nil
 }
 
 func (s *Selector) Network() []string {
-	if s.selected == nil {
+	selected := s.selectedOutbound()
+	if selected == nil {
 		return []string{N.NetworkTCP, N.NetworkUDP}
 	}
-	return s.selected.Network()
+	return selected.Network()
 }
 
 func (s *Selector) Start() error {
-	for i, tag := range s.tags {
+	for i, tag := range s.outboundTags {
 		detour, loaded := s.router.Outbound(tag)
 		if !loaded {
 			return E.New("outbound ", i, " not found: ", tag)
 		}
 		s.outbounds[tag] = detour
 	}
+	s.tags = s.outboundTags
+	for i, tag := range s.providerTags {
+		provider, loaded := s.router.OutboundProvider(tag)
+		if !loaded {
+			return E.New("provider ", i, " not found: ", tag)
+		}
+		s.providers = append(s.providers, provider)
+		s.providerCallbacks = append(s.providerCallbacks, provider.RegisterCallback(s.providerUpdated))
+	}
+	if len(s.providers) > 0 {
+		s.reloadOutbounds()
+	}
+	if len(s.tags) == 0 {
+		return nil
+	}
 
-	if s.tag != "" {
-		cacheFile := service.FromContext[adapter.CacheFile](s.ctx)
-		if cacheFile != nil {
-			selected := cacheFile.LoadSelected(s.tag)
-			if selected != "" {
-				detour, loaded := s.outbounds[selected]
-				if loaded {
-					s.selected = detour
-					return nil
-				}
-			}
+	if selected := s.loadSelected(); selected != "" {
+		detour, loaded := s.outbounds[selected]
+		if loaded {
+			s.selected = detour
+			return nil
 		}
 	}
 
 	if s.defaultTag != "" {
 		detour, loaded := s.outbounds[s.defaultTag]
-		if !loaded {
+		if loaded {
+			s.selected = detour
+			return nil
+		} else if len(s.providers) == 0 {
 			return E.New("default outbound not found: ", s.defaultTag)
 		}
-		s.selected = detour
-		return nil
 	}
 
 	s.selected = s.outbounds[s.tags[0]]
 	return nil
 }
 
+func (s *Selector) Close() error {
+	for i, provider := range s.providers {
+		provider.UnregisterCallback(s.providerCallbacks[i])
+	}
+	return nil
+}
+
 func (s *Selector) Now() string {
-	return s.selected.Tag()
+	selected := s.selectedOutbound()
+	if selected == nil {
+		return ""
+	}
+	return selected.Tag()
 }
 
 func (s *Selector) All() []string {
+	s.access.RLock()
+	defer s.access.RUnlock()
 	return s.tags
 }
 
 func (s *Selector) SelectOutbound(tag string) bool {
+	s.access.Lock()
 	detour, loaded := s.outbounds[tag]
 	if !loaded {
+		s.access.Unlock()
 		return false
 	}
 	if s.selected == detour {
+		s.access.Unlock()
 		return true
 	}
 	s.selected = detour
+	s.access.Unlock()
 	if s.tag != "" {
 		cacheFile := service.FromContext[adapter.CacheFile](s.ctx)
 		if cacheFile != nil {
@@ -126,8 +162,78 @@ func (s *Selector) SelectOutbound(tag string) bool {
 	return true
 }
 
+func (s *Selector) loadSelected() string {
+	if s.tag == "" {
+		return ""
+	}
+	cacheFile := service.FromContext[adapter.CacheFile](s.ctx)
+	if cacheFile == nil {
+		return ""
+	}
+	return cacheFile.LoadSelected(s.tag)
+}
+
+func (s *Selector) selectedOutbound() adapter.Outbound {
+	s.access.RLock()
+	defer s.access.RUnlock()
+	return s.selected
+}
+
+func (s *Selector) providerUpdated(_ adapter.OutboundProvider) {
+	s.reloadOutbounds()
+}
+
+func (s *Selector) reloadOutbounds() {
+	s.access.Lock()
+	tags := append([]string(nil), s.outboundTags...)
+	outbounds := make(map[string]adapter.Outbound)
+	for _, tag := range s.outboundTags {
+		outbounds[tag] = s.outbounds[tag]
+	}
+	for _, provider := range s.providers {
+		for _, detour := range provider.Outbounds() {
+			if _, exists := outbounds[detour.Tag()]; exists {
+				continue
+			}
+			tags = append(tags, detour.Tag())
+			outbounds[detour.Tag()] = detour
+		}
+	}
+	s.tags = tags
+	s.outbounds = outbounds
+	var changed bool
+	if s.selected != nil {
+		if detour, loaded := outbounds[s.selected.Tag()]; !loaded {
+			s.selected = nil
+			changed = true
+		} else if detour != s.selected {
+			s.selected = detour
+			changed = true
+		}
+	}
+	if s.selected == nil && len(tags) > 0 {
+		// selectors with only providers have nothing to select before the first load,
+		// so the cached selection is restored here.
+		if detour, loaded := outbounds[s.loadSelected()]; loaded {
+			s.selected = detour
+		} else if detour, loaded = outbounds[s.defaultTag]; loaded {
+			s.selected = detour
+		} else {
+			s.selected = outbounds[tags[0]]
+		}
+	}
+	s.access.Unlock()
+	if changed {
+		s.interruptGroup.Interrupt(s.interruptExternalConnections)
+	}
+}
+
 func (s *Selector) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
-	conn, err := s.selected.DialContext(ctx, network, destination)
+	selected := s.selectedOutbound()
+	if selected == nil {
+		return nil, E.New("missing selected outbound")
+	}
+	conn, err := selected.DialContext(ctx, network, destination)
 	if err != nil {
 		return nil, err
 	}
@@ -135,7 +241,11 @@ func (s *Selector) DialContext(ctx context.Context, network string, destination
 }
 
 func (s *Selector) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
-	conn, err := s.selected.ListenPacket(ctx, destination)
+	selected := s.selectedOutbound()
+	if selected == nil {
+		return nil, E.New("missing selected outbound")
+	}
+	conn, err := selected.ListenPacket(ctx, destination)
 	if err != nil {
 		return nil, err
 	}
@@ -143,13 +253,21 @@ func (s *Selector) ListenPacket(ctx context.Context, destination M.Socksaddr) (n
 }
 
 func (s *Selector) NewConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
+	selected := s.selectedOutbound()
+	if selected == nil {
+		return E.New("missing selected outbound")
+	}
 	ctx = interrupt.ContextWithIsExternalConnection(ctx)
-	return s.selected.NewConnection(ctx, conn, metadata)
+	return selected.NewConnection(ctx, conn, metadata)
 }
 
 func (s *Selector) NewPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext) error {
+	selected := s.selectedOutbound()
+	if selected == nil {
+		return E.New("missing selected outbound")
+	}
 	ctx = interrupt.ContextWithIsExternalConnection(ctx)
-	return s.selected.NewPacketConnection(ctx, conn, metadata)
+	return selected.NewPacketConnection(ctx, conn, metadata)
 }
 
 func RealTag(detour adapter.Outbound) string {
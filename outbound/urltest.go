@@ -29,108 +29,37 @@ var (
 )
 
 type URLTest struct {
-	myOutboundAdapter
-	ctx                          context.Context
-	tags                         []string
-	link                         string
-	interval                     time.Duration
-	tolerance                    uint16
-	idleTimeout                  time.Duration
-	group                        *URLTestGroup
-	interruptExternalConnections bool
+	groupAdapter
 }
 
 func NewURLTest(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.URLTestOutboundOptions) (*URLTest, error) {
-	outbound := &URLTest{
-		myOutboundAdapter: myOutboundAdapter{
-			protocol:     C.TypeURLTest,
-			network:      []string{N.NetworkTCP, N.NetworkUDP},
-			router:       router,
-			logger:       logger,
-			tag:          tag,
-			dependencies: options.Outbounds,
-		},
-		ctx:                          ctx,
-		tags:                         options.Outbounds,
-		link:                         options.URL,
-		interval:                     time.Duration(options.Interval),
-		tolerance:                    options.Tolerance,
-		idleTimeout:                  time.Duration(options.IdleTimeout),
-		interruptExternalConnections: options.InterruptExistConnections,
-	}
-	if len(outbound.tags) == 0 {
-		return nil, E.New("missing tags")
-	}
-	return outbound, nil
-}
-
-func (s *URLTest) Start() error {
-	outbounds := make([]adapter.Outbound, 0, len(s.tags))
-	for i, tag := range s.tags {
-		detour, loaded := s.router.Outbound(tag)
-		if !loaded {
-			return E.New("outbound ", i, " not found: ", tag)
-		}
-		outbounds = append(outbounds, detour)
-	}
-	group, err := NewURLTestGroup(
-		s.ctx,
-		s.router,
-		s.logger,
-		outbounds,
-		s.link,
-		s.interval,
-		s.tolerance,
-		s.idleTimeout,
-		s.interruptExternalConnections,
-	)
+	groupAdapter, err := newGroupAdapter(ctx, router, logger, C.TypeURLTest, tag, options.Outbounds, options.Providers)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	s.group = group
-	return nil
-}
-
-func (s *URLTest) PostStart() error {
-	s.group.PostStart()
-	return nil
-}
-
-func (s *URLTest) Close() error {
-	return common.Close(
-		common.PtrOrNil(s.group),
-	)
+	groupAdapter.link = options.URL
+	groupAdapter.interval = time.Duration(options.Interval)
+	groupAdapter.tolerance = options.Tolerance
+	groupAdapter.idleTimeout = time.Duration(options.IdleTimeout)
+	groupAdapter.interruptExternalConnections = options.InterruptExistConnections
+	return &URLTest{groupAdapter}, nil
 }
 
 func (s *URLTest) Now() string {
-	if s.group.selectedOutboundTCP != nil {
-		return s.group.selectedOutboundTCP.Tag()
-	} else if s.group.selectedOutboundUDP != nil {
-		return s.group.selectedOutboundUDP.Tag()
+	if selected := s.group.selectedOutbound(N.NetworkTCP); selected != nil {
+		return selected.Tag()
+	} else if selected = s.group.selectedOutbound(N.NetworkUDP); selected != nil {
+		return selected.Tag()
 	}
 	return ""
 }
 
-func (s *URLTest) All() []string {
-	return s.tags
-}
-
-func (s *URLTest) URLTest(ctx context.Context) (map[string]uint16, error) {
-	return s.group.URLTest(ctx)
-}
-
-func (s *URLTest) CheckOutbounds() {
-	s.group.CheckOutbounds(true)
-}
-
 func (s *URLTest) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
 	s.group.Touch()
 	var outbound adapter.Outbound
 	switch N.NetworkName(network) {
-	case N.NetworkTCP:
-		outbound = s.group.selectedOutboundTCP
-	case N.NetworkUDP:
-		outbound = s.group.selectedOutboundUDP
+	case N.NetworkTCP, N.NetworkUDP:
+		outbound = s.group.selectedOutbound(N.NetworkName(network))
 	default:
 		return nil, E.Extend(N.ErrUnknownNetwork, network)
 	}
@@ -151,7 +80,7 @@ func (s *URLTest) DialContext(ctx context.Context, network string, destination M
 
 func (s *URLTest) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
 	s.group.Touch()
-	outbound := s.group.selectedOutboundUDP
+	outbound := s.group.selectedOutbound(N.NetworkUDP)
 	if outbound == nil {
 		outbound, _ = s.group.Select(N.NetworkUDP)
 	}
@@ -177,15 +106,11 @@ func (s *URLTest) NewPacketConnection(ctx context.Context, conn N.PacketConn, me
 	return NewPacketConnection(ctx, s, conn, metadata)
 }
 
-func (s *URLTest) InterfaceUpdated() {
-	go s.group.CheckOutbounds(true)
-	return
-}
-
 type URLTestGroup struct {
 	ctx                          context.Context
 	router                       adapter.Router
 	logger                       log.Logger
+	outboundsAccess              sync.RWMutex
 	outbounds                    []adapter.Outbound
 	link                         string
 	interval                     time.Duration
@@ -259,6 +184,38 @@ func (g *URLTestGroup) PostStart() {
 	go g.CheckOutbounds(false)
 }
 
+func (g *URLTestGroup) Outbounds() []adapter.Outbound {
+	g.outboundsAccess.RLock()
+	defer g.outboundsAccess.RUnlock()
+	return g.outbounds
+}
+
+// selectedOutbound returns the outbound selected for network, guarded by outboundsAccess
+// since provider updates replace it concurrently with dials.
+func (g *URLTestGroup) selectedOutbound(network string) adapter.Outbound {
+	g.outboundsAccess.RLock()
+	defer g.outboundsAccess.RUnlock()
+	if network == N.NetworkUDP {
+		return g.selectedOutboundUDP
+	}
+	return g.selectedOutboundTCP
+}
+
+func (g *URLTestGroup) SetOutbounds(outbounds []adapter.Outbound) {
+	g.outboundsAccess.Lock()
+	g.outbounds = outbounds
+	if g.selectedOutboundTCP != nil && !common.Contains(outbounds, g.selectedOutboundTCP) {
+		g.selectedOutboundTCP = nil
+	}
+	if g.selectedOutboundUDP != nil && !common.Contains(outbounds, g.selectedOutboundUDP) {
+		g.selectedOutboundUDP = nil
+	}
+	g.outboundsAccess.Unlock()
+	if g.started {
+		go g.CheckOutbounds(true)
+	}
+}
+
 func (g *URLTestGroup) Touch() {
 	if !g.started {
 		return
@@ -289,22 +246,16 @@ func (g *URLTestGroup) Select(network string) (adapter.Outbound, bool) {
 	var minDelay uint16
 	var minOutbound adapter.Outbound
 	switch network {
-	case N.NetworkTCP:
-		if g.selectedOutboundTCP != nil {
-			if history := g.history.LoadURLTestHistory(RealTag(g.selectedOutboundTCP)); history != nil {
-				minOutbound = g.selectedOutboundTCP
-				minDelay = history.Delay
-			}
-		}
-	case N.NetworkUDP:
-		if g.selectedOutboundUDP != nil {
-			if history := g.history.LoadURLTestHistory(RealTag(g.selectedOutboundUDP)); history != nil {
-				minOutbound = g.selectedOutboundUDP
+	case N.NetworkTCP, N.NetworkUDP:
+		if selected := g.selectedOutbound(network); selected != nil {
+			if history := g.history.LoadURLTestHistory(RealTag(selected)); history != nil {
+				minOutbound = selected
 				minDelay = history.Delay
 			}
 		}
 	}
-	for _, detour := range g.outbounds {
+	outbounds := g.Outbounds()
+	for _, detour := range outbounds {
 		if !common.Contains(detour.Network(), network) {
 			continue
 		}
@@ -318,7 +269,7 @@ func (g *URLTestGroup) Select(network string) (adapter.Outbound, bool) {
 		}
 	}
 	if minOutbound == nil {
-		for _, detour := range g.outbounds {
+		for _, detour := range outbounds {
 			if !common.Contains(detour.Network(), network) {
 				continue
 			}
@@ -369,7 +320,7 @@ func (g *URLTestGroup) urlTest(ctx context.Context, force bool) (map[string]uint
 	b, _ := batch.New(ctx, batch.WithConcurrencyNum[any](10))
 	checked := make(map[string]bool)
 	var resultAccess sync.Mutex
-	for _, detour := range g.outbounds {
+	for _, detour := range g.Outbounds() {
 		tag := detour.Tag()
 		realTag := RealTag(detour)
 		if checked[realTag] {
@@ -411,14 +362,18 @@ func (g *URLTestGroup) urlTest(ctx context.Context, force bool) (map[string]uint
 
 func (g *URLTestGroup) performUpdateCheck() {
 	var updated bool
-	if outbound, exists := g.Select(N.NetworkTCP); outbound != nil && (g.selectedOutboundTCP == nil || (exists && outbound != g.selectedOutboundTCP)) {
-		g.selectedOutboundTCP = outbound
+	outboundTCP, existsTCP := g.Select(N.NetworkTCP)
+	outboundUDP, existsUDP := g.Select(N.NetworkUDP)
+	g.outboundsAccess.Lock()
+	if outboundTCP != nil && (g.selectedOutboundTCP == nil || (existsTCP && outboundTCP != g.selectedOutboundTCP)) {
+		g.selectedOutboundTCP = outboundTCP
 		updated = true
 	}
-	if outbound, exists := g.Select(N.NetworkUDP); outbound != nil && (g.selectedOutboundUDP == nil || (exists && outbound != g.selectedOutboundUDP)) {
-		g.selectedOutboundUDP = outbound
+	if outboundUDP != nil && (g.selectedOutboundUDP == nil || (existsUDP && outboundUDP != g.selectedOutboundUDP)) {
+		g.selectedOutboundUDP = outboundUDP
 		updated = true
 	}
+	g.outboundsAccess.Unlock()
 	if updated {
 		g.interruptGroup.Interrupt(g.interruptExternalConnections)
 	}
@@ -10,15 +10,17 @@ import (
 	"os/user"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/accesslog"
 	"github.com/sagernet/sing-box/common/conntrack"
 	"github.com/sagernet/sing-box/common/dialer"
+	"github.com/sagernet/sing-box/common/dnssec"
 	"github.com/sagernet/sing-box/common/geoip"
 	"github.com/sagernet/sing-box/common/geosite"
 	"github.com/sagernet/sing-box/common/process"
-	"github.com/sagernet/sing-box/common/sniff"
 	"github.com/sagernet/sing-box/common/taskmonitor"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/experimental/libbox/platform"
@@ -26,11 +28,13 @@ import (
 	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing-box/outbound"
 	"github.com/sagernet/sing-box/transport/fakeip"
+	"github.com/sagernet/sing-box/transport/hosts"
 	"github.com/sagernet/sing-dns"
 	"github.com/sagernet/sing-mux"
 	"github.com/sagernet/sing-tun"
 	"github.com/sagernet/sing-vmess"
 	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/atomic"
 	"github.com/sagernet/sing/common/buf"
 	"github.com/sagernet/sing/common/bufio"
 	"github.com/sagernet/sing/common/bufio/deadline"
@@ -45,6 +49,8 @@ import (
 	"github.com/sagernet/sing/common/winpowrprof"
 	"github.com/sagernet/sing/service"
 	"github.com/sagernet/sing/service/pause"
+
+	mDNS "github.com/miekg/dns"
 )
 
 var _ adapter.Router = (*Router)(nil)
@@ -56,6 +62,9 @@ type Router struct {
 	inboundByTag                       map[string]adapter.Inbound
 	outbounds                          []adapter.Outbound
 	outboundByTag                      map[string]adapter.Outbound
+	outboundProviders                  []adapter.OutboundProvider
+	outboundProviderByTag              map[string]adapter.OutboundProvider
+	ruleAccess                         sync.RWMutex
 	rules                              []adapter.Rule
 	defaultDetour                      string
 	defaultOutboundForConnection       adapter.Outbound
@@ -73,12 +82,24 @@ type Router struct {
 	dnsRules                           []adapter.DNSRule
 	ruleSets                           []adapter.RuleSet
 	ruleSetMap                         map[string]adapter.RuleSet
+	ruleSetOptions                     map[string]option.RuleSet
+	connections                        outboundConnections
 	defaultTransport                   dns.Transport
 	transports                         []dns.Transport
 	transportMap                       map[string]dns.Transport
 	transportDomainStrategy            map[dns.Transport]dns.DomainStrategy
+	trustAnchors                       []*mDNS.DS
+	dnssecTransports                   map[dns.Transport]bool
+	dnssecAccess                       sync.Mutex
+	ruleDNSSECTransports               map[dns.Transport]*dnssecTransport
 	dnsReverseMapping                  *DNSReverseMapping
+	dnsHijacker                        *outbound.DNS
 	fakeIPStore                        adapter.FakeIPStore
+	fakeIPStores                       []adapter.FakeIPStore
+	fakeIPExclusions                   map[string]*fakeIPExclusion
+	fakeIPRuleSets                     map[string]*RuleSetItem
+	reloadOutbounds                    *outboundState
+	reloadRuleSetMap                   map[string]adapter.RuleSet
 	interfaceFinder                    *control.DefaultInterfaceFinder
 	autoDetectInterface                bool
 	defaultInterface                   string
@@ -93,9 +114,12 @@ type Router struct {
 	pauseManager                       pause.Manager
 	clashServer                        adapter.ClashServer
 	v2rayServer                        adapter.V2RayServer
+	metricsServer                      adapter.MetricsServer
 	platformInterface                  platform.Interface
 	needWIFIState                      bool
 	needPackageManager                 bool
+	needLogFields                      bool
+	accessLog                          *accesslog.Writer
 	wifiState                          adapter.WIFIState
 	started                            bool
 }
@@ -117,6 +141,7 @@ func NewRouter(
 		rules:                 make([]adapter.Rule, 0, len(options.Rules)),
 		dnsRules:              make([]adapter.DNSRule, 0, len(dnsOptions.Rules)),
 		ruleSetMap:            make(map[string]adapter.RuleSet),
+		ruleSetOptions:        make(map[string]option.RuleSet),
 		needGeoIPDatabase:     hasRule(options.Rules, isGeoIPRule) || hasDNSRule(dnsOptions.Rules, isGeoIPDNSRule),
 		needGeositeDatabase:   hasRule(options.Rules, isGeositeRule) || hasDNSRule(dnsOptions.Rules, isGeositeDNSRule),
 		geoIPOptions:          common.PtrValueOrDefault(options.GeoIP),
@@ -136,6 +161,16 @@ func NewRouter(
 			return len(inbound.TunOptions.IncludePackage) > 0 || len(inbound.TunOptions.ExcludePackage) > 0
 		}),
 	}
+	if fieldsFactory, isFieldsFactory := logFactory.(log.FieldsFactory); isFieldsFactory {
+		router.needLogFields = fieldsFactory.NeedFields()
+	}
+	if options.AccessLog != nil && options.AccessLog.Enabled {
+		accessLog, err := accesslog.NewWriter(ctx, logFactory.NewLogger("access-log"), *options.AccessLog)
+		if err != nil {
+			return nil, E.Cause(err, "parse access log")
+		}
+		router.accessLog = accessLog
+	}
 	router.dnsClient = dns.NewClient(dns.ClientOptions{
 		DisableCache:     dnsOptions.DNSClientOptions.DisableCache,
 		DisableExpire:    dnsOptions.DNSClientOptions.DisableExpire,
@@ -152,6 +187,7 @@ func NewRouter(
 		},
 		Logger: router.dnsLogger,
 	})
+	router.dnsHijacker = outbound.NewDNS(router, C.RuleActionTypeHijackDNS)
 	for i, ruleOptions := range options.Rules {
 		routeRule, err := NewRule(router, router.logger, ruleOptions, true)
 		if err != nil {
@@ -176,6 +212,7 @@ func NewRouter(
 		}
 		router.ruleSets = append(router.ruleSets, ruleSet)
 		router.ruleSetMap[ruleSetOptions.Tag] = ruleSet
+		router.ruleSetOptions[ruleSetOptions.Tag] = ruleSetOptions
 	}
 
 	transports := make([]dns.Transport, len(dnsOptions.Servers))
@@ -184,6 +221,18 @@ func NewRouter(
 	transportTags := make([]string, len(dnsOptions.Servers))
 	transportTagMap := make(map[string]bool)
 	transportDomainStrategy := make(map[dns.Transport]dns.DomainStrategy)
+	dnssecTransports := make(map[dns.Transport]bool)
+	fakeIPExclusions := make(map[string]*fakeIPExclusion)
+	var fakeIPRanges []fakeIPRange
+	if len(dnsOptions.TrustAnchors) > 0 {
+		trustAnchors, err := dnssec.ParseTrustAnchors(dnsOptions.TrustAnchors)
+		if err != nil {
+			return nil, E.Cause(err, "parse dns trust anchors")
+		}
+		router.trustAnchors = trustAnchors
+	} else {
+		router.trustAnchors = dnssec.RootAnchors()
+	}
 	for i, server := range dnsOptions.Servers {
 		var tag string
 		if server.Tag != "" {
@@ -211,8 +260,38 @@ func NewRouter(
 			} else {
 				detour = dialer.NewDetour(router, server.Detour)
 			}
+			if server.Hosts != nil && server.Address != "hosts" {
+				return nil, E.New("parse dns server[", tag, "]: hosts options is only available for hosts server")
+			}
+			if server.Group != nil && server.Address != "group" {
+				return nil, E.New("parse dns server[", tag, "]: group options is only available for group server")
+			}
+			if server.FakeIP != nil && server.Address != "fakeip" {
+				return nil, E.New("parse dns server[", tag, "]: fakeip options is only available for fakeip server")
+			}
+			var groupMembers []dns.Transport
 			switch server.Address {
-			case "local":
+			case "local", "hosts":
+			case "group":
+				if server.Group == nil || len(server.Group.Servers) == 0 {
+					return nil, E.New("parse dns server[", tag, "]: missing group servers")
+				}
+				for _, memberTag := range server.Group.Servers {
+					if !transportTagMap[memberTag] {
+						return nil, E.New("parse dns server[", tag, "]: group server not found: ", memberTag)
+					}
+					member, exists := dummyTransportMap[memberTag]
+					if !exists {
+						break
+					}
+					if _, isFakeIP := member.(adapter.FakeIPTransport); isFakeIP {
+						return nil, E.New("parse dns server[", tag, "]: fakeip server can not be used in group: ", memberTag)
+					}
+					groupMembers = append(groupMembers, member)
+				}
+				if len(groupMembers) != len(server.Group.Servers) {
+					continue
+				}
 			default:
 				serverURL, _ := url.Parse(server.Address)
 				var serverAddress string
@@ -242,17 +321,63 @@ func NewRouter(
 			} else if dnsOptions.ClientSubnet != nil {
 				clientSubnet = dnsOptions.ClientSubnet.Build()
 			}
-			transport, err := dns.CreateTransport(dns.TransportOptions{
-				Context:      ctx,
-				Logger:       logFactory.NewLogger(F.ToString("dns/transport[", tag, "]")),
-				Name:         tag,
-				Dialer:       detour,
-				Address:      server.Address,
-				ClientSubnet: clientSubnet,
-			})
+			transportCtx := ctx
+			if server.Hosts != nil {
+				transportCtx = hosts.ContextWithOptions(ctx, *server.Hosts)
+			}
+			if server.FakeIP != nil {
+				if exclusion := newFakeIPExclusion(*server.FakeIP); exclusion != nil {
+					fakeIPExclusions[tag] = exclusion
+				}
+				if server.FakeIP.Inet4Range != nil || server.FakeIP.Inet6Range != nil {
+					inet4Range := common.PtrValueOrDefault(server.FakeIP.Inet4Range)
+					inet6Range := common.PtrValueOrDefault(server.FakeIP.Inet6Range)
+					for _, prefix := range []netip.Prefix{inet4Range, inet6Range} {
+						if prefix.IsValid() {
+							fakeIPRanges = append(fakeIPRanges, fakeIPRange{"dns server[" + tag + "]", prefix})
+						}
+					}
+					store := fakeip.NewStore(ctx, router.logger, tag, inet4Range, inet6Range)
+					router.fakeIPStores = append(router.fakeIPStores, store)
+					transportCtx = fakeip.ContextWithStore(transportCtx, store)
+				}
+			}
+			var (
+				transport dns.Transport
+				err       error
+			)
+			if server.Address == "group" {
+				transport, err = newDNSGroupTransport(tag, logFactory.NewLogger(F.ToString("dns/transport[", tag, "]")), detour, groupMembers, *server.Group)
+			} else {
+				transport, err = dns.CreateTransport(dns.TransportOptions{
+					Context:      transportCtx,
+					Logger:       logFactory.NewLogger(F.ToString("dns/transport[", tag, "]")),
+					Name:         tag,
+					Dialer:       detour,
+					Address:      server.Address,
+					ClientSubnet: clientSubnet,
+				})
+			}
 			if err != nil {
 				return nil, E.Cause(err, "parse dns server[", tag, "]")
 			}
+			validating := server.DNSSEC
+			if server.DNSSEC {
+				if !transport.Raw() {
+					return nil, E.New("parse dns server[", tag, "]: dnssec is not supported by ", server.Address)
+				}
+				transport = newDNSSECTransport(transport, router.dnsLogger, router.trustAnchors)
+			} else if server.Address == "group" {
+				validating = common.All(groupMembers, func(it dns.Transport) bool {
+					return dnssecTransports[it]
+				})
+			}
+			if !dnsOptions.DNSClientOptions.DisableCache {
+				transport = wrapPersistentCache(ctx, transport, router.dnsLogger)
+			}
+			if validating {
+				dnssecTransports[transport] = true
+			}
 			transports[i] = transport
 			dummyTransportMap[tag] = transport
 			if server.Tag != "" {
@@ -305,6 +430,13 @@ func NewRouter(
 	router.transports = transports
 	router.transportMap = transportMap
 	router.transportDomainStrategy = transportDomainStrategy
+	router.dnssecTransports = dnssecTransports
+	router.fakeIPExclusions = fakeIPExclusions
+	err := router.checkFakeIPRuleSets(router.ruleSetMap)
+	if err != nil {
+		return nil, err
+	}
+	router.ruleDNSSECTransports = make(map[dns.Transport]*dnssecTransport)
 
 	if dnsOptions.ReverseMapping {
 		router.dnsReverseMapping = NewDNSReverseMapping()
@@ -319,7 +451,17 @@ func NewRouter(
 		if fakeIPOptions.Inet6Range != nil {
 			inet6Range = *fakeIPOptions.Inet6Range
 		}
-		router.fakeIPStore = fakeip.NewStore(ctx, router.logger, inet4Range, inet6Range)
+		for _, prefix := range []netip.Prefix{inet4Range, inet6Range} {
+			if prefix.IsValid() {
+				fakeIPRanges = append(fakeIPRanges, fakeIPRange{"dns.fakeip", prefix})
+			}
+		}
+		router.fakeIPStore = fakeip.NewStore(ctx, router.logger, "", inet4Range, inet6Range)
+		router.fakeIPStores = append([]adapter.FakeIPStore{router.fakeIPStore}, router.fakeIPStores...)
+	}
+	err = checkFakeIPRanges(fakeIPRanges)
+	if err != nil {
+		return nil, err
 	}
 
 	usePlatformDefaultInterfaceMonitor := platformInterface != nil && platformInterface.UsePlatformDefaultInterfaceMonitor()
@@ -372,21 +514,52 @@ func NewRouter(
 	return router, nil
 }
 
-func (r *Router) Initialize(inbounds []adapter.Inbound, outbounds []adapter.Outbound, defaultOutbound func() adapter.Outbound) error {
+func (r *Router) Initialize(inbounds []adapter.Inbound, outbounds []adapter.Outbound, outboundProviders []adapter.OutboundProvider, defaultOutbound func() adapter.Outbound) error {
+	state, err := r.buildOutbounds(inbounds, outbounds, outboundProviders, r.rules, r.defaultDetour, defaultOutbound)
+	if err != nil {
+		return err
+	}
+	r.ruleAccess.Lock()
+	r.applyOutbounds(state)
+	r.ruleAccess.Unlock()
+	return nil
+}
+
+// outboundState holds inbounds and outbounds known to the router,
+// Reload builds a new one and swaps it in along with the rules.
+type outboundState struct {
+	inboundByTag                       map[string]adapter.Inbound
+	outbounds                          []adapter.Outbound
+	outboundByTag                      map[string]adapter.Outbound
+	outboundProviders                  []adapter.OutboundProvider
+	outboundProviderByTag              map[string]adapter.OutboundProvider
+	defaultDetour                      string
+	defaultOutboundForConnection       adapter.Outbound
+	defaultOutboundForPacketConnection adapter.Outbound
+}
+
+func (r *Router) buildOutbounds(inbounds []adapter.Inbound, outbounds []adapter.Outbound, outboundProviders []adapter.OutboundProvider, rules []adapter.Rule, defaultDetour string, defaultOutbound func() adapter.Outbound) (*outboundState, error) {
 	inboundByTag := make(map[string]adapter.Inbound)
 	for _, inbound := range inbounds {
 		inboundByTag[inbound.Tag()] = inbound
 	}
+	outboundProviderByTag := make(map[string]adapter.OutboundProvider)
+	for _, provider := range outboundProviders {
+		if _, exists := outboundProviderByTag[provider.Tag()]; exists {
+			return nil, E.New("duplicate provider tag: ", provider.Tag())
+		}
+		outboundProviderByTag[provider.Tag()] = provider
+	}
 	outboundByTag := make(map[string]adapter.Outbound)
 	for _, detour := range outbounds {
 		outboundByTag[detour.Tag()] = detour
 	}
 	var defaultOutboundForConnection adapter.Outbound
 	var defaultOutboundForPacketConnection adapter.Outbound
-	if r.defaultDetour != "" {
-		detour, loaded := outboundByTag[r.defaultDetour]
+	if defaultDetour != "" {
+		detour, loaded := outboundByTag[defaultDetour]
 		if !loaded {
-			return E.New("default detour not found: ", r.defaultDetour)
+			return nil, E.New("default detour not found: ", defaultDetour)
 		}
 		if common.Contains(detour.Network(), N.NetworkTCP) {
 			defaultOutboundForConnection = detour
@@ -422,28 +595,70 @@ func (r *Router) Initialize(inbounds []adapter.Inbound, outbounds []adapter.Outb
 		outbounds = append(outbounds, detour)
 		outboundByTag[detour.Tag()] = detour
 	}
-	r.inboundByTag = inboundByTag
-	r.outbounds = outbounds
-	r.defaultOutboundForConnection = defaultOutboundForConnection
-	r.defaultOutboundForPacketConnection = defaultOutboundForPacketConnection
-	r.outboundByTag = outboundByTag
-	for i, rule := range r.rules {
-		if _, loaded := outboundByTag[rule.Outbound()]; !loaded {
-			return E.New("outbound not found for rule[", i, "]: ", rule.Outbound())
+	for i, rule := range rules {
+		switch action := rule.Action().(type) {
+		case *RuleActionRoute:
+			if _, loaded := outboundByTag[action.Outbound]; !loaded {
+				return nil, E.New("outbound not found for rule[", i, "]: ", action.Outbound)
+			}
+		case *RuleActionResolve:
+			if action.Server != "" {
+				if _, loaded := r.transportMap[action.Server]; !loaded {
+					return nil, E.New("DNS server not found for rule[", i, "]: ", action.Server)
+				}
+			}
 		}
 	}
-	return nil
+	return &outboundState{
+		inboundByTag:                       inboundByTag,
+		outbounds:                          outbounds,
+		outboundByTag:                      outboundByTag,
+		outboundProviders:                  outboundProviders,
+		outboundProviderByTag:              outboundProviderByTag,
+		defaultDetour:                      defaultDetour,
+		defaultOutboundForConnection:       defaultOutboundForConnection,
+		defaultOutboundForPacketConnection: defaultOutboundForPacketConnection,
+	}, nil
+}
+
+// applyOutbounds must be called with ruleAccess held.
+func (r *Router) applyOutbounds(state *outboundState) {
+	r.inboundByTag = state.inboundByTag
+	r.outbounds = state.outbounds
+	r.outboundByTag = state.outboundByTag
+	r.outboundProviders = state.outboundProviders
+	r.outboundProviderByTag = state.outboundProviderByTag
+	r.defaultDetour = state.defaultDetour
+	r.defaultOutboundForConnection = state.defaultOutboundForConnection
+	r.defaultOutboundForPacketConnection = state.defaultOutboundForPacketConnection
+}
+
+func (r *Router) Inbound(tag string) (adapter.Inbound, bool) {
+	r.ruleAccess.RLock()
+	defer r.ruleAccess.RUnlock()
+	inbound, loaded := r.inboundByTag[tag]
+	return inbound, loaded
 }
 
 func (r *Router) Outbounds() []adapter.Outbound {
 	if !r.started {
 		return nil
 	}
+	r.ruleAccess.RLock()
+	defer r.ruleAccess.RUnlock()
 	return r.outbounds
 }
 
 func (r *Router) PreStart() error {
 	monitor := taskmonitor.New(r.logger, C.StartTimeout)
+	if r.accessLog != nil {
+		monitor.Start("initialize access log")
+		err := r.accessLog.Start()
+		monitor.Finish()
+		if err != nil {
+			return err
+		}
+	}
 	if r.interfaceMonitor != nil {
 		monitor.Start("initialize interface monitor")
 		err := r.interfaceMonitor.Start()
@@ -460,9 +675,9 @@ func (r *Router) PreStart() error {
 			return err
 		}
 	}
-	if r.fakeIPStore != nil {
+	for _, fakeIPStore := range r.fakeIPStores {
 		monitor.Start("initialize fakeip store")
-		err := r.fakeIPStore.Start()
+		err := fakeIPStore.Start()
 		monitor.Finish()
 		if err != nil {
 			return err
@@ -559,6 +774,15 @@ func (r *Router) Start() error {
 			return E.Cause(err, "initialize DNS rule[", i, "]")
 		}
 	}
+	if len(r.fakeIPExclusions) > 0 {
+		monitor.Start("initialize fakeip exclusions")
+		fakeIPRuleSets, err := r.startFakeIPRuleSets()
+		monitor.Finish()
+		if err != nil {
+			return err
+		}
+		r.fakeIPRuleSets = fakeIPRuleSets
+	}
 	for i, transport := range r.transports {
 		monitor.Start("initialize DNS transport[", i, "]")
 		err := transport.Start()
@@ -595,6 +819,7 @@ func (r *Router) Close() error {
 		})
 		monitor.Finish()
 	}
+	closeFakeIPRuleSets(r.fakeIPRuleSets)
 	for i, transport := range r.transports {
 		monitor.Start("close dns transport[", i, "]")
 		err = E.Append(err, transport.Close(), func(err error) error {
@@ -644,13 +869,20 @@ func (r *Router) Close() error {
 		})
 		monitor.Finish()
 	}
-	if r.fakeIPStore != nil {
+	for _, fakeIPStore := range r.fakeIPStores {
 		monitor.Start("close fakeip store")
-		err = E.Append(err, r.fakeIPStore.Close(), func(err error) error {
+		err = E.Append(err, fakeIPStore.Close(), func(err error) error {
 			return E.Cause(err, "close fakeip store")
 		})
 		monitor.Finish()
 	}
+	if r.accessLog != nil {
+		monitor.Start("close access log")
+		err = E.Append(err, r.accessLog.Close(), func(err error) error {
+			return E.Cause(err, "close access log")
+		})
+		monitor.Finish()
+	}
 	return err
 }
 
@@ -658,26 +890,11 @@ func (r *Router) PostStart() error {
 	monitor := taskmonitor.New(r.logger, C.StopTimeout)
 	if len(r.ruleSets) > 0 {
 		monitor.Start("initialize rule-set")
-		ruleSetStartContext := NewRuleSetStartContext()
-		var ruleSetStartGroup task.Group
-		for i, ruleSet := range r.ruleSets {
-			ruleSetInPlace := ruleSet
-			ruleSetStartGroup.Append0(func(ctx context.Context) error {
-				err := ruleSetInPlace.StartContext(ctx, ruleSetStartContext)
-				if err != nil {
-					return E.Cause(err, "initialize rule-set[", i, "]")
-				}
-				return nil
-			})
-		}
-		ruleSetStartGroup.Concurrency(5)
-		ruleSetStartGroup.FastFail()
-		err := ruleSetStartGroup.Run(r.ctx)
+		err := r.startRuleSets(r.ruleSets)
 		monitor.Finish()
 		if err != nil {
 			return err
 		}
-		ruleSetStartContext.Close()
 	}
 	var (
 		needProcessFromRuleSet   bool
@@ -740,6 +957,29 @@ func (r *Router) PostStart() error {
 	return nil
 }
 
+func (r *Router) startRuleSets(ruleSets []adapter.RuleSet) error {
+	ruleSetStartContext := NewRuleSetStartContext()
+	var ruleSetStartGroup task.Group
+	for i, ruleSet := range ruleSets {
+		ruleSetInPlace := ruleSet
+		ruleSetStartGroup.Append0(func(ctx context.Context) error {
+			err := ruleSetInPlace.StartContext(ctx, ruleSetStartContext)
+			if err != nil {
+				return E.Cause(err, "initialize rule-set[", i, "]")
+			}
+			return nil
+		})
+	}
+	ruleSetStartGroup.Concurrency(5)
+	ruleSetStartGroup.FastFail()
+	err := ruleSetStartGroup.Run(r.ctx)
+	if err != nil {
+		return err
+	}
+	ruleSetStartContext.Close()
+	return nil
+}
+
 func (r *Router) Cleanup() error {
 	for _, ruleSet := range r.ruleSetMap {
 		ruleSet.Cleanup()
@@ -748,22 +988,82 @@ func (r *Router) Cleanup() error {
 	return nil
 }
 
+// Outbound also returns outbounds being started by Reload,
+// so that new groups can look up their new members.
 func (r *Router) Outbound(tag string) (adapter.Outbound, bool) {
+	r.ruleAccess.RLock()
+	state := r.reloadOutbounds
+	r.ruleAccess.RUnlock()
+	if state != nil {
+		return state.outbound(tag)
+	}
+	return r.routeOutbound(tag)
+}
+
+// routeOutbound looks up an outbound without the ones being started by Reload.
+func (r *Router) routeOutbound(tag string) (adapter.Outbound, bool) {
+	r.ruleAccess.RLock()
 	outbound, loaded := r.outboundByTag[tag]
-	return outbound, loaded
+	outboundProviders := r.outboundProviders
+	r.ruleAccess.RUnlock()
+	if loaded {
+		return outbound, true
+	}
+	for _, provider := range outboundProviders {
+		outbound, loaded = provider.Outbound(tag)
+		if loaded {
+			return outbound, true
+		}
+	}
+	return nil, false
+}
+
+func (s *outboundState) outbound(tag string) (adapter.Outbound, bool) {
+	outbound, loaded := s.outboundByTag[tag]
+	if loaded {
+		return outbound, true
+	}
+	for _, provider := range s.outboundProviders {
+		outbound, loaded = provider.Outbound(tag)
+		if loaded {
+			return outbound, true
+		}
+	}
+	return nil, false
+}
+
+func (r *Router) OutboundProviders() []adapter.OutboundProvider {
+	r.ruleAccess.RLock()
+	defer r.ruleAccess.RUnlock()
+	return r.outboundProviders
+}
+
+func (r *Router) OutboundProvider(tag string) (adapter.OutboundProvider, bool) {
+	r.ruleAccess.RLock()
+	defer r.ruleAccess.RUnlock()
+	provider, loaded := r.outboundProviderByTag[tag]
+	return provider, loaded
 }
 
 func (r *Router) DefaultOutbound(network string) (adapter.Outbound, error) {
-	if network == N.NetworkTCP {
-		if r.defaultOutboundForConnection == nil {
+	defaultOutbound := r.defaultOutbound(network)
+	if defaultOutbound == nil {
+		if network == N.NetworkTCP {
 			return nil, E.New("missing default outbound for TCP connections")
-		}
-		return r.defaultOutboundForConnection, nil
-	} else {
-		if r.defaultOutboundForPacketConnection == nil {
+		} else {
 			return nil, E.New("missing default outbound for UDP connections")
 		}
-		return r.defaultOutboundForPacketConnection, nil
+	}
+	return defaultOutbound, nil
+}
+
+func (r *Router) defaultOutbound(network string) adapter.Outbound {
+	r.ruleAccess.RLock()
+	defer r.ruleAccess.RUnlock()
+	if network == N.NetworkTCP {
+		return r.defaultOutboundForConnection
+	} else {
+		return r.defaultOutboundForPacketConnection
 	}
 }
 
@@ -771,7 +1071,21 @@ func (r *Router) FakeIPStore() adapter.FakeIPStore {
 	return r.fakeIPStore
 }
 
+func (r *Router) RuleSets() []adapter.RuleSet {
+	r.ruleAccess.RLock()
+	defer r.ruleAccess.RUnlock()
+	return r.ruleSets
+}
+
+// RuleSet also returns rule-sets being started by Reload,
+// so that new rules can reference them.
 func (r *Router) RuleSet(tag string) (adapter.RuleSet, bool) {
+	r.ruleAccess.RLock()
+	defer r.ruleAccess.RUnlock()
+	if r.reloadRuleSetMap != nil {
+		ruleSet, loaded := r.reloadRuleSetMap[tag]
+		return ruleSet, loaded
+	}
 	ruleSet, loaded := r.ruleSetMap[tag]
 	return ruleSet, loaded
 }
@@ -781,6 +1095,16 @@ func (r *Router) NeedWIFIState() bool {
 }
 
 func (r *Router) RouteConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
+	if r.accessLog == nil || metadata.InboundDetour != "" {
+		return r.routeConnection(ctx, conn, metadata, nil)
+	}
+	tracker := r.newAccessLogTracker(ctx, N.NetworkTCP, metadata)
+	err := r.routeConnection(ctx, conn, metadata, tracker)
+	tracker.finish(err)
+	return err
+}
+
+func (r *Router) routeConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext, accessLogTracker *accessLogTracker) error {
 	if r.pauseManager.IsDevicePaused() {
 		return E.New("reject connection to ", metadata.Destination, " while device paused")
 	}
@@ -789,8 +1113,8 @@ func (r *Router) RouteConnection(ctx context.Context, conn net.Conn, metadata ad
 		if metadata.LastInbound == metadata.InboundDetour {
 			return E.New("routing loop on detour: ", metadata.InboundDetour)
 		}
-		detour := r.inboundByTag[metadata.InboundDetour]
-		if detour == nil {
+		detour, loaded := r.Inbound(metadata.InboundDetour)
+		if !loaded {
 			return E.New("inbound detour not found: ", metadata.InboundDetour)
 		}
 		injectable, isInjectable := detour.(adapter.InjectableInbound)
@@ -822,8 +1146,9 @@ func (r *Router) RouteConnection(ctx context.Context, conn net.Conn, metadata ad
 		return E.New("global UoT (legacy) not supported since sing-box v1.7.0.")
 	}
 
-	if r.fakeIPStore != nil && r.fakeIPStore.Contains(metadata.Destination.Addr) {
-		domain, loaded := r.fakeIPStore.Lookup(metadata.Destination.Addr)
+	if fakeIPStore := r.fakeIPStoreFor(metadata.Destination.Addr); fakeIPStore != nil {
+		defer fakeIPStore.Acquire(metadata.Destination.Addr)()
+		domain, loaded := fakeIPStore.Lookup(metadata.Destination.Addr)
 		if !loaded {
 			return E.New("missing fakeip context")
 		}
@@ -841,39 +1166,7 @@ func (r *Router) RouteConnection(ctx context.Context, conn net.Conn, metadata ad
 	}
 
 	if metadata.InboundOptions.SniffEnabled {
-		buffer := buf.NewPacket()
-		sniffMetadata, err := sniff.PeekStream(
-			ctx,
-			conn,
-			buffer,
-			time.Duration(metadata.InboundOptions.SniffTimeout),
-			sniff.StreamDomainNameQuery,
-			sniff.TLSClientHello,
-			sniff.HTTPHost,
-			sniff.BitTorrent,
-		)
-		if sniffMetadata != nil {
-			metadata.Protocol = sniffMetadata.Protocol
-			metadata.Domain = sniffMetadata.Domain
-			if metadata.InboundOptions.SniffOverrideDestination && M.IsDomainName(metadata.Domain) {
-				metadata.Destination = M.Socksaddr{
-					Fqdn: metadata.Domain,
-					Port: metadata.Destination.Port,
-				}
-			}
-			if metadata.Domain != "" {
-				r.logger.DebugContext(ctx, "sniffed protocol: ", metadata.Protocol, ", domain: ", metadata.Domain)
-			} else {
-				r.logger.DebugContext(ctx, "sniffed protocol: ", metadata.Protocol)
-			}
-		} else if err != nil {
-			r.logger.TraceContext(ctx, "sniffed no protocol: ", err)
-		}
-		if !buffer.IsEmpty() {
-			conn = bufio.NewCachedConn(conn, buffer)
-		} else {
-			buffer.Release()
-		}
+		conn = r.sniffConnection(ctx, conn, &metadata, time.Duration(metadata.InboundOptions.SniffTimeout), metadata.InboundOptions.SniffOverrideDestination, defaultStreamSniffers)
 	}
 
 	if r.dnsReverseMapping != nil && metadata.Domain == "" {
@@ -885,25 +1178,33 @@ func (r *Router) RouteConnection(ctx context.Context, conn net.Conn, metadata ad
 	}
 
 	if metadata.Destination.IsFqdn() && dns.DomainStrategy(metadata.InboundOptions.DomainStrategy) != dns.DomainStrategyAsIS {
-		addresses, err := r.Lookup(adapter.WithContext(ctx, &metadata), metadata.Destination.Fqdn, dns.DomainStrategy(metadata.InboundOptions.DomainStrategy))
+		err := r.resolveDestination(ctx, &metadata, dns.DomainStrategy(metadata.InboundOptions.DomainStrategy), "")
 		if err != nil {
 			return err
 		}
-		metadata.DestinationAddresses = addresses
-		r.dnsLogger.DebugContext(ctx, "resolved [", strings.Join(F.MapToString(metadata.DestinationAddresses), " "), "]")
 	}
 	if metadata.Destination.IsIPv4() {
 		metadata.IPVersion = 4
 	} else if metadata.Destination.IsIPv6() {
 		metadata.IPVersion = 6
 	}
-	ctx, matchedRule, detour, err := r.match(ctx, &metadata, r.defaultOutboundForConnection)
+	ctx, matchedRule, action, detour, err := r.match(ctx, &metadata, &conn, nil, r.defaultOutbound(N.NetworkTCP))
 	if err != nil {
 		return err
 	}
+	r.accessLogMatched(accessLogTracker, metadata, matchedRule, action, detour)
+	switch action := action.(type) {
+	case *RuleActionReject:
+		return r.rejectConnection(ctx, conn, metadata, action)
+	case *RuleActionHijackDNS:
+		return r.dnsHijacker.NewConnection(ctx, accessLogTracker.newConn(conn), metadata)
+	}
 	if !common.Contains(detour.Network(), N.NetworkTCP) {
 		return E.New("missing supported outbound, closing connection")
 	}
+	for _, limiter := range r.outboundBandwidthLimiters(detour) {
+		conn = limiter.NewConn(conn)
+	}
 	if r.clashServer != nil {
 		trackerConn, tracker := r.clashServer.RoutedConnection(ctx, conn, metadata, matchedRule)
 		defer tracker.Leave()
@@ -914,10 +1215,31 @@ func (r *Router) RouteConnection(ctx context.Context, conn net.Conn, metadata ad
 			conn = statsService.RoutedConnection(metadata.Inbound, detour.Tag(), metadata.User, conn)
 		}
 	}
+	if r.metricsServer != nil {
+		trackerConn, tracker := r.metricsServer.RoutedConnection(ctx, conn, metadata, matchedRule, r.outboundChain(detour))
+		defer tracker.Leave()
+		conn = trackerConn
+	}
+	if r.needLogFields {
+		upload, download := updateLogFields(ctx, metadata, matchedRule, detour)
+		conn = bufio.NewInt64CounterConn(conn, []*atomic.Int64{upload}, []*atomic.Int64{download})
+	}
+	conn = accessLogTracker.newConn(conn)
+	defer r.connections.Add(detour, conn)()
 	return detour.NewConnection(ctx, conn, metadata)
 }
 
 func (r *Router) RoutePacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext) error {
+	if r.accessLog == nil || metadata.InboundDetour != "" {
+		return r.routePacketConnection(ctx, conn, metadata, nil)
+	}
+	tracker := r.newAccessLogTracker(ctx, N.NetworkUDP, metadata)
+	err := r.routePacketConnection(ctx, conn, metadata, tracker)
+	tracker.finish(err)
+	return err
+}
+
+func (r *Router) routePacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext, accessLogTracker *accessLogTracker) error {
 	if r.pauseManager.IsDevicePaused() {
 		return E.New("reject packet connection to ", metadata.Destination, " while device paused")
 	}
@@ -925,8 +1247,8 @@ func (r *Router) RoutePacketConnection(ctx context.Context, conn N.PacketConn, m
 		if metadata.LastInbound == metadata.InboundDetour {
 			return E.New("routing loop on detour: ", metadata.InboundDetour)
 		}
-		detour := r.inboundByTag[metadata.InboundDetour]
-		if detour == nil {
+		detour, loaded := r.Inbound(metadata.InboundDetour)
+		if !loaded {
 			return E.New("inbound detour not found: ", metadata.InboundDetour)
 		}
 		injectable, isInjectable := detour.(adapter.InjectableInbound)
@@ -947,9 +1269,12 @@ func (r *Router) RoutePacketConnection(ctx context.Context, conn N.PacketConn, m
 	}
 	conntrack.KillerCheck()
 	metadata.Network = N.NetworkUDP
+	// The address the client sent to, before fakeip and sniffing rewrite it.
+	inboundDestination := metadata.Destination
 
-	if r.fakeIPStore != nil && r.fakeIPStore.Contains(metadata.Destination.Addr) {
-		domain, loaded := r.fakeIPStore.Lookup(metadata.Destination.Addr)
+	if fakeIPStore := r.fakeIPStoreFor(metadata.Destination.Addr); fakeIPStore != nil {
+		defer fakeIPStore.Acquire(metadata.Destination.Addr)()
+		domain, loaded := fakeIPStore.Lookup(metadata.Destination.Addr)
 		if !loaded {
 			return E.New("missing fakeip context")
 		}
@@ -976,32 +1301,10 @@ func (r *Router) RoutePacketConnection(ctx context.Context, conn N.PacketConn, m
 		}
 		if metadata.Destination.Addr.IsUnspecified() {
 			metadata.Destination = destination
+			inboundDestination = destination
 		}
 		if metadata.InboundOptions.SniffEnabled {
-			sniffMetadata, _ := sniff.PeekPacket(
-				ctx,
-				buffer.Bytes(),
-				sniff.DomainNameQuery,
-				sniff.QUICClientHello,
-				sniff.STUNMessage,
-				sniff.UTP,
-				sniff.UDPTracker,
-			)
-			if sniffMetadata != nil {
-				metadata.Protocol = sniffMetadata.Protocol
-				metadata.Domain = sniffMetadata.Domain
-				if metadata.InboundOptions.SniffOverrideDestination && M.IsDomainName(metadata.Domain) {
-					metadata.Destination = M.Socksaddr{
-						Fqdn: metadata.Domain,
-						Port: metadata.Destination.Port,
-					}
-				}
-				if metadata.Domain != "" {
-					r.logger.DebugContext(ctx, "sniffed packet protocol: ", metadata.Protocol, ", domain: ", metadata.Domain)
-				} else {
-					r.logger.DebugContext(ctx, "sniffed packet protocol: ", metadata.Protocol)
-				}
-			}
+			r.sniffPacket(ctx, buffer.Bytes(), &metadata, metadata.InboundOptions.SniffOverrideDestination, defaultPacketSniffers)
 		}
 		conn = bufio.NewCachedPacketConn(conn, buffer, destination)
 	}
@@ -1013,25 +1316,33 @@ func (r *Router) RoutePacketConnection(ctx context.Context, conn N.PacketConn, m
 		}
 	}
 	if metadata.Destination.IsFqdn() && dns.DomainStrategy(metadata.InboundOptions.DomainStrategy) != dns.DomainStrategyAsIS {
-		addresses, err := r.Lookup(adapter.WithContext(ctx, &metadata), metadata.Destination.Fqdn, dns.DomainStrategy(metadata.InboundOptions.DomainStrategy))
+		err := r.resolveDestination(ctx, &metadata, dns.DomainStrategy(metadata.InboundOptions.DomainStrategy), "")
 		if err != nil {
 			return err
 		}
-		metadata.DestinationAddresses = addresses
-		r.dnsLogger.DebugContext(ctx, "resolved [", strings.Join(F.MapToString(metadata.DestinationAddresses), " "), "]")
 	}
 	if metadata.Destination.IsIPv4() {
 		metadata.IPVersion = 4
 	} else if metadata.Destination.IsIPv6() {
 		metadata.IPVersion = 6
 	}
-	ctx, matchedRule, detour, err := r.match(ctx, &metadata, r.defaultOutboundForPacketConnection)
+	ctx, matchedRule, action, detour, err := r.match(ctx, &metadata, nil, &conn, r.defaultOutbound(N.NetworkUDP))
 	if err != nil {
 		return err
 	}
+	r.accessLogMatched(accessLogTracker, metadata, matchedRule, action, detour)
+	switch action := action.(type) {
+	case *RuleActionReject:
+		return r.rejectPacketConnection(ctx, conn, metadata, inboundDestination, action)
+	case *RuleActionHijackDNS:
+		return r.dnsHijacker.NewPacketConnection(ctx, accessLogTracker.newPacketConn(conn), metadata)
+	}
 	if !common.Contains(detour.Network(), N.NetworkUDP) {
 		return E.New("missing supported outbound, closing packet connection")
 	}
+	for _, limiter := range r.outboundBandwidthLimiters(detour) {
+		conn = limiter.NewPacketConn(conn)
+	}
 	if r.clashServer != nil {
 		trackerConn, tracker := r.clashServer.RoutedPacketConnection(ctx, conn, metadata, matchedRule)
 		defer tracker.Leave()
@@ -1042,24 +1353,68 @@ func (r *Router) RoutePacketConnection(ctx context.Context, conn N.PacketConn, m
 			conn = statsService.RoutedPacketConnection(metadata.Inbound, detour.Tag(), metadata.User, conn)
 		}
 	}
+	if r.metricsServer != nil {
+		trackerConn, tracker := r.metricsServer.RoutedPacketConnection(ctx, conn, metadata, matchedRule, r.outboundChain(detour))
+		defer tracker.Leave()
+		conn = trackerConn
+	}
+	if r.needLogFields {
+		upload, download := updateLogFields(ctx, metadata, matchedRule, detour)
+		conn = bufio.NewInt64CounterPacketConn(conn, []*atomic.Int64{upload}, []*atomic.Int64{download})
+	}
+	conn = accessLogTracker.newPacketConn(conn)
+	defer r.connections.Add(detour, conn)()
 	if metadata.FakeIP {
 		conn = bufio.NewNATPacketConn(bufio.NewNetPacketConn(conn), metadata.OriginDestination, metadata.Destination)
 	}
 	return detour.NewPacketConnection(ctx, conn, metadata)
 }
 
-func (r *Router) match(ctx context.Context, metadata *adapter.InboundContext, defaultOutbound adapter.Outbound) (context.Context, adapter.Rule, adapter.Outbound, error) {
-	matchRule, matchOutbound := r.match0(ctx, metadata, defaultOutbound)
+// updateLogFields attaches the routing result to the connection ID, so that
+// structured logs of the connection carry it.
+func updateLogFields(ctx context.Context, metadata adapter.InboundContext, matchedRule adapter.Rule, detour adapter.Outbound) (*atomic.Int64, *atomic.Int64) {
+	fields := log.Fields{
+		Inbound:  metadata.Inbound,
+		Domain:   metadata.Domain,
+		Outbound: detour.Tag(),
+		Upload:   new(atomic.Int64),
+		Download: new(atomic.Int64),
+	}
+	if metadata.Source.IsValid() {
+		fields.Source = metadata.Source.String()
+	}
+	if metadata.Destination.IsValid() {
+		fields.Destination = metadata.Destination.String()
+	}
+	if matchedRule != nil {
+		fields.Rule = matchedRule.String()
+	} else {
+		fields.Rule = "final"
+	}
+	log.UpdateFields(ctx, fields)
+	return fields.Upload, fields.Download
+}
+
+// match runs the actions of matched rules in order until a final action is reached.
+// The returned outbound is nil unless the final action routes the connection.
+func (r *Router) match(ctx context.Context, metadata *adapter.InboundContext, conn *net.Conn, packetConn *N.PacketConn, defaultOutbound adapter.Outbound) (context.Context, adapter.Rule, adapter.RuleAction, adapter.Outbound, error) {
+	matchRule, matchAction, matchOutbound, err := r.match0(ctx, metadata, conn, packetConn, defaultOutbound)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	if matchOutbound == nil {
+		return ctx, matchRule, matchAction, nil, nil
+	}
 	if contextOutbound, loaded := outbound.TagFromContext(ctx); loaded {
 		if contextOutbound == matchOutbound.Tag() {
-			return nil, nil, nil, E.New("connection loopback in outbound/", matchOutbound.Type(), "[", matchOutbound.Tag(), "]")
+			return nil, nil, nil, nil, E.New("connection loopback in outbound/", matchOutbound.Type(), "[", matchOutbound.Tag(), "]")
 		}
 	}
 	ctx = outbound.ContextWithTag(ctx, matchOutbound.Tag())
-	return ctx, matchRule, matchOutbound, nil
+	return ctx, matchRule, matchAction, matchOutbound, nil
 }
 
-func (r *Router) match0(ctx context.Context, metadata *adapter.InboundContext, defaultOutbound adapter.Outbound) (adapter.Rule, adapter.Outbound) {
+func (r *Router) match0(ctx context.Context, metadata *adapter.InboundContext, conn *net.Conn, packetConn *N.PacketConn, defaultOutbound adapter.Outbound) (adapter.Rule, adapter.RuleAction, adapter.Outbound, error) {
 	if r.processSearcher != nil {
 		var originDestination netip.AddrPort
 		if metadata.OriginDestination.IsValid() {
@@ -1091,18 +1446,51 @@ func (r *Router) match0(ctx context.Context, metadata *adapter.InboundContext, d
 			metadata.ProcessInfo = processInfo
 		}
 	}
-	for i, rule := range r.rules {
+	for i, rule := range r.Rules() {
 		metadata.ResetRuleCache()
-		if rule.Match(metadata) {
-			detour := rule.Outbound()
-			r.logger.DebugContext(ctx, "match[", i, "] ", rule.String(), " => ", detour)
-			if outbound, loaded := r.Outbound(detour); loaded {
-				return rule, outbound
+		if !rule.Match(metadata) {
+			continue
+		}
+		r.logger.DebugContext(ctx, "match[", i, "] ", rule.String(), " => ", rule.Action())
+		switch action := rule.Action().(type) {
+		case *RuleActionRoute:
+			detour, loaded := r.routeOutbound(action.Outbound)
+			if !loaded {
+				r.logger.ErrorContext(ctx, "outbound not found: ", action.Outbound)
+				continue
+			}
+			action.Apply(metadata)
+			action.Limit(conn, packetConn)
+			return rule, action, detour, nil
+		case *RuleActionRouteOptions:
+			action.Apply(metadata)
+			action.Limit(conn, packetConn)
+		case *RuleActionSniff:
+			if metadata.Protocol != "" {
+				continue
 			}
-			r.logger.ErrorContext(ctx, "outbound not found: ", detour)
+			if conn != nil {
+				*conn = r.sniffConnection(ctx, *conn, metadata, action.Timeout, action.OverrideDestination, action.StreamSniffers)
+			} else if packetConn != nil {
+				newConn, err := r.sniffPacketConnection(ctx, *packetConn, metadata, action.OverrideDestination, action.PacketSniffers)
+				if err != nil {
+					return nil, nil, nil, err
+				}
+				*packetConn = newConn
+			}
+		case *RuleActionResolve:
+			if !metadata.Destination.IsFqdn() {
+				continue
+			}
+			err := r.resolveDestination(ctx, metadata, action.Strategy, action.Server)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+		default:
+			return rule, action, nil, nil
 		}
 	}
-	return nil, defaultOutbound
+	return nil, nil, defaultOutbound, nil
 }
 
 func (r *Router) InterfaceFinder() control.InterfaceFinder {
@@ -1172,6 +1560,8 @@ func (r *Router) DefaultMark() uint32 {
 }
 
 func (r *Router) Rules() []adapter.Rule {
+	r.ruleAccess.RLock()
+	defer r.ruleAccess.RUnlock()
 	return r.rules
 }
 
@@ -1207,6 +1597,14 @@ func (r *Router) SetV2RayServer(server adapter.V2RayServer) {
 	r.v2rayServer = server
 }
 
+func (r *Router) MetricsServer() adapter.MetricsServer {
+	return r.metricsServer
+}
+
+func (r *Router) SetMetricsServer(server adapter.MetricsServer) {
+	r.metricsServer = server
+}
+
 func (r *Router) OnPackagesUpdated(packages int, sharedUsers int) {
 	r.logger.Info("updated packages list: ", packages, " packages, ", sharedUsers, " shared users")
 }
@@ -1249,7 +1647,10 @@ func (r *Router) notifyNetworkUpdate(event int) {
 func (r *Router) ResetNetwork() error {
 	conntrack.Close()
 
-	for _, outbound := range r.outbounds {
+	r.ruleAccess.RLock()
+	outbounds := r.outbounds
+	r.ruleAccess.RUnlock()
+	for _, outbound := range outbounds {
 		listener, isListener := outbound.(adapter.InterfaceUpdateListener)
 		if isListener {
 			listener.InterfaceUpdated()
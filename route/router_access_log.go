@@ -0,0 +1,114 @@
+package route
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/accesslog"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/atomic"
+	"github.com/sagernet/sing/common/bufio"
+	E "github.com/sagernet/sing/common/exceptions"
+	N "github.com/sagernet/sing/common/network"
+)
+
+type accessLogTracker struct {
+	writer   *accesslog.Writer
+	record   accesslog.Record
+	upload   atomic.Int64
+	download atomic.Int64
+}
+
+// newAccessLogTracker starts a record before routing, so that connections
+// failing before a rule is matched are logged too.
+func (r *Router) newAccessLogTracker(ctx context.Context, network string, metadata adapter.InboundContext) *accessLogTracker {
+	tracker := &accessLogTracker{
+		writer: r.accessLog,
+		record: accesslog.Record{
+			StartTime: time.Now(),
+			Network:   network,
+		},
+	}
+	if id, loaded := log.IDFromContext(ctx); loaded {
+		tracker.record.ID = id.ID
+		tracker.record.StartTime = id.CreatedAt
+	}
+	tracker.update(metadata)
+	return tracker
+}
+
+func (t *accessLogTracker) update(metadata adapter.InboundContext) {
+	record := &t.record
+	record.Inbound = metadata.Inbound
+	record.InboundType = metadata.InboundType
+	record.User = metadata.User
+	record.Domain = metadata.Domain
+	record.Protocol = metadata.Protocol
+	if metadata.Source.IsValid() {
+		record.Source = metadata.Source.String()
+	}
+	if metadata.Destination.IsValid() {
+		record.Destination = metadata.Destination.String()
+	}
+	if processInfo := metadata.ProcessInfo; processInfo != nil {
+		record.ProcessPath = processInfo.ProcessPath
+		record.PackageName = processInfo.PackageName
+		record.ProcessUser = processInfo.User
+	}
+}
+
+// accessLogMatched records the routing decision, detour is nil for actions other than route.
+func (r *Router) accessLogMatched(t *accessLogTracker, metadata adapter.InboundContext, matchedRule adapter.Rule, action adapter.RuleAction, detour adapter.Outbound) {
+	if t == nil {
+		return
+	}
+	t.update(metadata)
+	if matchedRule != nil {
+		t.record.Rule = matchedRule.String()
+	} else {
+		t.record.Rule = "final"
+	}
+	if action != nil {
+		t.record.Action = action.Type()
+	} else {
+		t.record.Action = C.RuleActionTypeRoute
+	}
+	if detour != nil {
+		t.record.Outbound = detour.Tag()
+		t.record.Chain = common.Map(r.outboundChain(detour), adapter.Outbound.Tag)
+	}
+}
+
+func (t *accessLogTracker) newConn(conn net.Conn) net.Conn {
+	if t == nil {
+		return conn
+	}
+	return bufio.NewInt64CounterConn(conn, []*atomic.Int64{&t.upload}, []*atomic.Int64{&t.download})
+}
+
+func (t *accessLogTracker) newPacketConn(conn N.PacketConn) N.PacketConn {
+	if t == nil {
+		return conn
+	}
+	return bufio.NewInt64CounterPacketConn(conn, []*atomic.Int64{&t.upload}, []*atomic.Int64{&t.download})
+}
+
+func (t *accessLogTracker) finish(err error) {
+	if t == nil {
+		return
+	}
+	t.record.EndTime = time.Now()
+	t.record.Duration = t.record.EndTime.Sub(t.record.StartTime).Milliseconds()
+	t.record.Upload = t.upload.Load()
+	t.record.Download = t.download.Load()
+	if err == nil || E.IsClosedOrCanceled(err) {
+		t.record.CloseReason = "closed"
+	} else {
+		t.record.CloseReason = err.Error()
+	}
+	t.writer.Write(t.record)
+}
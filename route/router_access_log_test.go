@@ -0,0 +1,98 @@
+package route
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/accesslog"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-box/outbound"
+	"github.com/sagernet/sing/common/json"
+	M "github.com/sagernet/sing/common/metadata"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessLogTerminalDecisions(t *testing.T) {
+	t.Parallel()
+	router := newTestRouter(t, []adapter.Outbound{&testOutbound{tag: "default"}}, nil)
+	router.dnsHijacker = outbound.NewDNS(router, C.RuleActionTypeHijackDNS)
+	path := filepath.Join(t.TempDir(), "access.log")
+	writer, err := accesslog.NewWriter(context.Background(), log.NewNOPFactory().Logger(), option.AccessLogOptions{
+		Enabled: true,
+		Output:  path,
+	})
+	require.NoError(t, err)
+	require.NoError(t, writer.Start())
+	router.accessLog = writer
+	setTestRules(t, router, []option.Rule{
+		{Type: C.RuleTypeDefault, DefaultOptions: option.DefaultRule{
+			Port:       []uint16{443},
+			RuleAction: option.RuleAction{Action: C.RuleActionTypeReject},
+		}},
+		{Type: C.RuleTypeDefault, DefaultOptions: option.DefaultRule{
+			Port:       []uint16{53},
+			RuleAction: option.RuleAction{Action: C.RuleActionTypeHijackDNS},
+		}},
+	})
+	for _, destination := range []M.Socksaddr{
+		M.ParseSocksaddrHostPort("10.0.0.1", 443),
+		M.ParseSocksaddrHostPort("10.0.0.1", 53),
+		M.ParseSocksaddrHostPort("sp.mux.sing-box.arpa", 444),
+		M.ParseSocksaddrHostPort("10.0.0.1", 80),
+	} {
+		conn, peer := net.Pipe()
+		peer.Close()
+		router.RouteConnection(context.Background(), conn, adapter.InboundContext{
+			Inbound:     "in",
+			Source:      M.ParseSocksaddrHostPort("10.0.0.2", 10000),
+			Destination: destination,
+		})
+	}
+	require.NoError(t, writer.Close())
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	var records []accesslog.Record
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record accesslog.Record
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		records = append(records, record)
+	}
+	require.Len(t, records, 4)
+	for _, record := range records {
+		require.Equal(t, "tcp", record.Network)
+		require.Equal(t, "in", record.Inbound)
+		require.Equal(t, "10.0.0.2:10000", record.Source)
+	}
+
+	require.Equal(t, "10.0.0.1:443", records[0].Destination)
+	require.Equal(t, C.RuleActionTypeReject, records[0].Action)
+	require.Equal(t, router.rules[0].String(), records[0].Rule)
+	require.Empty(t, records[0].Outbound)
+
+	require.Equal(t, C.RuleActionTypeHijackDNS, records[1].Action)
+	require.Equal(t, router.rules[1].String(), records[1].Rule)
+	require.Empty(t, records[1].Outbound)
+
+	// rejected before routing
+	require.Equal(t, "sp.mux.sing-box.arpa:444", records[2].Destination)
+	require.Empty(t, records[2].Action)
+	require.Empty(t, records[2].Rule)
+	require.Contains(t, records[2].CloseReason, "global multiplex")
+
+	require.Equal(t, C.RuleActionTypeRoute, records[3].Action)
+	require.Equal(t, "final", records[3].Rule)
+	require.Equal(t, "default", records[3].Outbound)
+	require.Equal(t, []string{"default"}, records[3].Chain)
+	require.Equal(t, "closed", records[3].CloseReason)
+}
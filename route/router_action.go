@@ -0,0 +1,217 @@
+package route
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/ratelimit"
+	"github.com/sagernet/sing-box/common/sniff"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-dns"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/buf"
+	"github.com/sagernet/sing/common/bufio"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+func (r *Router) sniffConnection(ctx context.Context, conn net.Conn, metadata *adapter.InboundContext, timeout time.Duration, overrideDestination bool, sniffers []sniff.StreamSniffer) net.Conn {
+	if len(sniffers) == 0 {
+		return conn
+	}
+	buffer := buf.NewPacket()
+	sniffMetadata, err := sniff.PeekStream(ctx, conn, buffer, timeout, sniffers...)
+	if sniffMetadata != nil {
+		metadata.Protocol = sniffMetadata.Protocol
+		metadata.Domain = sniffMetadata.Domain
+		metadata.ClientALPN = sniffMetadata.ClientALPN
+		metadata.ClientFingerprint = sniffMetadata.ClientFingerprint
+		if overrideDestination && M.IsDomainName(metadata.Domain) {
+			metadata.Destination = M.Socksaddr{
+				Fqdn: metadata.Domain,
+				Port: metadata.Destination.Port,
+			}
+		}
+		if metadata.Domain != "" {
+			r.logger.DebugContext(ctx, "sniffed protocol: ", metadata.Protocol, ", domain: ", metadata.Domain)
+		} else {
+			r.logger.DebugContext(ctx, "sniffed protocol: ", metadata.Protocol)
+		}
+		if metadata.ClientFingerprint != "" {
+			r.logger.DebugContext(ctx, "sniffed client fingerprint: ", metadata.ClientFingerprint, ", alpn: ", strings.Join(metadata.ClientALPN, ","))
+		}
+	} else if err != nil {
+		r.logger.TraceContext(ctx, "sniffed no protocol: ", err)
+	}
+	if !buffer.IsEmpty() {
+		return bufio.NewCachedConn(conn, buffer)
+	}
+	buffer.Release()
+	return conn
+}
+
+func (r *Router) sniffPacketConnection(ctx context.Context, conn N.PacketConn, metadata *adapter.InboundContext, overrideDestination bool, sniffers []sniff.PacketSniffer) (N.PacketConn, error) {
+	if len(sniffers) == 0 {
+		return conn, nil
+	}
+	buffer := buf.NewPacket()
+	destination, err := conn.ReadPacket(buffer)
+	if err != nil {
+		buffer.Release()
+		return nil, err
+	}
+	r.sniffPacket(ctx, buffer.Bytes(), metadata, overrideDestination, sniffers)
+	return bufio.NewCachedPacketConn(conn, buffer, destination), nil
+}
+
+func (r *Router) sniffPacket(ctx context.Context, packet []byte, metadata *adapter.InboundContext, overrideDestination bool, sniffers []sniff.PacketSniffer) {
+	sniffMetadata, _ := sniff.PeekPacket(ctx, packet, sniffers...)
+	if sniffMetadata == nil {
+		return
+	}
+	metadata.Protocol = sniffMetadata.Protocol
+	metadata.Domain = sniffMetadata.Domain
+	metadata.ClientALPN = sniffMetadata.ClientALPN
+	metadata.ClientFingerprint = sniffMetadata.ClientFingerprint
+	if overrideDestination && M.IsDomainName(metadata.Domain) {
+		metadata.Destination = M.Socksaddr{
+			Fqdn: metadata.Domain,
+			Port: metadata.Destination.Port,
+		}
+	}
+	if metadata.Domain != "" {
+		r.logger.DebugContext(ctx, "sniffed packet protocol: ", metadata.Protocol, ", domain: ", metadata.Domain)
+	} else {
+		r.logger.DebugContext(ctx, "sniffed packet protocol: ", metadata.Protocol)
+	}
+	if metadata.ClientFingerprint != "" {
+		r.logger.DebugContext(ctx, "sniffed packet client fingerprint: ", metadata.ClientFingerprint, ", alpn: ", strings.Join(metadata.ClientALPN, ","))
+	}
+}
+
+func (r *Router) resolveDestination(ctx context.Context, metadata *adapter.InboundContext, strategy dns.DomainStrategy, server string) error {
+	var (
+		addresses []netip.Addr
+		err       error
+	)
+	if server == "" {
+		addresses, err = r.Lookup(adapter.WithContext(ctx, metadata), metadata.Destination.Fqdn, strategy)
+	} else {
+		transport, loaded := r.transportMap[server]
+		if !loaded {
+			return E.New("DNS server not found: ", server)
+		}
+		if strategy == dns.DomainStrategyAsIS {
+			strategy = r.transportDomainStrategy[transport]
+		}
+		addresses, err = r.dnsClient.Lookup(adapter.WithContext(ctx, metadata), transport, metadata.Destination.Fqdn, strategy)
+	}
+	if err != nil {
+		return err
+	}
+	metadata.DestinationAddresses = addresses
+	r.dnsLogger.DebugContext(ctx, "resolved [", strings.Join(F.MapToString(metadata.DestinationAddresses), " "), "]")
+	return nil
+}
+
+func (r *Router) rejectConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext, action *RuleActionReject) error {
+	r.logger.InfoContext(ctx, "reject connection to ", metadata.Destination, " (", action.Method, ")")
+	switch action.Method {
+	case C.RuleActionRejectMethodDrop:
+		// Keep the connection silent until the client gives up.
+		conn.SetReadDeadline(time.Now().Add(C.RejectDropTimeout))
+		io.Copy(io.Discard, conn)
+	case C.RuleActionRejectMethodTCPReset:
+		if tcpConn, isTCPConn := common.Cast[*net.TCPConn](conn); isTCPConn {
+			// Closing with zero linger sends RST instead of FIN.
+			tcpConn.SetLinger(0)
+		} else {
+			r.logger.WarnContext(ctx, "tcp-reset is not supported by inbound/", metadata.InboundType, "[", metadata.Inbound, "], closing instead")
+		}
+	}
+	common.Close(conn)
+	return nil
+}
+
+// rejectPacketConnection rejects a UDP packet connection. destination is the address
+// the client sent to, which ICMP replies are sent from.
+func (r *Router) rejectPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext, destination M.Socksaddr, action *RuleActionReject) error {
+	r.logger.InfoContext(ctx, "reject packet connection to ", metadata.Destination, " (", action.Method, ")")
+	switch action.Method {
+	case C.RuleActionRejectMethodDefault:
+		// Close without a reply.
+	case C.RuleActionRejectMethodTCPReset:
+		r.logger.WarnContext(ctx, "tcp-reset is not supported for UDP connections, closing instead")
+	case C.RuleActionRejectMethodICMP:
+		r.rejectPacketWithICMP(ctx, metadata, destination)
+	case C.RuleActionRejectMethodDrop:
+		conn.SetReadDeadline(time.Now().Add(C.RejectDropTimeout))
+		for {
+			buffer := buf.NewPacket()
+			_, err := conn.ReadPacket(buffer)
+			buffer.Release()
+			if err != nil {
+				break
+			}
+		}
+	}
+	common.Close(conn)
+	return nil
+}
+
+// rejectPacketWithICMP replies with ICMP port unreachable if the inbound supports it,
+// otherwise the connection is just closed.
+func (r *Router) rejectPacketWithICMP(ctx context.Context, metadata adapter.InboundContext, destination M.Socksaddr) {
+	inbound, _ := r.Inbound(metadata.Inbound)
+	unreachableInbound, isUnreachableInbound := inbound.(adapter.PortUnreachableInbound)
+	if !isUnreachableInbound || !metadata.Source.IsIP() || !destination.IsIP() {
+		r.logger.WarnContext(ctx, "icmp is not supported by inbound/", metadata.InboundType, "[", metadata.Inbound, "], closing instead")
+		return
+	}
+	err := unreachableInbound.WritePortUnreachable(metadata.Source.AddrPort(), destination.AddrPort())
+	if err != nil {
+		r.logger.WarnContext(ctx, "write icmp port unreachable: ", err)
+	}
+}
+
+// outboundChain returns the outbound and the outbounds currently selected
+// through it, if it is a group.
+func (r *Router) outboundChain(detour adapter.Outbound) []adapter.Outbound {
+	chain := []adapter.Outbound{detour}
+	for {
+		group, isGroup := detour.(adapter.OutboundGroup)
+		if !isGroup {
+			return chain
+		}
+		next, loaded := r.Outbound(group.Now())
+		if !loaded || next == detour {
+			return chain
+		}
+		chain = append(chain, next)
+		detour = next
+	}
+}
+
+// outboundBandwidthLimiters returns the limiters of the outbound chain, up to a group
+// selecting its member for each connection, which applies the limits of the member itself.
+func (r *Router) outboundBandwidthLimiters(detour adapter.Outbound) []*ratelimit.Limiter {
+	var limiters []*ratelimit.Limiter
+	for _, outbound := range r.outboundChain(detour) {
+		if limitedOutbound, isLimited := outbound.(adapter.BandwidthLimitedOutbound); isLimited {
+			if limiter := limitedOutbound.BandwidthLimiter(); limiter != nil {
+				limiters = append(limiters, limiter)
+			}
+		}
+		if _, isPerConnection := outbound.(adapter.PerConnectionOutboundGroup); isPerConnection {
+			break
+		}
+	}
+	return limiters
+}
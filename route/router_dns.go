@@ -8,7 +8,10 @@ import (
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/transport/hosts"
 	"github.com/sagernet/sing-dns"
+	"github.com/sagernet/sing/common"
 	"github.com/sagernet/sing/common/cache"
 	E "github.com/sagernet/sing/common/exceptions"
 	F "github.com/sagernet/sing/common/format"
@@ -41,8 +44,10 @@ func (r *Router) matchDNS(ctx context.Context, allowFakeIP bool, index int, isAd
 	if metadata == nil {
 		panic("no context")
 	}
-	if index < len(r.dnsRules) {
-		dnsRules := r.dnsRules
+	r.ruleAccess.RLock()
+	dnsRules := r.dnsRules
+	r.ruleAccess.RUnlock()
+	if index < len(dnsRules) {
 		if index != -1 {
 			dnsRules = dnsRules[index+1:]
 		}
@@ -53,13 +58,20 @@ func (r *Router) matchDNS(ctx context.Context, allowFakeIP bool, index int, isAd
 			metadata.ResetRuleCache()
 			if rule.Match(metadata) {
 				detour := rule.Outbound()
-				transport, loaded := r.transportMap[detour]
-				if !loaded {
-					r.dnsLogger.ErrorContext(ctx, "transport not found: ", detour)
-					continue
+				transport := rule.Predefined()
+				isPredefined := transport != nil
+				if isPredefined {
+					detour = transport.Name()
+				} else {
+					var loaded bool
+					transport, loaded = r.transportMap[detour]
+					if !loaded {
+						r.dnsLogger.ErrorContext(ctx, "transport not found: ", detour)
+						continue
+					}
 				}
 				_, isFakeIP := transport.(adapter.FakeIPTransport)
-				if isFakeIP && !allowFakeIP {
+				if isFakeIP && (!allowFakeIP || r.fakeIPExcluded(transport.Name(), metadata)) {
 					continue
 				}
 				ruleIndex := currentRuleIndex
@@ -67,7 +79,18 @@ func (r *Router) matchDNS(ctx context.Context, allowFakeIP bool, index int, isAd
 					ruleIndex += index + 1
 				}
 				r.dnsLogger.DebugContext(ctx, "match[", ruleIndex, "] ", rule.String(), " => ", detour)
-				if isFakeIP || rule.DisableCache() {
+				domainStrategy, dsLoaded := r.transportDomainStrategy[transport]
+				if !dsLoaded {
+					domainStrategy = r.defaultDomainStrategy
+				}
+				var validateDNSSEC bool
+				if rule.DNSSEC() && !isFakeIP && !isPredefined && !r.isDNSSECTransport(transport) {
+					// responses cached without validation must not be served
+					transport = r.ruleDNSSECTransport(transport)
+					validateDNSSEC = true
+				}
+				_, isHosts := transport.(*hosts.Transport)
+				if isFakeIP || isPredefined || isHosts || validateDNSSEC || rule.DisableCache() {
 					ctx = dns.ContextWithDisableCache(ctx, true)
 				}
 				if rewriteTTL := rule.RewriteTTL(); rewriteTTL != nil {
@@ -76,14 +99,13 @@ func (r *Router) matchDNS(ctx context.Context, allowFakeIP bool, index int, isAd
 				if clientSubnet := rule.ClientSubnet(); clientSubnet != nil {
 					ctx = dns.ContextWithClientSubnet(ctx, *clientSubnet)
 				}
-				if domainStrategy, dsLoaded := r.transportDomainStrategy[transport]; dsLoaded {
-					return ctx, transport, domainStrategy, rule, ruleIndex
-				} else {
-					return ctx, transport, r.defaultDomainStrategy, rule, ruleIndex
-				}
+				return ctx, transport, domainStrategy, rule, ruleIndex
 			}
 		}
 	}
+	if _, isHosts := r.defaultTransport.(*hosts.Transport); isHosts {
+		ctx = dns.ContextWithDisableCache(ctx, true)
+	}
 	if domainStrategy, dsLoaded := r.transportDomainStrategy[r.defaultTransport]; dsLoaded {
 		return ctx, r.defaultTransport, domainStrategy, nil, -1
 	} else {
@@ -99,8 +121,10 @@ func (r *Router) Exchange(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, er
 		response  *mDNS.Msg
 		cached    bool
 		transport dns.Transport
+		rule      adapter.DNSRule
 		err       error
 	)
+	exchangeStart := time.Now()
 	response, cached = r.dnsClient.ExchangeCache(ctx, message)
 	if !cached {
 		var metadata *adapter.InboundContext
@@ -118,7 +142,6 @@ func (r *Router) Exchange(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, er
 		}
 		var (
 			strategy  dns.DomainStrategy
-			rule      adapter.DNSRule
 			ruleIndex int
 		)
 		ruleIndex = -1
@@ -131,14 +154,17 @@ func (r *Router) Exchange(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, er
 			dnsCtx = adapter.OverrideContext(dnsCtx)
 			if rule != nil && rule.WithAddressLimit() {
 				addressLimit = true
-				response, err = r.dnsClient.ExchangeWithResponseCheck(dnsCtx, transport, message, strategy, func(response *mDNS.Msg) bool {
+				responseChecker := func(response *mDNS.Msg) bool {
 					addresses, addrErr := dns.MessageToAddresses(response)
 					if addrErr != nil {
 						return false
 					}
 					metadata.DestinationAddresses = addresses
+					metadata.DNSSECStatus = r.dnssecStatus(transport, response)
 					return rule.MatchAddressLimit(metadata)
-				})
+				}
+				dnsCtx = contextWithResponseChecker(dnsCtx, responseChecker)
+				response, err = r.dnsClient.ExchangeWithResponseCheck(dnsCtx, transport, message, strategy, responseChecker)
 			} else {
 				addressLimit = false
 				response, err = r.dnsClient.Exchange(dnsCtx, transport, message, strategy)
@@ -163,6 +189,23 @@ func (r *Router) Exchange(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, er
 			break
 		}
 	}
+	r.dnsExchanged(transport, cached, exchangeStart, err)
+	if r.clashServer != nil {
+		event := adapter.DNSQueryEvent{
+			Time:   exchangeStart,
+			Rule:   rule,
+			Cached: cached,
+		}
+		if len(message.Question) > 0 {
+			event.Domain = fqdnToDomain(message.Question[0].Name)
+			event.QueryTypes = []uint16{message.Question[0].Qtype}
+		}
+		if err == nil {
+			event.RCode = response.Rcode
+			event.Answers = common.Map(response.Answer, formatAnswer)
+		}
+		r.dnsQueried(ctx, event, transport, err)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -187,8 +230,19 @@ func (r *Router) Lookup(ctx context.Context, domain string, strategy dns.DomainS
 		cached        bool
 		err           error
 	)
+	lookupStart := time.Now()
 	responseAddrs, cached = r.dnsClient.LookupCache(ctx, domain, strategy)
 	if cached {
+		r.dnsExchanged(nil, true, lookupStart, nil)
+		if r.clashServer != nil {
+			r.dnsQueried(ctx, adapter.DNSQueryEvent{
+				Time:       lookupStart,
+				Domain:     domain,
+				QueryTypes: lookupQueryTypes(strategy),
+				Cached:     true,
+				Answers:    F.MapToString(responseAddrs),
+			}, nil, nil)
+		}
 		return responseAddrs, nil
 	}
 	r.dnsLogger.DebugContext(ctx, "lookup domain ", domain)
@@ -214,8 +268,29 @@ func (r *Router) Lookup(ctx context.Context, domain string, strategy dns.DomainS
 		}
 		if rule != nil && rule.WithAddressLimit() {
 			addressLimit = true
-			responseAddrs, err = r.dnsClient.LookupWithResponseCheck(dnsCtx, transport, domain, strategy, func(responseAddrs []netip.Addr) bool {
+			dnsCtx = contextWithResponseChecker(dnsCtx, func(response *mDNS.Msg) bool {
+				responseAddrs, addrErr := dns.MessageToAddresses(response)
+				if addrErr != nil {
+					return false
+				}
+				responseMetadata := *metadata
+				responseMetadata.DestinationAddresses = responseAddrs
+				responseMetadata.DNSSECStatus = r.dnssecStatus(transport, response)
+				return rule.MatchAddressLimit(&responseMetadata)
+			})
+			lookupTransport := transport
+			var recorder *dnssecStatusRecorder
+			if transport.Raw() {
+				recorder = &dnssecStatusRecorder{Transport: transport, router: r}
+				lookupTransport = recorder
+			}
+			responseAddrs, err = r.dnsClient.LookupWithResponseCheck(dnsCtx, lookupTransport, domain, strategy, func(responseAddrs []netip.Addr) bool {
 				metadata.DestinationAddresses = responseAddrs
+				if recorder != nil {
+					metadata.DNSSECStatus = recorder.Status()
+				} else {
+					metadata.DNSSECStatus = C.DNSSECStatusInsecure
+				}
 				return rule.MatchAddressLimit(metadata)
 			})
 		} else {
@@ -238,9 +313,19 @@ func (r *Router) Lookup(ctx context.Context, domain string, strategy dns.DomainS
 			break
 		}
 	}
+	r.dnsExchanged(transport, false, lookupStart, err)
 	if len(responseAddrs) > 0 {
 		r.dnsLogger.InfoContext(ctx, "lookup succeed for ", domain, ": ", strings.Join(F.MapToString(responseAddrs), " "))
 	}
+	if r.clashServer != nil {
+		r.dnsQueried(ctx, adapter.DNSQueryEvent{
+			Time:       lookupStart,
+			Domain:     domain,
+			QueryTypes: lookupQueryTypes(strategy),
+			Rule:       rule,
+			Answers:    F.MapToString(responseAddrs),
+		}, transport, err)
+	}
 	return responseAddrs, err
 }
 
@@ -255,6 +340,60 @@ func (r *Router) ClearDNSCache() {
 	}
 }
 
+func (r *Router) dnsExchanged(transport dns.Transport, cached bool, start time.Time, err error) {
+	if r.metricsServer == nil {
+		return
+	}
+	var server string
+	if transport != nil {
+		server = transport.Name()
+	}
+	r.metricsServer.DNSExchanged(server, cached, time.Since(start), err)
+}
+
+func (r *Router) dnsQueried(ctx context.Context, event adapter.DNSQueryEvent, transport dns.Transport, err error) {
+	event.Duration = time.Since(event.Time)
+	if metadata := adapter.ContextFrom(ctx); metadata != nil {
+		event.Inbound = metadata.Inbound
+		event.Source = metadata.Source
+	}
+	if transport != nil {
+		event.Transport = transport.Name()
+		switch transport := transport.(type) {
+		case *dns.RCodeTransport:
+			event.Blocked = true
+		case *predefinedTransport:
+			event.Blocked = transport.Blocked()
+		}
+	}
+	if err != nil {
+		event.Error = err
+		var rcodeErr dns.RCodeError
+		if errors.As(err, &rcodeErr) {
+			event.RCode = int(rcodeErr)
+		} else {
+			event.RCode = mDNS.RcodeServerFailure
+		}
+	}
+	r.clashServer.DNSQueried(event)
+}
+
+func lookupQueryTypes(strategy dns.DomainStrategy) []uint16 {
+	switch strategy {
+	case dns.DomainStrategyUseIPv4:
+		return []uint16{mDNS.TypeA}
+	case dns.DomainStrategyUseIPv6:
+		return []uint16{mDNS.TypeAAAA}
+	default:
+		return []uint16{mDNS.TypeA, mDNS.TypeAAAA}
+	}
+}
+
+func formatAnswer(answer mDNS.RR) string {
+	header := answer.Header()
+	return mDNS.TypeToString[header.Rrtype] + " " + strings.TrimSpace(answer.String()[len(header.String()):])
+}
+
 func isAddressQuery(message *mDNS.Msg) bool {
 	for _, question := range message.Question {
 		if question.Qtype == mDNS.TypeA || question.Qtype == mDNS.TypeAAAA || question.Qtype == mDNS.TypeHTTPS {
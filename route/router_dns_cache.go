@@ -0,0 +1,140 @@
+package route
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/transport/hosts"
+	"github.com/sagernet/sing-dns"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	"github.com/sagernet/sing/service"
+
+	mDNS "github.com/miekg/dns"
+)
+
+// staleAnswerTTL is the TTL of expired answers served from the cache file,
+// as recommended by RFC 8767.
+const staleAnswerTTL = 30
+
+var _ dns.Transport = (*persistentCacheTransport)(nil)
+
+// persistentCacheTransport answers queries from the DNS cache in the cache file
+// before the in-memory cache of dns.Client is warmed up, and serves expired
+// answers while refreshing them in the background.
+type persistentCacheTransport struct {
+	dns.Transport
+	ctx           context.Context
+	logger        logger.ContextLogger
+	cacheFile     adapter.CacheFile
+	refreshAccess sync.Mutex
+	refreshing    map[mDNS.Question]bool
+}
+
+func wrapPersistentCache(ctx context.Context, transport dns.Transport, logger logger.ContextLogger) dns.Transport {
+	if !transport.Raw() {
+		return transport
+	}
+	switch transport.(type) {
+	case adapter.FakeIPTransport, *dns.RCodeTransport, *hosts.Transport, *dnsGroupTransport:
+		return transport
+	}
+	return &persistentCacheTransport{
+		Transport:  transport,
+		ctx:        ctx,
+		logger:     logger,
+		refreshing: make(map[mDNS.Question]bool),
+	}
+}
+
+func (t *persistentCacheTransport) Start() error {
+	cacheFile := service.FromContext[adapter.CacheFile](t.ctx)
+	if cacheFile != nil && cacheFile.StoreDNS() {
+		t.cacheFile = cacheFile
+	}
+	return t.Transport.Start()
+}
+
+func (t *persistentCacheTransport) Exchange(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, error) {
+	if t.cacheFile == nil || dns.DisableCacheFromContext(ctx) || len(message.Question) != 1 || len(message.Ns) > 0 || len(message.Extra) > 0 {
+		return t.Transport.Exchange(ctx, message)
+	}
+	question := message.Question[0]
+	response, expireAt, loaded := t.cacheFile.LoadDNSCache(t.Name(), question.Name, question.Qtype)
+	if loaded {
+		var timeToLive uint32
+		if remaining := time.Until(expireAt); remaining > 0 {
+			timeToLive = uint32(remaining / time.Second)
+			if timeToLive == 0 {
+				timeToLive = 1
+			}
+		} else {
+			timeToLive = staleAnswerTTL
+			t.logger.DebugContext(ctx, "serve stale ", formatQuestion(question.String()))
+			t.refresh(message)
+		}
+		for _, recordList := range [][]mDNS.RR{response.Answer, response.Ns, response.Extra} {
+			for _, record := range recordList {
+				record.Header().Ttl = timeToLive
+			}
+		}
+		response.Id = message.Id
+		return response, nil
+	}
+	response, err := t.Transport.Exchange(ctx, message)
+	if err != nil {
+		return nil, err
+	}
+	t.save(question, response)
+	return response, nil
+}
+
+func (t *persistentCacheTransport) refresh(message *mDNS.Msg) {
+	question := message.Question[0]
+	t.refreshAccess.Lock()
+	if t.refreshing[question] {
+		t.refreshAccess.Unlock()
+		return
+	}
+	t.refreshing[question] = true
+	t.refreshAccess.Unlock()
+	message = message.Copy()
+	go func() {
+		defer func() {
+			t.refreshAccess.Lock()
+			delete(t.refreshing, question)
+			t.refreshAccess.Unlock()
+		}()
+		ctx, cancel := context.WithTimeout(t.ctx, C.DNSTimeout)
+		defer cancel()
+		response, err := t.Transport.Exchange(ctx, message)
+		if err != nil {
+			t.logger.Debug(E.Cause(err, "refresh stale ", formatQuestion(question.String())))
+			return
+		}
+		t.save(question, response)
+	}()
+}
+
+// save stores positive answers only.
+func (t *persistentCacheTransport) save(question mDNS.Question, response *mDNS.Msg) {
+	if response.Rcode != mDNS.RcodeSuccess || len(response.Answer) == 0 {
+		return
+	}
+	var timeToLive uint32
+	for _, recordList := range [][]mDNS.RR{response.Answer, response.Ns, response.Extra} {
+		for _, record := range recordList {
+			if timeToLive == 0 || record.Header().Ttl > 0 && record.Header().Ttl < timeToLive {
+				timeToLive = record.Header().Ttl
+			}
+		}
+	}
+	if timeToLive == 0 {
+		return
+	}
+	expireAt := time.Now().Add(time.Duration(timeToLive) * time.Second)
+	t.cacheFile.SaveDNSCacheAsync(t.Name(), question.Name, question.Qtype, response.Copy(), expireAt, t.logger)
+}
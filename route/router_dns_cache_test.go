@@ -0,0 +1,143 @@
+package route
+
+import (
+	"context"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/experimental/cachefile"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-dns"
+	"github.com/sagernet/sing/service"
+
+	mDNS "github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+// countingTransport counts queries sent to transport.
+type countingTransport struct {
+	*testTransport
+	queries atomic.Int32
+}
+
+func (t *countingTransport) Exchange(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, error) {
+	t.queries.Add(1)
+	return t.testTransport.Exchange(ctx, message)
+}
+
+func newTestPersistentCache(t *testing.T, transport dns.Transport) (*persistentCacheTransport, *cachefile.CacheFile) {
+	cacheFile := cachefile.New(context.Background(), option.CacheFileOptions{
+		Enabled:         true,
+		Path:            filepath.Join(t.TempDir(), "cache.db"),
+		StoreDNS:        true,
+		DNSStaleTimeout: option.Duration(time.Hour),
+	})
+	require.NoError(t, cacheFile.PreStart())
+	t.Cleanup(func() {
+		cacheFile.Close()
+	})
+	ctx := service.ContextWith[adapter.CacheFile](context.Background(), cacheFile)
+	wrapped, isPersistent := wrapPersistentCache(ctx, transport, log.NewNOPFactory().Logger()).(*persistentCacheTransport)
+	require.True(t, isPersistent)
+	require.NoError(t, wrapped.Start())
+	return wrapped, cacheFile
+}
+
+func exchangeCached(ctx context.Context, t *testing.T, transport dns.Transport, name string) *mDNS.Msg {
+	message := new(mDNS.Msg)
+	message.SetQuestion(name, mDNS.TypeA)
+	response, err := transport.Exchange(ctx, message)
+	require.NoError(t, err)
+	require.Equal(t, message.Id, response.Id)
+	return response
+}
+
+func TestWrapPersistentCache(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewNOPFactory().Logger()
+	block, err := dns.NewRCodeTransport(dns.TransportOptions{Name: "block", Address: "rcode://refused"})
+	require.NoError(t, err)
+	for _, transport := range []dns.Transport{
+		block,
+		newTestGroup(t, nil, "", newAddressTransport("member", "10.0.0.1")),
+	} {
+		require.Same(t, transport, wrapPersistentCache(ctx, transport, logger))
+	}
+	_, isPersistent := wrapPersistentCache(ctx, newAddressTransport("remote", "10.0.0.1"), logger).(*persistentCacheTransport)
+	require.True(t, isPersistent)
+}
+
+func TestPersistentCacheServesSavedAnswers(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	upstream := &countingTransport{testTransport: newAddressTransport("remote", "10.0.0.1")}
+	transport, cacheFile := newTestPersistentCache(t, upstream)
+
+	response := exchangeCached(ctx, t, transport, "example.com.")
+	requireAddresses(t, response, "10.0.0.1")
+	require.Eventually(t, func() bool {
+		_, _, loaded := cacheFile.LoadDNSCache("remote", "example.com.", mDNS.TypeA)
+		return loaded
+	}, time.Second, 10*time.Millisecond)
+
+	response = exchangeCached(ctx, t, transport, "example.com.")
+	requireAddresses(t, response, "10.0.0.1")
+	require.LessOrEqual(t, response.Answer[0].Header().Ttl, uint32(60))
+	require.Greater(t, response.Answer[0].Header().Ttl, uint32(staleAnswerTTL))
+	require.Equal(t, int32(1), upstream.queries.Load())
+
+	// the cache file is skipped if the DNS rule disables the cache
+	exchangeCached(dns.ContextWithDisableCache(ctx, true), t, transport, "example.com.")
+	require.Equal(t, int32(2), upstream.queries.Load())
+}
+
+func TestPersistentCacheSkipsNegativeAnswers(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	upstream := &countingTransport{testTransport: newRCodeTestTransport("remote", mDNS.RcodeNameError)}
+	transport, _ := newTestPersistentCache(t, upstream)
+	for i := 0; i < 2; i++ {
+		require.Equal(t, mDNS.RcodeNameError, exchangeCached(ctx, t, transport, "example.com.").Rcode)
+	}
+	require.Equal(t, int32(2), upstream.queries.Load())
+}
+
+func TestPersistentCacheServesStaleAnswers(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	refreshed := make(chan struct{})
+	upstream := &countingTransport{testTransport: &testTransport{
+		name: "remote",
+		exchange: func(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, error) {
+			<-refreshed
+			return newAddressTransport("remote", "10.0.0.2").exchange(ctx, message)
+		},
+	}}
+	transport, cacheFile := newTestPersistentCache(t, upstream)
+	stale, err := newAddressTransport("remote", "10.0.0.1").exchange(ctx, new(mDNS.Msg).SetQuestion("example.com.", mDNS.TypeA))
+	require.NoError(t, err)
+	require.NoError(t, cacheFile.SaveDNSCache("remote", "example.com.", mDNS.TypeA, stale, time.Now().Add(-time.Minute)))
+
+	// expired answers are served at once and refreshed once in the background
+	for i := 0; i < 3; i++ {
+		response := exchangeCached(ctx, t, transport, "example.com.")
+		requireAddresses(t, response, "10.0.0.1")
+		require.Equal(t, uint32(staleAnswerTTL), response.Answer[0].Header().Ttl)
+	}
+	close(refreshed)
+	require.Eventually(t, func() bool {
+		response, _, loaded := cacheFile.LoadDNSCache("remote", "example.com.", mDNS.TypeA)
+		return loaded && responseAddresses(response)[0].String() == "10.0.0.2"
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, int32(1), upstream.queries.Load())
+
+	response := exchangeCached(ctx, t, transport, "example.com.")
+	requireAddresses(t, response, "10.0.0.2")
+	require.Greater(t, response.Answer[0].Header().Ttl, uint32(staleAnswerTTL))
+	require.Equal(t, int32(1), upstream.queries.Load())
+}
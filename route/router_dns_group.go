@@ -0,0 +1,362 @@
+package route
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"os"
+	"time"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-dns"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	mDNS "github.com/miekg/dns"
+)
+
+type responseCheckerKey struct{}
+
+// contextWithResponseChecker passes the address limit check of a DNS rule to
+// group transports, so that rejected responses can be skipped per server.
+func contextWithResponseChecker(ctx context.Context, checker func(response *mDNS.Msg) bool) context.Context {
+	return context.WithValue(ctx, (*responseCheckerKey)(nil), checker)
+}
+
+func responseCheckerFromContext(ctx context.Context) func(response *mDNS.Msg) bool {
+	checker, _ := ctx.Value((*responseCheckerKey)(nil)).(func(response *mDNS.Msg) bool)
+	return checker
+}
+
+var _ dns.Transport = (*dnsGroupTransport)(nil)
+
+// dnsGroupTransport sends queries to several servers.
+//
+// race returns the first valid response; fastest_ip also waits for the other
+// servers until probe_timeout has passed since the first valid response, then
+// returns the response whose address accepts a TCP connection first;
+// fallback_on_error tries servers in order.
+type dnsGroupTransport struct {
+	name         string
+	logger       logger.ContextLogger
+	dialer       N.Dialer
+	members      []dns.Transport
+	mode         string
+	probePort    uint16
+	probeTimeout time.Duration
+}
+
+type dnsGroupResult struct {
+	member   dns.Transport
+	response *mDNS.Msg
+	err      error
+}
+
+func newDNSGroupTransport(name string, logger logger.ContextLogger, dialer N.Dialer, members []dns.Transport, options option.DNSGroupOptions) (*dnsGroupTransport, error) {
+	transport := &dnsGroupTransport{
+		name:         name,
+		logger:       logger,
+		dialer:       dialer,
+		members:      members,
+		mode:         options.Mode,
+		probePort:    options.ProbePort,
+		probeTimeout: time.Duration(options.ProbeTimeout),
+	}
+	switch transport.mode {
+	case "":
+		transport.mode = C.DNSGroupModeRace
+	case C.DNSGroupModeRace, C.DNSGroupModeFastestIP, C.DNSGroupModeFallbackOnError:
+	default:
+		return nil, E.New("unknown group mode: ", transport.mode)
+	}
+	if transport.probePort == 0 {
+		transport.probePort = 443
+	}
+	if transport.probeTimeout == 0 {
+		transport.probeTimeout = C.TCPTimeout / 10
+	}
+	return transport, nil
+}
+
+func (t *dnsGroupTransport) Name() string {
+	return t.name
+}
+
+func (t *dnsGroupTransport) Start() error {
+	return nil
+}
+
+func (t *dnsGroupTransport) Reset() {
+}
+
+func (t *dnsGroupTransport) Close() error {
+	return nil
+}
+
+func (t *dnsGroupTransport) Raw() bool {
+	return true
+}
+
+func (t *dnsGroupTransport) Exchange(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, error) {
+	switch t.mode {
+	case C.DNSGroupModeFallbackOnError:
+		return t.exchangeFallback(ctx, message)
+	case C.DNSGroupModeFastestIP:
+		if len(message.Question) == 0 {
+			return nil, E.New("missing question")
+		}
+		qType := message.Question[0].Qtype
+		if qType == mDNS.TypeA || qType == mDNS.TypeAAAA {
+			return t.exchangeFastestIP(ctx, message)
+		}
+	}
+	return t.exchangeRace(ctx, message)
+}
+
+func (t *dnsGroupTransport) Lookup(ctx context.Context, domain string, strategy dns.DomainStrategy) ([]netip.Addr, error) {
+	return nil, os.ErrInvalid
+}
+
+func (t *dnsGroupTransport) isValid(ctx context.Context, response *mDNS.Msg) bool {
+	if response.Rcode != mDNS.RcodeSuccess && response.Rcode != mDNS.RcodeNameError {
+		return false
+	}
+	checker := responseCheckerFromContext(ctx)
+	return checker == nil || checker(response)
+}
+
+func (t *dnsGroupTransport) exchangeAll(ctx context.Context, message *mDNS.Msg) <-chan dnsGroupResult {
+	results := make(chan dnsGroupResult, len(t.members))
+	for _, member := range t.members {
+		go func(member dns.Transport) {
+			response, err := exchangeMember(ctx, member, message.Copy())
+			results <- dnsGroupResult{member, response, err}
+		}(member)
+	}
+	return results
+}
+
+func (t *dnsGroupTransport) exchangeRace(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := t.exchangeAll(ctx, message)
+	var (
+		lastResponse *mDNS.Msg
+		errors       []error
+	)
+	for range t.members {
+		result := <-results
+		if result.err != nil {
+			errors = append(errors, E.Cause(result.err, result.member.Name()))
+			continue
+		}
+		if t.isValid(ctx, result.response) {
+			return result.response, nil
+		}
+		lastResponse = result.response
+	}
+	if lastResponse != nil {
+		return lastResponse, nil
+	}
+	return nil, E.Errors(errors...)
+}
+
+func (t *dnsGroupTransport) exchangeFallback(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, error) {
+	var (
+		lastResponse *mDNS.Msg
+		errors       []error
+	)
+	for _, member := range t.members {
+		response, err := exchangeMember(ctx, member, message.Copy())
+		if err != nil {
+			t.logger.DebugContext(ctx, E.Cause(err, "exchange failed on ", member.Name()))
+			errors = append(errors, E.Cause(err, member.Name()))
+			continue
+		}
+		if t.isValid(ctx, response) {
+			return response, nil
+		}
+		t.logger.DebugContext(ctx, "invalid response from ", member.Name(), ": ", mDNS.RcodeToString[response.Rcode])
+		lastResponse = response
+	}
+	if lastResponse != nil {
+		return lastResponse, nil
+	}
+	return nil, E.Errors(errors...)
+}
+
+func (t *dnsGroupTransport) exchangeFastestIP(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, error) {
+	exchangeCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := t.exchangeAll(exchangeCtx, message)
+	var (
+		responses    []*mDNS.Msg
+		lastResponse *mDNS.Msg
+		errors       []error
+		deadline     <-chan time.Time
+	)
+collect:
+	for range t.members {
+		var result dnsGroupResult
+		select {
+		case result = <-results:
+		case <-deadline:
+			break collect
+		}
+		if result.err != nil {
+			errors = append(errors, E.Cause(result.err, result.member.Name()))
+			continue
+		}
+		if !t.isValid(ctx, result.response) {
+			lastResponse = result.response
+			continue
+		}
+		responses = append(responses, result.response)
+		if deadline == nil {
+			timer := time.NewTimer(t.probeTimeout)
+			defer timer.Stop()
+			deadline = timer.C
+		}
+	}
+	cancel()
+	switch len(responses) {
+	case 0:
+		if lastResponse != nil {
+			return lastResponse, nil
+		}
+		return nil, E.Errors(errors...)
+	case 1:
+		if len(responseAddresses(responses[0])) <= 1 {
+			return responses[0], nil
+		}
+	}
+	return t.probeFastest(ctx, responses), nil
+}
+
+// probeFastest returns the response containing the first address that accepts
+// a TCP connection, with the address moved to the front of the answers.
+func (t *dnsGroupTransport) probeFastest(ctx context.Context, responses []*mDNS.Msg) *mDNS.Msg {
+	probeCtx, cancel := context.WithTimeout(ctx, t.probeTimeout)
+	defer cancel()
+	addressResponse := make(map[netip.Addr]*mDNS.Msg)
+	for _, response := range responses {
+		for _, address := range responseAddresses(response) {
+			if _, loaded := addressResponse[address]; !loaded {
+				addressResponse[address] = response
+			}
+		}
+	}
+	probed := make(chan netip.Addr, len(addressResponse))
+	for address := range addressResponse {
+		go func(address netip.Addr) {
+			conn, err := t.dialer.DialContext(probeCtx, N.NetworkTCP, M.SocksaddrFrom(address, t.probePort))
+			if err != nil {
+				probed <- netip.Addr{}
+				return
+			}
+			conn.Close()
+			probed <- address
+		}(address)
+	}
+	for range addressResponse {
+		address := <-probed
+		if !address.IsValid() {
+			continue
+		}
+		t.logger.DebugContext(ctx, "fastest address ", address)
+		return moveAddressFirst(addressResponse[address], address)
+	}
+	return responses[0]
+}
+
+func exchangeMember(ctx context.Context, member dns.Transport, message *mDNS.Msg) (*mDNS.Msg, error) {
+	if member.Raw() {
+		return member.Exchange(ctx, message)
+	}
+	if len(message.Question) == 0 {
+		return nil, E.New("missing question")
+	}
+	question := message.Question[0]
+	var strategy dns.DomainStrategy
+	switch question.Qtype {
+	case mDNS.TypeA:
+		strategy = dns.DomainStrategyUseIPv4
+	case mDNS.TypeAAAA:
+		strategy = dns.DomainStrategyUseIPv6
+	default:
+		return nil, dns.ErrNoRawSupport
+	}
+	response := &mDNS.Msg{
+		MsgHdr: mDNS.MsgHdr{
+			Id:       message.Id,
+			Response: true,
+			Rcode:    mDNS.RcodeSuccess,
+		},
+		Question: []mDNS.Question{question},
+	}
+	addresses, err := member.Lookup(ctx, fqdnToDomain(question.Name), strategy)
+	if err != nil {
+		var rcodeErr dns.RCodeError
+		if errors.As(err, &rcodeErr) {
+			response.Rcode = int(rcodeErr)
+			return response, nil
+		}
+		return nil, err
+	}
+	header := mDNS.RR_Header{
+		Name:   question.Name,
+		Rrtype: question.Qtype,
+		Class:  mDNS.ClassINET,
+		Ttl:    dns.DefaultTTL,
+	}
+	for _, address := range addresses {
+		address = address.Unmap()
+		if question.Qtype == mDNS.TypeA && address.Is4() {
+			response.Answer = append(response.Answer, &mDNS.A{Hdr: header, A: address.AsSlice()})
+		} else if question.Qtype == mDNS.TypeAAAA && address.Is6() {
+			response.Answer = append(response.Answer, &mDNS.AAAA{Hdr: header, AAAA: address.AsSlice()})
+		}
+	}
+	return response, nil
+}
+
+func responseAddresses(response *mDNS.Msg) []netip.Addr {
+	return common.FlatMap(response.Answer, func(it mDNS.RR) []netip.Addr {
+		switch record := it.(type) {
+		case *mDNS.A:
+			return []netip.Addr{M.AddrFromIP(record.A)}
+		case *mDNS.AAAA:
+			return []netip.Addr{M.AddrFromIP(record.AAAA)}
+		default:
+			return nil
+		}
+	})
+}
+
+func moveAddressFirst(response *mDNS.Msg, address netip.Addr) *mDNS.Msg {
+	response = response.Copy()
+	firstIndex := -1
+	for i, record := range response.Answer {
+		var recordAddress netip.Addr
+		switch record := record.(type) {
+		case *mDNS.A:
+			recordAddress = M.AddrFromIP(record.A)
+		case *mDNS.AAAA:
+			recordAddress = M.AddrFromIP(record.AAAA)
+		default:
+			continue
+		}
+		if firstIndex == -1 {
+			firstIndex = i
+		}
+		if recordAddress == address {
+			response.Answer[firstIndex], response.Answer[i] = response.Answer[i], response.Answer[firstIndex]
+			break
+		}
+	}
+	return response
+}
@@ -0,0 +1,252 @@
+package route
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-dns"
+	"github.com/sagernet/sing/common"
+	M "github.com/sagernet/sing/common/metadata"
+
+	mDNS "github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+// delayedTransport answers after delay, or fails when the query is canceled.
+func delayedTransport(transport *testTransport, delay time.Duration) *testTransport {
+	return &testTransport{
+		name: transport.name,
+		exchange: func(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, error) {
+			select {
+			case <-time.After(delay):
+				return transport.exchange(ctx, message)
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		},
+	}
+}
+
+func newRCodeTestTransport(name string, rcode int) *testTransport {
+	return &testTransport{
+		name: name,
+		exchange: func(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, error) {
+			response := new(mDNS.Msg)
+			response.SetRcode(message, rcode)
+			return response, nil
+		},
+	}
+}
+
+func newErrorTestTransport(name string) *testTransport {
+	return &testTransport{
+		name: name,
+		exchange: func(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, error) {
+			return nil, errors.New("connection refused")
+		},
+	}
+}
+
+// probeDialer accepts connections to the listed addresses after their delay.
+type probeDialer struct {
+	delays map[netip.Addr]time.Duration
+	access sync.Mutex
+	dialed []M.Socksaddr
+}
+
+func (d *probeDialer) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	d.access.Lock()
+	d.dialed = append(d.dialed, destination)
+	d.access.Unlock()
+	delay, loaded := d.delays[destination.Addr]
+	if !loaded {
+		return nil, errors.New("connection refused")
+	}
+	select {
+	case <-time.After(delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	conn, peer := net.Pipe()
+	peer.Close()
+	return conn, nil
+}
+
+func (d *probeDialer) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
+	return nil, errors.New("unsupported")
+}
+
+func newTestGroup(t *testing.T, dialer *probeDialer, mode string, members ...dns.Transport) *dnsGroupTransport {
+	if dialer == nil {
+		dialer = &probeDialer{}
+	}
+	transport, err := newDNSGroupTransport("group", log.NewNOPFactory().Logger(), dialer, members, option.DNSGroupOptions{
+		Mode:         mode,
+		ProbeTimeout: option.Duration(200 * time.Millisecond),
+	})
+	require.NoError(t, err)
+	return transport
+}
+
+func exchangeGroup(ctx context.Context, t *testing.T, transport *dnsGroupTransport, qType uint16) (*mDNS.Msg, error) {
+	message := new(mDNS.Msg)
+	message.SetQuestion("example.com.", qType)
+	return transport.Exchange(ctx, message)
+}
+
+func requireAddresses(t *testing.T, response *mDNS.Msg, addresses ...string) {
+	expected := make([]netip.Addr, 0, len(addresses))
+	for _, address := range addresses {
+		expected = append(expected, netip.MustParseAddr(address))
+	}
+	require.Equal(t, expected, responseAddresses(response))
+}
+
+func TestDNSGroupMode(t *testing.T) {
+	t.Parallel()
+	require.Equal(t, C.DNSGroupModeRace, newTestGroup(t, nil, "").mode)
+	_, err := newDNSGroupTransport("group", log.NewNOPFactory().Logger(), &probeDialer{}, nil, option.DNSGroupOptions{Mode: "random"})
+	require.Error(t, err)
+}
+
+func TestDNSGroupRace(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	slow := delayedTransport(newAddressTransport("slow", "10.0.0.1"), 100*time.Millisecond)
+	fast := newAddressTransport("fast", "10.0.0.2")
+
+	response, err := exchangeGroup(ctx, t, newTestGroup(t, nil, C.DNSGroupModeRace, slow, fast), mDNS.TypeA)
+	require.NoError(t, err)
+	requireAddresses(t, response, "10.0.0.2")
+
+	// failed and invalid responses are skipped
+	response, err = exchangeGroup(ctx, t, newTestGroup(t, nil, C.DNSGroupModeRace, newErrorTestTransport("error"), newRCodeTestTransport("refused", mDNS.RcodeRefused), slow), mDNS.TypeA)
+	require.NoError(t, err)
+	requireAddresses(t, response, "10.0.0.1")
+
+	// responses rejected by address_limit are skipped
+	checkerCtx := contextWithResponseChecker(ctx, func(response *mDNS.Msg) bool {
+		return !common.Contains(responseAddresses(response), netip.MustParseAddr("10.0.0.2"))
+	})
+	response, err = exchangeGroup(checkerCtx, t, newTestGroup(t, nil, C.DNSGroupModeRace, slow, fast), mDNS.TypeA)
+	require.NoError(t, err)
+	requireAddresses(t, response, "10.0.0.1")
+
+	// the last invalid response is returned if there is no valid one
+	response, err = exchangeGroup(ctx, t, newTestGroup(t, nil, C.DNSGroupModeRace, newErrorTestTransport("error"), newRCodeTestTransport("refused", mDNS.RcodeRefused)), mDNS.TypeA)
+	require.NoError(t, err)
+	require.Equal(t, mDNS.RcodeRefused, response.Rcode)
+	_, err = exchangeGroup(ctx, t, newTestGroup(t, nil, C.DNSGroupModeRace, newErrorTestTransport("first"), newErrorTestTransport("second")), mDNS.TypeA)
+	require.ErrorContains(t, err, "first")
+	require.ErrorContains(t, err, "second")
+}
+
+func TestDNSGroupFallbackOnError(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	var (
+		access sync.Mutex
+		called []string
+	)
+	recorded := func(transport *testTransport) *testTransport {
+		return &testTransport{
+			name: transport.name,
+			exchange: func(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, error) {
+				access.Lock()
+				called = append(called, transport.name)
+				access.Unlock()
+				return transport.exchange(ctx, message)
+			},
+		}
+	}
+	group := newTestGroup(t, nil, C.DNSGroupModeFallbackOnError,
+		recorded(newErrorTestTransport("error")),
+		recorded(newRCodeTestTransport("failure", mDNS.RcodeServerFailure)),
+		recorded(delayedTransport(newAddressTransport("primary", "10.0.0.1"), 50*time.Millisecond)),
+		recorded(newAddressTransport("secondary", "10.0.0.2")),
+	)
+	response, err := exchangeGroup(ctx, t, group, mDNS.TypeA)
+	require.NoError(t, err)
+	requireAddresses(t, response, "10.0.0.1")
+	require.Equal(t, []string{"error", "failure", "primary"}, called)
+
+	// name errors are valid answers
+	response, err = exchangeGroup(ctx, t, newTestGroup(t, nil, C.DNSGroupModeFallbackOnError, newRCodeTestTransport("nxdomain", mDNS.RcodeNameError), newAddressTransport("secondary", "10.0.0.2")), mDNS.TypeA)
+	require.NoError(t, err)
+	require.Equal(t, mDNS.RcodeNameError, response.Rcode)
+
+	response, err = exchangeGroup(ctx, t, newTestGroup(t, nil, C.DNSGroupModeFallbackOnError, newRCodeTestTransport("failure", mDNS.RcodeServerFailure), newErrorTestTransport("error")), mDNS.TypeA)
+	require.NoError(t, err)
+	require.Equal(t, mDNS.RcodeServerFailure, response.Rcode)
+	_, err = exchangeGroup(ctx, t, newTestGroup(t, nil, C.DNSGroupModeFallbackOnError, newErrorTestTransport("error")), mDNS.TypeA)
+	require.Error(t, err)
+}
+
+func TestDNSGroupFastestIP(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dialer := &probeDialer{delays: map[netip.Addr]time.Duration{
+		netip.MustParseAddr("10.0.0.1"): 100 * time.Millisecond,
+		netip.MustParseAddr("10.0.0.3"): 0,
+	}}
+	first := newAddressTransport("first", "10.0.0.1", "10.0.0.2")
+	second := delayedTransport(newAddressTransport("second", "10.0.0.4", "10.0.0.3"), 20*time.Millisecond)
+	group := newTestGroup(t, dialer, C.DNSGroupModeFastestIP, first, second)
+
+	// the slower response wins because its address accepts connections first
+	response, err := exchangeGroup(ctx, t, group, mDNS.TypeA)
+	require.NoError(t, err)
+	requireAddresses(t, response, "10.0.0.3", "10.0.0.4")
+	dialer.access.Lock()
+	for _, destination := range dialer.dialed {
+		require.Equal(t, uint16(443), destination.Port)
+	}
+	dialer.access.Unlock()
+
+	// addresses of a single response are reordered
+	response, err = exchangeGroup(ctx, t, newTestGroup(t, dialer, C.DNSGroupModeFastestIP, newAddressTransport("single", "10.0.0.2", "10.0.0.1")), mDNS.TypeA)
+	require.NoError(t, err)
+	requireAddresses(t, response, "10.0.0.1", "10.0.0.2")
+
+	// the first valid response is kept if no address accepts connections
+	response, err = exchangeGroup(ctx, t, newTestGroup(t, &probeDialer{}, C.DNSGroupModeFastestIP, first, second), mDNS.TypeA)
+	require.NoError(t, err)
+	requireAddresses(t, response, "10.0.0.1", "10.0.0.2")
+
+	// responses arriving after probe_timeout are ignored
+	late := delayedTransport(newAddressTransport("late", "10.0.0.3"), time.Second)
+	response, err = exchangeGroup(ctx, t, newTestGroup(t, dialer, C.DNSGroupModeFastestIP, first, late), mDNS.TypeA)
+	require.NoError(t, err)
+	requireAddresses(t, response, "10.0.0.1", "10.0.0.2")
+
+	// other query types are raced
+	response, err = exchangeGroup(ctx, t, newTestGroup(t, dialer, C.DNSGroupModeFastestIP, newRCodeTestTransport("refused", mDNS.RcodeRefused), newRCodeTestTransport("nxdomain", mDNS.RcodeNameError)), mDNS.TypeTXT)
+	require.NoError(t, err)
+	require.Equal(t, mDNS.RcodeNameError, response.Rcode)
+}
+
+// lookupTransport is a transport without raw exchange support.
+type lookupTransport struct {
+	*testTransport
+}
+
+func (t *lookupTransport) Raw() bool {
+	return false
+}
+
+func TestDNSGroupMissingQuestion(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	_, err := newTestGroup(t, nil, C.DNSGroupModeFastestIP, newAddressTransport("member", "10.0.0.1")).Exchange(ctx, new(mDNS.Msg))
+	require.Error(t, err)
+	_, err = newTestGroup(t, nil, C.DNSGroupModeRace, &lookupTransport{newAddressTransport("member", "10.0.0.1")}).Exchange(ctx, new(mDNS.Msg))
+	require.Error(t, err)
+}
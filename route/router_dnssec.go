@@ -0,0 +1,139 @@
+package route
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sagernet/sing-box/common/dnssec"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-dns"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/logger"
+
+	mDNS "github.com/miekg/dns"
+)
+
+var _ dns.Transport = (*dnssecTransport)(nil)
+
+// dnssecTransport validates responses of the underlying transport. Bogus
+// responses are replaced with SERVFAIL unless the query has the CD bit set,
+// and the AD bit is set only on secure responses.
+type dnssecTransport struct {
+	dns.Transport
+	logger    logger.ContextLogger
+	validator *dnssec.Validator
+}
+
+func newDNSSECTransport(transport dns.Transport, logger logger.ContextLogger, trustAnchors []*mDNS.DS) *dnssecTransport {
+	return &dnssecTransport{
+		Transport: transport,
+		logger:    logger,
+		validator: dnssec.NewValidator(transport, trustAnchors),
+	}
+}
+
+func (t *dnssecTransport) Exchange(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, error) {
+	response, status, err := t.validator.Exchange(ctx, message)
+	if err != nil {
+		return nil, err
+	}
+	if status == dnssec.StatusBogus {
+		t.logger.WarnContext(ctx, "dnssec validation failed for ", formatQuestion(message.Question[0].String()), " from ", t.Name())
+		if !message.CheckingDisabled {
+			return &mDNS.Msg{
+				MsgHdr: mDNS.MsgHdr{
+					Id:                 message.Id,
+					Response:           true,
+					RecursionDesired:   message.RecursionDesired,
+					RecursionAvailable: true,
+					Rcode:              mDNS.RcodeServerFailure,
+				},
+				Question: message.Question,
+			}, nil
+		}
+	}
+	response.AuthenticatedData = status == dnssec.StatusSecure
+	response.CheckingDisabled = message.CheckingDisabled
+	if opt := message.IsEdns0(); opt == nil || !opt.Do() {
+		qType := message.Question[0].Qtype
+		response.Answer = stripDNSSECRecords(response.Answer, qType)
+		response.Ns = stripDNSSECRecords(response.Ns, 0)
+		if opt == nil {
+			response.Extra = common.Filter(response.Extra, func(it mDNS.RR) bool {
+				return it.Header().Rrtype != mDNS.TypeOPT
+			})
+		}
+	}
+	return response, nil
+}
+
+func stripDNSSECRecords(records []mDNS.RR, qType uint16) []mDNS.RR {
+	return common.Filter(records, func(it mDNS.RR) bool {
+		switch rrType := it.Header().Rrtype; rrType {
+		case mDNS.TypeRRSIG, mDNS.TypeNSEC, mDNS.TypeNSEC3:
+			return rrType == qType
+		default:
+			return true
+		}
+	})
+}
+
+// ruleDNSSECTransport returns a validating wrapper of transport for DNS rules
+// with dnssec enabled.
+func (r *Router) ruleDNSSECTransport(transport dns.Transport) dns.Transport {
+	if r.isDNSSECTransport(transport) || !transport.Raw() {
+		return transport
+	}
+	r.dnssecAccess.Lock()
+	defer r.dnssecAccess.Unlock()
+	validatingTransport, loaded := r.ruleDNSSECTransports[transport]
+	if !loaded {
+		validatingTransport = newDNSSECTransport(transport, r.dnsLogger, r.trustAnchors)
+		r.ruleDNSSECTransports[transport] = validatingTransport
+	}
+	return validatingTransport
+}
+
+func (r *Router) isDNSSECTransport(transport dns.Transport) bool {
+	if _, isDNSSEC := transport.(*dnssecTransport); isDNSSEC {
+		return true
+	}
+	return r.dnssecTransports[transport]
+}
+
+// dnssecStatusRecorder records the DNSSEC status of the responses of a lookup,
+// which is secure only if all responses are.
+type dnssecStatusRecorder struct {
+	dns.Transport
+	router *Router
+	access sync.Mutex
+	status string
+}
+
+func (t *dnssecStatusRecorder) Exchange(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, error) {
+	response, err := t.Transport.Exchange(ctx, message)
+	if err != nil {
+		return nil, err
+	}
+	status := t.router.dnssecStatus(t.Transport, response)
+	t.access.Lock()
+	if t.status == "" || status == C.DNSSECStatusInsecure {
+		t.status = status
+	}
+	t.access.Unlock()
+	return response, nil
+}
+
+func (t *dnssecStatusRecorder) Status() string {
+	t.access.Lock()
+	defer t.access.Unlock()
+	return t.status
+}
+
+// dnssecStatus trusts the AD bit only from validating transports.
+func (r *Router) dnssecStatus(transport dns.Transport, response *mDNS.Msg) string {
+	if response.AuthenticatedData && r.isDNSSECTransport(transport) {
+		return C.DNSSECStatusSecure
+	}
+	return C.DNSSECStatusInsecure
+}
@@ -0,0 +1,117 @@
+package route
+
+import (
+	"net/netip"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+// fakeIPExclusion holds queries that a fakeip server does not answer,
+// DNS rules pointing to the server are skipped for them.
+type fakeIPExclusion struct {
+	ruleSets   []string
+	queryTypes []uint16
+}
+
+func newFakeIPExclusion(options option.DNSFakeIPServerOptions) *fakeIPExclusion {
+	if len(options.ExcludeRuleSet) == 0 && len(options.ExcludeQueryType) == 0 {
+		return nil
+	}
+	return &fakeIPExclusion{
+		ruleSets: options.ExcludeRuleSet,
+		queryTypes: common.Map(options.ExcludeQueryType, func(it option.DNSQueryType) uint16 {
+			return uint16(it)
+		}),
+	}
+}
+
+func (r *Router) fakeIPExcluded(transportName string, metadata *adapter.InboundContext) bool {
+	exclusion := r.fakeIPExclusions[transportName]
+	if exclusion == nil {
+		return false
+	}
+	if common.Contains(exclusion.queryTypes, metadata.QueryType) {
+		return true
+	}
+	r.ruleAccess.RLock()
+	ruleSetItem := r.fakeIPRuleSets[transportName]
+	r.ruleAccess.RUnlock()
+	if ruleSetItem == nil {
+		return false
+	}
+	metadata.IgnoreDestinationIPCIDRMatch = true
+	defer func() {
+		metadata.IgnoreDestinationIPCIDRMatch = false
+	}()
+	metadata.ResetRuleCache()
+	return ruleSetItem.Match(metadata)
+}
+
+// startFakeIPRuleSets references the rule-sets excluded by fakeip servers,
+// it is called again with the new rule-sets on reload.
+func (r *Router) startFakeIPRuleSets() (map[string]*RuleSetItem, error) {
+	ruleSetItems := make(map[string]*RuleSetItem)
+	for transportName, exclusion := range r.fakeIPExclusions {
+		if len(exclusion.ruleSets) == 0 {
+			continue
+		}
+		ruleSetItem := NewRuleSetItem(r, exclusion.ruleSets, false, false)
+		err := ruleSetItem.Start()
+		if err != nil {
+			closeFakeIPRuleSets(ruleSetItems)
+			return nil, E.Cause(err, "initialize dns server[", transportName, "]")
+		}
+		ruleSetItems[transportName] = ruleSetItem
+	}
+	return ruleSetItems, nil
+}
+
+func closeFakeIPRuleSets(ruleSetItems map[string]*RuleSetItem) {
+	for _, ruleSetItem := range ruleSetItems {
+		ruleSetItem.Close()
+	}
+}
+
+// checkFakeIPRuleSets checks that rule-sets excluded by fakeip servers exist.
+func (r *Router) checkFakeIPRuleSets(ruleSetMap map[string]adapter.RuleSet) error {
+	for transportName, exclusion := range r.fakeIPExclusions {
+		for _, tag := range exclusion.ruleSets {
+			if _, loaded := ruleSetMap[tag]; !loaded {
+				return E.New("parse dns server[", transportName, "]: exclude rule-set not found: ", tag)
+			}
+		}
+	}
+	return nil
+}
+
+type fakeIPRange struct {
+	name   string
+	prefix netip.Prefix
+}
+
+func checkFakeIPRanges(ranges []fakeIPRange) error {
+	for i, current := range ranges {
+		for _, other := range ranges[:i] {
+			if current.prefix.Overlaps(other.prefix) {
+				return E.New("fakeip range ", current.prefix, " of ", current.name, " overlaps with ", other.prefix, " of ", other.name)
+			}
+		}
+	}
+	return nil
+}
+
+func (r *Router) fakeIPStoreFor(address netip.Addr) adapter.FakeIPStore {
+	for _, store := range r.fakeIPStores {
+		if store.Contains(address) {
+			return store
+		}
+	}
+	return nil
+}
+
+func (r *Router) FakeIPStores() []adapter.FakeIPStore {
+	return r.fakeIPStores
+}
@@ -0,0 +1,111 @@
+package route
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	F "github.com/sagernet/sing/common/format"
+
+	mDNS "github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckFakeIPRanges(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		name    string
+		ranges  []string
+		overlap bool
+	}{
+		{"disjoint", []string{"198.18.0.0/16", "198.19.0.0/16", "fc00::/18", "fc00:4000::/18"}, false},
+		{"adjacent", []string{"198.18.0.0/24", "198.18.1.0/24"}, false},
+		{"equal", []string{"198.18.0.0/15", "198.18.0.0/15"}, true},
+		{"contained", []string{"198.18.0.0/15", "198.19.128.0/17"}, true},
+		{"containing", []string{"fc00:4000::/18", "fc00::/16"}, true},
+		{"not adjacent", []string{"198.18.0.0/16", "fc00::/18", "198.19.0.0/16", "198.18.255.0/24"}, true},
+	} {
+		ranges := make([]fakeIPRange, 0, len(testCase.ranges))
+		for i, prefix := range testCase.ranges {
+			ranges = append(ranges, fakeIPRange{F.ToString("dns server[fakeip-", i, "]"), netip.MustParsePrefix(prefix)})
+		}
+		err := checkFakeIPRanges(ranges)
+		if testCase.overlap {
+			require.Error(t, err, testCase.name)
+		} else {
+			require.NoError(t, err, testCase.name)
+		}
+	}
+}
+
+func TestFakeIPExcluded(t *testing.T) {
+	t.Parallel()
+	router := newTestRouter(t, nil, nil)
+	require.Nil(t, newFakeIPExclusion(option.DNSFakeIPServerOptions{}))
+	ruleSet, err := NewLocalRuleSet(router, router.logger, option.RuleSet{
+		Type: C.RuleSetTypeInline,
+		Tag:  "direct-domains",
+		InlineOptions: option.PlainRuleSet{
+			Rules: []option.HeadlessRule{{
+				Type: C.RuleTypeDefault,
+				DefaultOptions: option.DefaultHeadlessRule{
+					DomainSuffix: []string{"example.cn"},
+					IPCIDR:       []string{"10.0.0.0/8"},
+				},
+			}},
+		},
+	})
+	require.NoError(t, err)
+	router.ruleSetMap = map[string]adapter.RuleSet{"direct-domains": ruleSet}
+	router.fakeIPExclusions = map[string]*fakeIPExclusion{
+		"fakeip-types": newFakeIPExclusion(option.DNSFakeIPServerOptions{
+			ExcludeQueryType: []option.DNSQueryType{option.DNSQueryType(mDNS.TypeHTTPS)},
+		}),
+		"fakeip-rule-set": newFakeIPExclusion(option.DNSFakeIPServerOptions{
+			ExcludeRuleSet: []string{"direct-domains"},
+		}),
+	}
+	require.NoError(t, router.checkFakeIPRuleSets(router.ruleSetMap))
+	router.fakeIPRuleSets, err = router.startFakeIPRuleSets()
+	require.NoError(t, err)
+	defer closeFakeIPRuleSets(router.fakeIPRuleSets)
+
+	for _, testCase := range []struct {
+		transport string
+		domain    string
+		queryType uint16
+		excluded  bool
+	}{
+		{"fakeip", "www.example.cn", mDNS.TypeA, false},
+		{"fakeip-types", "www.example.com", mDNS.TypeHTTPS, true},
+		{"fakeip-types", "www.example.com", mDNS.TypeA, false},
+		{"fakeip-types", "www.example.cn", mDNS.TypeA, false},
+		{"fakeip-rule-set", "www.example.cn", mDNS.TypeAAAA, true},
+		{"fakeip-rule-set", "www.example.com", mDNS.TypeA, false},
+		{"fakeip-rule-set", "www.example.com", mDNS.TypeHTTPS, false},
+	} {
+		metadata := &adapter.InboundContext{
+			Domain:    testCase.domain,
+			QueryType: testCase.queryType,
+			// addresses of an earlier response must not match ip_cidr of the rule-set
+			DestinationAddresses: []netip.Addr{netip.MustParseAddr("10.0.0.1")},
+		}
+		require.Equal(t, testCase.excluded, router.fakeIPExcluded(testCase.transport, metadata), testCase)
+		require.False(t, metadata.IgnoreDestinationIPCIDRMatch)
+	}
+}
+
+func TestFakeIPExcludedRuleSetNotFound(t *testing.T) {
+	t.Parallel()
+	router := newTestRouter(t, nil, nil)
+	router.fakeIPExclusions = map[string]*fakeIPExclusion{
+		"fakeip": newFakeIPExclusion(option.DNSFakeIPServerOptions{
+			ExcludeRuleSet: []string{"missing"},
+		}),
+	}
+	require.Error(t, router.checkFakeIPRuleSets(map[string]adapter.RuleSet{}))
+	_, err := router.startFakeIPRuleSets()
+	require.Error(t, err)
+}
@@ -15,6 +15,7 @@ import (
 	C "github.com/sagernet/sing-box/constant"
 	E "github.com/sagernet/sing/common/exceptions"
 	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
 	"github.com/sagernet/sing/common/rw"
 	"github.com/sagernet/sing/service/filemanager"
 )
@@ -148,7 +149,7 @@ func (r *Router) downloadGeoIPDatabase(savePath string) error {
 		}
 		detour = outbound
 	} else {
-		detour = r.defaultOutboundForConnection
+		detour = r.defaultOutbound(N.NetworkTCP)
 	}
 
 	if parentDir := filepath.Dir(savePath); parentDir != "" {
@@ -203,7 +204,7 @@ func (r *Router) downloadGeositeDatabase(savePath string) error {
 		}
 		detour = outbound
 	} else {
-		detour = r.defaultOutboundForConnection
+		detour = r.defaultOutbound(N.NetworkTCP)
 	}
 
 	if parentDir := filepath.Dir(savePath); parentDir != "" {
@@ -0,0 +1,272 @@
+package route
+
+import (
+	"io"
+	"sync"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/x/list"
+)
+
+// Reload replaces inbounds, outbounds, rules, DNS rules and rule-sets of a started router.
+// Rule-sets with unchanged options are kept. New outbounds and rule-sets are visible to
+// Outbound and RuleSet while startOutbounds and the new rules are started, and everything
+// is swapped in at once only after all of them started, otherwise the old ones keep serving.
+func (r *Router) Reload(
+	options option.RouteOptions,
+	dnsOptions option.DNSOptions,
+	inbounds []adapter.Inbound,
+	outbounds []adapter.Outbound,
+	defaultOutbound func() adapter.Outbound,
+	startOutbounds func() error,
+) error {
+	if hasRule(options.Rules, isGeositeRule) || hasDNSRule(dnsOptions.Rules, isGeositeDNSRule) {
+		return E.New("geosite rules can not be reloaded")
+	}
+	if (hasRule(options.Rules, isGeoIPRule) || hasDNSRule(dnsOptions.Rules, isGeoIPDNSRule)) && r.geoIPReader == nil {
+		return E.New("geoip database is not loaded")
+	}
+	if (hasRule(options.Rules, isProcessRule) || hasDNSRule(dnsOptions.Rules, isProcessDNSRule)) && r.processSearcher == nil {
+		return E.New("process searcher is not initialized")
+	}
+	if (hasRule(options.Rules, isWIFIRule) || hasDNSRule(dnsOptions.Rules, isWIFIDNSRule)) && !r.needWIFIState {
+		return E.New("WIFI state is not initialized")
+	}
+	rules := make([]adapter.Rule, 0, len(options.Rules))
+	for i, ruleOptions := range options.Rules {
+		routeRule, err := NewRule(r, r.logger, ruleOptions, true)
+		if err != nil {
+			return E.Cause(err, "parse rule[", i, "]")
+		}
+		rules = append(rules, routeRule)
+	}
+	dnsRules := make([]adapter.DNSRule, 0, len(dnsOptions.Rules))
+	for i, dnsRuleOptions := range dnsOptions.Rules {
+		dnsRule, err := NewDNSRule(r, r.logger, dnsRuleOptions, true)
+		if err != nil {
+			return E.Cause(err, "parse dns rule[", i, "]")
+		}
+		dnsRules = append(dnsRules, dnsRule)
+	}
+	r.ruleAccess.RLock()
+	oldRuleSetMap := r.ruleSetMap
+	oldRuleSetOptions := r.ruleSetOptions
+	outboundProviders := r.outboundProviders
+	r.ruleAccess.RUnlock()
+	var (
+		ruleSets       []adapter.RuleSet
+		newRuleSets    []adapter.RuleSet
+		ruleSetMap     = make(map[string]adapter.RuleSet)
+		ruleSetOptions = make(map[string]option.RuleSet)
+		fakeIPRuleSets map[string]*RuleSetItem
+	)
+	closeNewOnFailed := func() {
+		for _, rule := range rules {
+			rule.Close()
+		}
+		for _, rule := range dnsRules {
+			rule.Close()
+		}
+		closeFakeIPRuleSets(fakeIPRuleSets)
+		closeRuleSets(newRuleSets)
+	}
+	for i, ruleSetOption := range options.RuleSet {
+		if _, exists := ruleSetMap[ruleSetOption.Tag]; exists {
+			closeNewOnFailed()
+			return E.New("duplicate rule-set tag: ", ruleSetOption.Tag)
+		}
+		ruleSet, loaded := oldRuleSetMap[ruleSetOption.Tag]
+		if !loaded || !option.Equal(oldRuleSetOptions[ruleSetOption.Tag], ruleSetOption) {
+			var err error
+			ruleSet, err = NewRuleSet(r.ctx, r, r.logger, ruleSetOption)
+			if err != nil {
+				closeNewOnFailed()
+				return E.Cause(err, "parse rule-set[", i, "]")
+			}
+			newRuleSets = append(newRuleSets, ruleSet)
+		}
+		ruleSets = append(ruleSets, ruleSet)
+		ruleSetMap[ruleSetOption.Tag] = ruleSet
+		ruleSetOptions[ruleSetOption.Tag] = ruleSetOption
+	}
+	err := r.checkFakeIPRuleSets(ruleSetMap)
+	if err != nil {
+		closeNewOnFailed()
+		return err
+	}
+	state, err := r.buildOutbounds(inbounds, outbounds, outboundProviders, rules, options.Final, defaultOutbound)
+	if err != nil {
+		closeNewOnFailed()
+		return err
+	}
+	r.ruleAccess.Lock()
+	r.reloadOutbounds = state
+	r.reloadRuleSetMap = ruleSetMap
+	r.ruleAccess.Unlock()
+	err = r.startReload(rules, dnsRules, newRuleSets, startOutbounds, &fakeIPRuleSets)
+	if err != nil {
+		r.ruleAccess.Lock()
+		r.reloadOutbounds = nil
+		r.reloadRuleSetMap = nil
+		r.ruleAccess.Unlock()
+		closeNewOnFailed()
+		return err
+	}
+	r.ruleAccess.Lock()
+	oldRules := r.rules
+	oldDNSRules := r.dnsRules
+	oldFakeIPRuleSets := r.fakeIPRuleSets
+	r.applyOutbounds(state)
+	r.rules = rules
+	r.dnsRules = dnsRules
+	r.fakeIPRuleSets = fakeIPRuleSets
+	r.ruleSets = ruleSets
+	r.ruleSetMap = ruleSetMap
+	r.ruleSetOptions = ruleSetOptions
+	r.reloadOutbounds = nil
+	r.reloadRuleSetMap = nil
+	r.ruleAccess.Unlock()
+	for i, rule := range oldRules {
+		err = rule.Close()
+		if err != nil {
+			r.logger.Error(E.Cause(err, "close rule[", i, "]"))
+		}
+	}
+	for i, rule := range oldDNSRules {
+		err = rule.Close()
+		if err != nil {
+			r.logger.Error(E.Cause(err, "close dns rule[", i, "]"))
+		}
+	}
+	closeFakeIPRuleSets(oldFakeIPRuleSets)
+	var removedRuleSets []adapter.RuleSet
+	for tag, ruleSet := range oldRuleSetMap {
+		if ruleSetMap[tag] != ruleSet {
+			removedRuleSets = append(removedRuleSets, ruleSet)
+		}
+	}
+	closeRuleSets(removedRuleSets)
+	for _, ruleSet := range newRuleSets {
+		ruleSet.Cleanup()
+	}
+	r.dnsClient.ClearCache()
+	activeOutbounds := append([]adapter.Outbound(nil), state.outbounds...)
+	for _, provider := range outboundProviders {
+		activeOutbounds = append(activeOutbounds, provider.Outbounds()...)
+	}
+	r.connections.CloseExcept(activeOutbounds)
+	return nil
+}
+
+// UpdateInbounds replaces the inbounds known to the router without reloading
+// anything else, for inbounds the box recreated while reloading.
+func (r *Router) UpdateInbounds(inbounds []adapter.Inbound) {
+	inboundByTag := make(map[string]adapter.Inbound)
+	for _, inbound := range inbounds {
+		inboundByTag[inbound.Tag()] = inbound
+	}
+	r.ruleAccess.Lock()
+	r.inboundByTag = inboundByTag
+	r.ruleAccess.Unlock()
+}
+
+func (r *Router) startReload(rules []adapter.Rule, dnsRules []adapter.DNSRule, newRuleSets []adapter.RuleSet, startOutbounds func() error, fakeIPRuleSets *map[string]*RuleSetItem) error {
+	err := startOutbounds()
+	if err != nil {
+		return err
+	}
+	if len(newRuleSets) > 0 {
+		err = r.startRuleSets(newRuleSets)
+		if err != nil {
+			return err
+		}
+	}
+	for i, rule := range rules {
+		err = rule.Start()
+		if err != nil {
+			return E.Cause(err, "initialize rule[", i, "]")
+		}
+	}
+	for i, rule := range dnsRules {
+		err = rule.Start()
+		if err != nil {
+			return E.Cause(err, "initialize DNS rule[", i, "]")
+		}
+	}
+	*fakeIPRuleSets, err = r.startFakeIPRuleSets()
+	if err != nil {
+		return err
+	}
+	for _, ruleSet := range newRuleSets {
+		err = ruleSet.PostStart()
+		if err != nil {
+			return E.Cause(err, "post start rule_set[", ruleSet.Name(), "]")
+		}
+	}
+	return nil
+}
+
+func closeRuleSets(ruleSets []adapter.RuleSet) {
+	for _, ruleSet := range ruleSets {
+		ruleSet.Close()
+	}
+}
+
+type outboundConnections struct {
+	access      sync.Mutex
+	connections map[adapter.Outbound]*list.List[io.Closer]
+}
+
+// Add tracks a connection routed to the outbound and returns a function to stop tracking it.
+func (c *outboundConnections) Add(outbound adapter.Outbound, conn io.Closer) func() {
+	c.access.Lock()
+	defer c.access.Unlock()
+	if c.connections == nil {
+		c.connections = make(map[adapter.Outbound]*list.List[io.Closer])
+	}
+	connections := c.connections[outbound]
+	if connections == nil {
+		connections = new(list.List[io.Closer])
+		c.connections[outbound] = connections
+	}
+	element := connections.PushBack(conn)
+	return func() {
+		c.access.Lock()
+		defer c.access.Unlock()
+		if element.Value == nil {
+			return
+		}
+		connections.Remove(element)
+		element.Value = nil
+		if connections.Len() == 0 && c.connections[outbound] == connections {
+			delete(c.connections, outbound)
+		}
+	}
+}
+
+// CloseExcept closes connections routed to outbounds that are no longer in use.
+func (c *outboundConnections) CloseExcept(outbounds []adapter.Outbound) {
+	activeOutbounds := make(map[adapter.Outbound]bool, len(outbounds))
+	for _, outbound := range outbounds {
+		activeOutbounds[outbound] = true
+	}
+	var closers []io.Closer
+	c.access.Lock()
+	for outbound, connections := range c.connections {
+		if activeOutbounds[outbound] {
+			continue
+		}
+		for element := connections.Front(); element != nil; element = element.Next() {
+			closers = append(closers, element.Value)
+			element.Value = nil
+		}
+		delete(c.connections, outbound)
+	}
+	c.access.Unlock()
+	for _, closer := range closers {
+		common.Close(closer)
+	}
+}
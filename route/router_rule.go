@@ -101,3 +101,40 @@ func isWIFIHeadlessRule(rule option.DefaultHeadlessRule) bool {
 func isIPCIDRHeadlessRule(rule option.DefaultHeadlessRule) bool {
 	return len(rule.IPCIDR) > 0 || rule.IPSet != nil
 }
+
+// ruleSetBehavior returns domain if all rules only match domains or domain suffixes,
+// ipcidr if all rules only match IP CIDRs, and classical otherwise.
+func ruleSetBehavior(rules []option.HeadlessRule) string {
+	if len(rules) == 0 {
+		return C.RuleSetBehaviorClassical
+	}
+	if common.All(rules, func(rule option.HeadlessRule) bool {
+		return isOnlyHeadlessRule(rule, func(rule *option.DefaultHeadlessRule) bool {
+			matched := len(rule.Domain) > 0 || len(rule.DomainSuffix) > 0 || rule.DomainMatcher != nil
+			rule.Domain, rule.DomainSuffix, rule.DomainMatcher = nil, nil, nil
+			return matched
+		})
+	}) {
+		return C.RuleSetBehaviorDomain
+	}
+	if common.All(rules, func(rule option.HeadlessRule) bool {
+		return isOnlyHeadlessRule(rule, func(rule *option.DefaultHeadlessRule) bool {
+			matched := isIPCIDRHeadlessRule(*rule)
+			rule.IPCIDR, rule.IPSet = nil, nil
+			return matched
+		})
+	}) {
+		return C.RuleSetBehaviorIPCIDR
+	}
+	return C.RuleSetBehaviorClassical
+}
+
+// isOnlyHeadlessRule reports whether rule is a default rule matched by cond,
+// with no items left after cond clears the ones it checked.
+func isOnlyHeadlessRule(rule option.HeadlessRule, cond func(rule *option.DefaultHeadlessRule) bool) bool {
+	if rule.Type != C.RuleTypeDefault || rule.DefaultOptions.Invert {
+		return false
+	}
+	remaining := rule.DefaultOptions
+	return cond(&remaining) && !remaining.IsValid()
+}
@@ -0,0 +1,432 @@
+package route
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/netip"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-dns"
+	"github.com/sagernet/sing/common/buf"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/service"
+	"github.com/sagernet/sing/service/pause"
+
+	mDNS "github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+type testOutbound struct {
+	tag string
+}
+
+func (o *testOutbound) Type() string {
+	return "test"
+}
+
+func (o *testOutbound) Tag() string {
+	return o.tag
+}
+
+func (o *testOutbound) Network() []string {
+	return []string{N.NetworkTCP, N.NetworkUDP}
+}
+
+func (o *testOutbound) Dependencies() []string {
+	return nil
+}
+
+func (o *testOutbound) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	return nil, os.ErrInvalid
+}
+
+func (o *testOutbound) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
+	return nil, os.ErrInvalid
+}
+
+func (o *testOutbound) NewConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
+	return conn.Close()
+}
+
+func (o *testOutbound) NewPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext) error {
+	return conn.Close()
+}
+
+// testTransport is a raw DNS transport answering with exchange.
+type testTransport struct {
+	name     string
+	exchange func(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, error)
+}
+
+func (t *testTransport) Name() string {
+	return t.name
+}
+
+func (t *testTransport) Start() error {
+	return nil
+}
+
+func (t *testTransport) Reset() {
+}
+
+func (t *testTransport) Close() error {
+	return nil
+}
+
+func (t *testTransport) Raw() bool {
+	return true
+}
+
+func (t *testTransport) Exchange(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, error) {
+	return t.exchange(ctx, message)
+}
+
+func (t *testTransport) Lookup(ctx context.Context, domain string, strategy dns.DomainStrategy) ([]netip.Addr, error) {
+	return nil, os.ErrInvalid
+}
+
+// newAddressTransport returns a transport answering every query with the addresses of its type.
+func newAddressTransport(name string, addresses ...string) *testTransport {
+	return &testTransport{
+		name: name,
+		exchange: func(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, error) {
+			response := new(mDNS.Msg)
+			response.SetReply(message)
+			question := message.Question[0]
+			for _, address := range addresses {
+				addr := netip.MustParseAddr(address)
+				header := mDNS.RR_Header{Name: question.Name, Class: mDNS.ClassINET, Rrtype: question.Qtype, Ttl: 60}
+				if question.Qtype == mDNS.TypeA && addr.Is4() {
+					response.Answer = append(response.Answer, &mDNS.A{Hdr: header, A: addr.AsSlice()})
+				} else if question.Qtype == mDNS.TypeAAAA && addr.Is6() {
+					response.Answer = append(response.Answer, &mDNS.AAAA{Hdr: header, AAAA: addr.AsSlice()})
+				}
+			}
+			return response, nil
+		},
+	}
+}
+
+func newTestRouter(t *testing.T, outbounds []adapter.Outbound, transports []dns.Transport) *Router {
+	logger := log.NewNOPFactory().Logger()
+	router := &Router{
+		ctx:           context.Background(),
+		logger:        logger,
+		dnsLogger:     logger,
+		pauseManager:  service.FromContext[pause.Manager](pause.WithDefaultManager(context.Background())),
+		outboundByTag: make(map[string]adapter.Outbound),
+		transportMap:  make(map[string]dns.Transport),
+		dnsClient: dns.NewClient(dns.ClientOptions{
+			DisableCache: true,
+			Logger:       logger,
+		}),
+	}
+	for _, outbound := range outbounds {
+		router.outbounds = append(router.outbounds, outbound)
+		router.outboundByTag[outbound.Tag()] = outbound
+	}
+	if len(outbounds) > 0 {
+		router.defaultOutboundForConnection = outbounds[0]
+		router.defaultOutboundForPacketConnection = outbounds[0]
+	}
+	for _, transport := range transports {
+		router.transports = append(router.transports, transport)
+		router.transportMap[transport.Name()] = transport
+	}
+	return router
+}
+
+func setTestRules(t *testing.T, router *Router, rules []option.Rule) {
+	for i, ruleOptions := range rules {
+		rule, err := NewRule(router, router.logger, ruleOptions, true)
+		require.NoError(t, err, "rule[", i, "]")
+		require.NoError(t, rule.Start())
+		router.rules = append(router.rules, rule)
+	}
+}
+
+func actionRule(action option.RuleAction) option.Rule {
+	return option.Rule{
+		Type:           C.RuleTypeDefault,
+		DefaultOptions: option.DefaultRule{RuleAction: action},
+	}
+}
+
+func routeRule(outbound string) option.Rule {
+	return option.Rule{
+		Type:           C.RuleTypeDefault,
+		DefaultOptions: option.DefaultRule{Network: []string{N.NetworkTCP, N.NetworkUDP}, Outbound: outbound},
+	}
+}
+
+func TestMatchContinuingActions(t *testing.T) {
+	t.Parallel()
+	direct := &testOutbound{tag: "direct"}
+	router := newTestRouter(t, []adapter.Outbound{direct, &testOutbound{tag: "default"}}, []dns.Transport{newAddressTransport("local", "1.2.3.4")})
+	setTestRules(t, router, []option.Rule{
+		actionRule(option.RuleAction{Action: C.RuleActionTypeRouteOptions, OverridePort: 8443}),
+		actionRule(option.RuleAction{Action: C.RuleActionTypeSniff, Sniffer: []string{C.ProtocolHTTP}, OverrideDestination: true}),
+		actionRule(option.RuleAction{Action: C.RuleActionTypeResolve, Server: "local"}),
+		routeRule("direct"),
+	})
+	conn, peer := net.Pipe()
+	defer conn.Close()
+	defer peer.Close()
+	go peer.Write([]byte("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n"))
+	metadata := adapter.InboundContext{
+		Network:     N.NetworkTCP,
+		Destination: M.ParseSocksaddrHostPort("10.0.0.1", 80),
+	}
+	matchedRule, action, detour, err := router.match0(context.Background(), &metadata, &conn, nil, router.outboundByTag["default"])
+	require.NoError(t, err)
+	require.Equal(t, router.rules[3], matchedRule)
+	require.IsType(t, &RuleActionRoute{}, action)
+	require.Equal(t, direct, detour)
+	require.Equal(t, C.ProtocolHTTP, metadata.Protocol)
+	require.Equal(t, "example.com", metadata.Destination.Fqdn)
+	require.Equal(t, uint16(8443), metadata.Destination.Port)
+	require.Equal(t, []netip.Addr{netip.MustParseAddr("1.2.3.4")}, metadata.DestinationAddresses)
+}
+
+func TestMatchFinalActions(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		name   string
+		rule   option.Rule
+		action adapter.RuleAction
+	}{
+		{"route", routeRule("a"), &RuleActionRoute{}},
+		{"reject", actionRule(option.RuleAction{Action: C.RuleActionTypeReject}), &RuleActionReject{}},
+		{"hijack-dns", actionRule(option.RuleAction{Action: C.RuleActionTypeHijackDNS}), &RuleActionHijackDNS{}},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			outboundA := &testOutbound{tag: "a"}
+			outboundB := &testOutbound{tag: "b"}
+			router := newTestRouter(t, []adapter.Outbound{outboundA, outboundB}, nil)
+			setTestRules(t, router, []option.Rule{testCase.rule, routeRule("b")})
+			metadata := adapter.InboundContext{
+				Network:     N.NetworkUDP,
+				Destination: M.ParseSocksaddrHostPort("10.0.0.1", 53),
+			}
+			matchedRule, action, detour, err := router.match0(context.Background(), &metadata, nil, nil, outboundB)
+			require.NoError(t, err)
+			require.Equal(t, router.rules[0], matchedRule)
+			require.IsType(t, testCase.action, action)
+			if testCase.name == "route" {
+				require.Equal(t, outboundA, detour)
+			} else {
+				require.Nil(t, detour)
+			}
+		})
+	}
+}
+
+func TestMatchDefaultOutbound(t *testing.T) {
+	t.Parallel()
+	defaultOutbound := &testOutbound{tag: "default"}
+	router := newTestRouter(t, []adapter.Outbound{defaultOutbound}, nil)
+	setTestRules(t, router, []option.Rule{
+		actionRule(option.RuleAction{Action: C.RuleActionTypeRouteOptions, OverridePort: 8080}),
+	})
+	metadata := adapter.InboundContext{
+		Network:     N.NetworkTCP,
+		Destination: M.ParseSocksaddrHostPort("10.0.0.1", 80),
+	}
+	matchedRule, action, detour, err := router.match0(context.Background(), &metadata, nil, nil, defaultOutbound)
+	require.NoError(t, err)
+	require.Nil(t, matchedRule)
+	require.Nil(t, action)
+	require.Equal(t, defaultOutbound, detour)
+	require.Equal(t, uint16(8080), metadata.Destination.Port)
+}
+
+func TestRuleWithoutConditions(t *testing.T) {
+	t.Parallel()
+	router := newTestRouter(t, nil, nil)
+	_, err := NewRule(router, router.logger, option.Rule{Type: C.RuleTypeDefault}, true)
+	require.Error(t, err)
+	_, err = NewRule(router, router.logger, actionRule(option.RuleAction{Action: C.RuleActionTypeSniff}), true)
+	require.NoError(t, err)
+	_, err = NewRule(router, router.logger, actionRule(option.RuleAction{Action: C.RuleActionTypeReject, Method: C.RuleActionRejectMethodICMP}), true)
+	require.NoError(t, err)
+	_, err = NewRule(router, router.logger, actionRule(option.RuleAction{Action: C.RuleActionTypeReject, Method: "unknown"}), true)
+	require.Error(t, err)
+}
+
+func TestRejectMethods(t *testing.T) {
+	t.Parallel()
+	router := newTestRouter(t, nil, nil)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	for _, testCase := range []struct {
+		method string
+		reset  bool
+	}{
+		{C.RuleActionRejectMethodDefault, false},
+		{C.RuleActionRejectMethodTCPReset, true},
+		{C.RuleActionRejectMethodICMP, false},
+	} {
+		clientConn, err := net.Dial("tcp", listener.Addr().String())
+		require.NoError(t, err)
+		serverConn, err := listener.Accept()
+		require.NoError(t, err)
+		err = router.rejectConnection(context.Background(), serverConn, adapter.InboundContext{}, &RuleActionReject{Method: testCase.method})
+		require.NoError(t, err)
+		_, err = clientConn.Read(make([]byte, 1))
+		if testCase.reset {
+			require.ErrorIs(t, err, syscall.ECONNRESET, testCase.method)
+		} else {
+			require.ErrorIs(t, err, io.EOF, testCase.method)
+		}
+		clientConn.Close()
+	}
+}
+
+// testUnreachableInbound records the ICMP port unreachable replies it is asked to write.
+type testUnreachableInbound struct {
+	tag         string
+	unreachable [][2]netip.AddrPort
+}
+
+func (i *testUnreachableInbound) Type() string {
+	return C.TypeTun
+}
+
+func (i *testUnreachableInbound) Tag() string {
+	return i.tag
+}
+
+func (i *testUnreachableInbound) Start() error {
+	return nil
+}
+
+func (i *testUnreachableInbound) Close() error {
+	return nil
+}
+
+func (i *testUnreachableInbound) WritePortUnreachable(source netip.AddrPort, destination netip.AddrPort) error {
+	i.unreachable = append(i.unreachable, [2]netip.AddrPort{source, destination})
+	return nil
+}
+
+// testPacketConn reads the queued packets until packets is closed and counts the packets written.
+type testPacketConn struct {
+	packets chan []byte
+	written atomic.Int32
+	closed  atomic.Bool
+}
+
+func (c *testPacketConn) ReadPacket(buffer *buf.Buffer) (M.Socksaddr, error) {
+	packet, loaded := <-c.packets
+	if !loaded {
+		return M.Socksaddr{}, io.EOF
+	}
+	_, err := buffer.Write(packet)
+	return M.ParseSocksaddr("10.0.0.1:53"), err
+}
+
+func (c *testPacketConn) WritePacket(buffer *buf.Buffer, destination M.Socksaddr) error {
+	buffer.Release()
+	c.written.Add(1)
+	return nil
+}
+
+func (c *testPacketConn) Close() error {
+	c.closed.Store(true)
+	return nil
+}
+
+func (c *testPacketConn) LocalAddr() net.Addr {
+	return nil
+}
+
+func (c *testPacketConn) SetDeadline(t time.Time) error {
+	return nil
+}
+
+func (c *testPacketConn) SetReadDeadline(t time.Time) error {
+	return nil
+}
+
+func (c *testPacketConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
+
+func TestRejectPacketMethods(t *testing.T) {
+	t.Parallel()
+	router := newTestRouter(t, nil, nil)
+	inbound := &testUnreachableInbound{tag: "tun-in"}
+	router.inboundByTag = map[string]adapter.Inbound{inbound.tag: inbound}
+	source := netip.MustParseAddrPort("172.19.0.2:40000")
+	destination := M.ParseSocksaddr("198.18.0.1:53")
+	metadata := adapter.InboundContext{
+		Inbound:     inbound.tag,
+		InboundType: C.TypeTun,
+		Source:      M.SocksaddrFromNetIP(source),
+		Destination: M.ParseSocksaddr("example.com:53"),
+	}
+	for _, testCase := range []struct {
+		method      string
+		inbound     string
+		unreachable bool
+	}{
+		{C.RuleActionRejectMethodDefault, inbound.tag, false},
+		{C.RuleActionRejectMethodTCPReset, inbound.tag, false},
+		{C.RuleActionRejectMethodICMP, inbound.tag, true},
+		{C.RuleActionRejectMethodICMP, "socks-in", false},
+	} {
+		inbound.unreachable = nil
+		conn := &testPacketConn{packets: make(chan []byte)}
+		testMetadata := metadata
+		testMetadata.Inbound = testCase.inbound
+		err := router.rejectPacketConnection(context.Background(), conn, testMetadata, destination, &RuleActionReject{Method: testCase.method})
+		require.NoError(t, err)
+		require.True(t, conn.closed.Load(), testCase.method)
+		require.Zero(t, conn.written.Load(), testCase.method)
+		if testCase.unreachable {
+			require.Equal(t, [][2]netip.AddrPort{{source, destination.AddrPort()}}, inbound.unreachable, testCase.method)
+		} else {
+			require.Empty(t, inbound.unreachable, testCase.method)
+		}
+	}
+}
+
+func TestRejectPacketDrop(t *testing.T) {
+	t.Parallel()
+	router := newTestRouter(t, nil, nil)
+	inbound := &testUnreachableInbound{tag: "tun-in"}
+	router.inboundByTag = map[string]adapter.Inbound{inbound.tag: inbound}
+	conn := &testPacketConn{packets: make(chan []byte)}
+	done := make(chan error, 1)
+	go func() {
+		done <- router.rejectPacketConnection(context.Background(), conn, adapter.InboundContext{
+			Inbound: inbound.tag,
+			Source:  M.ParseSocksaddr("172.19.0.2:40000"),
+		}, M.ParseSocksaddr("198.18.0.1:53"), &RuleActionReject{Method: C.RuleActionRejectMethodDrop})
+	}()
+	for i := 0; i < 3; i++ {
+		conn.packets <- []byte("query")
+	}
+	select {
+	case <-done:
+		t.Fatal("drop returned while the client was still sending")
+	case <-time.After(100 * time.Millisecond):
+	}
+	require.False(t, conn.closed.Load())
+	close(conn.packets)
+	require.NoError(t, <-done)
+	require.True(t, conn.closed.Load())
+	require.Zero(t, conn.written.Load())
+	require.Empty(t, inbound.unreachable)
+}
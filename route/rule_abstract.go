@@ -21,6 +21,7 @@ type abstractDefaultRule struct {
 	ruleSetItem             RuleItem
 	invert                  bool
 	outbound                string
+	action                  adapter.RuleAction
 }
 
 func (r *abstractDefaultRule) Type() string {
@@ -154,6 +155,10 @@ func (r *abstractDefaultRule) Outbound() string {
 	return r.outbound
 }
 
+func (r *abstractDefaultRule) Action() adapter.RuleAction {
+	return r.action
+}
+
 func (r *abstractDefaultRule) String() string {
 	if !r.invert {
 		return strings.Join(F.MapToString(r.allItems), " ")
@@ -167,6 +172,7 @@ type abstractLogicalRule struct {
 	mode     string
 	invert   bool
 	outbound string
+	action   adapter.RuleAction
 }
 
 func (r *abstractLogicalRule) Type() string {
@@ -235,6 +241,10 @@ func (r *abstractLogicalRule) Outbound() string {
 	return r.outbound
 }
 
+func (r *abstractLogicalRule) Action() adapter.RuleAction {
+	return r.action
+}
+
 func (r *abstractLogicalRule) String() string {
 	var op string
 	switch r.mode {
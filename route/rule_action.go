@@ -0,0 +1,283 @@
+package route
+
+import (
+	"net"
+	"strings"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/ratelimit"
+	"github.com/sagernet/sing-box/common/sniff"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-dns"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+var (
+	defaultStreamSniffers = []sniff.StreamSniffer{
+		sniff.StreamDomainNameQuery,
+		sniff.TLSClientHello,
+		sniff.HTTPHost,
+		sniff.BitTorrent,
+		sniff.SSH,
+		sniff.RDP,
+	}
+	defaultPacketSniffers = []sniff.PacketSniffer{
+		sniff.DomainNameQuery,
+		sniff.QUICClientHello,
+		sniff.STUNMessage,
+		sniff.UTP,
+		sniff.UDPTracker,
+		sniff.DHT,
+		sniff.DTLSClientHello,
+		sniff.NTP,
+	}
+)
+
+func NewRuleAction(options option.RuleAction, outbound string) (adapter.RuleAction, error) {
+	action := options.Action
+	if action == "" {
+		action = C.RuleActionTypeRoute
+	}
+	if outbound != "" && action != C.RuleActionTypeRoute {
+		return nil, E.New("outbound is only allowed with action ", C.RuleActionTypeRoute)
+	}
+	switch action {
+	case C.RuleActionTypeRoute:
+		if outbound == "" {
+			return nil, E.New("missing outbound field")
+		}
+		routeOptions, err := newRuleActionRouteOptions(options)
+		if err != nil {
+			return nil, err
+		}
+		return &RuleActionRoute{
+			Outbound:               outbound,
+			RuleActionRouteOptions: *routeOptions,
+		}, nil
+	case C.RuleActionTypeRouteOptions:
+		return newRuleActionRouteOptions(options)
+	case C.RuleActionTypeReject:
+		switch options.Method {
+		case "", C.RuleActionRejectMethodDefault:
+			return &RuleActionReject{Method: C.RuleActionRejectMethodDefault}, nil
+		case C.RuleActionRejectMethodTCPReset, C.RuleActionRejectMethodICMP, C.RuleActionRejectMethodDrop:
+			return &RuleActionReject{Method: options.Method}, nil
+		default:
+			return nil, E.New("unknown reject method: ", options.Method)
+		}
+	case C.RuleActionTypeHijackDNS:
+		return &RuleActionHijackDNS{}, nil
+	case C.RuleActionTypeSniff:
+		sniffAction := &RuleActionSniff{
+			Sniffers:            options.Sniffer,
+			Timeout:             time.Duration(options.Timeout),
+			OverrideDestination: options.OverrideDestination,
+		}
+		if len(options.Sniffer) == 0 {
+			sniffAction.StreamSniffers = defaultStreamSniffers
+			sniffAction.PacketSniffers = defaultPacketSniffers
+		}
+		for _, name := range options.Sniffer {
+			switch name {
+			case C.ProtocolDNS:
+				sniffAction.StreamSniffers = append(sniffAction.StreamSniffers, sniff.StreamDomainNameQuery)
+				sniffAction.PacketSniffers = append(sniffAction.PacketSniffers, sniff.DomainNameQuery)
+			case C.ProtocolTLS:
+				sniffAction.StreamSniffers = append(sniffAction.StreamSniffers, sniff.TLSClientHello)
+			case C.ProtocolHTTP:
+				sniffAction.StreamSniffers = append(sniffAction.StreamSniffers, sniff.HTTPHost)
+			case C.ProtocolQUIC:
+				sniffAction.PacketSniffers = append(sniffAction.PacketSniffers, sniff.QUICClientHello)
+			case C.ProtocolSTUN:
+				sniffAction.PacketSniffers = append(sniffAction.PacketSniffers, sniff.STUNMessage)
+			case C.ProtocolBitTorrent:
+				sniffAction.StreamSniffers = append(sniffAction.StreamSniffers, sniff.BitTorrent)
+				sniffAction.PacketSniffers = append(sniffAction.PacketSniffers, sniff.UTP, sniff.UDPTracker, sniff.DHT)
+			case C.ProtocolSSH:
+				sniffAction.StreamSniffers = append(sniffAction.StreamSniffers, sniff.SSH)
+			case C.ProtocolRDP:
+				sniffAction.StreamSniffers = append(sniffAction.StreamSniffers, sniff.RDP)
+			case C.ProtocolNTP:
+				sniffAction.PacketSniffers = append(sniffAction.PacketSniffers, sniff.NTP)
+			case C.ProtocolDTLS:
+				sniffAction.PacketSniffers = append(sniffAction.PacketSniffers, sniff.DTLSClientHello)
+			default:
+				return nil, E.New("unknown sniffer: ", name)
+			}
+		}
+		return sniffAction, nil
+	case C.RuleActionTypeResolve:
+		return &RuleActionResolve{
+			Strategy: dns.DomainStrategy(options.Strategy),
+			Server:   options.Server,
+		}, nil
+	default:
+		return nil, E.New("unknown rule action: ", action)
+	}
+}
+
+func newRuleActionRouteOptions(options option.RuleAction) (*RuleActionRouteOptions, error) {
+	routeOptions := &RuleActionRouteOptions{
+		OverridePort:              options.OverridePort,
+		UDPDisableDomainUnmapping: options.UDPDisableDomainUnmapping,
+	}
+	if options.BandwidthLimit != nil {
+		routeOptions.BandwidthLimit = *options.BandwidthLimit
+		routeOptions.BandwidthLimiter = ratelimit.NewLimiter(*options.BandwidthLimit)
+	}
+	if options.OverrideAddress != "" {
+		routeOptions.OverrideAddress = M.ParseSocksaddrHostPort(options.OverrideAddress, 0)
+		if !routeOptions.OverrideAddress.IsValid() {
+			return nil, E.New("invalid override address: ", options.OverrideAddress)
+		}
+	}
+	return routeOptions, nil
+}
+
+type RuleActionRoute struct {
+	Outbound string
+	RuleActionRouteOptions
+}
+
+func (r *RuleActionRoute) Type() string {
+	return C.RuleActionTypeRoute
+}
+
+func (r *RuleActionRoute) String() string {
+	return r.Outbound
+}
+
+type RuleActionRouteOptions struct {
+	OverrideAddress           M.Socksaddr
+	OverridePort              uint16
+	UDPDisableDomainUnmapping bool
+	BandwidthLimit            option.BandwidthLimitOptions
+	BandwidthLimiter          *ratelimit.Limiter
+}
+
+func (r *RuleActionRouteOptions) Type() string {
+	return C.RuleActionTypeRouteOptions
+}
+
+func (r *RuleActionRouteOptions) String() string {
+	var descriptions []string
+	if r.OverrideAddress.IsValid() {
+		descriptions = append(descriptions, F.ToString("override_address=", r.OverrideAddress.AddrString()))
+	}
+	if r.OverridePort > 0 {
+		descriptions = append(descriptions, F.ToString("override_port=", r.OverridePort))
+	}
+	if r.UDPDisableDomainUnmapping {
+		descriptions = append(descriptions, "udp_disable_domain_unmapping")
+	}
+	if r.BandwidthLimit.UpMbps > 0 {
+		descriptions = append(descriptions, F.ToString("up_mbps=", r.BandwidthLimit.UpMbps))
+	}
+	if r.BandwidthLimit.DownMbps > 0 {
+		descriptions = append(descriptions, F.ToString("down_mbps=", r.BandwidthLimit.DownMbps))
+	}
+	return "route-options(" + strings.Join(descriptions, ",") + ")"
+}
+
+// Apply overrides the destination of metadata.
+func (r *RuleActionRouteOptions) Apply(metadata *adapter.InboundContext) {
+	if r.OverrideAddress.IsValid() {
+		metadata.Destination = M.Socksaddr{
+			Addr: r.OverrideAddress.Addr,
+			Fqdn: r.OverrideAddress.Fqdn,
+			Port: metadata.Destination.Port,
+		}
+		metadata.DestinationAddresses = nil
+		if metadata.Destination.IsIPv4() {
+			metadata.IPVersion = 4
+		} else if metadata.Destination.IsIPv6() {
+			metadata.IPVersion = 6
+		} else {
+			metadata.IPVersion = 0
+		}
+	}
+	if r.OverridePort > 0 {
+		metadata.Destination.Port = r.OverridePort
+	}
+	if r.UDPDisableDomainUnmapping {
+		metadata.InboundOptions.UDPDisableDomainUnmapping = true
+	}
+}
+
+// Limit wraps the connection with the bandwidth limiter shared by all
+// connections matching the rule.
+func (r *RuleActionRouteOptions) Limit(conn *net.Conn, packetConn *N.PacketConn) {
+	if r.BandwidthLimiter == nil {
+		return
+	}
+	if conn != nil {
+		*conn = r.BandwidthLimiter.NewConn(*conn)
+	} else if packetConn != nil {
+		*packetConn = r.BandwidthLimiter.NewPacketConn(*packetConn)
+	}
+}
+
+type RuleActionReject struct {
+	Method string
+}
+
+func (r *RuleActionReject) Type() string {
+	return C.RuleActionTypeReject
+}
+
+func (r *RuleActionReject) String() string {
+	if r.Method == C.RuleActionRejectMethodDefault {
+		return "reject"
+	}
+	return "reject(" + r.Method + ")"
+}
+
+type RuleActionHijackDNS struct{}
+
+func (r *RuleActionHijackDNS) Type() string {
+	return C.RuleActionTypeHijackDNS
+}
+
+func (r *RuleActionHijackDNS) String() string {
+	return "hijack-dns"
+}
+
+type RuleActionSniff struct {
+	Sniffers            []string
+	StreamSniffers      []sniff.StreamSniffer
+	PacketSniffers      []sniff.PacketSniffer
+	Timeout             time.Duration
+	OverrideDestination bool
+}
+
+func (r *RuleActionSniff) Type() string {
+	return C.RuleActionTypeSniff
+}
+
+func (r *RuleActionSniff) String() string {
+	if len(r.Sniffers) == 0 {
+		return "sniff"
+	}
+	return "sniff(" + strings.Join(r.Sniffers, ",") + ")"
+}
+
+type RuleActionResolve struct {
+	Strategy dns.DomainStrategy
+	Server   string
+}
+
+func (r *RuleActionResolve) Type() string {
+	return C.RuleActionTypeResolve
+}
+
+func (r *RuleActionResolve) String() string {
+	if r.Server == "" {
+		return "resolve"
+	}
+	return "resolve(" + r.Server + ")"
+}
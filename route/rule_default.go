@@ -11,21 +11,42 @@ import (
 func NewRule(router adapter.Router, logger log.ContextLogger, options option.Rule, checkOutbound bool) (adapter.Rule, error) {
 	switch options.Type {
 	case "", C.RuleTypeDefault:
-		if !options.DefaultOptions.IsValid() {
+		// A rule with an explicit action may omit conditions to match everything.
+		if !options.DefaultOptions.IsValid() && options.DefaultOptions.Action == "" {
 			return nil, E.New("missing conditions")
 		}
-		if options.DefaultOptions.Outbound == "" && checkOutbound {
-			return nil, E.New("missing outbound field")
+		var action adapter.RuleAction
+		if checkOutbound {
+			var err error
+			action, err = NewRuleAction(options.DefaultOptions.RuleAction, options.DefaultOptions.Outbound)
+			if err != nil {
+				return nil, err
+			}
 		}
-		return NewDefaultRule(router, logger, options.DefaultOptions)
+		rule, err := NewDefaultRule(router, logger, options.DefaultOptions)
+		if err != nil {
+			return nil, err
+		}
+		rule.action = action
+		return rule, nil
 	case C.RuleTypeLogical:
 		if !options.LogicalOptions.IsValid() {
 			return nil, E.New("missing conditions")
 		}
-		if options.LogicalOptions.Outbound == "" && checkOutbound {
-			return nil, E.New("missing outbound field")
+		var action adapter.RuleAction
+		if checkOutbound {
+			var err error
+			action, err = NewRuleAction(options.LogicalOptions.RuleAction, options.LogicalOptions.Outbound)
+			if err != nil {
+				return nil, err
+			}
 		}
-		return NewLogicalRule(router, logger, options.LogicalOptions)
+		rule, err := NewLogicalRule(router, logger, options.LogicalOptions)
+		if err != nil {
+			return nil, err
+		}
+		rule.action = action
+		return rule, nil
 	default:
 		return nil, E.New("unknown rule type: ", options.Type)
 	}
@@ -79,6 +100,16 @@ func NewDefaultRule(router adapter.Router, logger log.ContextLogger, options opt
 		rule.items = append(rule.items, item)
 		rule.allItems = append(rule.allItems, item)
 	}
+	if len(options.ClientALPN) > 0 {
+		item := NewClientALPNItem(options.ClientALPN)
+		rule.items = append(rule.items, item)
+		rule.allItems = append(rule.allItems, item)
+	}
+	if len(options.ClientFingerprint) > 0 {
+		item := NewClientFingerprintItem(options.ClientFingerprint)
+		rule.items = append(rule.items, item)
+		rule.allItems = append(rule.allItems, item)
+	}
 	if len(options.Domain) > 0 || len(options.DomainSuffix) > 0 {
 		item := NewDomainItem(options.Domain, options.DomainSuffix)
 		rule.destinationAddressItems = append(rule.destinationAddressItems, item)
@@ -7,6 +7,7 @@ import (
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-dns"
 	"github.com/sagernet/sing/common"
 	E "github.com/sagernet/sing/common/exceptions"
 )
@@ -17,7 +18,11 @@ func NewDNSRule(router adapter.Router, logger log.ContextLogger, options option.
 		if !options.DefaultOptions.IsValid() {
 			return nil, E.New("missing conditions")
 		}
-		if options.DefaultOptions.Server == "" && checkServer {
+		if options.DefaultOptions.Predefined != nil {
+			if options.DefaultOptions.Server != "" {
+				return nil, E.New("server and predefined are mutually exclusive")
+			}
+		} else if options.DefaultOptions.Server == "" && checkServer {
 			return nil, E.New("missing server field")
 		}
 		return NewDefaultDNSRule(router, logger, options.DefaultOptions)
@@ -25,7 +30,11 @@ func NewDNSRule(router adapter.Router, logger log.ContextLogger, options option.
 		if !options.LogicalOptions.IsValid() {
 			return nil, E.New("missing conditions")
 		}
-		if options.LogicalOptions.Server == "" && checkServer {
+		if options.LogicalOptions.Predefined != nil {
+			if options.LogicalOptions.Server != "" {
+				return nil, E.New("server and predefined are mutually exclusive")
+			}
+		} else if options.LogicalOptions.Server == "" && checkServer {
 			return nil, E.New("missing server field")
 		}
 		return NewLogicalDNSRule(router, logger, options.LogicalOptions)
@@ -38,9 +47,12 @@ var _ adapter.DNSRule = (*DefaultDNSRule)(nil)
 
 type DefaultDNSRule struct {
 	abstractDefaultRule
-	disableCache bool
-	rewriteTTL   *uint32
-	clientSubnet *netip.Prefix
+	responseItems []RuleItem
+	disableCache  bool
+	rewriteTTL    *uint32
+	clientSubnet  *netip.Prefix
+	predefined    *predefinedTransport
+	dnssec        bool
 }
 
 func NewDefaultDNSRule(router adapter.Router, logger log.ContextLogger, options option.DefaultDNSRule) (*DefaultDNSRule, error) {
@@ -52,6 +64,14 @@ func NewDefaultDNSRule(router adapter.Router, logger log.ContextLogger, options
 		disableCache: options.DisableCache,
 		rewriteTTL:   options.RewriteTTL,
 		clientSubnet: (*netip.Prefix)(options.ClientSubnet),
+		dnssec:       options.DNSSEC,
+	}
+	if options.Predefined != nil {
+		predefined, err := newPredefinedTransport(*options.Predefined)
+		if err != nil {
+			return nil, E.Cause(err, "predefined")
+		}
+		rule.predefined = predefined
 	}
 	if len(options.Inbound) > 0 {
 		item := NewInboundRule(options.Inbound)
@@ -223,6 +243,14 @@ func NewDefaultDNSRule(router adapter.Router, logger log.ContextLogger, options
 		rule.items = append(rule.items, item)
 		rule.allItems = append(rule.allItems, item)
 	}
+	if len(options.DNSSECStatus) > 0 {
+		item, err := NewDNSSECStatusItem(options.DNSSECStatus)
+		if err != nil {
+			return nil, E.Cause(err, "dnssec_status")
+		}
+		rule.responseItems = append(rule.responseItems, item)
+		rule.allItems = append(rule.allItems, item)
+	}
 	return rule, nil
 }
 
@@ -238,8 +266,19 @@ func (r *DefaultDNSRule) ClientSubnet() *netip.Prefix {
 	return r.clientSubnet
 }
 
+func (r *DefaultDNSRule) Predefined() dns.Transport {
+	if r.predefined == nil {
+		return nil
+	}
+	return r.predefined
+}
+
+func (r *DefaultDNSRule) DNSSEC() bool {
+	return r.dnssec
+}
+
 func (r *DefaultDNSRule) WithAddressLimit() bool {
-	if len(r.destinationIPCIDRItems) > 0 {
+	if len(r.destinationIPCIDRItems) > 0 || len(r.responseItems) > 0 {
 		return true
 	}
 	for _, rawRule := range r.items {
@@ -263,6 +302,12 @@ func (r *DefaultDNSRule) Match(metadata *adapter.InboundContext) bool {
 }
 
 func (r *DefaultDNSRule) MatchAddressLimit(metadata *adapter.InboundContext) bool {
+	for _, item := range r.responseItems {
+		metadata.DidMatch = true
+		if !item.Match(metadata) {
+			return r.invert
+		}
+	}
 	return r.abstractDefaultRule.Match(metadata)
 }
 
@@ -273,6 +318,8 @@ type LogicalDNSRule struct {
 	disableCache bool
 	rewriteTTL   *uint32
 	clientSubnet *netip.Prefix
+	predefined   *predefinedTransport
+	dnssec       bool
 }
 
 func NewLogicalDNSRule(router adapter.Router, logger log.ContextLogger, options option.LogicalDNSRule) (*LogicalDNSRule, error) {
@@ -285,6 +332,14 @@ func NewLogicalDNSRule(router adapter.Router, logger log.ContextLogger, options
 		disableCache: options.DisableCache,
 		rewriteTTL:   options.RewriteTTL,
 		clientSubnet: (*netip.Prefix)(options.ClientSubnet),
+		dnssec:       options.DNSSEC,
+	}
+	if options.Predefined != nil {
+		predefined, err := newPredefinedTransport(*options.Predefined)
+		if err != nil {
+			return nil, E.Cause(err, "predefined")
+		}
+		r.predefined = predefined
 	}
 	switch options.Mode {
 	case C.LogicalTypeAnd:
@@ -316,6 +371,17 @@ func (r *LogicalDNSRule) ClientSubnet() *netip.Prefix {
 	return r.clientSubnet
 }
 
+func (r *LogicalDNSRule) Predefined() dns.Transport {
+	if r.predefined == nil {
+		return nil
+	}
+	return r.predefined
+}
+
+func (r *LogicalDNSRule) DNSSEC() bool {
+	return r.dnssec
+}
+
 func (r *LogicalDNSRule) WithAddressLimit() bool {
 	for _, rawRule := range r.rules {
 		switch rule := rawRule.(type) {
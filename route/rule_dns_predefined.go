@@ -0,0 +1,144 @@
+package route
+
+import (
+	"context"
+	"net/netip"
+
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-dns"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	mDNS "github.com/miekg/dns"
+)
+
+var predefinedRCodes = map[string]int{
+	"success":         mDNS.RcodeSuccess,
+	"format_error":    mDNS.RcodeFormatError,
+	"server_failure":  mDNS.RcodeServerFailure,
+	"name_error":      mDNS.RcodeNameError,
+	"not_implemented": mDNS.RcodeNotImplemented,
+	"refused":         mDNS.RcodeRefused,
+}
+
+var _ dns.Transport = (*predefinedTransport)(nil)
+
+// predefinedTransport answers queries matched by a DNS rule with the
+// configured rcode and records, without going to the network.
+type predefinedTransport struct {
+	rcode  int
+	answer []mDNS.RR
+}
+
+func newPredefinedTransport(options option.DNSPredefinedOptions) (*predefinedTransport, error) {
+	transport := &predefinedTransport{}
+	if options.RCode != "" {
+		rcode, loaded := predefinedRCodes[options.RCode]
+		if !loaded {
+			return nil, E.New("unknown rcode: ", options.RCode)
+		}
+		transport.rcode = rcode
+	}
+	for i, record := range options.Answer {
+		answer, err := mDNS.NewRR(record)
+		if err != nil {
+			return nil, E.Cause(err, "parse answer[", i, "]")
+		}
+		if answer == nil {
+			return nil, E.New("parse answer[", i, "]: empty record")
+		}
+		transport.answer = append(transport.answer, answer)
+	}
+	return transport, nil
+}
+
+func (t *predefinedTransport) Name() string {
+	return "predefined"
+}
+
+func (t *predefinedTransport) Start() error {
+	return nil
+}
+
+func (t *predefinedTransport) Reset() {
+}
+
+func (t *predefinedTransport) Close() error {
+	return nil
+}
+
+func (t *predefinedTransport) Raw() bool {
+	return true
+}
+
+func (t *predefinedTransport) Blocked() bool {
+	return t.rcode != mDNS.RcodeSuccess
+}
+
+// Exchange returns records owned by the query name, or by `@`, whose type matches the question.
+// CNAME records are followed to records listed after them.
+func (t *predefinedTransport) Exchange(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, error) {
+	question := message.Question[0]
+	response := &mDNS.Msg{
+		MsgHdr: mDNS.MsgHdr{
+			Id:                 message.Id,
+			Response:           true,
+			RecursionDesired:   message.RecursionDesired,
+			RecursionAvailable: true,
+			Rcode:              t.rcode,
+		},
+		Question: []mDNS.Question{question},
+	}
+	names := []string{mDNS.CanonicalName(question.Name)}
+	for _, record := range t.answer {
+		header := record.Header()
+		name := mDNS.CanonicalName(header.Name)
+		if name == "." {
+			name = names[0]
+		}
+		if !containsName(names, name) {
+			continue
+		}
+		if header.Rrtype != question.Qtype && header.Rrtype != mDNS.TypeCNAME && question.Qtype != mDNS.TypeANY {
+			continue
+		}
+		record = mDNS.Copy(record)
+		if record.Header().Name == "." {
+			record.Header().Name = question.Name
+		}
+		response.Answer = append(response.Answer, record)
+		if cname, isCNAME := record.(*mDNS.CNAME); isCNAME {
+			names = append(names, mDNS.CanonicalName(cname.Target))
+		}
+	}
+	return response, nil
+}
+
+func (t *predefinedTransport) Lookup(ctx context.Context, domain string, strategy dns.DomainStrategy) ([]netip.Addr, error) {
+	var addresses []netip.Addr
+	for _, qType := range []uint16{mDNS.TypeA, mDNS.TypeAAAA} {
+		if qType == mDNS.TypeA && strategy == dns.DomainStrategyUseIPv6 || qType == mDNS.TypeAAAA && strategy == dns.DomainStrategyUseIPv4 {
+			continue
+		}
+		message := new(mDNS.Msg)
+		message.SetQuestion(mDNS.Fqdn(domain), qType)
+		response, _ := t.Exchange(ctx, message)
+		if response.Rcode != mDNS.RcodeSuccess {
+			return nil, dns.RCodeError(response.Rcode)
+		}
+		responseAddrs, _ := dns.MessageToAddresses(response)
+		for _, address := range responseAddrs {
+			// A records are parsed into 16-byte addresses
+			addresses = append(addresses, address.Unmap())
+		}
+	}
+	return addresses, nil
+}
+
+func containsName(names []string, name string) bool {
+	for _, it := range names {
+		if it == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+package route
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-dns"
+
+	mDNS "github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+func exchangePredefined(t *testing.T, transport *predefinedTransport, name string, qType uint16) *mDNS.Msg {
+	message := new(mDNS.Msg)
+	message.SetQuestion(name, qType)
+	response, err := transport.Exchange(context.Background(), message)
+	require.NoError(t, err)
+	require.Equal(t, message.Id, response.Id)
+	require.Equal(t, message.Question, response.Question)
+	return response
+}
+
+func answerStrings(response *mDNS.Msg) []string {
+	answers := make([]string, 0, len(response.Answer))
+	for _, record := range response.Answer {
+		answers = append(answers, record.String())
+	}
+	return answers
+}
+
+func TestPredefinedFollowsCNAME(t *testing.T) {
+	t.Parallel()
+	transport, err := newPredefinedTransport(option.DNSPredefinedOptions{
+		Answer: []string{
+			"www.example.com. 60 IN CNAME edge.example.net.",
+			"edge.example.net. 60 IN CNAME edge.example.org.",
+			"edge.example.org. 60 IN A 10.0.0.1",
+			"edge.example.org. 60 IN AAAA 2001:db8::1",
+			"other.example.com. 60 IN A 10.0.0.2",
+		},
+	})
+	require.NoError(t, err)
+
+	response := exchangePredefined(t, transport, "WWW.example.com.", mDNS.TypeA)
+	require.Equal(t, mDNS.RcodeSuccess, response.Rcode)
+	require.Equal(t, []string{
+		"www.example.com.\t60\tIN\tCNAME\tedge.example.net.",
+		"edge.example.net.\t60\tIN\tCNAME\tedge.example.org.",
+		"edge.example.org.\t60\tIN\tA\t10.0.0.1",
+	}, answerStrings(response))
+
+	response = exchangePredefined(t, transport, "edge.example.net.", mDNS.TypeAAAA)
+	require.Equal(t, []string{
+		"edge.example.net.\t60\tIN\tCNAME\tedge.example.org.",
+		"edge.example.org.\t60\tIN\tAAAA\t2001:db8::1",
+	}, answerStrings(response))
+
+	// records are only followed forwards
+	response = exchangePredefined(t, transport, "edge.example.org.", mDNS.TypeA)
+	require.Equal(t, []string{"edge.example.org.\t60\tIN\tA\t10.0.0.1"}, answerStrings(response))
+	response = exchangePredefined(t, transport, "missing.example.com.", mDNS.TypeA)
+	require.Empty(t, response.Answer)
+
+	addresses, err := transport.Lookup(context.Background(), "www.example.com", dns.DomainStrategyAsIS)
+	require.NoError(t, err)
+	require.Equal(t, []netip.Addr{netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("2001:db8::1")}, addresses)
+	addresses, err = transport.Lookup(context.Background(), "www.example.com", dns.DomainStrategyUseIPv6)
+	require.NoError(t, err)
+	require.Equal(t, []netip.Addr{netip.MustParseAddr("2001:db8::1")}, addresses)
+}
+
+func TestPredefinedOwnerAt(t *testing.T) {
+	t.Parallel()
+	transport, err := newPredefinedTransport(option.DNSPredefinedOptions{
+		Answer: []string{
+			"@ 60 IN CNAME target.example.com.",
+			"target.example.com. 60 IN A 10.0.0.1",
+		},
+	})
+	require.NoError(t, err)
+	response := exchangePredefined(t, transport, "any.example.com.", mDNS.TypeA)
+	require.Equal(t, []string{
+		"any.example.com.\t60\tIN\tCNAME\ttarget.example.com.",
+		"target.example.com.\t60\tIN\tA\t10.0.0.1",
+	}, answerStrings(response))
+	// the configured record is not modified
+	require.Equal(t, ".", transport.answer[0].Header().Name)
+}
+
+func TestPredefinedRCode(t *testing.T) {
+	t.Parallel()
+	transport, err := newPredefinedTransport(option.DNSPredefinedOptions{RCode: "name_error"})
+	require.NoError(t, err)
+	require.True(t, transport.Blocked())
+	response := exchangePredefined(t, transport, "example.com.", mDNS.TypeA)
+	require.Equal(t, mDNS.RcodeNameError, response.Rcode)
+	_, err = transport.Lookup(context.Background(), "example.com", dns.DomainStrategyAsIS)
+	require.Equal(t, dns.RCodeError(mDNS.RcodeNameError), err)
+
+	_, err = newPredefinedTransport(option.DNSPredefinedOptions{RCode: "unknown"})
+	require.Error(t, err)
+}
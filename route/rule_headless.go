@@ -142,6 +142,16 @@ func NewDefaultHeadlessRule(router adapter.Router, options option.DefaultHeadles
 			rule.allItems = append(rule.allItems, item)
 		}
 	}
+	if len(options.ClientALPN) > 0 {
+		item := NewClientALPNItem(options.ClientALPN)
+		rule.items = append(rule.items, item)
+		rule.allItems = append(rule.allItems, item)
+	}
+	if len(options.ClientFingerprint) > 0 {
+		item := NewClientFingerprintItem(options.ClientFingerprint)
+		rule.items = append(rule.items, item)
+		rule.allItems = append(rule.allItems, item)
+	}
 	return rule, nil
 }
 
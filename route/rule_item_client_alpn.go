@@ -0,0 +1,42 @@
+package route
+
+import (
+	"strings"
+
+	"github.com/sagernet/sing-box/adapter"
+	F "github.com/sagernet/sing/common/format"
+)
+
+var _ RuleItem = (*ClientALPNItem)(nil)
+
+type ClientALPNItem struct {
+	alpnList []string
+	alpnMap  map[string]bool
+}
+
+func NewClientALPNItem(alpnList []string) *ClientALPNItem {
+	alpnMap := make(map[string]bool)
+	for _, alpn := range alpnList {
+		alpnMap[alpn] = true
+	}
+	return &ClientALPNItem{
+		alpnList: alpnList,
+		alpnMap:  alpnMap,
+	}
+}
+
+func (r *ClientALPNItem) Match(metadata *adapter.InboundContext) bool {
+	for _, alpn := range metadata.ClientALPN {
+		if r.alpnMap[alpn] {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *ClientALPNItem) String() string {
+	if len(r.alpnList) == 1 {
+		return F.ToString("client_alpn=", r.alpnList[0])
+	}
+	return F.ToString("client_alpn=[", strings.Join(r.alpnList, " "), "]")
+}
@@ -0,0 +1,46 @@
+package route
+
+import (
+	"strings"
+
+	"github.com/sagernet/sing-box/adapter"
+	F "github.com/sagernet/sing/common/format"
+)
+
+var _ RuleItem = (*ClientFingerprintItem)(nil)
+
+type ClientFingerprintItem struct {
+	fingerprints   []string
+	fingerprintMap map[string]bool
+}
+
+func NewClientFingerprintItem(fingerprints []string) *ClientFingerprintItem {
+	fingerprintMap := make(map[string]bool)
+	for _, fingerprint := range fingerprints {
+		fingerprintMap[fingerprint] = true
+	}
+	return &ClientFingerprintItem{
+		fingerprints:   fingerprints,
+		fingerprintMap: fingerprintMap,
+	}
+}
+
+// Match matches the full JA4 fingerprint, or its first section which describes
+// the protocol, version, SNI, counts and ALPN of the ClientHello.
+func (r *ClientFingerprintItem) Match(metadata *adapter.InboundContext) bool {
+	if metadata.ClientFingerprint == "" {
+		return false
+	}
+	if r.fingerprintMap[metadata.ClientFingerprint] {
+		return true
+	}
+	prefix, _, _ := strings.Cut(metadata.ClientFingerprint, "_")
+	return r.fingerprintMap[prefix]
+}
+
+func (r *ClientFingerprintItem) String() string {
+	if len(r.fingerprints) == 1 {
+		return F.ToString("client_fingerprint=", r.fingerprints[0])
+	}
+	return F.ToString("client_fingerprint=[", strings.Join(r.fingerprints, " "), "]")
+}
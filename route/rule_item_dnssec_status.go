@@ -0,0 +1,45 @@
+package route
+
+import (
+	"strings"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+var _ RuleItem = (*DNSSECStatusItem)(nil)
+
+// DNSSECStatusItem matches the validation status of DNS responses, so it is
+// checked with the address limit items.
+type DNSSECStatusItem struct {
+	statusList []string
+	statusMap  map[string]bool
+}
+
+func NewDNSSECStatusItem(statusList []string) (*DNSSECStatusItem, error) {
+	statusMap := make(map[string]bool)
+	for _, status := range statusList {
+		switch status {
+		case C.DNSSECStatusSecure, C.DNSSECStatusInsecure:
+		default:
+			return nil, E.New("unknown dnssec status: ", status)
+		}
+		statusMap[status] = true
+	}
+	return &DNSSECStatusItem{
+		statusList: statusList,
+		statusMap:  statusMap,
+	}, nil
+}
+
+func (r *DNSSECStatusItem) Match(metadata *adapter.InboundContext) bool {
+	return r.statusMap[metadata.DNSSECStatus]
+}
+
+func (r *DNSSECStatusItem) String() string {
+	if len(r.statusList) == 1 {
+		return "dnssec_status=" + r.statusList[0]
+	}
+	return "dnssec_status=[" + strings.Join(r.statusList, " ") + "]"
+}
@@ -40,6 +40,13 @@ func (r *RuleSetItem) Start() error {
 	return nil
 }
 
+func (r *RuleSetItem) Close() error {
+	for _, ruleSet := range r.setList {
+		ruleSet.DecRef()
+	}
+	return nil
+}
+
 func (r *RuleSetItem) Match(metadata *adapter.InboundContext) bool {
 	metadata.IPCIDRMatchSource = r.ipCidrMatchSource
 	metadata.IPCIDRAcceptEmpty = r.ipCidrAcceptEmpty
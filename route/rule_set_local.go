@@ -5,9 +5,11 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/sagernet/fswatch"
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/rulelist"
 	"github.com/sagernet/sing-box/common/srs"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/option"
@@ -25,22 +27,29 @@ import (
 var _ adapter.RuleSet = (*LocalRuleSet)(nil)
 
 type LocalRuleSet struct {
-	router     adapter.Router
-	logger     logger.Logger
-	tag        string
-	rules      []adapter.HeadlessRule
-	metadata   adapter.RuleSetMetadata
-	fileFormat string
-	watcher    *fswatch.Watcher
-	refs       atomic.Int32
+	router      adapter.Router
+	logger      logger.Logger
+	tag         string
+	ruleSetType string
+	path        string
+	rules       []adapter.HeadlessRule
+	ruleCount   int
+	behavior    string
+	metadata    adapter.RuleSetMetadata
+	fileFormat  string
+	watcher     *fswatch.Watcher
+	refs        atomic.Int32
+	lastUpdated time.Time
 }
 
 func NewLocalRuleSet(router adapter.Router, logger logger.Logger, options option.RuleSet) (*LocalRuleSet, error) {
 	ruleSet := &LocalRuleSet{
-		router:     router,
-		logger:     logger,
-		tag:        options.Tag,
-		fileFormat: options.Format,
+		router:      router,
+		logger:      logger,
+		tag:         options.Tag,
+		ruleSetType: options.Type,
+		path:        options.LocalOptions.Path,
+		fileFormat:  options.Format,
 	}
 	if options.Type == C.RuleSetTypeInline {
 		if len(options.InlineOptions.Rules) == 0 {
@@ -80,6 +89,33 @@ func (s *LocalRuleSet) Name() string {
 	return s.tag
 }
 
+func (s *LocalRuleSet) Type() string {
+	return s.ruleSetType
+}
+
+func (s *LocalRuleSet) Format() string {
+	return s.fileFormat
+}
+
+func (s *LocalRuleSet) Behavior() string {
+	return s.behavior
+}
+
+func (s *LocalRuleSet) RuleCount() int {
+	return s.ruleCount
+}
+
+func (s *LocalRuleSet) UpdatedAt() time.Time {
+	return s.lastUpdated
+}
+
+func (s *LocalRuleSet) Update(ctx context.Context) error {
+	if s.ruleSetType == C.RuleSetTypeInline {
+		return E.New("inline rule-set cannot be updated")
+	}
+	return s.reloadFile(s.path)
+}
+
 func (s *LocalRuleSet) String() string {
 	return strings.Join(F.MapToString(s.rules), " ")
 }
@@ -115,12 +151,27 @@ func (s *LocalRuleSet) reloadFile(path string) error {
 		if err != nil {
 			return err
 		}
-		plainRuleSet, err = srs.Read(setFile, false)
+		compat, err := srs.Read(setFile, false)
+		setFile.Close()
+		if err != nil {
+			return err
+		}
+		plainRuleSet, err = compat.Upgrade()
 		if err != nil {
 			return err
 		}
 	default:
-		return E.New("unknown rule-set format: ", s.fileFormat)
+		if !rulelist.IsFormat(s.fileFormat) {
+			return E.New("unknown rule-set format: ", s.fileFormat)
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		plainRuleSet, err = rulelist.Parse(s.fileFormat, content)
+		if err != nil {
+			return err
+		}
 	}
 	return s.reloadRules(plainRuleSet.Rules)
 }
@@ -139,7 +190,10 @@ func (s *LocalRuleSet) reloadRules(headlessRules []option.HeadlessRule) error {
 	metadata.ContainsWIFIRule = hasHeadlessRule(headlessRules, isWIFIHeadlessRule)
 	metadata.ContainsIPCIDRRule = hasHeadlessRule(headlessRules, isIPCIDRHeadlessRule)
 	s.rules = rules
+	s.ruleCount = len(rules)
+	s.behavior = ruleSetBehavior(headlessRules)
 	s.metadata = metadata
+	s.lastUpdated = time.Now()
 	return nil
 }
 
@@ -168,6 +222,7 @@ func (s *LocalRuleSet) DecRef() {
 func (s *LocalRuleSet) Cleanup() {
 	if s.refs.Load() == 0 {
 		s.rules = nil
+		s.ruleCount = 0
 	}
 }
 
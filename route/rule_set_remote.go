@@ -3,6 +3,7 @@ package route
 import (
 	"bytes"
 	"context"
+	"errors"
 	"io"
 	"net"
 	"net/http"
@@ -12,6 +13,7 @@ import (
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/rulelist"
 	"github.com/sagernet/sing-box/common/srs"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/option"
@@ -32,6 +34,8 @@ import (
 
 var _ adapter.RuleSet = (*RemoteRuleSet)(nil)
 
+var errRuleSetUpdating = E.New("rule-set is updating")
+
 type RemoteRuleSet struct {
 	ctx            context.Context
 	cancel         context.CancelFunc
@@ -42,6 +46,9 @@ type RemoteRuleSet struct {
 	updateInterval time.Duration
 	dialer         N.Dialer
 	rules          []adapter.HeadlessRule
+	ruleCount      int
+	behavior       string
+	updating       atomic.Bool
 	lastUpdated    time.Time
 	lastEtag       string
 	updateTicker   *time.Ticker
@@ -74,6 +81,38 @@ func (s *RemoteRuleSet) Name() string {
 	return s.options.Tag
 }
 
+func (s *RemoteRuleSet) Type() string {
+	return C.RuleSetTypeRemote
+}
+
+func (s *RemoteRuleSet) Format() string {
+	return s.options.Format
+}
+
+func (s *RemoteRuleSet) Behavior() string {
+	return s.behavior
+}
+
+func (s *RemoteRuleSet) RuleCount() int {
+	return s.ruleCount
+}
+
+func (s *RemoteRuleSet) UpdatedAt() time.Time {
+	return s.lastUpdated
+}
+
+func (s *RemoteRuleSet) Update(ctx context.Context) error {
+	if s.dialer == nil {
+		return E.New("rule-set ", s.options.Tag, " not started")
+	}
+	err := s.fetchOnce(ctx, nil)
+	if err != nil {
+		return err
+	}
+	s.Cleanup()
+	return nil
+}
+
 func (s *RemoteRuleSet) String() string {
 	return strings.Join(F.MapToString(s.rules), " ")
 }
@@ -141,6 +180,7 @@ func (s *RemoteRuleSet) DecRef() {
 func (s *RemoteRuleSet) Cleanup() {
 	if s.refs.Load() == 0 {
 		s.rules = nil
+		s.ruleCount = 0
 	}
 }
 
@@ -173,12 +213,23 @@ func (s *RemoteRuleSet) loadBytes(content []byte) error {
 			return err
 		}
 	case C.RuleSetFormatBinary:
-		plainRuleSet, err = srs.Read(bytes.NewReader(content), false)
+		var compat option.PlainRuleSetCompat
+		compat, err = srs.Read(bytes.NewReader(content), false)
+		if err != nil {
+			return err
+		}
+		plainRuleSet, err = compat.Upgrade()
 		if err != nil {
 			return err
 		}
 	default:
-		return E.New("unknown rule-set format: ", s.options.Format)
+		if !rulelist.IsFormat(s.options.Format) {
+			return E.New("unknown rule-set format: ", s.options.Format)
+		}
+		plainRuleSet, err = rulelist.Parse(s.options.Format, content)
+		if err != nil {
+			return err
+		}
 	}
 	rules := make([]adapter.HeadlessRule, len(plainRuleSet.Rules))
 	for i, ruleOptions := range plainRuleSet.Rules {
@@ -191,6 +242,8 @@ func (s *RemoteRuleSet) loadBytes(content []byte) error {
 	s.metadata.ContainsWIFIRule = hasHeadlessRule(plainRuleSet.Rules, isWIFIHeadlessRule)
 	s.metadata.ContainsIPCIDRRule = hasHeadlessRule(plainRuleSet.Rules, isIPCIDRHeadlessRule)
 	s.rules = rules
+	s.ruleCount = len(rules)
+	s.behavior = ruleSetBehavior(plainRuleSet.Rules)
 	s.callbackAccess.Lock()
 	callbacks := s.callbacks.Array()
 	s.callbackAccess.Unlock()
@@ -202,12 +255,7 @@ func (s *RemoteRuleSet) loadBytes(content []byte) error {
 
 func (s *RemoteRuleSet) loopUpdate() {
 	if time.Since(s.lastUpdated) > s.updateInterval {
-		err := s.fetchOnce(s.ctx, nil)
-		if err != nil {
-			s.logger.Error("fetch rule-set ", s.options.Tag, ": ", err)
-		} else if s.refs.Load() == 0 {
-			s.rules = nil
-		}
+		s.updateOnce()
 	}
 	for {
 		runtime.GC()
@@ -216,17 +264,29 @@ func (s *RemoteRuleSet) loopUpdate() {
 			return
 		case <-s.updateTicker.C:
 			s.pauseManager.WaitActive()
-			err := s.fetchOnce(s.ctx, nil)
-			if err != nil {
-				s.logger.Error("fetch rule-set ", s.options.Tag, ": ", err)
-			} else if s.refs.Load() == 0 {
-				s.rules = nil
-			}
+			s.updateOnce()
 		}
 	}
 }
 
+func (s *RemoteRuleSet) updateOnce() {
+	err := s.fetchOnce(s.ctx, nil)
+	if errors.Is(err, errRuleSetUpdating) {
+		// Already being updated through the API.
+		return
+	}
+	if err != nil {
+		s.logger.Error("fetch rule-set ", s.options.Tag, ": ", err)
+		return
+	}
+	s.Cleanup()
+}
+
 func (s *RemoteRuleSet) fetchOnce(ctx context.Context, startContext adapter.RuleSetStartContext) error {
+	if s.updating.Swap(true) {
+		return E.Cause(errRuleSetUpdating, s.options.Tag)
+	}
+	defer s.updating.Store(false)
 	s.logger.Debug("updating rule-set ", s.options.Tag, " from URL: ", s.options.RemoteOptions.URL)
 	var httpClient *http.Client
 	if startContext != nil {
@@ -0,0 +1,208 @@
+package route
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	N "github.com/sagernet/sing/common/network"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRuleSetBehavior(t *testing.T) {
+	t.Parallel()
+	defaultRule := func(options option.DefaultHeadlessRule) option.HeadlessRule {
+		return option.HeadlessRule{Type: C.RuleTypeDefault, DefaultOptions: options}
+	}
+	for _, testCase := range []struct {
+		name     string
+		rules    []option.HeadlessRule
+		behavior string
+	}{
+		{"empty", nil, C.RuleSetBehaviorClassical},
+		{"domain", []option.HeadlessRule{
+			defaultRule(option.DefaultHeadlessRule{Domain: []string{"example.com"}}),
+			defaultRule(option.DefaultHeadlessRule{DomainSuffix: []string{"example.org"}}),
+		}, C.RuleSetBehaviorDomain},
+		{"ipcidr", []option.HeadlessRule{
+			defaultRule(option.DefaultHeadlessRule{IPCIDR: []string{"10.0.0.0/8"}}),
+		}, C.RuleSetBehaviorIPCIDR},
+		{"mixed rules", []option.HeadlessRule{
+			defaultRule(option.DefaultHeadlessRule{Domain: []string{"example.com"}}),
+			defaultRule(option.DefaultHeadlessRule{IPCIDR: []string{"10.0.0.0/8"}}),
+		}, C.RuleSetBehaviorClassical},
+		{"domain with port", []option.HeadlessRule{
+			defaultRule(option.DefaultHeadlessRule{Domain: []string{"example.com"}, Port: []uint16{443}}),
+		}, C.RuleSetBehaviorClassical},
+		{"domain keyword", []option.HeadlessRule{
+			defaultRule(option.DefaultHeadlessRule{DomainKeyword: []string{"example"}}),
+		}, C.RuleSetBehaviorClassical},
+		{"inverted", []option.HeadlessRule{
+			defaultRule(option.DefaultHeadlessRule{Domain: []string{"example.com"}, Invert: true}),
+		}, C.RuleSetBehaviorClassical},
+		{"logical", []option.HeadlessRule{{
+			Type: C.RuleTypeLogical,
+			LogicalOptions: option.LogicalHeadlessRule{
+				Mode:  C.LogicalTypeOr,
+				Rules: []option.HeadlessRule{defaultRule(option.DefaultHeadlessRule{Domain: []string{"example.com"}})},
+			},
+		}}, C.RuleSetBehaviorClassical},
+	} {
+		require.Equal(t, testCase.behavior, ruleSetBehavior(testCase.rules), testCase.name)
+	}
+}
+
+func TestLocalRuleSetUpdate(t *testing.T) {
+	t.Parallel()
+	router := newTestRouter(t, nil, nil)
+	path := filepath.Join(t.TempDir(), "rule-set.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"version":2,"rules":[{"domain_suffix":["example.com"]}]}`), 0o644))
+	ruleSet, err := NewLocalRuleSet(router, router.logger, option.RuleSet{
+		Type:         C.RuleSetTypeLocal,
+		Tag:          "local",
+		Format:       C.RuleSetFormatSource,
+		LocalOptions: option.LocalRuleSet{Path: path},
+	})
+	require.NoError(t, err)
+	defer ruleSet.Close()
+	require.Equal(t, C.RuleSetTypeLocal, ruleSet.Type())
+	require.Equal(t, C.RuleSetFormatSource, ruleSet.Format())
+	require.Equal(t, C.RuleSetBehaviorDomain, ruleSet.Behavior())
+	require.Equal(t, 1, ruleSet.RuleCount())
+	updatedAt := ruleSet.UpdatedAt()
+	require.False(t, updatedAt.IsZero())
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"version":2,"rules":[{"ip_cidr":["10.0.0.0/8"]},{"ip_cidr":["fd00::/8"]}]}`), 0o644))
+	require.NoError(t, ruleSet.Update(context.Background()))
+	require.Equal(t, C.RuleSetBehaviorIPCIDR, ruleSet.Behavior())
+	require.Equal(t, 2, ruleSet.RuleCount())
+	require.False(t, ruleSet.UpdatedAt().Before(updatedAt))
+
+	require.NoError(t, os.Remove(path))
+	require.Error(t, ruleSet.Update(context.Background()))
+	require.Equal(t, 2, ruleSet.RuleCount())
+}
+
+func TestInlineRuleSetUpdate(t *testing.T) {
+	t.Parallel()
+	router := newTestRouter(t, nil, nil)
+	ruleSet, err := NewLocalRuleSet(router, router.logger, option.RuleSet{
+		Type: C.RuleSetTypeInline,
+		Tag:  "inline",
+		InlineOptions: option.PlainRuleSet{
+			Rules: []option.HeadlessRule{{
+				Type:           C.RuleTypeDefault,
+				DefaultOptions: option.DefaultHeadlessRule{Domain: []string{"example.com"}, Port: []uint16{443}},
+			}},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, C.RuleSetTypeInline, ruleSet.Type())
+	require.Equal(t, C.RuleSetBehaviorClassical, ruleSet.Behavior())
+	require.Equal(t, 1, ruleSet.RuleCount())
+	require.Error(t, ruleSet.Update(context.Background()))
+	ruleSet.Cleanup()
+	require.Zero(t, ruleSet.RuleCount())
+}
+
+// syncBuffer is a log writer safe for concurrent use.
+type syncBuffer struct {
+	access sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.access.Lock()
+	defer b.access.Unlock()
+	return b.buffer.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.access.Lock()
+	defer b.access.Unlock()
+	return b.buffer.String()
+}
+
+func (b *syncBuffer) Reset() {
+	b.access.Lock()
+	defer b.access.Unlock()
+	b.buffer.Reset()
+}
+
+func TestRemoteRuleSetUpdate(t *testing.T) {
+	t.Parallel()
+	var (
+		contentAccess sync.Mutex
+		content       = `{"version":2,"rules":[{"domain":["example.com"]},{"domain_suffix":["example.org"]}]}`
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentAccess.Lock()
+		defer contentAccess.Unlock()
+		if content == "" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(content))
+	}))
+	defer server.Close()
+	setContent := func(newContent string) {
+		contentAccess.Lock()
+		content = newContent
+		contentAccess.Unlock()
+	}
+
+	var output syncBuffer
+	logFactory := log.NewDefaultFactory(context.Background(), log.Formatter{BaseTime: time.Now(), DisableColors: true}, &output, "", nil, false)
+	router := newTestRouter(t, nil, nil)
+	ruleSet := NewRemoteRuleSet(context.Background(), router, logFactory.NewLogger("rule-set"), option.RuleSet{
+		Type:          C.RuleSetTypeRemote,
+		Tag:           "remote",
+		Format:        C.RuleSetFormatSource,
+		RemoteOptions: option.RemoteRuleSet{URL: server.URL},
+	})
+	defer ruleSet.cancel()
+	require.Equal(t, C.RuleSetTypeRemote, ruleSet.Type())
+	require.Equal(t, C.RuleSetFormatSource, ruleSet.Format())
+	require.Error(t, ruleSet.Update(context.Background()))
+	ruleSet.dialer = N.SystemDialer
+
+	ruleSet.IncRef()
+	require.NoError(t, ruleSet.Update(context.Background()))
+	require.Equal(t, C.RuleSetBehaviorDomain, ruleSet.Behavior())
+	require.Equal(t, 2, ruleSet.RuleCount())
+	updatedAt := ruleSet.UpdatedAt()
+	require.False(t, updatedAt.IsZero())
+
+	setContent(`{"version":2,"rules":[{"domain":["example.com"]},{"ip_cidr":["10.0.0.0/8"]},{"port":[443]}]}`)
+	require.NoError(t, ruleSet.Update(context.Background()))
+	require.Equal(t, C.RuleSetBehaviorClassical, ruleSet.Behavior())
+	require.Equal(t, 3, ruleSet.RuleCount())
+	require.False(t, ruleSet.UpdatedAt().Before(updatedAt))
+
+	// Rules of unreferenced rule-sets are released after updating.
+	ruleSet.DecRef()
+	require.NoError(t, ruleSet.Update(context.Background()))
+	require.Nil(t, ruleSet.rules)
+	require.Zero(t, ruleSet.RuleCount())
+
+	setContent("")
+	ruleSet.updateOnce()
+	require.Contains(t, output.String(), "fetch rule-set remote")
+
+	// An update running through the API is skipped by the update loop without an error.
+	output.Reset()
+	ruleSet.updating.Store(true)
+	require.ErrorIs(t, ruleSet.Update(context.Background()), errRuleSetUpdating)
+	ruleSet.updateOnce()
+	require.Empty(t, output.String())
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"testing"
+	"time"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	M "github.com/sagernet/sing/common/metadata"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestDNSInbound(t *testing.T) {
+	caPem, certPem, keyPem := createSelfSignedCertificate(t, "example.org")
+	inboundTLSOptions := option.InboundTLSOptionsContainer{
+		TLS: &option.InboundTLSOptions{
+			Enabled:         true,
+			ServerName:      "example.org",
+			CertificatePath: certPem,
+			KeyPath:         keyPem,
+		},
+	}
+	startInstance(t, option.Options{
+		Inbounds: []option.Inbound{
+			{
+				Type: C.TypeDNS,
+				Tag:  "dns-in",
+				DNSOptions: option.DNSInboundOptions{
+					ListenOptions: option.ListenOptions{
+						Listen:     option.NewListenAddress(netip.IPv4Unspecified()),
+						ListenPort: serverPort,
+					},
+				},
+			},
+			{
+				Type: C.TypeDNS,
+				Tag:  "dot-in",
+				DNSOptions: option.DNSInboundOptions{
+					ListenOptions: option.ListenOptions{
+						Listen:     option.NewListenAddress(netip.IPv4Unspecified()),
+						ListenPort: otherPort,
+					},
+					Protocol:                   C.DNSInboundProtocolTLS,
+					InboundTLSOptionsContainer: inboundTLSOptions,
+				},
+			},
+			{
+				Type: C.TypeDNS,
+				Tag:  "doh-in",
+				DNSOptions: option.DNSInboundOptions{
+					ListenOptions: option.ListenOptions{
+						Listen:     option.NewListenAddress(netip.IPv4Unspecified()),
+						ListenPort: clientPort,
+					},
+					Protocol:                   C.DNSInboundProtocolHTTPS,
+					InboundTLSOptionsContainer: inboundTLSOptions,
+				},
+			},
+		},
+		DNS: &option.DNSOptions{
+			Servers: []option.DNSServerOptions{
+				{
+					Tag:     "hosts",
+					Address: "hosts",
+					Hosts: &option.DNSHostsOptions{
+						Path: []string{os.DevNull},
+						Predefined: map[string]option.Listable[netip.Addr]{
+							"example.com":        {netip.MustParseAddr("10.0.0.1")},
+							"client.example.com": {netip.MustParseAddr("10.0.0.1")},
+						},
+					},
+				},
+			},
+			Rules: []option.DNSRule{
+				{
+					Type: C.RuleTypeDefault,
+					DefaultOptions: option.DefaultDNSRule{
+						SourceIPCIDR: []string{"192.168.0.0/16"},
+						DomainSuffix: []string{"example.com"},
+						Predefined: &option.DNSPredefinedOptions{
+							Answer: []string{"@ IN A 10.0.0.3"},
+						},
+					},
+				},
+				{
+					Type: C.RuleTypeDefault,
+					DefaultOptions: option.DefaultDNSRule{
+						Inbound:      []string{"dns-in", "dot-in", "doh-in"},
+						SourceIPCIDR: []string{"127.0.0.0/8"},
+						Domain:       []string{"client.example.com"},
+						Predefined: &option.DNSPredefinedOptions{
+							Answer: []string{"@ IN A 10.0.0.2"},
+						},
+					},
+				},
+			},
+		},
+	})
+	caPemContent, err := os.ReadFile(caPem)
+	require.NoError(t, err)
+	rootCAs := x509.NewCertPool()
+	require.True(t, rootCAs.AppendCertsFromPEM(caPemContent))
+	tlsConfig := &tls.Config{
+		ServerName: "example.org",
+		RootCAs:    rootCAs,
+	}
+	var dialer net.Dialer
+	for _, testCase := range []struct {
+		name string
+		dial func(ctx context.Context) (net.Conn, error)
+	}{
+		{"udp", func(ctx context.Context) (net.Conn, error) {
+			return dialer.DialContext(ctx, "udp", M.ParseSocksaddrHostPort("127.0.0.1", serverPort).String())
+		}},
+		{"tcp", func(ctx context.Context) (net.Conn, error) {
+			conn, err := dialer.DialContext(ctx, "tcp", M.ParseSocksaddrHostPort("127.0.0.1", serverPort).String())
+			if err != nil {
+				return nil, err
+			}
+			// hide net.PacketConn of the connection, so that the resolver uses TCP framing
+			return struct{ net.Conn }{conn}, nil
+		}},
+		{"tls", func(ctx context.Context) (net.Conn, error) {
+			tlsDialer := tls.Dialer{Config: tlsConfig}
+			return tlsDialer.DialContext(ctx, "tcp", M.ParseSocksaddrHostPort("127.0.0.1", otherPort).String())
+		}},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			resolver := &net.Resolver{
+				PreferGo: true,
+				Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+					return testCase.dial(ctx)
+				},
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			addresses, err := resolver.LookupNetIP(ctx, "ip4", "example.com")
+			require.NoError(t, err)
+			require.Equal(t, []netip.Addr{netip.MustParseAddr("10.0.0.1")}, addresses)
+			addresses, err = resolver.LookupNetIP(ctx, "ip4", "client.example.com")
+			require.NoError(t, err)
+			require.Equal(t, []netip.Addr{netip.MustParseAddr("10.0.0.2")}, addresses)
+		})
+	}
+	t.Run("https", func(t *testing.T) {
+		client := &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: tlsConfig,
+			},
+			Timeout: 5 * time.Second,
+		}
+		defer client.CloseIdleConnections()
+		require.Equal(t, netip.MustParseAddr("10.0.0.1"), exchangeDoH(t, client, "example.com."))
+		require.Equal(t, netip.MustParseAddr("10.0.0.2"), exchangeDoH(t, client, "client.example.com."))
+	})
+}
+
+func exchangeDoH(t *testing.T, client *http.Client, name string) netip.Addr {
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{RecursionDesired: true})
+	require.NoError(t, builder.StartQuestions())
+	require.NoError(t, builder.Question(dnsmessage.Question{
+		Name:  dnsmessage.MustNewName(name),
+		Type:  dnsmessage.TypeA,
+		Class: dnsmessage.ClassINET,
+	}))
+	query, err := builder.Finish()
+	require.NoError(t, err)
+	response, err := client.Post("https://"+M.ParseSocksaddrHostPort("127.0.0.1", clientPort).String()+"/dns-query", "application/dns-message", bytes.NewReader(query))
+	require.NoError(t, err)
+	defer response.Body.Close()
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	content, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	var parser dnsmessage.Parser
+	_, err = parser.Start(content)
+	require.NoError(t, err)
+	require.NoError(t, parser.SkipAllQuestions())
+	answer, err := parser.AnswerHeader()
+	require.NoError(t, err)
+	require.Equal(t, dnsmessage.TypeA, answer.Type)
+	record, err := parser.AResource()
+	require.NoError(t, err)
+	return netip.AddrFrom4(record.A)
+}
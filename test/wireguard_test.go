@@ -49,3 +49,79 @@ func _TestWireGuard(t *testing.T) {
 	})
 	testSuitWg(t, clientPort, testPort)
 }
+
+func TestWireGuardSelf(t *testing.T) {
+	startInstance(t, option.Options{
+		Inbounds: []option.Inbound{
+			{
+				Type: C.TypeMixed,
+				Tag:  "mixed-in",
+				MixedOptions: option.HTTPMixedInboundOptions{
+					ListenOptions: option.ListenOptions{
+						Listen:     option.NewListenAddress(netip.IPv4Unspecified()),
+						ListenPort: clientPort,
+					},
+				},
+			},
+			{
+				Type: C.TypeWireGuard,
+				Tag:  "wg-in",
+				WireGuardOptions: option.WireGuardInboundOptions{
+					ListenOptions: option.ListenOptions{
+						Listen:     option.NewListenAddress(netip.IPv4Unspecified()),
+						ListenPort: serverPort,
+					},
+					PrivateKey: "pPFgzV3Pld2JfulXqzrSBqyTkBoM5KBl+VwhV1V28Pg=",
+					Peers: []option.WireGuardPeer{
+						{
+							PublicKey:  "j6+14mBttMmy1xkyGXOT8bdpICJfolwgGUYqCoZCgSw=",
+							AllowedIPs: []string{"10.0.0.2/32"},
+						},
+					},
+				},
+			},
+		},
+		Outbounds: []option.Outbound{
+			{
+				Type: C.TypeDirect,
+			},
+			{
+				Type: C.TypeDirect,
+				Tag:  "direct-out",
+				DirectOptions: option.DirectOutboundOptions{
+					OverrideAddress: "127.0.0.1",
+				},
+			},
+			{
+				Type: C.TypeWireGuard,
+				Tag:  "wg-out",
+				WireGuardOptions: option.WireGuardOutboundOptions{
+					ServerOptions: option.ServerOptions{
+						Server:     "127.0.0.1",
+						ServerPort: serverPort,
+					},
+					LocalAddress:  []netip.Prefix{netip.MustParsePrefix("10.0.0.2/32")},
+					PrivateKey:    "tIQ+edYeQQ+ezs8U4pVyu1uBeNDqpJSyYbVyBiBJRS4=",
+					PeerPublicKey: "65Utykhr3ysnpKVfcLhvy/E84Ccid5tfiH0bMRnI7lo=",
+				},
+			},
+		},
+		Route: &option.RouteOptions{
+			Rules: []option.Rule{
+				{
+					DefaultOptions: option.DefaultRule{
+						Inbound:  []string{"mixed-in"},
+						Outbound: "wg-out",
+					},
+				},
+				{
+					DefaultOptions: option.DefaultRule{
+						Inbound:  []string{"wg-in"},
+						Outbound: "direct-out",
+					},
+				},
+			},
+		},
+	})
+	testSuitWg(t, clientPort, testPort)
+}
@@ -81,6 +81,16 @@ func (s *MemoryStorage) FakeIPLoadDomain(domain string, isIPv6 bool) (netip.Addr
 	}
 }
 
+func (s *MemoryStorage) FakeIPAddresses() []netip.Addr {
+	s.addressAccess.RLock()
+	defer s.addressAccess.RUnlock()
+	addresses := make([]netip.Addr, 0, len(s.addressCache))
+	for address := range s.addressCache {
+		addresses = append(addresses, address)
+	}
+	return addresses
+}
+
 func (s *MemoryStorage) FakeIPReset() error {
 	s.addressCache = make(map[netip.Addr]string)
 	s.domainCache4 = make(map[string]netip.Addr)
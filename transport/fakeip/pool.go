@@ -0,0 +1,158 @@
+package fakeip
+
+import (
+	"container/list"
+	"math"
+	"net/netip"
+	"sort"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+// pool allocates addresses of a range, unused addresses are handed out in order
+// and then the least recently used address without active connections is reused.
+type pool struct {
+	prefix  netip.Prefix
+	current netip.Addr
+	entries map[netip.Addr]*list.Element
+	lru     *list.List
+	active  int
+}
+
+type poolEntry struct {
+	address  netip.Addr
+	lastUsed time.Time
+	active   int
+}
+
+func newPool(prefix netip.Prefix) *pool {
+	return &pool{
+		prefix:  prefix,
+		current: prefix.Addr().Next(),
+		entries: make(map[netip.Addr]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// restore adds addresses allocated before a restart as the least recently used,
+// addresses after current are from an earlier round and go first.
+func (p *pool) restore(addresses []netip.Addr) {
+	sort.Slice(addresses, func(i, j int) bool {
+		return addresses[i].Less(addresses[j])
+	})
+	var before []netip.Addr
+	for _, address := range addresses {
+		if !p.prefix.Contains(address) {
+			continue
+		}
+		if address.Compare(p.current) <= 0 {
+			before = append(before, address)
+		} else {
+			p.add(address, time.Time{})
+		}
+	}
+	for _, address := range before {
+		p.add(address, time.Time{})
+	}
+}
+
+func (p *pool) add(address netip.Addr, lastUsed time.Time) *poolEntry {
+	if element, loaded := p.entries[address]; loaded {
+		return element.Value.(*poolEntry)
+	}
+	entry := &poolEntry{address: address, lastUsed: lastUsed}
+	p.entries[address] = p.lru.PushBack(entry)
+	return entry
+}
+
+func (p *pool) touch(address netip.Addr) *poolEntry {
+	now := time.Now()
+	element, loaded := p.entries[address]
+	if !loaded {
+		return p.add(address, now)
+	}
+	entry := element.Value.(*poolEntry)
+	entry.lastUsed = now
+	p.lru.MoveToBack(element)
+	return entry
+}
+
+func (p *pool) allocate() (netip.Addr, error) {
+	for {
+		nextAddress := p.current.Next()
+		if !p.prefix.Contains(nextAddress) {
+			break
+		}
+		p.current = nextAddress
+		if _, loaded := p.entries[nextAddress]; !loaded {
+			p.touch(nextAddress)
+			return nextAddress, nil
+		}
+	}
+	for element := p.lru.Front(); element != nil; element = element.Next() {
+		entry := element.Value.(*poolEntry)
+		if entry.active == 0 {
+			p.touch(entry.address)
+			return entry.address, nil
+		}
+	}
+	return netip.Addr{}, E.New("fakeip address range ", p.prefix, " exhausted by active connections")
+}
+
+func (p *pool) acquire(address netip.Addr) *poolEntry {
+	if _, loaded := p.entries[address]; !loaded {
+		return nil
+	}
+	entry := p.touch(address)
+	if entry.active == 0 {
+		p.active++
+	}
+	entry.active++
+	return entry
+}
+
+func (p *pool) release(entry *poolEntry) {
+	element, loaded := p.entries[entry.address]
+	if !loaded || element.Value != entry {
+		return
+	}
+	entry.active--
+	if entry.active == 0 {
+		p.active--
+	}
+	p.touch(entry.address)
+}
+
+// reset forgets all addresses except ones with active connections.
+func (p *pool) reset() {
+	p.current = p.prefix.Addr().Next()
+	for element := p.lru.Front(); element != nil; {
+		next := element.Next()
+		entry := element.Value.(*poolEntry)
+		if entry.active == 0 {
+			p.lru.Remove(element)
+			delete(p.entries, entry.address)
+		}
+		element = next
+	}
+}
+
+func (p *pool) usage() adapter.FakeIPUsage {
+	usage := adapter.FakeIPUsage{
+		Range:  p.prefix,
+		Used:   len(p.entries),
+		Active: p.active,
+	}
+	hostBits := p.prefix.Addr().BitLen() - p.prefix.Bits()
+	if hostBits >= 64 {
+		usage.Size = math.MaxUint64
+	} else if hostBits > 1 {
+		usage.Size = 1<<hostBits - 2
+	}
+	if element := p.lru.Front(); element != nil {
+		usage.LeastRecentlyUsed = element.Value.(*poolEntry).lastUsed
+	}
+	return usage
+}
@@ -24,6 +24,18 @@ func init() {
 	})
 }
 
+type storeKey struct{}
+
+// ContextWithStore sets the store of a fakeip server with its own ranges.
+func ContextWithStore(ctx context.Context, store adapter.FakeIPStore) context.Context {
+	return context.WithValue(ctx, (*storeKey)(nil), store)
+}
+
+func storeFromContext(ctx context.Context) adapter.FakeIPStore {
+	store, _ := ctx.Value((*storeKey)(nil)).(adapter.FakeIPStore)
+	return store
+}
+
 type Transport struct {
 	name   string
 	router adapter.Router
@@ -39,6 +51,7 @@ func NewTransport(options dns.TransportOptions) (*Transport, error) {
 	return &Transport{
 		name:   options.Name,
 		router: router,
+		store:  storeFromContext(options.Context),
 		logger: options.Logger,
 	}, nil
 }
@@ -48,6 +61,9 @@ func (s *Transport) Name() string {
 }
 
 func (s *Transport) Start() error {
+	if s.store != nil {
+		return nil
+	}
 	s.store = s.router.FakeIPStore()
 	if s.store == nil {
 		return E.New("fakeip not enabled")
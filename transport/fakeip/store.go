@@ -3,6 +3,7 @@ package fakeip
 import (
 	"context"
 	"net/netip"
+	"sync"
 
 	"github.com/sagernet/sing-box/adapter"
 	E "github.com/sagernet/sing/common/exceptions"
@@ -13,19 +14,24 @@ import (
 var _ adapter.FakeIPStore = (*Store)(nil)
 
 type Store struct {
-	ctx          context.Context
-	logger       logger.Logger
-	inet4Range   netip.Prefix
-	inet6Range   netip.Prefix
-	storage      adapter.FakeIPStorage
-	inet4Current netip.Addr
-	inet6Current netip.Addr
+	ctx        context.Context
+	logger     logger.Logger
+	name       string
+	inet4Range netip.Prefix
+	inet6Range netip.Prefix
+	storage    adapter.FakeIPStorage
+	access     sync.Mutex
+	inet4Pool  *pool
+	inet6Pool  *pool
 }
 
-func NewStore(ctx context.Context, logger logger.Logger, inet4Range netip.Prefix, inet6Range netip.Prefix) *Store {
+// NewStore creates a store of the ranges, stores of fakeip servers with their own
+// ranges are named by the server tag and the default store has an empty name.
+func NewStore(ctx context.Context, logger logger.Logger, name string, inet4Range netip.Prefix, inet6Range netip.Prefix) *Store {
 	return &Store{
 		ctx:        ctx,
 		logger:     logger,
+		name:       name,
 		inet4Range: inet4Range,
 		inet6Range: inet6Range,
 	}
@@ -35,22 +41,29 @@ func (s *Store) Start() error {
 	var storage adapter.FakeIPStorage
 	cacheFile := service.FromContext[adapter.CacheFile](s.ctx)
 	if cacheFile != nil && cacheFile.StoreFakeIP() {
-		storage = cacheFile
+		storage = cacheFile.NamedFakeIPStorage(s.name)
 	}
 	if storage == nil {
 		storage = NewMemoryStorage()
 	}
+	if s.inet4Range.IsValid() {
+		s.inet4Pool = newPool(s.inet4Range)
+	}
+	if s.inet6Range.IsValid() {
+		s.inet6Pool = newPool(s.inet6Range)
+	}
 	metadata := storage.FakeIPMetadata()
 	if metadata != nil && metadata.Inet4Range == s.inet4Range && metadata.Inet6Range == s.inet6Range {
-		s.inet4Current = metadata.Inet4Current
-		s.inet6Current = metadata.Inet6Current
-	} else {
-		if s.inet4Range.IsValid() {
-			s.inet4Current = s.inet4Range.Addr().Next().Next()
+		addresses := storage.FakeIPAddresses()
+		if s.inet4Pool != nil {
+			s.inet4Pool.current = metadata.Inet4Current
+			s.inet4Pool.restore(addresses)
 		}
-		if s.inet6Range.IsValid() {
-			s.inet6Current = s.inet6Range.Addr().Next().Next()
+		if s.inet6Pool != nil {
+			s.inet6Pool.current = metadata.Inet6Current
+			s.inet6Pool.restore(addresses)
 		}
+	} else {
 		_ = storage.FakeIPReset()
 	}
 	s.storage = storage
@@ -65,47 +78,60 @@ func (s *Store) Close() error {
 	if s.storage == nil {
 		return nil
 	}
-	return s.storage.FakeIPSaveMetadata(&adapter.FakeIPMetadata{
-		Inet4Range:   s.inet4Range,
-		Inet6Range:   s.inet6Range,
-		Inet4Current: s.inet4Current,
-		Inet6Current: s.inet6Current,
-	})
+	s.access.Lock()
+	defer s.access.Unlock()
+	return s.storage.FakeIPSaveMetadata(s.metadata())
 }
 
-func (s *Store) Create(domain string, isIPv6 bool) (netip.Addr, error) {
-	if address, loaded := s.storage.FakeIPLoadDomain(domain, isIPv6); loaded {
-		return address, nil
+func (s *Store) metadata() *adapter.FakeIPMetadata {
+	metadata := &adapter.FakeIPMetadata{
+		Inet4Range: s.inet4Range,
+		Inet6Range: s.inet6Range,
+	}
+	if s.inet4Pool != nil {
+		metadata.Inet4Current = s.inet4Pool.current
+	}
+	if s.inet6Pool != nil {
+		metadata.Inet6Current = s.inet6Pool.current
 	}
-	var address netip.Addr
+	return metadata
+}
+
+func (s *Store) pool(address netip.Addr) *pool {
+	if s.inet4Pool != nil && s.inet4Range.Contains(address) {
+		return s.inet4Pool
+	}
+	if s.inet6Pool != nil && s.inet6Range.Contains(address) {
+		return s.inet6Pool
+	}
+	return nil
+}
+
+func (s *Store) Create(domain string, isIPv6 bool) (netip.Addr, error) {
+	s.access.Lock()
+	defer s.access.Unlock()
+	var addressPool *pool
 	if !isIPv6 {
-		if !s.inet4Current.IsValid() {
+		if s.inet4Pool == nil {
 			return netip.Addr{}, E.New("missing IPv4 fakeip address range")
 		}
-		nextAddress := s.inet4Current.Next()
-		if !s.inet4Range.Contains(nextAddress) {
-			nextAddress = s.inet4Range.Addr().Next().Next()
-		}
-		s.inet4Current = nextAddress
-		address = nextAddress
+		addressPool = s.inet4Pool
 	} else {
-		if !s.inet6Current.IsValid() {
+		if s.inet6Pool == nil {
 			return netip.Addr{}, E.New("missing IPv6 fakeip address range")
 		}
-		nextAddress := s.inet6Current.Next()
-		if !s.inet6Range.Contains(nextAddress) {
-			nextAddress = s.inet6Range.Addr().Next().Next()
-		}
-		s.inet6Current = nextAddress
-		address = nextAddress
+		addressPool = s.inet6Pool
+	}
+	if address, loaded := s.storage.FakeIPLoadDomain(domain, isIPv6); loaded {
+		addressPool.touch(address)
+		return address, nil
+	}
+	address, err := addressPool.allocate()
+	if err != nil {
+		return netip.Addr{}, err
 	}
 	s.storage.FakeIPStoreAsync(address, domain, s.logger)
-	s.storage.FakeIPSaveMetadataAsync(&adapter.FakeIPMetadata{
-		Inet4Range:   s.inet4Range,
-		Inet6Range:   s.inet6Range,
-		Inet4Current: s.inet4Current,
-		Inet6Current: s.inet6Current,
-	})
+	s.storage.FakeIPSaveMetadataAsync(s.metadata())
 	return address, nil
 }
 
@@ -113,6 +139,50 @@ func (s *Store) Lookup(address netip.Addr) (string, bool) {
 	return s.storage.FakeIPLoad(address)
 }
 
+// Acquire marks the address as used by a connection until release is called,
+// addresses with active connections are never reused for other domains.
+func (s *Store) Acquire(address netip.Addr) (release func()) {
+	s.access.Lock()
+	defer s.access.Unlock()
+	addressPool := s.pool(address)
+	if addressPool == nil {
+		return func() {}
+	}
+	entry := addressPool.acquire(address)
+	if entry == nil {
+		return func() {}
+	}
+	return func() {
+		s.access.Lock()
+		defer s.access.Unlock()
+		addressPool.release(entry)
+	}
+}
+
+func (s *Store) Usage() []adapter.FakeIPUsage {
+	s.access.Lock()
+	defer s.access.Unlock()
+	var usage []adapter.FakeIPUsage
+	if s.inet4Pool != nil {
+		usage = append(usage, s.inet4Pool.usage())
+	}
+	if s.inet6Pool != nil {
+		usage = append(usage, s.inet6Pool.usage())
+	}
+	for i := range usage {
+		usage[i].Name = s.name
+	}
+	return usage
+}
+
 func (s *Store) Reset() error {
+	s.access.Lock()
+	defer s.access.Unlock()
+	if s.inet4Pool != nil {
+		s.inet4Pool.reset()
+	}
+	if s.inet6Pool != nil {
+		s.inet6Pool.reset()
+	}
 	return s.storage.FakeIPReset()
 }
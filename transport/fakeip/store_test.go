@@ -0,0 +1,149 @@
+package fakeip
+
+import (
+	"context"
+	"net/netip"
+	"sync"
+	"testing"
+
+	"github.com/sagernet/sing-box/log"
+	F "github.com/sagernet/sing/common/format"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStore(t *testing.T, inet4Range string) *Store {
+	store := NewStore(context.Background(), log.NewNOPFactory().Logger(), "", netip.MustParsePrefix(inet4Range), netip.Prefix{})
+	require.NoError(t, store.Start())
+	t.Cleanup(func() {
+		store.Close()
+	})
+	return store
+}
+
+func createAddress(t *testing.T, store *Store, domain string) netip.Addr {
+	address, err := store.Create(domain, false)
+	require.NoError(t, err)
+	return address
+}
+
+func requireLookup(t *testing.T, store *Store, address netip.Addr, domain string) {
+	loadedDomain, loaded := store.Lookup(address)
+	require.True(t, loaded, address)
+	require.Equal(t, domain, loadedDomain, address)
+}
+
+func TestStoreWraparound(t *testing.T) {
+	t.Parallel()
+	// 198.18.0.2 to 198.18.0.7 are allocated
+	store := newTestStore(t, "198.18.0.0/29")
+	for i := 0; i < 6; i++ {
+		require.Equal(t, netip.AddrFrom4([4]byte{198, 18, 0, byte(i + 2)}), createAddress(t, store, F.ToString("domain-", i)))
+	}
+	require.Equal(t, netip.MustParseAddr("198.18.0.5"), createAddress(t, store, "domain-3"))
+
+	// the range is exhausted, so the first address is reused after wrapping around
+	address := createAddress(t, store, "domain-6")
+	require.Equal(t, netip.MustParseAddr("198.18.0.2"), address)
+	requireLookup(t, store, address, "domain-6")
+	require.Equal(t, netip.MustParseAddr("198.18.0.3"), createAddress(t, store, "domain-0"))
+	requireLookup(t, store, netip.MustParseAddr("198.18.0.3"), "domain-0")
+
+	usage := store.Usage()
+	require.Len(t, usage, 1)
+	require.Equal(t, uint64(6), usage[0].Size)
+	require.Equal(t, 6, usage[0].Used)
+}
+
+func TestStoreLeastRecentlyUsed(t *testing.T) {
+	t.Parallel()
+	store := newTestStore(t, "198.18.0.0/29")
+	addresses := make([]netip.Addr, 6)
+	for i := range addresses {
+		addresses[i] = createAddress(t, store, F.ToString("domain-", i))
+	}
+	// queried and connected addresses are recently used
+	createAddress(t, store, "domain-0")
+	store.Acquire(addresses[1])()
+	createAddress(t, store, "domain-3")
+
+	for _, i := range []int{2, 4, 5, 0, 1, 3} {
+		domain := F.ToString("new-", i)
+		require.Equal(t, addresses[i], createAddress(t, store, domain))
+		requireLookup(t, store, addresses[i], domain)
+	}
+}
+
+func TestStoreKeepsActiveAddresses(t *testing.T) {
+	t.Parallel()
+	store := newTestStore(t, "198.18.0.0/29")
+	addresses := make([]netip.Addr, 6)
+	releases := make([]func(), 6)
+	for i := range addresses {
+		addresses[i] = createAddress(t, store, F.ToString("domain-", i))
+		if i != 3 {
+			releases[i] = store.Acquire(addresses[i])
+		}
+	}
+	require.Equal(t, 5, store.Usage()[0].Active)
+
+	// only the address without connections is reused
+	require.Equal(t, addresses[3], createAddress(t, store, "new-0"))
+	release := store.Acquire(addresses[3])
+	_, err := store.Create("new-1", false)
+	require.Error(t, err)
+	for i, address := range addresses {
+		if i != 3 {
+			requireLookup(t, store, address, F.ToString("domain-", i))
+		}
+	}
+
+	// a second connection keeps the address in use after the first one is closed
+	secondRelease := store.Acquire(addresses[0])
+	releases[0]()
+	_, err = store.Create("new-1", false)
+	require.Error(t, err)
+	secondRelease()
+	require.Equal(t, addresses[0], createAddress(t, store, "new-1"))
+	release()
+
+	// addresses with connections are kept on reset
+	require.NoError(t, store.Reset())
+	require.Equal(t, 4, store.Usage()[0].Used)
+	require.Equal(t, addresses[0], createAddress(t, store, "new-2"))
+}
+
+func TestStoreConcurrentCreateAcquire(t *testing.T) {
+	t.Parallel()
+	store := newTestStore(t, "198.18.0.0/27")
+	var group sync.WaitGroup
+	for worker := 0; worker < 8; worker++ {
+		worker := worker
+		group.Add(1)
+		go func() {
+			defer group.Done()
+			for i := 0; i < 500; i++ {
+				domain := F.ToString("domain-", worker, "-", i%40)
+				address, err := store.Create(domain, false)
+				if err != nil {
+					continue
+				}
+				release := store.Acquire(address)
+				loadedDomain, loaded := store.Lookup(address)
+				for j := 0; j < 3; j++ {
+					_, _ = store.Create(F.ToString("other-", worker, "-", i, "-", j), false)
+				}
+				reloadedDomain, reloaded := store.Lookup(address)
+				release()
+				if loaded != reloaded || loadedDomain != reloadedDomain {
+					t.Errorf("address %s of %s reused while in use: %s", address, loadedDomain, reloadedDomain)
+					return
+				}
+			}
+		}()
+	}
+	group.Wait()
+	usage := store.Usage()
+	require.Zero(t, usage[0].Active)
+	require.LessOrEqual(t, uint64(usage[0].Used), usage[0].Size)
+}
@@ -0,0 +1,73 @@
+package hosts
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/sagernet/sing-box/option"
+
+	mDNS "github.com/miekg/dns"
+)
+
+type optionsKey struct{}
+
+// ContextWithOptions attaches hosts server options to the context passed to dns.CreateTransport.
+func ContextWithOptions(ctx context.Context, options option.DNSHostsOptions) context.Context {
+	return context.WithValue(ctx, (*optionsKey)(nil), options)
+}
+
+func optionsFromContext(ctx context.Context) option.DNSHostsOptions {
+	options, _ := ctx.Value((*optionsKey)(nil)).(option.DNSHostsOptions)
+	return options
+}
+
+func DefaultPath() string {
+	if runtime.GOOS == "windows" {
+		systemRoot := os.Getenv("SystemRoot")
+		if systemRoot == "" {
+			systemRoot = `C:\Windows`
+		}
+		return filepath.Join(systemRoot, "System32", "drivers", "etc", "hosts")
+	}
+	return "/etc/hosts"
+}
+
+// Parse reads entries in hosts file format, keyed by lowercase FQDN.
+func Parse(reader io.Reader, entries map[string][]netip.Addr) error {
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if commentIndex := strings.IndexByte(line, '#'); commentIndex != -1 {
+			line = line[:commentIndex]
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		address, err := netip.ParseAddr(fields[0])
+		if err != nil {
+			continue
+		}
+		address = address.Unmap().WithZone("")
+		for _, name := range fields[1:] {
+			name = mDNS.CanonicalName(name)
+			entries[name] = appendAddress(entries[name], address)
+		}
+	}
+	return scanner.Err()
+}
+
+func appendAddress(addresses []netip.Addr, address netip.Addr) []netip.Addr {
+	for _, it := range addresses {
+		if it == address {
+			return addresses
+		}
+	}
+	return append(addresses, address)
+}
@@ -0,0 +1,40 @@
+package hosts
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+	entries := make(map[string][]netip.Addr)
+	require.NoError(t, Parse(strings.NewReader(`# comment line
+127.0.0.1	localhost Local.Example.COM # trailing comment
+::1 localhost
+::ffff:10.0.0.1 mapped.example.com
+fe80::1%eth0 zoned.example.com
+10.0.0.2 mapped.example.com
+10.0.0.1 mapped.example.com.
+not-an-address invalid.example.com
+10.0.0.3
+  # 10.0.0.4 commented.example.com
+`), entries))
+	require.Equal(t, map[string][]netip.Addr{
+		"localhost.":          {netip.MustParseAddr("127.0.0.1"), netip.MustParseAddr("::1")},
+		"local.example.com.":  {netip.MustParseAddr("127.0.0.1")},
+		"mapped.example.com.": {netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("10.0.0.2")},
+		"zoned.example.com.":  {netip.MustParseAddr("fe80::1")},
+	}, entries)
+}
+
+func TestParseMerge(t *testing.T) {
+	t.Parallel()
+	entries := map[string][]netip.Addr{
+		"example.com.": {netip.MustParseAddr("10.0.0.1")},
+	}
+	require.NoError(t, Parse(strings.NewReader("10.0.0.1 example.com\n10.0.0.2 EXAMPLE.com\n"), entries))
+	require.Equal(t, []netip.Addr{netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("10.0.0.2")}, entries["example.com."])
+}
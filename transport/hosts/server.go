@@ -0,0 +1,193 @@
+package hosts
+
+import (
+	"context"
+	"net/netip"
+	"os"
+	"sync"
+
+	"github.com/sagernet/fswatch"
+	"github.com/sagernet/sing-dns"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	"github.com/sagernet/sing/service/filemanager"
+
+	mDNS "github.com/miekg/dns"
+)
+
+var _ dns.Transport = (*Transport)(nil)
+
+func init() {
+	dns.RegisterTransport([]string{"hosts"}, func(options dns.TransportOptions) (dns.Transport, error) {
+		return NewTransport(options)
+	})
+}
+
+// Transport answers A and AAAA queries from inline entries and hosts files,
+// reloading the files when they change. Answers have a TTL of zero, and the
+// router disables the DNS cache for queries to the transport, since zero-TTL
+// answers would never expire with disable_expire.
+type Transport struct {
+	name        string
+	logger      logger.ContextLogger
+	paths       []string
+	defaultPath bool
+	predefined  map[string][]netip.Addr
+	watcher     *fswatch.Watcher
+	access      sync.RWMutex
+	entries     map[string][]netip.Addr
+}
+
+func NewTransport(options dns.TransportOptions) (*Transport, error) {
+	hostsOptions := optionsFromContext(options.Context)
+	transport := &Transport{
+		name:       options.Name,
+		logger:     options.Logger,
+		predefined: make(map[string][]netip.Addr),
+	}
+	for name, addresses := range hostsOptions.Predefined {
+		name = mDNS.CanonicalName(name)
+		for _, address := range addresses {
+			transport.predefined[name] = appendAddress(transport.predefined[name], address.Unmap())
+		}
+	}
+	if len(hostsOptions.Path) > 0 {
+		for _, path := range hostsOptions.Path {
+			transport.paths = append(transport.paths, filemanager.BasePath(options.Context, os.ExpandEnv(path)))
+		}
+	} else {
+		transport.paths = []string{DefaultPath()}
+		transport.defaultPath = true
+	}
+	err := transport.reload()
+	if err != nil {
+		return nil, err
+	}
+	watchPath := common.Filter(transport.paths, func(it string) bool {
+		_, statErr := os.Stat(it)
+		return statErr == nil
+	})
+	if len(watchPath) > 0 {
+		transport.watcher, err = fswatch.NewWatcher(fswatch.Options{
+			Path: watchPath,
+			Callback: func(path string) {
+				uErr := transport.reload()
+				if uErr != nil {
+					transport.logger.Error(E.Cause(uErr, "reload hosts"))
+				} else {
+					transport.logger.Info("reloaded hosts from ", path)
+				}
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return transport, nil
+}
+
+func (t *Transport) reload() error {
+	entries := make(map[string][]netip.Addr)
+	for _, path := range t.paths {
+		file, err := os.Open(path)
+		if err != nil {
+			if t.defaultPath && os.IsNotExist(err) {
+				continue
+			}
+			return E.Cause(err, "open hosts file")
+		}
+		err = Parse(file, entries)
+		file.Close()
+		if err != nil {
+			return E.Cause(err, "read hosts file ", path)
+		}
+	}
+	for name, addresses := range t.predefined {
+		entries[name] = addresses
+	}
+	t.access.Lock()
+	t.entries = entries
+	t.access.Unlock()
+	return nil
+}
+
+func (t *Transport) lookup(name string) ([]netip.Addr, bool) {
+	t.access.RLock()
+	defer t.access.RUnlock()
+	addresses, loaded := t.entries[mDNS.CanonicalName(name)]
+	return addresses, loaded
+}
+
+func (t *Transport) Name() string {
+	return t.name
+}
+
+func (t *Transport) Start() error {
+	if t.watcher != nil {
+		err := t.watcher.Start()
+		if err != nil {
+			t.logger.Error(E.Cause(err, "watch hosts file"))
+		}
+	}
+	return nil
+}
+
+func (t *Transport) Reset() {
+}
+
+func (t *Transport) Close() error {
+	return common.Close(common.PtrOrNil(t.watcher))
+}
+
+func (t *Transport) Raw() bool {
+	return true
+}
+
+func (t *Transport) Exchange(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, error) {
+	question := message.Question[0]
+	response := &mDNS.Msg{
+		MsgHdr: mDNS.MsgHdr{
+			Id:                 message.Id,
+			Response:           true,
+			Authoritative:      true,
+			RecursionDesired:   message.RecursionDesired,
+			RecursionAvailable: true,
+			Rcode:              mDNS.RcodeSuccess,
+		},
+		Question: []mDNS.Question{question},
+	}
+	addresses, loaded := t.lookup(question.Name)
+	if !loaded {
+		response.Rcode = mDNS.RcodeNameError
+		return response, nil
+	}
+	header := mDNS.RR_Header{
+		Name:  question.Name,
+		Class: mDNS.ClassINET,
+	}
+	for _, address := range addresses {
+		if question.Qtype == mDNS.TypeA && address.Is4() {
+			header.Rrtype = mDNS.TypeA
+			response.Answer = append(response.Answer, &mDNS.A{Hdr: header, A: address.AsSlice()})
+		} else if question.Qtype == mDNS.TypeAAAA && address.Is6() {
+			header.Rrtype = mDNS.TypeAAAA
+			response.Answer = append(response.Answer, &mDNS.AAAA{Hdr: header, AAAA: address.AsSlice()})
+		}
+	}
+	return response, nil
+}
+
+func (t *Transport) Lookup(ctx context.Context, domain string, strategy dns.DomainStrategy) ([]netip.Addr, error) {
+	addresses, loaded := t.lookup(domain)
+	if !loaded {
+		return nil, dns.RCodeNameError
+	}
+	switch strategy {
+	case dns.DomainStrategyUseIPv4:
+		addresses = common.Filter(addresses, netip.Addr.Is4)
+	case dns.DomainStrategyUseIPv6:
+		addresses = common.Filter(addresses, netip.Addr.Is6)
+	}
+	return addresses, nil
+}
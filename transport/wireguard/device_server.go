@@ -0,0 +1,203 @@
+//go:build with_gvisor
+
+package wireguard
+
+import (
+	"os"
+
+	"github.com/sagernet/gvisor/pkg/buffer"
+	"github.com/sagernet/gvisor/pkg/tcpip"
+	"github.com/sagernet/gvisor/pkg/tcpip/header"
+	"github.com/sagernet/gvisor/pkg/tcpip/stack"
+	"github.com/sagernet/sing-tun"
+	"github.com/sagernet/sing/common/buf"
+	wgTun "github.com/sagernet/wireguard-go/tun"
+)
+
+var _ wgTun.Device = (*ServerDevice)(nil)
+
+// ServerDevice connects a WireGuard device to a tun stack, which accepts the
+// decrypted connections of all peers.
+type ServerDevice struct {
+	mtu        uint32
+	events     chan wgTun.Event
+	outbound   chan *stack.PacketBuffer
+	done       chan struct{}
+	dispatcher stack.NetworkDispatcher
+}
+
+func NewServerDevice(mtu uint32) (*ServerDevice, error) {
+	return &ServerDevice{
+		mtu:      mtu,
+		events:   make(chan wgTun.Event, 1),
+		outbound: make(chan *stack.PacketBuffer, 256),
+		done:     make(chan struct{}),
+	}, nil
+}
+
+// Tun returns the tun to create the stack with.
+func (w *ServerDevice) Tun() tun.Tun {
+	return (*serverTun)(w)
+}
+
+func (w *ServerDevice) Start() error {
+	w.events <- wgTun.EventUp
+	return nil
+}
+
+func (w *ServerDevice) File() *os.File {
+	return nil
+}
+
+func (w *ServerDevice) Read(bufs [][]byte, sizes []int, offset int) (count int, err error) {
+	select {
+	case packetBuffer, ok := <-w.outbound:
+		if !ok {
+			return 0, os.ErrClosed
+		}
+		defer packetBuffer.DecRef()
+		p := bufs[0]
+		p = p[offset:]
+		n := 0
+		for _, slice := range packetBuffer.AsSlices() {
+			n += copy(p[n:], slice)
+		}
+		sizes[0] = n
+		count = 1
+		return
+	case <-w.done:
+		return 0, os.ErrClosed
+	}
+}
+
+func (w *ServerDevice) Write(bufs [][]byte, offset int) (count int, err error) {
+	if w.dispatcher == nil {
+		return 0, os.ErrInvalid
+	}
+	for _, b := range bufs {
+		b = b[offset:]
+		if len(b) == 0 {
+			continue
+		}
+		var networkProtocol tcpip.NetworkProtocolNumber
+		switch header.IPVersion(b) {
+		case header.IPv4Version:
+			networkProtocol = header.IPv4ProtocolNumber
+		case header.IPv6Version:
+			networkProtocol = header.IPv6ProtocolNumber
+		}
+		packetBuffer := stack.NewPacketBuffer(stack.PacketBufferOptions{
+			Payload: buffer.MakeWithData(b),
+		})
+		w.dispatcher.DeliverNetworkPacket(networkProtocol, packetBuffer)
+		packetBuffer.DecRef()
+		count++
+	}
+	return
+}
+
+func (w *ServerDevice) MTU() (int, error) {
+	return int(w.mtu), nil
+}
+
+func (w *ServerDevice) Name() (string, error) {
+	return "sing-box", nil
+}
+
+func (w *ServerDevice) Events() <-chan wgTun.Event {
+	return w.events
+}
+
+func (w *ServerDevice) Close() error {
+	select {
+	case <-w.done:
+		return os.ErrClosed
+	default:
+	}
+	close(w.done)
+	return nil
+}
+
+func (w *ServerDevice) BatchSize() int {
+	return 1
+}
+
+var _ tun.GVisorTun = (*serverTun)(nil)
+
+type serverTun ServerDevice
+
+func (t *serverTun) NewEndpoint() (stack.LinkEndpoint, error) {
+	return (*serverEndpoint)(t), nil
+}
+
+func (t *serverTun) Read(p []byte) (n int, err error) {
+	return 0, os.ErrInvalid
+}
+
+// Write discards packets not handled by the stack, such as broadcast packets.
+func (t *serverTun) Write(p []byte) (n int, err error) {
+	return len(p), nil
+}
+
+func (t *serverTun) WriteVectorised(buffers []*buf.Buffer) error {
+	buf.ReleaseMulti(buffers)
+	return nil
+}
+
+func (t *serverTun) Close() error {
+	return nil
+}
+
+var _ stack.LinkEndpoint = (*serverEndpoint)(nil)
+
+type serverEndpoint ServerDevice
+
+func (ep *serverEndpoint) MTU() uint32 {
+	return ep.mtu
+}
+
+func (ep *serverEndpoint) MaxHeaderLength() uint16 {
+	return 0
+}
+
+func (ep *serverEndpoint) LinkAddress() tcpip.LinkAddress {
+	return ""
+}
+
+func (ep *serverEndpoint) Capabilities() stack.LinkEndpointCapabilities {
+	return stack.CapabilityRXChecksumOffload
+}
+
+func (ep *serverEndpoint) Attach(dispatcher stack.NetworkDispatcher) {
+	ep.dispatcher = dispatcher
+}
+
+func (ep *serverEndpoint) IsAttached() bool {
+	return ep.dispatcher != nil
+}
+
+func (ep *serverEndpoint) Wait() {
+}
+
+func (ep *serverEndpoint) ARPHardwareType() header.ARPHardwareType {
+	return header.ARPHardwareNone
+}
+
+func (ep *serverEndpoint) AddHeader(buffer *stack.PacketBuffer) {
+}
+
+func (ep *serverEndpoint) ParseHeader(ptr *stack.PacketBuffer) bool {
+	return true
+}
+
+func (ep *serverEndpoint) WritePackets(list stack.PacketBufferList) (int, tcpip.Error) {
+	for _, packetBuffer := range list.AsSlice() {
+		packetBuffer.IncRef()
+		select {
+		case <-ep.done:
+			return 0, &tcpip.ErrClosedForSend{}
+		case ep.outbound <- packetBuffer:
+		}
+	}
+	return list.Len(), nil
+}
@@ -25,7 +25,9 @@ type PeerConfig struct {
 
 func (c PeerConfig) GenerateIpcLines() string {
 	ipcLines := "\npublic_key=" + c.PublicKey
-	ipcLines += "\nendpoint=" + c.Endpoint.String()
+	if c.Endpoint.IsValid() {
+		ipcLines += "\nendpoint=" + c.Endpoint.String()
+	}
 	if c.PreSharedKey != "" {
 		ipcLines += "\npreshared_key=" + c.PreSharedKey
 	}
@@ -125,6 +127,46 @@ func ParsePeers(options option.WireGuardOutboundOptions) ([]PeerConfig, error) {
 	return peers, nil
 }
 
+// ParseInboundPeers parses peers of a WireGuard inbound, whose endpoints are optional
+// and learned from handshakes if not set.
+func ParseInboundPeers(options []option.WireGuardPeer) ([]PeerConfig, error) {
+	if len(options) == 0 {
+		return nil, E.New("missing peers")
+	}
+	var peers []PeerConfig
+	for peerIndex, rawPeer := range options {
+		peer := PeerConfig{
+			AllowedIPs: rawPeer.AllowedIPs,
+		}
+		if rawPeer.Server != "" {
+			destination := rawPeer.ServerOptions.Build()
+			if !destination.IsIP() {
+				return nil, E.New("endpoint of peer ", peerIndex, " must be an IP address")
+			}
+			peer.Endpoint = destination.AddrPort()
+		}
+		{
+			bytes, err := base64.StdEncoding.DecodeString(rawPeer.PublicKey)
+			if err != nil {
+				return nil, E.Cause(err, "decode public key for peer ", peerIndex)
+			}
+			peer.PublicKey = hex.EncodeToString(bytes)
+		}
+		if rawPeer.PreSharedKey != "" {
+			bytes, err := base64.StdEncoding.DecodeString(rawPeer.PreSharedKey)
+			if err != nil {
+				return nil, E.Cause(err, "decode pre shared key for peer ", peerIndex)
+			}
+			peer.PreSharedKey = hex.EncodeToString(bytes)
+		}
+		if len(rawPeer.AllowedIPs) == 0 {
+			return nil, E.New("missing allowed_ips for peer ", peerIndex)
+		}
+		peers = append(peers, peer)
+	}
+	return peers, nil
+}
+
 func ResolvePeers(ctx context.Context, router adapter.Router, peers []PeerConfig) error {
 	for peerIndex, peer := range peers {
 		if peer.Endpoint.IsValid() {
@@ -0,0 +1,104 @@
+package wireguard
+
+import (
+	"net"
+	"net/netip"
+	"sync"
+
+	"github.com/sagernet/sing/common"
+	M "github.com/sagernet/sing/common/metadata"
+	"github.com/sagernet/wireguard-go/conn"
+)
+
+var _ conn.Bind = (*ServerBind)(nil)
+
+// ServerBind receives packets from all peers on a single listening UDP socket.
+type ServerBind struct {
+	listen     func() (net.PacketConn, error)
+	connAccess sync.Mutex
+	conn       net.PacketConn
+}
+
+func NewServerBind(listen func() (net.PacketConn, error)) *ServerBind {
+	return &ServerBind{
+		listen: listen,
+	}
+}
+
+func (s *ServerBind) Open(port uint16) (fns []conn.ReceiveFunc, actualPort uint16, err error) {
+	s.connAccess.Lock()
+	defer s.connAccess.Unlock()
+	if s.conn != nil {
+		return nil, 0, conn.ErrBindAlreadyOpen
+	}
+	udpConn, err := s.listen()
+	if err != nil {
+		return nil, 0, err
+	}
+	s.conn = udpConn
+	return []conn.ReceiveFunc{s.receiveFunc(udpConn)}, M.SocksaddrFromNet(udpConn.LocalAddr()).Port, nil
+}
+
+func (s *ServerBind) receiveFunc(udpConn net.PacketConn) conn.ReceiveFunc {
+	return func(packets [][]byte, sizes []int, eps []conn.Endpoint) (count int, err error) {
+		n, addr, err := udpConn.ReadFrom(packets[0])
+		if err != nil {
+			return
+		}
+		sizes[0] = n
+		if n > 3 {
+			b := packets[0]
+			common.ClearArray(b[1:4])
+		}
+		eps[0] = Endpoint(M.AddrPortFromNet(addr))
+		count = 1
+		return
+	}
+}
+
+func (s *ServerBind) Close() error {
+	s.connAccess.Lock()
+	defer s.connAccess.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+func (s *ServerBind) SetMark(mark uint32) error {
+	return nil
+}
+
+func (s *ServerBind) Send(bufs [][]byte, ep conn.Endpoint) error {
+	s.connAccess.Lock()
+	udpConn := s.conn
+	s.connAccess.Unlock()
+	if udpConn == nil {
+		return net.ErrClosed
+	}
+	destination := M.SocksaddrFromNetIP(netip.AddrPort(ep.(Endpoint))).UDPAddr()
+	for _, b := range bufs {
+		_, err := udpConn.WriteTo(b, destination)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *ServerBind) ParseEndpoint(str string) (conn.Endpoint, error) {
+	ap, err := netip.ParseAddrPort(str)
+	if err != nil {
+		return nil, err
+	}
+	return Endpoint(ap), nil
+}
+
+func (s *ServerBind) BatchSize() int {
+	return 1
+}
+
+func (s *ServerBind) SetReservedForEndpoint(destination netip.AddrPort, reserved [3]byte) {
+}